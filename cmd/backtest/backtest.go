@@ -0,0 +1,250 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/pkg/backtest"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+)
+
+var (
+	strategyName string
+	instanceID   string
+	fromDate     string
+	toDate       string
+	dataDir      string
+	initialCash  float64
+	runAt        string
+	fillRule     string
+	equityOut    string
+	outputFormat string
+	showTrades   bool
+	verbose      bool
+	// configParams are the strategy parameters from --config, applied beneath any swept values
+	configParams map[string]string
+)
+
+// NewBacktestCmd creates the backtest command
+func NewBacktestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backtest",
+		Short: "Backtest a trading strategy on historical data",
+		Long: `Replay historical daily bars, intraday bars and option snapshots from local CSV files
+through a strategy using a simulated broker, and report performance and risk metrics
+(CAGR, max drawdown, Sharpe, Sortino, win rate, holding period, return per trade, exposure).
+
+The data directory is expected to contain:
+- daily/<SYMBOL>.csv: date,open,high,low,close,volume
+- intraday/<SYMBOL>.csv: timestamp,open,high,low,close,volume (optional)
+- options/<UNDERLYING>.csv: date,symbol,bid,ask,delta[,gamma,theta,vega,rho,implied_volatility]`,
+		RunE: runBacktest,
+	}
+
+	// Add flags
+	addDataFlags(cmd)
+	cmd.Flags().StringVar(&equityOut, "equity-out", "", "Write the daily equity curve to this CSV file")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Report format: table or json")
+	cmd.Flags().BoolVar(&showTrades, "trades", false, "List every trade after the report (table output only)")
+
+	return cmd
+}
+
+func runBacktest(cmd *cobra.Command, args []string) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return fmt.Errorf("unknown output format: %s", outputFormat)
+	}
+
+	if err := loadConfigParams(cmd); err != nil {
+		return err
+	}
+
+	// Create strategy factory based on name
+	factory, err := buildFactory(nil)
+	if err != nil {
+		return err
+	}
+
+	data, config, err := loadBacktest()
+	if err != nil {
+		return err
+	}
+
+	backtester := backtest.NewBacktester(data, config, factory)
+
+	log.Printf("Backtesting strategy %s from %s to %s", strategyName, fromDate, toDate)
+
+	if !verbose {
+		log.SetOutput(io.Discard)
+	}
+	result, err := backtester.Run(context.Background())
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to run backtest: %w", err)
+	}
+
+	if equityOut != "" {
+		f, err := os.Create(equityOut)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", equityOut, err)
+		}
+		defer f.Close()
+		if err := backtest.WriteEquityCurveCSV(f, result.EquityCurve); err != nil {
+			return fmt.Errorf("failed to write equity curve: %w", err)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	rep := result.Report()
+	if outputFormat == "json" {
+		return rep.WriteJSON(out)
+	}
+
+	fmt.Fprintf(out, "Strategy: %s\n", strategyName)
+	if result.RunErrors > 0 {
+		fmt.Fprintf(out, "Failed runs: %d\n", result.RunErrors)
+	}
+	fmt.Fprintln(out)
+	if err := rep.WriteTable(out); err != nil {
+		return err
+	}
+
+	if showTrades {
+		fmt.Fprintln(out)
+		for _, trade := range result.Trades {
+			fmt.Fprintf(out, "  %-22s x%-3d  %s @ %s -> %s @ %s  %+8.2f%%  (%s)\n",
+				trade.Symbol, trade.Quantity,
+				trade.EntryDate.Format("2006-01-02"), trade.EntryPrice,
+				trade.ExitDate.Format("2006-01-02"), trade.ExitPrice,
+				trade.ReturnPercent(), trade.ExitReason)
+		}
+	}
+
+	return nil
+}
+
+// loadBacktest parses the flags shared by backtest and sweep and loads the historical data
+func loadBacktest() (*backtest.Data, backtest.Config, error) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return nil, backtest.Config{}, fmt.Errorf("failed to load exchange time zone: %w", err)
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", fromDate, loc)
+	if err != nil {
+		return nil, backtest.Config{}, fmt.Errorf("invalid --from date: %w", err)
+	}
+	to, err := time.ParseInLocation("2006-01-02", toDate, loc)
+	if err != nil {
+		return nil, backtest.Config{}, fmt.Errorf("invalid --to date: %w", err)
+	}
+	if to.Before(from) {
+		return nil, backtest.Config{}, fmt.Errorf("--to must not be before --from")
+	}
+
+	runAtTime, err := time.Parse("15:04", runAt)
+	if err != nil {
+		return nil, backtest.Config{}, fmt.Errorf("invalid --run-at time: %w", err)
+	}
+
+	var rule sim.FillRule
+	switch fillRule {
+	case "limit":
+		rule = sim.FillAtLimit
+	case "marketable":
+		rule = sim.FillWhenMarketable
+	default:
+		return nil, backtest.Config{}, fmt.Errorf("unknown fill rule: %s", fillRule)
+	}
+
+	data, err := backtest.LoadData(dataDir, loc)
+	if err != nil {
+		return nil, backtest.Config{}, fmt.Errorf("failed to load historical data: %w", err)
+	}
+
+	return data, backtest.Config{
+		From:        from,
+		To:          to,
+		InitialCash: money.NewFromFloat(initialCash),
+		RunAt:       time.Duration(runAtTime.Hour())*time.Hour + time.Duration(runAtTime.Minute())*time.Minute,
+		Location:    loc,
+		FillRule:    rule,
+	}, nil
+}
+
+// loadConfigParams loads the parameters of the strategy instance from the --config file, if one is given
+func loadConfigParams(cmd *cobra.Command) error {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+	if _, err := strategies.Lookup(strategyName); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	instances, err := cfg.Instances(strategyName, instanceID)
+	if err != nil {
+		return err
+	}
+	if len(instances) > 1 {
+		return fmt.Errorf("config has %d instances of %s; choose one with --id", len(instances), strategyName)
+	}
+	if instanceID == "" {
+		instanceID = instances[0].InstanceID()
+	}
+	configParams, err = cfg.Overrides(instances[0])
+	return err
+}
+
+// buildFactory returns the factory of the named strategy with the config parameters and the given
+// overrides applied
+func buildFactory(params map[string]string) (backtest.StrategyFactory, error) {
+	def, err := strategies.Lookup(strategyName)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]string, len(configParams)+len(params))
+	for name, value := range configParams {
+		overrides[name] = value
+	}
+	for name, value := range params {
+		overrides[name] = value
+	}
+	if _, err := def.Resolve(overrides); err != nil {
+		return nil, err
+	}
+
+	return func(broker broker.Broker) (strategies.Strategy, error) {
+		return def.Build(instanceID, broker, overrides)
+	}, nil
+}
+
+// addDataFlags adds the flags shared by backtest and sweep
+func addDataFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&strategyName, "name", "n", "", "Name of the strategy to backtest (required)")
+	cmd.Flags().StringVar(&instanceID, "id", "", "ID of the configured strategy instance whose parameters to use")
+	cmd.Flags().StringVar(&fromDate, "from", "", "First day of the backtest, YYYY-MM-DD (required)")
+	cmd.Flags().StringVar(&toDate, "to", "", "Last day of the backtest, YYYY-MM-DD (required)")
+	cmd.Flags().StringVar(&dataDir, "data", "data", "Directory containing the historical data")
+	cmd.Flags().Float64Var(&initialCash, "cash", 100000, "Starting cash")
+	cmd.Flags().StringVar(&runAt, "run-at", "09:35", "Time of day (exchange time) at which the strategy runs")
+	cmd.Flags().StringVar(&fillRule, "fill-rule", "limit", "How entry orders fill: limit (always at the limit price) or marketable (only once the ask reaches the limit)")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show strategy logs while replaying")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("to")
+}
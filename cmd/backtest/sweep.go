@@ -0,0 +1,108 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/pkg/backtest"
+)
+
+var (
+	gridEntries []string
+	workers     int
+	rankBy      string
+	sweepOut    string
+)
+
+// NewSweepCmd creates the sweep command
+func NewSweepCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sweep",
+		Short: "Backtest a strategy across a grid of parameter values",
+		Long: `Run one backtest per combination of the given parameter values, rank the
+results by a report metric and write them to CSV.
+
+Example:
+  athenax sweep --name two-percent-down --from 2020-01-01 --to 2024-12-31 \
+    --param gap_threshold=-1.5,-2,-3 --param min_delta=0.6,0.7 --rank-by sharpe --out sweep.csv
+
+Run "athenax list-strategies" to see each strategy's parameters.`,
+		RunE: runSweep,
+	}
+
+	// Add flags
+	addDataFlags(cmd)
+	cmd.Flags().StringArrayVarP(&gridEntries, "param", "p", nil, "Parameter values to sweep as name=v1,v2,... (repeatable)")
+	cmd.Flags().IntVarP(&workers, "workers", "w", runtime.NumCPU(), "Maximum number of backtests to run concurrently")
+	cmd.Flags().StringVar(&rankBy, "rank-by", "sharpe", "Report metric to rank results by")
+	cmd.Flags().StringVar(&sweepOut, "out", "sweep.csv", "CSV file to write the ranked results to")
+	cmd.MarkFlagRequired("param")
+
+	return cmd
+}
+
+func runSweep(cmd *cobra.Command, args []string) error {
+	grid, err := backtest.ParseGrid(gridEntries)
+	if err != nil {
+		return err
+	}
+
+	if err := loadConfigParams(cmd); err != nil {
+		return err
+	}
+
+	// Validate every combination before spending time on the backtests
+	combinations := grid.Combinations()
+	for _, params := range combinations {
+		if _, err := buildFactory(params); err != nil {
+			return err
+		}
+	}
+
+	data, config, err := loadBacktest()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sweeping strategy %s over %d parameter combinations with %d workers", strategyName, len(combinations), workers)
+
+	if !verbose {
+		log.SetOutput(io.Discard)
+	}
+	results := backtest.Sweep(context.Background(), data, config, grid, buildFactory, workers)
+	log.SetOutput(os.Stderr)
+
+	if err := backtest.RankSweep(results, rankBy); err != nil {
+		return err
+	}
+
+	f, err := os.Create(sweepOut)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", sweepOut, err)
+	}
+	defer f.Close()
+	if err := backtest.WriteSweepCSV(f, grid, results); err != nil {
+		return fmt.Errorf("failed to write sweep results: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Ran %d backtests, results written to %s\n", len(results), sweepOut)
+	for i, result := range results {
+		if i == 5 {
+			break
+		}
+		if result.Err != nil {
+			fmt.Fprintf(out, "%d. %v: error: %v\n", i+1, result.Params, result.Err)
+			continue
+		}
+		value, _ := result.Report.Metric(rankBy)
+		fmt.Fprintf(out, "%d. %v: %s=%.4f\n", i+1, result.Params, rankBy, value)
+	}
+
+	return nil
+}
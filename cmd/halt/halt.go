@@ -0,0 +1,129 @@
+package halt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
+	"github.com/vignesh-goutham/AthenaX/pkg/halt"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+)
+
+var reason string
+
+// NewHaltCmd creates the halt command
+func NewHaltCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "halt",
+		Short: "Stop all order submission until resumed",
+		Long: `Set the halt flag in the configured state store. While it is set no order is submitted, buying
+or selling: strategies and the position manager are skipped with a notification, and client-managed
+exits aren't enforced. Cancellations still go through, and orders already resting at the broker,
+including native bracket legs, are left in place.
+
+The flag is shared through the state store, so halting from the CLI against the S3 backend stops
+the Lambda on its next run, or its next order, without a redeploy. It also trips on its own after
+risk.halt_after_order_failures consecutive failed orders, or when the risk manager refuses an order
+for the daily loss limit.`,
+		Args: cobra.NoArgs,
+		RunE: runHalt,
+	}
+	cmd.Flags().StringVar(&reason, "reason", "", "Why trading is halted, shown in every blocked notification (required)")
+	_ = cmd.MarkFlagRequired("reason")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether trading is halted",
+		Args:  cobra.NoArgs,
+		RunE:  showStatus,
+	})
+	return cmd
+}
+
+// NewResumeCmd creates the resume command
+func NewResumeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Lift a halt and clear the order failure count",
+		Args:  cobra.NoArgs,
+		RunE:  runResume,
+	}
+}
+
+func runHalt(cmd *cobra.Command, args []string) error {
+	cfg, sw, err := open(cmd)
+	if err != nil {
+		return err
+	}
+
+	status, err := sw.Halt(context.Background(), halt.SourceManual, reason, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Trading %s\n", status)
+	_ = notify(cfg).Halted(fmt.Sprintf("Trading %s\nRun athenax resume to trade again.", status))
+	return nil
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	cfg, sw, err := open(cmd)
+	if err != nil {
+		return err
+	}
+
+	previous, err := sw.Resume(context.Background())
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if !previous.Halted {
+		fmt.Fprintln(out, "Trading was not halted; the order failure count is cleared")
+		return nil
+	}
+	fmt.Fprintf(out, "Trading resumed; it was %s\n", previous)
+	_ = notify(cfg).Resumed(fmt.Sprintf("Trading resumed; it was %s", previous))
+	return nil
+}
+
+func showStatus(cmd *cobra.Command, args []string) error {
+	_, sw, err := open(cmd)
+	if err != nil {
+		return err
+	}
+	status, err := sw.Status(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Trading %s\n", status)
+	return nil
+}
+
+// open loads the config and the halt switch in its state store
+func open(cmd *cobra.Command) (*config.Config, *halt.Switch, error) {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := cfg.OpenStateStore()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	return cfg, cfg.HaltSwitch(store), nil
+}
+
+// notify returns the configured notification client, or one that sends nothing if it is misconfigured
+func notify(cfg *config.Config) *notification.Client {
+	notifier, err := notification.NewClient(cfg.Notification.Method, cfg.Notification.NoisyWebhookURL, cfg.Notification.NormalWebhookURL, cfg.Broker.Environment)
+	if err != nil {
+		return notification.NewNoopClient()
+	}
+	return notifier
+}
@@ -0,0 +1,415 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+var (
+	dbPath       string
+	fromDate     string
+	toDate       string
+	strategy     string
+	symbol       string
+	outputFormat string
+	environment  string
+)
+
+// NewJournalCmd creates the journal command
+func NewJournalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Query the trade journal",
+		Long: `Query the runs, trades, position manager decisions, rolls and risk rejections recorded in the trade journal,
+or report the performance of live or paper trading with the same metrics as a backtest.
+
+The journal is read from the configured state store (journal.path, default athenax.db, under
+state.path or in the S3 bucket), unless --db names a local journal file. Dates are exchange
+dates and both ends are inclusive.`,
+	}
+
+	runsCmd := &cobra.Command{
+		Use:   "runs",
+		Short: "List strategy runs with their decisions and signals",
+		Args:  cobra.NoArgs,
+		RunE:  listRuns,
+	}
+	tradesCmd := &cobra.Command{
+		Use:   "trades",
+		Short: "List order submissions with their fills and exits",
+		Args:  cobra.NoArgs,
+		RunE:  listTrades,
+	}
+	decisionsCmd := &cobra.Command{
+		Use:   "decisions",
+		Short: "List position manager decisions with the exit rules that fired",
+		Args:  cobra.NoArgs,
+		RunE:  listDecisions,
+	}
+	rollsCmd := &cobra.Command{
+		Use:   "rolls",
+		Short: "List rolls of positions into a later expiry, with both legs",
+		Args:  cobra.NoArgs,
+		RunE:  listRolls,
+	}
+	rejectionsCmd := &cobra.Command{
+		Use:   "rejections",
+		Short: "List orders the risk manager rejected, with the limit each would have breached",
+		Args:  cobra.NoArgs,
+		RunE:  listRejections,
+	}
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report performance and risk metrics of journaled trading, as a backtest does",
+		Long: `Compute the backtest report from the journal: the equity curve is the account equity recorded
+by the last run of each exchange day, and each exit of a filled buy order is a closed trade. Open
+positions aren't counted as trades. --strategy and --symbol narrow the trades only, since the
+equity is the whole account's.`,
+		Args: cobra.NoArgs,
+		RunE: showReport,
+	}
+	reportCmd.Flags().StringVar(&environment, "env", "", "Trading environment to report, paper or live (required when the journal has both)")
+
+	for _, sub := range []*cobra.Command{runsCmd, tradesCmd, decisionsCmd, rollsCmd, rejectionsCmd, reportCmd} {
+		sub.Flags().StringVar(&dbPath, "db", "", "Path to a local journal file (defaults to the journal in the configured state store)")
+		sub.Flags().StringVar(&fromDate, "from", "", "First day to include, YYYY-MM-DD")
+		sub.Flags().StringVar(&toDate, "to", "", "Last day to include, YYYY-MM-DD")
+		sub.Flags().StringVar(&strategy, "strategy", "", "Only include this strategy instance ID or strategy name")
+		sub.Flags().StringVar(&symbol, "symbol", "", "Only include orders in this option symbol or underlying")
+		sub.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table or json")
+		cmd.AddCommand(sub)
+	}
+
+	return cmd
+}
+
+func listRuns(cmd *cobra.Command, args []string) error {
+	j, filter, err := openJournal(cmd)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	runs, err := j.Runs(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return writeJSON(out, runs)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tENV\tINSTANCE\tSTRATEGY\tOUTCOME\tSIGNALS\tMESSAGE")
+	for _, run := range runs {
+		message := run.Message
+		if run.Error != "" {
+			message = run.Error
+		}
+		if run.DryRun {
+			message = "[dry run] " + message
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			run.StartedAt.In(exchangeLocation()).Format("2006-01-02 15:04"), run.Environment, run.InstanceID,
+			run.Strategy, run.Outcome, signalsSummary(run.Signals), message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d runs\n", len(runs))
+	return nil
+}
+
+func listTrades(cmd *cobra.Command, args []string) error {
+	j, filter, err := openJournal(cmd)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	trades, err := j.Trades(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return writeJSON(out, trades)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUBMITTED\tENV\tINSTANCE\tSYMBOL\tSIDE\tQTY\tLIMIT\tSTATUS\tFILL\tEXITED\tP&L\tORDER ID")
+	filled, realized := 0, money.Zero
+	for _, trade := range trades {
+		fill := "-"
+		if trade.Fill != nil {
+			fill = fmt.Sprintf("%g @ %s", trade.Fill.Qty, trade.Fill.Price)
+			filled++
+		}
+		exited := "-"
+		if len(trade.Exits) > 0 {
+			reasons := make([]string, 0, len(trade.Exits))
+			for _, exit := range trade.Exits {
+				reasons = append(reasons, fmt.Sprintf("%g @ %s %s", exit.Qty, exit.Price, exit.Reason))
+			}
+			exited = strings.Join(reasons, ", ")
+		}
+		pnl := trade.RealizedPnL()
+		realized = realized.Add(pnl)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%g\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			trade.SubmittedAt.In(exchangeLocation()).Format("2006-01-02 15:04"), trade.Environment, trade.InstanceID,
+			trade.Symbol, trade.Side, trade.Qty, trade.LimitPrice, trade.Status, fill, exited, pnl.Signed(), trade.AlpacaOrderID)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d orders, %d filled, realized P&L %s\n", len(trades), filled, realized.Signed())
+	return nil
+}
+
+func listDecisions(cmd *cobra.Command, args []string) error {
+	j, filter, err := openJournal(cmd)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	decisions, err := j.PositionDecisions(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return writeJSON(out, decisions)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AT\tENV\tSYMBOL\tQTY\tENTRY\tMARK\tHIGH\tDELTA\tHELD\tDTE\tACTION\tRULE\tREASON")
+	exits := 0
+	for _, decision := range decisions {
+		action := decision.Action
+		if decision.DryRun {
+			action += " (dry run)"
+		}
+		if decision.Action == "exit" {
+			exits++
+		}
+		reason := decision.Reason
+		if decision.Error != "" {
+			reason += ": " + decision.Error
+		}
+		rule := decision.Rule
+		if rule == "" {
+			rule = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%g\t%s\t%s\t%s\t%.2f\t%d\t%d\t%s\t%s\t%s\n",
+			decision.At.In(exchangeLocation()).Format("2006-01-02 15:04"), decision.Environment, decision.Symbol,
+			decision.Qty, decision.EntryPrice, decision.Mark, decision.HighWater, decision.Delta, decision.DaysHeld,
+			decision.DaysToExpiry, action, rule, reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d decisions, %d exits\n", len(decisions), exits)
+	return nil
+}
+
+func listRolls(cmd *cobra.Command, args []string) error {
+	j, filter, err := openJournal(cmd)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	rolls, err := j.Rolls(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return writeJSON(out, rolls)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AT\tENV\tINSTANCE\tMETHOD\tCLOSED\tQTY\tPRICE\tOPENED\tQTY\tPRICE\tSTATUS\tERROR")
+	rolled := 0
+	for _, roll := range rolls {
+		status := roll.Status
+		if roll.DryRun {
+			status += " (dry run)"
+		}
+		if roll.Status == "rolled" {
+			rolled++
+		}
+		rollErr := roll.Error
+		if rollErr == "" {
+			rollErr = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%g\t%s\t%s\t%g\t%s\t%s\t%s\n",
+			roll.At.In(exchangeLocation()).Format("2006-01-02 15:04"), roll.Environment, roll.InstanceID, roll.Method,
+			roll.CloseSymbol, roll.ClosedQty, roll.ClosePrice, roll.OpenSymbol, roll.OpenedQty, roll.OpenPrice, status, rollErr)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d rolls, %d rolled\n", len(rolls), rolled)
+	return nil
+}
+
+func listRejections(cmd *cobra.Command, args []string) error {
+	j, filter, err := openJournal(cmd)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	rejections, err := j.RiskRejections(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return writeJSON(out, rejections)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AT\tENV\tINSTANCE\tSYMBOL\tLIMIT\tVALUE\tTHRESHOLD\tREASON")
+	for _, rejection := range rejections {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%.2f\t%s\n",
+			rejection.At.In(exchangeLocation()).Format("2006-01-02 15:04"), rejection.Environment, rejection.InstanceID,
+			rejection.Symbol, rejection.Limit, rejection.Value, rejection.Threshold, rejection.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d rejections\n", len(rejections))
+	return nil
+}
+
+func showReport(cmd *cobra.Command, args []string) error {
+	j, filter, err := openJournal(cmd)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	ctx := context.Background()
+	env := environment
+	if env == "" {
+		environments, err := j.Environments(ctx)
+		if err != nil {
+			return err
+		}
+		if len(environments) > 1 {
+			return fmt.Errorf("the journal has runs in %s; choose one with --env", strings.Join(environments, " and "))
+		}
+		if len(environments) == 1 {
+			env = environments[0]
+		}
+	}
+
+	rep, err := j.Report(ctx, filter, env, exchangeLocation())
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return rep.WriteJSON(out)
+	}
+	fmt.Fprintf(out, "Environment: %s\n\n", env)
+	return rep.WriteTable(out)
+}
+
+// openJournal opens the journal named by --db or the config and builds the filter from the flags
+func openJournal(cmd *cobra.Command) (*journal.Journal, journal.Filter, error) {
+	filter := journal.Filter{Strategy: strategy, Symbol: symbol}
+	if outputFormat != "table" && outputFormat != "json" {
+		return nil, filter, fmt.Errorf("unsupported output format %q: use table or json", outputFormat)
+	}
+
+	loc := exchangeLocation()
+	if fromDate != "" {
+		from, err := time.ParseInLocation("2006-01-02", fromDate, loc)
+		if err != nil {
+			return nil, filter, fmt.Errorf("invalid --from date: %w", err)
+		}
+		filter.From = from
+	}
+	if toDate != "" {
+		to, err := time.ParseInLocation("2006-01-02", toDate, loc)
+		if err != nil {
+			return nil, filter, fmt.Errorf("invalid --to date: %w", err)
+		}
+		// Include the whole last day
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, filter, fmt.Errorf("--to must not be before --from")
+	}
+
+	if dbPath != "" {
+		j, err := journal.Open(dbPath)
+		return j, filter, err
+	}
+
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, filter, err
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, filter, err
+	}
+	store, err := cfg.OpenStateStore()
+	if err != nil {
+		return nil, filter, fmt.Errorf("failed to open state store: %w", err)
+	}
+	j, err := journal.OpenStore(context.Background(), store, cfg.Journal.Path)
+	return j, filter, err
+}
+
+// exchangeLocation is the time zone dates are given and shown in, falling back to UTC when the
+// time zone database is unavailable
+func exchangeLocation() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func signalsSummary(signals []journal.Signal) string {
+	if len(signals) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(signals))
+	for _, signal := range signals {
+		mark := ""
+		if signal.Triggered {
+			mark = "*"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%.2f%s", signal.Name, signal.Value, mark))
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
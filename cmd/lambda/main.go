@@ -4,18 +4,36 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
 	"github.com/vignesh-goutham/AthenaX/pkg/engine"
 	"github.com/vignesh-goutham/AthenaX/pkg/notification"
-	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
+	"github.com/vignesh-goutham/AthenaX/pkg/positions"
 )
 
+// ModePositionManager runs the position manager instead of the strategies
+const ModePositionManager = "position-manager"
+
 // LambdaEvent represents the input event for the Lambda function
 type LambdaEvent struct {
+	// Mode is empty to run strategies, or ModePositionManager to enforce exit rules on open positions
+	Mode string `json:"mode"`
+
+	// StrategyName runs every configured instance of the strategy, or one with defaults if none is configured
 	StrategyName string `json:"strategy_name"`
-	// Add other fields as needed for your use case
+	// InstanceID runs a single configured strategy instance
+	InstanceID string `json:"instance_id"`
+	// When both are empty every instance in the config runs
+
+	// DryRun computes, logs and notifies orders without sending them to the broker
+	DryRun bool `json:"dry_run"`
 }
 
 // LambdaResponse represents the response from the Lambda function
@@ -23,23 +41,67 @@ type LambdaResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 	Error   string `json:"error,omitempty"`
+	// Environment is the trading environment the run used
+	Environment string `json:"environment,omitempty"`
+	// Strategies holds each strategy instance's outcome once the engine has run
+	Strategies []engine.StrategyResult `json:"strategies,omitempty"`
+	// Positions holds the position manager's decisions once it has run
+	Positions []positions.Decision `json:"positions,omitempty"`
 }
 
 // Handler is the main Lambda function handler
 func Handler(ctx context.Context, event LambdaEvent) (LambdaResponse, error) {
 	log.Printf("Received event: %+v", event)
 
-	// Validate strategy name
-	if event.StrategyName == "" {
+	// Load and validate the config named by ATHENAX_CONFIG, if any
+	cfg, err := config.Load(os.Getenv(config.PathEnv))
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
 		return LambdaResponse{
 			Status:  "error",
-			Message: "Strategy name is required",
-			Error:   "strategy_name is empty",
+			Message: "Failed to load config",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	// Refuse to trade live unless ATHENAX_CONFIRM_LIVE is set, and tag every log line with the environment
+	if err := cfg.Broker.ConfirmLive(false); err != nil {
+		log.Printf("Refusing to run: %v", err)
+		return LambdaResponse{
+			Status:      "error",
+			Message:     "Live trading is not confirmed",
+			Error:       err.Error(),
+			Environment: cfg.Broker.Environment,
+		}, nil
+	}
+	log.SetPrefix("[" + strings.ToUpper(cfg.Broker.Environment) + "] ")
+	log.Printf("Trading environment: %s (%s)", cfg.Broker.Environment, cfg.Broker.Env().BaseURL())
+
+	switch event.Mode {
+	case "":
+	case ModePositionManager:
+		return managePositions(ctx, cfg, event), nil
+	default:
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Unknown mode",
+			Error:   fmt.Sprintf("mode must be empty or %q, got %q", ModePositionManager, event.Mode),
+		}, nil
+	}
+
+	// Select the strategy instances to run
+	instances, err := cfg.Instances(event.StrategyName, event.InstanceID)
+	if err != nil {
+		return LambdaResponse{
+			Status:  "error",
+			Message: "No strategy to run",
+			Error:   err.Error(),
 		}, nil
 	}
 
 	// Create broker client
-	broker, err := alpaca.NewClient()
+	credentials := cfg.Broker.Credentials()
+	broker, err := alpaca.NewClient(cfg.Broker.Env(), credentials.APIKey, credentials.SecretKey)
 	if err != nil {
 		log.Printf("Failed to create broker client: %v", err)
 		return LambdaResponse{
@@ -50,7 +112,7 @@ func Handler(ctx context.Context, event LambdaEvent) (LambdaResponse, error) {
 	}
 
 	// Create notification client
-	notifier, err := notification.NewClient()
+	notifier, err := notification.NewClient(cfg.Notification.Method, cfg.Notification.NoisyWebhookURL, cfg.Notification.NormalWebhookURL, cfg.Broker.Environment)
 	if err != nil {
 		log.Printf("Failed to create notification client: %v", err)
 		return LambdaResponse{
@@ -60,41 +122,211 @@ func Handler(ctx context.Context, event LambdaEvent) (LambdaResponse, error) {
 		}, nil
 	}
 
-	// Create strategy based on name
-	var strategy strategies.Strategy
-	switch event.StrategyName {
-	case "two-percent-down":
-		strategy = strategies.NewTwoPercentDown(broker, notifier)
-	default:
+	// Open the state store and the trade journal kept in it
+	store, err := cfg.OpenStateStore()
+	if err != nil {
+		log.Printf("Failed to open state store: %v", err)
 		return LambdaResponse{
 			Status:  "error",
-			Message: fmt.Sprintf("Unknown strategy: %s", event.StrategyName),
-			Error:   "unknown strategy",
+			Message: "Failed to open state store",
+			Error:   err.Error(),
 		}, nil
 	}
+	j, err := cfg.OpenJournal(ctx, store)
+	if err != nil {
+		log.Printf("Failed to open journal: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to open journal",
+			Error:   err.Error(),
+		}, nil
+	}
+	if j != nil {
+		defer func() {
+			if err := j.Close(); err != nil {
+				log.Printf("Failed to save journal: %v", err)
+				_ = notifier.ActionNeeded(fmt.Sprintf("Failed to save journal: %v", err), err)
+			}
+		}()
+	}
+
+	// Every order goes through the halt switch and the risk manager; dry-run instances compute theirs without them
+	sw := cfg.HaltSwitch(store)
+	gated := cfg.Gate(broker, sw, store, j)
+	strats, err := cfg.Build(instances, gated, event.DryRun)
+	if err != nil {
+		log.Printf("Failed to create strategies: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to create strategies",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	ids := make([]string, 0, len(strats))
+	for _, strategy := range strats {
+		ids = append(ids, strategy.ID())
+	}
+	names := strings.Join(ids, ", ")
 
-	// Create engine with the strategy
-	eng := engine.NewEngine([]strategies.Strategy{strategy}, broker, notifier)
+	// Create engine with the strategies
+	eng := engine.NewEngine(strats, broker, notifier)
+	eng.SetStrategyTimeout(cfg.Engine.Timeout())
+	eng.SetJournal(j)
+	eng.SetStateStore(store)
+	eng.SetHaltSwitch(sw)
+	if trackerConfig, ok := cfg.Orders.Tracker(); ok {
+		eng.SetOrderTracker(ordertracker.New(gated, trackerConfig))
+	}
+	// A dry run never holds positions to exit
+	if !event.DryRun {
+		eng.SetExitMonitor(bracket.NewMonitor(broker, store))
+	}
 
-	log.Printf("Running strategy: %s", event.StrategyName)
+	if event.DryRun {
+		log.Printf("DRY RUN: orders will be computed but not sent")
+	}
+	log.Printf("Running strategies: %s", names)
 
 	// Run the engine
-	if err := eng.Run(ctx); err != nil {
-		log.Printf("Failed to run strategy: %v", err)
+	result, err := eng.Run(ctx)
+	if err != nil {
+		log.Printf("Failed to run strategies: %v", err)
 		return LambdaResponse{
 			Status:  "error",
-			Message: "Failed to run strategy",
+			Message: "Failed to run strategies",
 			Error:   err.Error(),
 		}, nil
 	}
 
-	log.Printf("Strategy %s completed successfully", event.StrategyName)
+	log.Printf("Run finished: %s", result.Summary())
+	if failed := result.Failed(); failed > 0 {
+		return LambdaResponse{
+			Status:      "error",
+			Message:     result.Summary(),
+			Error:       fmt.Sprintf("%d of %d strategies failed", failed, len(result.Strategies)),
+			Environment: cfg.Broker.Environment,
+			Strategies:  result.Strategies,
+		}, nil
+	}
 	return LambdaResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("Strategy %s completed successfully", event.StrategyName),
+		Status:      "success",
+		Message:     result.Summary(),
+		Environment: cfg.Broker.Environment,
+		Strategies:  result.Strategies,
 	}, nil
 }
 
+// managePositions runs the position manager over the configured underlyings
+func managePositions(ctx context.Context, cfg *config.Config, event LambdaEvent) LambdaResponse {
+	rules := cfg.Positions.Rules()
+	roll := cfg.Positions.RollConfig()
+	if len(cfg.Positions.Underlyings) == 0 || (!rules.Enabled() && !roll.Enabled()) {
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Position manager is not configured",
+			Error:   "set positions.underlyings and at least one exit rule or positions.roll",
+		}
+	}
+
+	credentials := cfg.Broker.Credentials()
+	client, err := alpaca.NewClient(cfg.Broker.Env(), credentials.APIKey, credentials.SecretKey)
+	if err != nil {
+		log.Printf("Failed to create broker client: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to create broker client",
+			Error:   err.Error(),
+		}
+	}
+	notifier, err := notification.NewClient(cfg.Notification.Method, cfg.Notification.NoisyWebhookURL, cfg.Notification.NormalWebhookURL, cfg.Broker.Environment)
+	if err != nil {
+		log.Printf("Failed to create notification client: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to create notification client",
+			Error:   err.Error(),
+		}
+	}
+
+	store, err := cfg.OpenStateStore()
+	if err != nil {
+		log.Printf("Failed to open state store: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to open state store",
+			Error:   err.Error(),
+		}
+	}
+	j, err := cfg.OpenJournal(ctx, store)
+	if err != nil {
+		log.Printf("Failed to open journal: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to open journal",
+			Error:   err.Error(),
+		}
+	}
+	if j != nil {
+		defer func() {
+			if err := j.Close(); err != nil {
+				log.Printf("Failed to save journal: %v", err)
+				_ = notifier.ActionNeeded(fmt.Sprintf("Failed to save journal: %v", err), err)
+			}
+		}()
+	}
+
+	// Nothing is sold or rolled while trading is halted
+	sw := cfg.HaltSwitch(store)
+	if err := sw.Check(ctx); err != nil {
+		_ = notifier.Halted(fmt.Sprintf("Position manager not run: %v\nRun athenax resume to trade again.", err))
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Trading is halted",
+			Error:   err.Error(),
+		}
+	}
+
+	// Orders go through the halt switch and roll opens through the risk manager; a dry run computes its
+	// orders without them
+	b := cfg.Gate(client, sw, store, j)
+	if event.DryRun {
+		b = dryrun.NewBroker(b)
+		log.Printf("DRY RUN: exits will be computed but not sent")
+	}
+
+	manager := positions.NewManager(b, store, rules, notifier)
+	manager.SetJournal(j)
+	manager.SetExitMonitor(bracket.NewMonitor(client, store))
+	if roll.Enabled() {
+		manager.SetRoll(roll)
+	}
+
+	log.Printf("Checking option positions on: %s", strings.Join(cfg.Positions.Underlyings, ", "))
+	result, err := manager.Run(ctx, cfg.Positions.Underlyings)
+	if err != nil {
+		log.Printf("Failed to manage positions: %v", err)
+		return LambdaResponse{
+			Status:  "error",
+			Message: "Failed to manage positions",
+			Error:   err.Error(),
+		}
+	}
+
+	log.Printf("Run finished: %s", result.Summary())
+	response := LambdaResponse{
+		Status:      "success",
+		Message:     result.Summary(),
+		Environment: cfg.Broker.Environment,
+		Positions:   result.Decisions,
+	}
+	if failed := result.Failed(); failed > 0 {
+		response.Status = "error"
+		response.Error = fmt.Sprintf("%d of %d positions failed", failed, len(result.Decisions))
+	}
+	return response
+}
+
 func main() {
 	lambda.Start(Handler)
 }
@@ -0,0 +1,47 @@
+package liststrategies
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+)
+
+// NewListStrategiesCmd creates the list-strategies command
+func NewListStrategiesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list-strategies",
+		Short: "List available trading strategies and their parameters",
+		Args:  cobra.NoArgs,
+		RunE:  listStrategies,
+	}
+}
+
+func listStrategies(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	for i, def := range strategies.List() {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "%s\n  %s\n", def.Name, def.Description)
+		if len(def.Params) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(out, "\n  Parameters:")
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "    NAME\tTYPE\tDEFAULT\tDESCRIPTION")
+		for _, param := range def.Params {
+			description := param.Description
+			if param.Env != "" {
+				description += fmt.Sprintf(" (env: %s)", param.Env)
+			}
+			fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\n", param.Name, param.Type, param.Default, description)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
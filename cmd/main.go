@@ -5,7 +5,13 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/cmd/backtest"
+	"github.com/vignesh-goutham/AthenaX/cmd/halt"
+	"github.com/vignesh-goutham/AthenaX/cmd/journal"
+	"github.com/vignesh-goutham/AthenaX/cmd/liststrategies"
+	"github.com/vignesh-goutham/AthenaX/cmd/positionmanager"
 	"github.com/vignesh-goutham/AthenaX/cmd/runstrategy"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
 )
 
 func main() {
@@ -16,8 +22,18 @@ func main() {
 It provides various subcommands to run different trading strategies.`,
 	}
 
+	rootCmd.PersistentFlags().String("config", os.Getenv(config.PathEnv),
+		"Path to a YAML or JSON config file (defaults to $"+config.PathEnv+")")
+
 	// Add subcommands
 	rootCmd.AddCommand(runstrategy.NewRunStrategyCmd())
+	rootCmd.AddCommand(backtest.NewBacktestCmd())
+	rootCmd.AddCommand(backtest.NewSweepCmd())
+	rootCmd.AddCommand(liststrategies.NewListStrategiesCmd())
+	rootCmd.AddCommand(journal.NewJournalCmd())
+	rootCmd.AddCommand(positionmanager.NewPositionManagerCmd())
+	rootCmd.AddCommand(halt.NewHaltCmd())
+	rootCmd.AddCommand(halt.NewResumeCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -0,0 +1,150 @@
+package positionmanager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/positions"
+)
+
+var (
+	underlyings []string
+	dryRun      bool
+	confirmLive bool
+)
+
+// NewPositionManagerCmd creates the position-manager command
+func NewPositionManagerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "position-manager",
+		Short: "Enforce exit rules on open option positions and roll expiring calls",
+		Long: `Check every open long option position on the configured underlyings against the exit rules
+in the positions section of the config, and sell the positions a rule calls to close:
+
+- max_hold_days: held for this many calendar days
+- min_days_to_expiry: this many calendar days or fewer before expiry
+- trailing_stop_percent: fallen this far below the highest mark seen
+- min_delta: delta decayed below this value
+- profit_locks: gain fell back to the floor locked in by the highest rung reached
+
+With positions.roll.days_to_expiry set, calls that close in on expiry are rolled into the LEAP call
+selected by delta instead, before the exit rules apply. A roll is one multi-leg order where the broker
+supports it, and otherwise a close followed by an open that buys the position back if the open fails.
+
+Positions are checked whichever strategy opened them. Every decision is recorded in the journal.`,
+		Args: cobra.NoArgs,
+		RunE: runPositionManager,
+	}
+
+	cmd.Flags().StringSliceVarP(&underlyings, "underlying", "u", nil, "Underlying ticker to check, repeatable (defaults to positions.underlyings)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Evaluate and log exits without sending orders to the broker")
+	cmd.Flags().BoolVar(&confirmLive, "confirm-live", false, "Confirm trading with real money when broker.environment is live")
+
+	return cmd
+}
+
+func runPositionManager(cmd *cobra.Command, args []string) error {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Broker.ConfirmLive(confirmLive); err != nil {
+		return err
+	}
+	log.SetPrefix("[" + strings.ToUpper(cfg.Broker.Environment) + "] ")
+	log.Printf("Trading environment: %s (%s)", cfg.Broker.Environment, cfg.Broker.Env().BaseURL())
+
+	if len(underlyings) == 0 {
+		underlyings = cfg.Positions.Underlyings
+	}
+	if len(underlyings) == 0 {
+		return fmt.Errorf("no underlyings to check: pass --underlying or set positions.underlyings")
+	}
+	rules := cfg.Positions.Rules()
+	roll := cfg.Positions.RollConfig()
+	if !rules.Enabled() && !roll.Enabled() {
+		return fmt.Errorf("no exit rules or rolling configured in the positions section")
+	}
+
+	credentials := cfg.Broker.Credentials()
+	client, err := alpaca.NewClient(cfg.Broker.Env(), credentials.APIKey, credentials.SecretKey)
+	if err != nil {
+		return fmt.Errorf("failed to create broker client: %w", err)
+	}
+	notifier, err := notification.NewClient(cfg.Notification.Method, cfg.Notification.NoisyWebhookURL, cfg.Notification.NormalWebhookURL, cfg.Broker.Environment)
+	if err != nil {
+		return fmt.Errorf("failed to create notification client: %w", err)
+	}
+
+	ctx := context.Background()
+	store, err := cfg.OpenStateStore()
+	if err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
+	j, err := cfg.OpenJournal(ctx, store)
+	if err != nil {
+		return err
+	}
+	if j != nil {
+		defer func() {
+			if err := j.Close(); err != nil {
+				log.Printf("Failed to save journal: %v", err)
+				_ = notifier.ActionNeeded(fmt.Sprintf("Failed to save journal: %v", err), err)
+			}
+		}()
+	}
+
+	// Nothing is sold or rolled while trading is halted
+	sw := cfg.HaltSwitch(store)
+	if err := sw.Check(ctx); err != nil {
+		_ = notifier.Halted(fmt.Sprintf("Position manager not run: %v\nRun athenax resume to trade again.", err))
+		return err
+	}
+
+	// Orders go through the halt switch and roll opens through the risk manager; a dry run computes its
+	// orders without them
+	b := cfg.Gate(client, sw, store, j)
+	if dryRun {
+		b = dryrun.NewBroker(b)
+		log.Printf("DRY RUN: exits will be computed but not sent")
+	}
+
+	manager := positions.NewManager(b, store, rules, notifier)
+	manager.SetJournal(j)
+	manager.SetExitMonitor(bracket.NewMonitor(client, store))
+	if roll.Enabled() {
+		manager.SetRoll(roll)
+	}
+
+	log.Printf("Checking option positions on: %s", strings.Join(underlyings, ", "))
+	result, err := manager.Run(ctx, underlyings)
+	if err != nil {
+		return fmt.Errorf("failed to manage positions: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out, result.Summary())
+	if len(result.Decisions) > 0 {
+		if err := result.WriteTable(out); err != nil {
+			return err
+		}
+	}
+
+	if failed := result.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d positions failed", failed, len(result.Decisions))
+	}
+	return nil
+}
@@ -4,71 +4,163 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
 	"github.com/vignesh-goutham/AthenaX/pkg/engine"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
 	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
 	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
 )
 
 var (
 	strategyName string
+	instanceID   string
+	dryRun       bool
+	confirmLive  bool
 )
 
 // NewRunStrategyCmd creates the run-strategy command
 func NewRunStrategyCmd() *cobra.Command {
+	long := `Run trading strategies in a single engine run.
+
+With --name, runs every instance of that strategy declared in the config file, or a single
+instance with default parameters when the config has none. With --id, runs the one configured
+instance with that ID. With neither, runs every instance in the config file.
+Available strategies:`
+	for _, def := range strategies.List() {
+		long += fmt.Sprintf("\n- %s: %s", def.Name, def.Description)
+	}
+
 	cmd := &cobra.Command{
 		Use:   "run-strategy",
-		Short: "Run a specific trading strategy",
-		Long: `Run a specific trading strategy by name.
-Available strategies:
-- two-percent-down: Executes the 2% gap down strategy`,
-		RunE: runStrategy,
+		Short: "Run trading strategies",
+		Long:  long,
+		RunE:  runStrategy,
 	}
 
 	// Add flags
-	cmd.Flags().StringVarP(&strategyName, "name", "n", "", "Name of the strategy to run (required)")
-	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&strategyName, "name", "n", "", "Name of the strategy to run")
+	cmd.Flags().StringVar(&instanceID, "id", "", "ID of the configured strategy instance to run")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Compute, log and notify orders without sending them to the broker")
+	cmd.Flags().BoolVar(&confirmLive, "confirm-live", false, "Confirm trading with real money when broker.environment is live")
 
 	return cmd
 }
 
 func runStrategy(cmd *cobra.Command, args []string) error {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+
+	// Load and validate the config
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to trade live without explicit confirmation, and tag every log line with the environment
+	if err := cfg.Broker.ConfirmLive(confirmLive); err != nil {
+		return err
+	}
+	log.SetPrefix("[" + strings.ToUpper(cfg.Broker.Environment) + "] ")
+	log.Printf("Trading environment: %s (%s)", cfg.Broker.Environment, cfg.Broker.Env().BaseURL())
+
+	// Select the strategy instances to run
+	instances, err := cfg.Instances(strategyName, instanceID)
+	if err != nil {
+		return err
+	}
+
 	// Create broker client
-	broker, err := alpaca.NewClient()
+	credentials := cfg.Broker.Credentials()
+	broker, err := alpaca.NewClient(cfg.Broker.Env(), credentials.APIKey, credentials.SecretKey)
 	if err != nil {
 		return fmt.Errorf("failed to create broker client: %w", err)
 	}
 
 	// Create notification client
-	notifier, err := notification.NewClient()
+	notifier, err := notification.NewClient(cfg.Notification.Method, cfg.Notification.NoisyWebhookURL, cfg.Notification.NormalWebhookURL, cfg.Broker.Environment)
 	if err != nil {
 		return fmt.Errorf("failed to create notification client: %w", err)
 	}
 
-	// Create strategy based on name
-	var strategy strategies.Strategy
-	switch strategyName {
-	case "two-percent-down":
-		strategy = strategies.NewTwoPercentDown(broker, notifier)
-	default:
-		return fmt.Errorf("unknown strategy: %s", strategyName)
+	// Create context
+	ctx := context.Background()
+
+	// Open the state store and the trade journal kept in it
+	store, err := cfg.OpenStateStore()
+	if err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
+	j, err := cfg.OpenJournal(ctx, store)
+	if err != nil {
+		return err
+	}
+	if j != nil {
+		defer closeJournal(j, notifier)
 	}
 
-	// Create engine with the strategy
-	eng := engine.NewEngine([]strategies.Strategy{strategy}, broker, notifier)
+	// Every order goes through the halt switch and the risk manager; dry-run instances compute theirs without them
+	sw := cfg.HaltSwitch(store)
+	gated := cfg.Gate(broker, sw, store, j)
+	strats, err := cfg.Build(instances, gated, dryRun)
+	if err != nil {
+		return err
+	}
 
-	// Create context
-	ctx := context.Background()
+	// Create engine with the strategies
+	eng := engine.NewEngine(strats, broker, notifier)
+	eng.SetStrategyTimeout(cfg.Engine.Timeout())
+	eng.SetJournal(j)
+	eng.SetStateStore(store)
+	eng.SetHaltSwitch(sw)
+	if trackerConfig, ok := cfg.Orders.Tracker(); ok {
+		eng.SetOrderTracker(ordertracker.New(gated, trackerConfig))
+	}
+	// A dry run never holds positions to exit
+	if !dryRun {
+		eng.SetExitMonitor(bracket.NewMonitor(broker, store))
+	}
 
-	log.Printf("Running strategy: %s", strategyName)
+	if dryRun {
+		log.Printf("DRY RUN: orders will be computed but not sent")
+	}
+	log.Printf("Running strategies: %s", instanceIDs(strats))
 
 	// Run the engine
-	if err := eng.Run(ctx); err != nil {
-		return fmt.Errorf("failed to run strategy: %w", err)
+	result, err := eng.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run strategies: %w", err)
 	}
 
-	log.Printf("Strategy %s completed successfully", strategyName)
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out, result.Summary())
+	fmt.Fprint(out, result.Breakdown())
+
+	if failed := result.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d strategies failed", failed, len(result.Strategies))
+	}
 	return nil
 }
+
+// closeJournal closes the journal, reporting a failure to save it
+func closeJournal(j *journal.Journal, notifier *notification.Client) {
+	if err := j.Close(); err != nil {
+		log.Printf("Failed to save journal: %v", err)
+		_ = notifier.ActionNeeded(fmt.Sprintf("Failed to save journal: %v", err), err)
+	}
+}
+
+func instanceIDs(strats []strategies.Strategy) string {
+	ids := make([]string, 0, len(strats))
+	for _, strategy := range strats {
+		ids = append(ids, strategy.ID())
+	}
+	return strings.Join(ids, ", ")
+}
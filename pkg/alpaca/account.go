@@ -2,13 +2,19 @@ package alpaca
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
 )
 
 // GetAllPositions retrieves all positions in the account
@@ -22,16 +28,25 @@ func (c *Client) GetAllPositions(ctx context.Context) ([]alpaca.Position, error)
 }
 
 // GetNonMarginableBuyingPower retrieves the non-marginable buying power in the account
-func (c *Client) GetNonMarginableBuyingPower(ctx context.Context) (float64, error) {
+func (c *Client) GetNonMarginableBuyingPower(ctx context.Context) (money.Money, error) {
 	account, err := c.tradingClient.GetAccount()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get account: %w", err)
+		return money.Zero, fmt.Errorf("failed to get account: %w", err)
 	}
 
 	log.Printf("Cash balance: %s", account.Cash)
 	log.Printf("Non-marginable buying power: %s", account.NonMarginBuyingPower)
 
-	return account.NonMarginBuyingPower.InexactFloat64(), nil
+	return money.New(account.NonMarginBuyingPower), nil
+}
+
+// GetEquity retrieves the account's equity now and as of the previous trading day's close
+func (c *Client) GetEquity(ctx context.Context) (money.Money, money.Money, error) {
+	account, err := c.tradingClient.GetAccount()
+	if err != nil {
+		return money.Zero, money.Zero, fmt.Errorf("failed to get account: %w", err)
+	}
+	return money.New(account.Equity), money.New(account.LastEquity), nil
 }
 
 // GetOptionsPositions retrieves all option positions for a specific underlying ticker
@@ -66,10 +81,252 @@ func (c *Client) GetOptionsPositions(ctx context.Context, underlyingTicker strin
 	return optionPositions, nil
 }
 
-// PlaceOptionLimitOrderWithTakeProfit places a bracket order for an option with entry at 99% of ask price and take profit
+// GetOrder retrieves an order and its bracket legs by order ID
+func (c *Client) GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error) {
+	order, err := c.tradingClient.GetOrder(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", orderID, err)
+	}
+
+	return order, nil
+}
+
+// GetOrderByClientOrderID retrieves the order submitted with a client order ID, or nil if there is none
+func (c *Client) GetOrderByClientOrderID(ctx context.Context, clientOrderID string) (*alpaca.Order, error) {
+	order, err := c.tradingClient.GetOrderByClientOrderID(clientOrderID)
+	var apiErr *alpaca.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order by client order ID %s: %w", clientOrderID, err)
+	}
+
+	return order, nil
+}
+
+// GetOpenOrders retrieves the open orders in a symbol, with the legs of bracket orders listed on their own
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]alpaca.Order, error) {
+	orders, err := c.tradingClient.GetOrders(alpaca.GetOrdersRequest{
+		Status:  "open",
+		Limit:   500,
+		Symbols: []string{symbol},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders for %s: %w", symbol, err)
+	}
+
+	return orders, nil
+}
+
+// CancelOrder cancels an open order; cancelling a bracket's entry order cancels its legs
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
+	if err := c.tradingClient.CancelOrder(orderID); err != nil {
+		return fmt.Errorf("failed to cancel order %s: %w", orderID, err)
+	}
+
+	log.Printf("Order cancel requested: ID=%s", orderID)
+	return nil
+}
+
+// ReplaceOrder replaces an open limit order with one at limitPrice, submitted under clientOrderID
+func (c *Client) ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error) {
+	order, err := c.tradingClient.ReplaceOrder(orderID, alpaca.ReplaceOrderRequest{
+		LimitPrice:    limitPrice.Ptr(),
+		ClientOrderID: clientOrderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace order %s: %w", orderID, err)
+	}
+
+	log.Printf("Order replaced: ID=%s replaced by ID=%s, limitPrice=%s", orderID, order.ID, limitPrice)
+	return order, nil
+}
+
+// PlaceOptionSellOrder sells qty contracts of an option to close a position, at limitPrice or at
+// market when limitPrice is zero
+func (c *Client) PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	req := alpaca.PlaceOrderRequest{
+		Symbol:         optionSymbol,
+		Qty:            &qty,
+		Side:           alpaca.Sell,
+		Type:           alpaca.Market,
+		TimeInForce:    alpaca.Day,
+		PositionIntent: alpaca.SellToClose,
+		ClientOrderID:  clientOrderID,
+	}
+	if limitPrice.IsPositive() {
+		req.Type = alpaca.Limit
+		req.LimitPrice = limitPrice.Ptr()
+	}
+
+	order, err := c.tradingClient.PlaceOrder(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place sell order for %s: %w", optionSymbol, err)
+	}
+
+	log.Printf("Sell order placed: ID=%s, symbol=%s, qty=%s, type=%s, Status=%s", order.ID, optionSymbol, qty, order.Type, order.Status)
+	return order, nil
+}
+
+// PlaceOptionBuyOrder places a DAY limit order buying qty contracts of an option to open a position
+func (c *Client) PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	if !limitPrice.IsPositive() {
+		return nil, fmt.Errorf("limit price must be greater than 0, got %s", limitPrice)
+	}
+	req := alpaca.PlaceOrderRequest{
+		Symbol:         optionSymbol,
+		Qty:            &qty,
+		Side:           alpaca.Buy,
+		Type:           alpaca.Limit,
+		TimeInForce:    alpaca.Day,
+		LimitPrice:     limitPrice.Ptr(),
+		PositionIntent: alpaca.BuyToOpen,
+		ClientOrderID:  clientOrderID,
+	}
+
+	order, err := c.tradingClient.PlaceOrder(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place buy order for %s: %w", optionSymbol, err)
+	}
+
+	log.Printf("Buy order placed: ID=%s, symbol=%s, qty=%s, limitPrice=%s, Status=%s", order.ID, optionSymbol, qty, limitPrice, order.Status)
+	return order, nil
+}
+
+// PlaceOptionRollOrder places a DAY multi-leg limit order selling qty contracts of closeSymbol to close
+// and buying qty contracts of openSymbol to open, at a net price per contract of at most netDebit. Alpaca
+// fills both legs or neither; a negative netDebit requires a credit.
+func (c *Client) PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error) {
+	one := decimal.NewFromInt(1)
+	req := alpaca.PlaceOrderRequest{
+		Qty:           &qty,
+		Type:          alpaca.Limit,
+		TimeInForce:   alpaca.Day,
+		LimitPrice:    netDebit.Ptr(),
+		OrderClass:    alpaca.MLeg,
+		ClientOrderID: clientOrderID,
+		Legs: []alpaca.Leg{
+			{Symbol: closeSymbol, Side: alpaca.Sell, PositionIntent: alpaca.SellToClose, RatioQty: one},
+			{Symbol: openSymbol, Side: alpaca.Buy, PositionIntent: alpaca.BuyToOpen, RatioQty: one},
+		},
+	}
+
+	order, err := c.tradingClient.PlaceOrder(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place roll order from %s to %s: %w", closeSymbol, openSymbol, err)
+	}
+
+	log.Printf("Roll order placed: ID=%s, %s -> %s, qty=%s, netDebit=%s, Status=%s", order.ID, closeSymbol, openSymbol, qty, netDebit, order.Status)
+	return order, nil
+}
+
+// PlaceOptionBracketOrder places a limit order for an option with entry priced by policy and exits attached
 // Since options don't support fractional shares, it calculates the appropriate quantity
-// takeProfitPercentage is a percentage (e.g., 20.0 means 20% profit)
-func (m *Client) PlaceOptionLimitOrderWithTakeProfit(ctx context.Context, investmentSize float64, optionSymbol string, optionQuote *marketdata.OptionQuote, takeProfitPercentage float64) (*alpaca.Order, error) {
+// clientOrderID tags the order with the strategy instance placing it (see NewClientOrderID)
+// When the exits can't be held by Alpaca, or Alpaca rejects the bracket, the entry is placed alone and
+// the returned OCO carries the exits a bracket.Monitor must enforce; otherwise the OCO is nil
+func (m *Client) PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error) {
+	bracketOrder, err := BuildOptionBracketOrder(clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Place the bracket order
+	order, err := m.tradingClient.PlaceOrder(*bracketOrder.Request)
+	if err != nil && bracketOrder.HasLegs() && isBracketRejection(err) {
+		log.Printf("Bracket order for %s rejected (%v); placing the entry alone with client-managed exits", optionSymbol, err)
+		bracketOrder.WithClientManagedExits()
+		order, err = m.tradingClient.PlaceOrder(*bracketOrder.Request)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to place bracket order: %w", err)
+	}
+
+	log.Printf("Bracket order placed successfully: ID=%s, Status=%s, OrderClass=%s", order.ID, order.Status, order.OrderClass)
+	return order, bracketOrder.OCO(order), nil
+}
+
+// codeUnprocessable is the code Alpaca gives an order it rejects outright, whatever the reason
+const codeUnprocessable = 42210000
+
+// bracketRejectionClasses and bracketRejectionReasons are the parts of Alpaca's messages for an order class the account or contract
+// doesn't support, e.g. "complex orders not supported for options" or "bracket orders are not allowed"
+var (
+	bracketRejectionClasses = []string{"order class", "order_class", "bracket", "complex order", "advanced order"}
+	bracketRejectionReasons = []string{"not supported", "unsupported", "does not support", "not allowed", "not available"}
+)
+
+// isBracketRejection reports whether Alpaca refused an order because it can't hold bracket legs, rather
+// than for anything else about the order, such as its price, its client order ID or the buying power
+func isBracketRejection(err error) bool {
+	var apiErr *alpaca.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	if apiErr.Code != 0 && apiErr.Code != codeUnprocessable {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return containsAny(message, bracketRejectionClasses) && containsAny(message, bracketRejectionReasons)
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// OptionBracketOrder is an option entry order together with the exits attached to it
+type OptionBracketOrder struct {
+	// Request is the order to submit: the entry with its exits as legs, or the entry alone when
+	// the exits are client-managed
+	Request *alpaca.PlaceOrderRequest
+	Prices  bracket.Prices
+	// ClientManaged means the broker doesn't hold the exits and a bracket.Monitor must enforce them
+	ClientManaged bool
+}
+
+// HasLegs reports whether the request carries exits for the broker to hold
+func (o *OptionBracketOrder) HasLegs() bool {
+	return o.Request.TakeProfit != nil || o.Request.StopLoss != nil
+}
+
+// WithClientManagedExits strips the exit legs from the request, leaving the exits to a bracket.Monitor
+func (o *OptionBracketOrder) WithClientManagedExits() {
+	o.Request.OrderClass = alpaca.Simple
+	o.Request.TakeProfit = nil
+	o.Request.StopLoss = nil
+	o.ClientManaged = o.Prices.Any()
+}
+
+// OCO returns the client-managed bracket of the placed entry order, or nil when the broker holds the exits
+func (o *OptionBracketOrder) OCO(entry *alpaca.Order) *bracket.OCO {
+	if !o.ClientManaged {
+		return nil
+	}
+	oco := &bracket.OCO{
+		EntryOrderID:  entry.ID,
+		ClientOrderID: entry.ClientOrderID,
+		Symbol:        o.Request.Symbol,
+		Prices:        o.Prices,
+		CreatedAt:     entry.CreatedAt,
+	}
+	if option, err := ParseOptionTicker(o.Request.Symbol); err == nil {
+		oco.Underlying = option.Underlying
+	}
+	return oco
+}
+
+// BuildOptionBracketOrder computes the order placed by PlaceOptionBracketOrder without submitting it,
+// so other broker implementations size and price orders exactly like Alpaca does. Exits Alpaca can hold
+// become legs of a bracket order, or of an OTO order when there is only one; otherwise every exit is
+// client-managed. It returns an error wrapping pricing.ErrSpreadTooWide when the policy's spread guard
+// refuses the quote.
+func BuildOptionBracketOrder(clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*OptionBracketOrder, error) {
 	if optionSymbol == "" {
 		return nil, fmt.Errorf("option symbol cannot be empty")
 	}
@@ -78,63 +335,80 @@ func (m *Client) PlaceOptionLimitOrderWithTakeProfit(ctx context.Context, invest
 		return nil, fmt.Errorf("option quote cannot be nil")
 	}
 
-	if investmentSize <= 0 {
+	if !investmentSize.IsPositive() {
 		return nil, fmt.Errorf("investment size must be greater than 0")
 	}
 
-	if takeProfitPercentage <= 0 {
-		return nil, fmt.Errorf("take profit percentage must be greater than 0")
+	if err := exits.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid exits: %w", err)
 	}
 
-	// Calculate limit price as 99% of ask price
-	if optionQuote.BidPrice <= 0 || optionQuote.AskPrice <= 0 {
-		return nil, fmt.Errorf("invalid bid/ask prices: bid=%.2f, ask=%.2f", optionQuote.BidPrice, optionQuote.AskPrice)
-	}
+	bid := money.NewFromFloat(optionQuote.BidPrice)
+	ask := money.NewFromFloat(optionQuote.AskPrice)
 
-	limitPrice := optionQuote.AskPrice * 0.99
-	// Round to 2 decimal places for Alpaca API compliance
-	limitPrice = float64(int(limitPrice*100)) / 100
+	// Price the entry from the bid and ask, on the option's tick grid
+	limitPrice, err := policy.LimitPrice(bid, ask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price %s: %w", optionSymbol, err)
+	}
 
-	// Calculate take profit price as a percentage of the limit price
-	takeProfitPrice := limitPrice * (1 + takeProfitPercentage/100)
-	// Round to 2 decimal places for Alpaca API compliance
-	takeProfitPrice = float64(int(takeProfitPrice*100)) / 100
+	// Price the exits from the limit price: take profit to the nearest tick, stop rounded down
+	prices := exits.Prices(limitPrice, policy.TickRule)
+	if exits.StopLoss.Enabled() && exits.Native() && !prices.Stop.IsPositive() {
+		return nil, fmt.Errorf("stop-loss of %s rounds to %s at limit price %s", optionSymbol, prices.Stop, limitPrice)
+	}
+	if prices.Stop.GreaterThanOrEqual(limitPrice) {
+		return nil, fmt.Errorf("stop-loss %s must be below the limit price %s of %s", prices.Stop, limitPrice, optionSymbol)
+	}
 
 	// Calculate quantity (options are typically sold in contracts of 100 shares)
 	// Each option contract represents 100 shares of the underlying
-	quantity := int(investmentSize / (optionQuote.AskPrice * 100))
+	contractCost := ask.MulInt(money.ContractMultiplier)
+	quantity := investmentSize.Ratio(contractCost).Floor()
 
-	if quantity <= 0 {
-		return nil, fmt.Errorf("calculated quantity is 0 or negative: investment=%.2f, askPrice=%.2f, quantity=%d",
-			investmentSize, optionQuote.AskPrice, quantity)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("calculated quantity is 0 or negative: investment=%s, askPrice=%s, quantity=%s",
+			investmentSize, ask, quantity)
 	}
 
 	// Calculate actual order value
-	actualOrderValue := float64(quantity) * optionQuote.AskPrice * 100
-
-	log.Printf("Placing bracket order: symbol=%s, quantity=%d contracts, limitPrice=%.2f, orderValue=%.2f, takeProfit=%.1f%% (price=%.2f)",
-		optionSymbol, quantity, limitPrice, actualOrderValue, takeProfitPercentage, takeProfitPrice)
-
-	// Place the bracket order
-	qty := decimal.NewFromFloat(float64(quantity))
-	limitPriceDecimal := decimal.NewFromFloat(limitPrice)
-	takeProfitPriceDecimal := decimal.NewFromFloat(takeProfitPrice)
-
-	order, err := m.tradingClient.PlaceOrder(alpaca.PlaceOrderRequest{
-		Symbol:      optionSymbol,
-		Qty:         &qty,
-		Side:        alpaca.Buy,
-		Type:        alpaca.Limit,
-		TimeInForce: alpaca.Day,
-		LimitPrice:  &limitPriceDecimal,
-		TakeProfit:  &alpaca.TakeProfit{LimitPrice: &takeProfitPriceDecimal},
-	})
+	actualOrderValue := contractCost.Mul(quantity)
+
+	log.Printf("Placing bracket order: clientOrderID=%s, symbol=%s, quantity=%s contracts, bid=%s, ask=%s, limitPrice=%s (%s), orderValue=%s, exits: %s",
+		clientOrderID, optionSymbol, quantity, bid, ask, limitPrice, policy, actualOrderValue, prices)
+
+	order := &OptionBracketOrder{
+		Request: &alpaca.PlaceOrderRequest{
+			Symbol:      optionSymbol,
+			Qty:         &quantity,
+			Side:        alpaca.Buy,
+			Type:        alpaca.Limit,
+			TimeInForce: alpaca.Day,
+			LimitPrice:  limitPrice.Ptr(),
+			OrderClass:  alpaca.Simple,
+			// Alpaca generates an ID when it's empty
+			ClientOrderID: clientOrderID,
+		},
+		Prices: prices,
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to place bracket order: %w", err)
+	if !exits.Native() {
+		order.WithClientManagedExits()
+		return order, nil
 	}
 
-	log.Printf("Bracket order placed successfully: ID=%s, Status=%s", order.ID, order.Status)
+	if prices.TakeProfit.IsPositive() {
+		order.Request.TakeProfit = &alpaca.TakeProfit{LimitPrice: prices.TakeProfit.Ptr()}
+	}
+	if prices.Stop.IsPositive() {
+		order.Request.StopLoss = &alpaca.StopLoss{StopPrice: prices.Stop.Ptr()}
+	}
+	switch {
+	case order.Request.TakeProfit != nil && order.Request.StopLoss != nil:
+		order.Request.OrderClass = alpaca.Bracket
+	case order.HasLegs():
+		order.Request.OrderClass = alpaca.OTO
+	}
 	return order, nil
 }
 
@@ -0,0 +1,103 @@
+package alpaca
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+func TestPlaceOptionBracketOrderFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		// status and body are Alpaca's response to the bracket order
+		status int
+		body   string
+		// fallback means the entry is resubmitted alone with client-managed exits
+		fallback bool
+	}{
+		{
+			name:     "bracket unsupported for options",
+			status:   http.StatusUnprocessableEntity,
+			body:     `{"code":42210000,"message":"complex orders not supported for options trading"}`,
+			fallback: true,
+		},
+		{
+			name:     "order class not allowed",
+			status:   http.StatusUnprocessableEntity,
+			body:     `{"code":42210000,"message":"order_class bracket is not allowed for this account"}`,
+			fallback: true,
+		},
+		{
+			name:   "insufficient buying power",
+			status: http.StatusForbidden,
+			body:   `{"code":40310000,"message":"insufficient buying power"}`,
+		},
+		{
+			name:   "insufficient buying power unprocessable",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"code":42210000,"message":"insufficient options buying power for bracket order"}`,
+		},
+		{
+			name:   "duplicate client order ID",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"code":40010001,"message":"client_order_id must be unique"}`,
+		},
+		{
+			name:   "bad take profit price",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"code":42210000,"message":"take_profit.limit_price must be >= base_price + 0.01"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []alpaca.PlaceOrderRequest
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v2/orders" {
+					t.Errorf("unexpected request for %s %s", r.Method, r.URL.Path)
+					http.NotFound(w, r)
+					return
+				}
+				var req alpaca.PlaceOrderRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal(err)
+				}
+				requests = append(requests, req)
+				if req.OrderClass == alpaca.Bracket || req.OrderClass == alpaca.OTO {
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(tt.body))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(alpaca.Order{ID: "entry", ClientOrderID: req.ClientOrderID, Symbol: req.Symbol,
+					Qty: req.Qty, LimitPrice: req.LimitPrice, Side: req.Side, Status: "new"})
+			})
+
+			clientOrderID := NewClientOrderID("qqq-gap", day, "gap-down")
+			order, oco, err := client.PlaceOptionBracketOrder(context.Background(), clientOrderID, money.NewFromInt(2000), near,
+				&marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, pricing.PercentOfAsk(100), bracket.Exits{TakeProfitPercent: 50})
+
+			if !tt.fallback {
+				if err == nil || len(requests) != 1 {
+					t.Errorf("error = %v after %d requests, want the rejection without a resubmit", err, len(requests))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != 2 || requests[1].TakeProfit != nil || requests[1].StopLoss != nil {
+				t.Fatalf("requests = %+v, want the entry resubmitted without legs", requests)
+			}
+			if order.ID != "entry" || oco == nil || oco.EntryOrderID != "entry" || !oco.Prices.TakeProfit.Equal(money.NewFromInt(15)) {
+				t.Errorf("order %s with OCO %+v, want entry's exits client-managed with a take profit at 15.00", order.ID, oco)
+			}
+		})
+	}
+}
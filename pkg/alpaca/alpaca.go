@@ -2,25 +2,54 @@ package alpaca
 
 import (
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
 )
 
+// Environment selects the Alpaca trading API: paper or live
+type Environment string
+
+const (
+	Paper Environment = "paper"
+	Live  Environment = "live"
+)
+
+// ParseEnvironment parses "paper" or "live"
+func ParseEnvironment(s string) (Environment, error) {
+	switch env := Environment(s); env {
+	case Paper, Live:
+		return env, nil
+	default:
+		return "", fmt.Errorf("unknown Alpaca environment %q: must be %q or %q", s, Paper, Live)
+	}
+}
+
+// BaseURL returns the trading API URL of the environment
+func (e Environment) BaseURL() string {
+	if e == Live {
+		return "https://api.alpaca.markets"
+	}
+	return "https://paper-api.alpaca.markets"
+}
+
 // Client wraps the Alpaca market data client
 type Client struct {
 	marketDataClient *marketdata.Client
 	tradingClient    *alpaca.Client
+	environment      Environment
 }
 
-// NewClient creates a new client using environment variables
-func NewClient() (*Client, error) {
-	apiKey := os.Getenv("ALPACA_API_KEY")
-	secretKey := os.Getenv("ALPACA_SECRET_KEY")
+// NewClient creates a new client for the given environment with that environment's API credentials
+func NewClient(environment Environment, apiKey, secretKey string) (*Client, error) {
+	if _, err := ParseEnvironment(string(environment)); err != nil {
+		return nil, err
+	}
 
 	if apiKey == "" || secretKey == "" {
-		return nil, fmt.Errorf("ALPACA_API_KEY and ALPACA_SECRET_KEY environment variables must be set")
+		return nil, fmt.Errorf("alpaca %s API key and secret key must be set", environment)
 	}
 
 	marketDataClient := marketdata.NewClient(marketdata.ClientOpts{
@@ -31,11 +60,28 @@ func NewClient() (*Client, error) {
 	tradingClient := alpaca.NewClient(alpaca.ClientOpts{
 		APIKey:    apiKey,
 		APISecret: secretKey,
-		BaseURL:   "https://paper-api.alpaca.markets",
+		BaseURL:   environment.BaseURL(),
 	})
 
 	return &Client{
 		marketDataClient: marketDataClient,
 		tradingClient:    tradingClient,
+		environment:      environment,
 	}, nil
 }
+
+// Environment returns the environment the client trades in
+func (c *Client) Environment() Environment {
+	return c.environment
+}
+
+// Now returns the current time
+func (c *Client) Now() time.Time {
+	return time.Now()
+}
+
+// Ensure Client satisfies the broker interfaces
+var (
+	_ broker.Broker         = (*Client)(nil)
+	_ broker.MultiLegBroker = (*Client)(nil)
+)
@@ -0,0 +1,271 @@
+package alpaca
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// Client order IDs are "<instance ID>.<trading day>.<signal>"; instance IDs cannot contain the separator,
+// so an order always maps back to exactly one strategy instance
+const clientOrderIDSeparator = "."
+
+// maxSignalLength keeps client order IDs within Alpaca's 128 character limit
+const maxSignalLength = 64
+
+var (
+	instanceIDPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]{1,48}$`)
+	signalInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+)
+
+// ValidateInstanceID checks that id can be embedded in client order IDs
+func ValidateInstanceID(id string) error {
+	if !instanceIDPattern.MatchString(id) {
+		return fmt.Errorf("instance ID %q must be 1-48 letters, digits, '-' or '_'", id)
+	}
+	return nil
+}
+
+// NewClientOrderID returns the client order ID of the order a strategy instance places on a trading day
+// in response to a signal. The ID is deterministic, so a repeated run acting on the same signal the same
+// day produces the same ID, which Alpaca refuses to accept twice.
+func NewClientOrderID(instanceID string, tradingDay time.Time, signal string) string {
+	signal = signalInvalidChars.ReplaceAllString(signal, "-")
+	if len(signal) > maxSignalLength {
+		signal = signal[:maxSignalLength]
+	}
+	return strings.Join([]string{instanceID, tradingDay.Format("20060102"), signal}, clientOrderIDSeparator)
+}
+
+// ReplacementClientOrderID returns the client order ID of the step-th replacement of an order, keeping
+// the instance, trading day and signal of the original so the replacement is attributed the same way
+func ReplacementClientOrderID(clientOrderID string, step int) string {
+	return fmt.Sprintf("%s-r%d", clientOrderID, step)
+}
+
+// InstanceOfClientOrderID returns the strategy instance ID encoded in a client order ID
+func InstanceOfClientOrderID(clientOrderID string) (string, bool) {
+	instanceID, _, found := strings.Cut(clientOrderID, clientOrderIDSeparator)
+	if !found || ValidateInstanceID(instanceID) != nil {
+		return "", false
+	}
+	return instanceID, true
+}
+
+// ordersPageSize is the most orders Alpaca returns in one page
+const ordersPageSize = 500
+
+// GetInstanceOptionsPositions retrieves the option positions on a ticker that were opened by the given strategy instance
+func (c *Client) GetInstanceOptionsPositions(ctx context.Context, instanceID, underlyingTicker string) ([]alpaca.Position, error) {
+	positions, err := c.GetOptionsPositions(ctx, underlyingTicker)
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	symbols := make([]string, 0, len(positions))
+	for _, position := range positions {
+		symbols = append(symbols, position.Symbol)
+	}
+
+	orders, err := c.getAllOrders(alpaca.GetOrdersRequest{
+		Status:  "all",
+		Nested:  true,
+		Symbols: symbols,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+
+	return AttributePositions(positions, orders, instanceID), nil
+}
+
+// getAllOrders retrieves every order matching req, oldest first, a page at a time
+func (c *Client) getAllOrders(req alpaca.GetOrdersRequest) ([]alpaca.Order, error) {
+	req.Limit = ordersPageSize
+	req.Direction = "asc"
+
+	var orders []alpaca.Order
+	seen := make(map[string]bool)
+	for {
+		page, err := c.tradingClient.GetOrders(req)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, order := range page {
+			if seen[order.ID] {
+				continue
+			}
+			seen[order.ID] = true
+			orders = append(orders, order)
+			added++
+		}
+		if len(page) < ordersPageSize || added == 0 {
+			return orders, nil
+		}
+		// after is exclusive and sent to the second, so step back a second to not skip orders submitted
+		// in the same second as the last one; the orders seen again are skipped
+		req.After = page[len(page)-1].SubmittedAt.Add(-time.Second)
+	}
+}
+
+// AttributePositions returns the part of each position held through orders placed by instanceID.
+// Fills are replayed in the order they happened. Every buy opens a lot held by the instance its order
+// is tagged with, as do the filled buy legs of its bracket orders. A sell closes the lots of the
+// instance it is tagged with first, then the oldest lots of the symbol whichever instance opened them,
+// so exits placed under other client order IDs, such as the position manager's, are taken from the
+// instances that opened the position. The buy legs of a multi-leg order inherit the instances of the
+// lots its sell legs closed, so a roll stays attributed. Holdings are capped at the account's position.
+func AttributePositions(positions []alpaca.Position, orders []alpaca.Order, instanceID string) []alpaca.Position {
+	lots := make(map[string][]lot)
+	for _, event := range fillEvents(orders) {
+		var closed []lot
+		for _, f := range event {
+			if f.side == alpaca.Sell {
+				closed = append(closed, closeLots(lots, f.symbol, f.instanceID, f.qty)...)
+			}
+		}
+		for _, f := range event {
+			if f.side == alpaca.Buy {
+				closed = openLots(lots, f, closed)
+			}
+		}
+	}
+
+	held := make(map[string]decimal.Decimal)
+	for symbol, symbolLots := range lots {
+		for _, l := range symbolLots {
+			if l.instanceID == instanceID {
+				held[symbol] = held[symbol].Add(l.qty)
+			}
+		}
+	}
+
+	var attributed []alpaca.Position
+	for _, position := range positions {
+		qty := decimal.Min(held[position.Symbol], position.Qty)
+		if !qty.IsPositive() {
+			continue
+		}
+		if qty.LessThan(position.Qty) {
+			position = scalePosition(position, qty)
+		}
+		attributed = append(attributed, position)
+	}
+	return attributed
+}
+
+// fill is the filled quantity of an order or one of its legs
+type fill struct {
+	// instanceID is the instance the order is tagged with, empty for orders placed outside AthenaX
+	instanceID string
+	symbol     string
+	side       alpaca.Side
+	qty        decimal.Decimal
+	at         time.Time
+}
+
+// lot is the contracts of a symbol held through one instance's buy
+type lot struct {
+	instanceID string
+	qty        decimal.Decimal
+}
+
+// fillEvents returns the fills of the orders in the order they happened. The legs of a multi-leg order
+// trade together and make up one event; every other fill is an event of its own.
+func fillEvents(orders []alpaca.Order) [][]fill {
+	var events [][]fill
+	for _, order := range orders {
+		instanceID, _ := InstanceOfClientOrderID(order.ClientOrderID)
+		var fills []fill
+		for _, o := range append([]alpaca.Order{order}, order.Legs...) {
+			// A multi-leg order has no symbol of its own; its legs trade
+			if o.Symbol == "" || !o.FilledQty.IsPositive() {
+				continue
+			}
+			at := o.SubmittedAt
+			if o.FilledAt != nil {
+				at = *o.FilledAt
+			}
+			fills = append(fills, fill{instanceID: instanceID, symbol: o.Symbol, side: o.Side, qty: o.FilledQty, at: at})
+		}
+		if order.OrderClass == alpaca.MLeg {
+			if len(fills) > 0 {
+				events = append(events, fills)
+			}
+			continue
+		}
+		for _, f := range fills {
+			events = append(events, []fill{f})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i][0].at.Before(events[j][0].at) })
+	return events
+}
+
+// closeLots takes qty contracts of symbol out of the lots of instanceID, then out of the oldest lots,
+// returning the lots closed
+func closeLots(lots map[string][]lot, symbol, instanceID string, qty decimal.Decimal) []lot {
+	var closed []lot
+	for _, own := range []bool{true, false} {
+		remaining := lots[symbol][:0]
+		for _, l := range lots[symbol] {
+			if qty.IsPositive() && (!own || l.instanceID == instanceID) {
+				taken := decimal.Min(l.qty, qty)
+				closed = append(closed, lot{instanceID: l.instanceID, qty: taken})
+				qty = qty.Sub(taken)
+				l.qty = l.qty.Sub(taken)
+			}
+			if l.qty.IsPositive() {
+				remaining = append(remaining, l)
+			}
+		}
+		lots[symbol] = remaining
+	}
+	return closed
+}
+
+// openLots adds the lots bought by f, held by the instances of the lots closed in the same multi-leg
+// order before its own, and returns the closed lots left to carry over to the order's other buys
+func openLots(lots map[string][]lot, f fill, closed []lot) []lot {
+	qty := f.qty
+	for len(closed) > 0 && qty.IsPositive() {
+		carried := decimal.Min(closed[0].qty, qty)
+		lots[f.symbol] = append(lots[f.symbol], lot{instanceID: closed[0].instanceID, qty: carried})
+		qty = qty.Sub(carried)
+		closed[0].qty = closed[0].qty.Sub(carried)
+		if !closed[0].qty.IsPositive() {
+			closed = closed[1:]
+		}
+	}
+	if qty.IsPositive() {
+		lots[f.symbol] = append(lots[f.symbol], lot{instanceID: f.instanceID, qty: qty})
+	}
+	return closed
+}
+
+// scalePosition shrinks a position and its values to qty contracts
+func scalePosition(position alpaca.Position, qty decimal.Decimal) alpaca.Position {
+	ratio := qty.Div(position.Qty)
+	position.Qty = qty
+	position.QtyAvailable = decimal.Min(position.QtyAvailable, qty)
+	position.CostBasis = position.CostBasis.Mul(ratio)
+	if position.MarketValue != nil {
+		marketValue := position.MarketValue.Mul(ratio)
+		position.MarketValue = &marketValue
+	}
+	if position.UnrealizedPL != nil {
+		unrealizedPL := position.UnrealizedPL.Mul(ratio)
+		position.UnrealizedPL = &unrealizedPL
+	}
+	return position
+}
@@ -0,0 +1,252 @@
+package alpaca
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// near is the LEAP the instances buy; far is the LEAP it rolls into
+	near = "QQQ260320C00450000"
+	far  = "QQQ270115C00450000"
+)
+
+var day = time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC)
+
+// newTestClient returns a client trading against handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &Client{
+		tradingClient: alpaca.NewClient(alpaca.ClientOpts{APIKey: "key", APISecret: "secret", BaseURL: server.URL}),
+		environment:   Paper,
+	}
+}
+
+// filled returns an order under clientOrderID that filled qty contracts of symbol minute minutes into the day
+func filled(clientOrderID, symbol string, side alpaca.Side, qty int64, minute int) alpaca.Order {
+	at := day.Add(time.Duration(minute) * time.Minute)
+	return alpaca.Order{
+		ID:            fmt.Sprintf("%s-%d", clientOrderID, minute),
+		ClientOrderID: clientOrderID,
+		Symbol:        symbol,
+		Side:          side,
+		Qty:           decimalPtr(qty),
+		FilledQty:     decimal.NewFromInt(qty),
+		Status:        "filled",
+		SubmittedAt:   at,
+		FilledAt:      &at,
+	}
+}
+
+// roll returns a multi-leg order under clientOrderID that sold qty contracts of near and bought qty of far
+func roll(clientOrderID string, qty int64, minute int) alpaca.Order {
+	order := filled(clientOrderID, "", alpaca.Buy, qty, minute)
+	order.OrderClass = alpaca.MLeg
+	order.Legs = []alpaca.Order{
+		filled(clientOrderID+"-close", near, alpaca.Sell, qty, minute),
+		filled(clientOrderID+"-open", far, alpaca.Buy, qty, minute),
+	}
+	return order
+}
+
+func decimalPtr(n int64) *decimal.Decimal {
+	d := decimal.NewFromInt(n)
+	return &d
+}
+
+func position(symbol string, qty int64) alpaca.Position {
+	return alpaca.Position{Symbol: symbol, Qty: decimal.NewFromInt(qty), QtyAvailable: decimal.NewFromInt(qty),
+		CostBasis: decimal.NewFromInt(qty * 1000)}
+}
+
+func TestAttributePositions(t *testing.T) {
+	gapEntry := NewClientOrderID("qqq-gap", day, "gap-down")
+	dipEntry := NewClientOrderID("qqq-dip", day, "add-delta")
+	exit := NewClientOrderID("position-manager", day, "exit-"+near)
+
+	tests := []struct {
+		name      string
+		positions []alpaca.Position
+		orders    []alpaca.Order
+		// want is qqq-gap's holding in each symbol it holds
+		want map[string]int64
+	}{
+		{
+			name:      "own fills only",
+			positions: []alpaca.Position{position(near, 6)},
+			orders: []alpaca.Order{
+				filled(gapEntry, near, alpaca.Buy, 3, 0),
+				filled(dipEntry, near, alpaca.Buy, 2, 1),
+				filled("manual-order", near, alpaca.Buy, 1, 2),
+			},
+			want: map[string]int64{near: 3},
+		},
+		{
+			name:      "bracket take profit leg",
+			positions: []alpaca.Position{position(near, 1)},
+			orders: func() []alpaca.Order {
+				entry := filled(gapEntry, near, alpaca.Buy, 3, 0)
+				entry.Legs = []alpaca.Order{filled("leg", near, alpaca.Sell, 2, 5)}
+				return []alpaca.Order{entry}
+			}(),
+			want: map[string]int64{near: 1},
+		},
+		{
+			// The position manager's exit takes the oldest lots: qqq-gap's 3, then 1 of qqq-dip's
+			name:      "exit under another client order ID",
+			positions: []alpaca.Position{position(near, 1)},
+			orders: []alpaca.Order{
+				filled(gapEntry, near, alpaca.Buy, 3, 0),
+				filled(dipEntry, near, alpaca.Buy, 2, 1),
+				filled(exit, near, alpaca.Sell, 4, 2),
+			},
+			want: map[string]int64{},
+		},
+		{
+			name:      "partial exit keeps the newer lots",
+			positions: []alpaca.Position{position(near, 4)},
+			orders: []alpaca.Order{
+				filled(dipEntry, near, alpaca.Buy, 2, 0),
+				filled(gapEntry, near, alpaca.Buy, 3, 1),
+				filled(exit, near, alpaca.Sell, 1, 2),
+			},
+			want: map[string]int64{near: 3},
+		},
+		{
+			// qqq-dip's trim comes out of its own lot, though qqq-gap's is older
+			name:      "sell closes its own instance's lots first",
+			positions: []alpaca.Position{position(near, 4)},
+			orders: []alpaca.Order{
+				filled(gapEntry, near, alpaca.Buy, 3, 0),
+				filled(dipEntry, near, alpaca.Buy, 2, 1),
+				filled(NewClientOrderID("qqq-dip", day, "trim-"+near), near, alpaca.Sell, 1, 2),
+			},
+			want: map[string]int64{near: 3},
+		},
+		{
+			name:      "replayed in fill order",
+			positions: []alpaca.Position{position(near, 2)},
+			orders: []alpaca.Order{
+				filled(gapEntry+"-2", near, alpaca.Buy, 2, 10),
+				filled(exit, near, alpaca.Sell, 3, 5),
+				filled(gapEntry, near, alpaca.Buy, 3, 0),
+			},
+			want: map[string]int64{near: 2},
+		},
+		{
+			// A roll the position manager placed under its own ID carries each instance's lots into far
+			name:      "multi-leg roll",
+			positions: []alpaca.Position{position(near, 1), position(far, 4)},
+			orders: []alpaca.Order{
+				filled(gapEntry, near, alpaca.Buy, 3, 0),
+				filled(dipEntry, near, alpaca.Buy, 2, 1),
+				roll(NewClientOrderID("position-manager", day, "roll-"+near), 4, 2),
+			},
+			want: map[string]int64{far: 3},
+		},
+		{
+			name:      "roll under the instance's ID",
+			positions: []alpaca.Position{position(near, 2), position(far, 2)},
+			orders: []alpaca.Order{
+				filled(dipEntry, near, alpaca.Buy, 2, 0),
+				filled(gapEntry, near, alpaca.Buy, 2, 1),
+				roll(NewClientOrderID("qqq-gap", day, "roll-"+near), 2, 2),
+			},
+			want: map[string]int64{far: 2},
+		},
+		{
+			name:      "capped at the account's position",
+			positions: []alpaca.Position{position(near, 2)},
+			orders:    []alpaca.Order{filled(gapEntry, near, alpaca.Buy, 3, 0)},
+			want:      map[string]int64{near: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]int64)
+			for _, p := range AttributePositions(tt.positions, tt.orders, "qqq-gap") {
+				got[p.Symbol] = p.Qty.IntPart()
+				// A scaled position keeps its cost per contract
+				if want := p.Qty.Mul(decimal.NewFromInt(1000)); !p.CostBasis.Equal(want) || p.QtyAvailable.GreaterThan(p.Qty) {
+					t.Errorf("%s: cost basis %s and %s available for %s contracts, want %s and at most %s",
+						p.Symbol, p.CostBasis, p.QtyAvailable, p.Qty, want, p.Qty)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("attributed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetInstanceOptionsPositionsPages(t *testing.T) {
+	// More than two pages of orders, several submitted within each second
+	const buys = 1001
+	var orders []alpaca.Order
+	for i := 0; i < buys; i++ {
+		order := filled(NewClientOrderID("qqq-gap", day, "gap-down"), near, alpaca.Buy, 1, 0)
+		order.ID = fmt.Sprintf("order-%d", i)
+		order.SubmittedAt = day.Add(time.Duration(i) * 300 * time.Millisecond)
+		order.FilledAt = &order.SubmittedAt
+		orders = append(orders, order)
+	}
+	exit := filled(NewClientOrderID("position-manager", day, "exit-"+near), near, alpaca.Sell, 1, 0)
+	exit.SubmittedAt = day.Add(time.Hour)
+	exit.FilledAt = &exit.SubmittedAt
+	orders = append(orders, exit)
+
+	var requests int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/positions":
+			_ = json.NewEncoder(w).Encode([]alpaca.Position{position(near, buys-1), position("QQQ", 100)})
+		case "/v2/orders":
+			requests++
+			q := r.URL.Query()
+			if q.Get("status") != "all" || q.Get("nested") != "true" || q.Get("symbols") != near || q.Get("direction") != "asc" {
+				t.Errorf("orders requested with %s", r.URL.RawQuery)
+			}
+			limit := 0
+			fmt.Sscan(q.Get("limit"), &limit)
+			var after time.Time
+			if q.Get("after") != "" {
+				var err error
+				if after, err = time.Parse(time.RFC3339, q.Get("after")); err != nil {
+					t.Errorf("after = %q: %v", q.Get("after"), err)
+				}
+			}
+			page := []alpaca.Order{}
+			for _, order := range orders {
+				if order.SubmittedAt.After(after) && len(page) < limit {
+					page = append(page, order)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(page)
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	positions, err := client.GetInstanceOptionsPositions(context.Background(), "qqq-gap", "QQQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 || !positions[0].Qty.Equal(decimal.NewFromInt(buys-1)) {
+		t.Errorf("positions = %+v, want all %d contracts left after the exit", positions, buys-1)
+	}
+	if requests < 3 {
+		t.Errorf("%d requests for orders, want a page for every 500", requests)
+	}
+}
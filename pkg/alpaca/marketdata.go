@@ -9,11 +9,12 @@ import (
 
 	"cloud.google.com/go/civil"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
 )
 
-// GetCallLeapsByDelta finds the lowest strike call LEAPS option with delta >= 60
-// LEAPS are options with expiration > 11 months from current date
-func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker string, minDelta float64) (string, *marketdata.OptionSnapshot, error) {
+// GetCallLeapsByDelta finds the lowest strike call LEAPS option with delta >= minDelta
+// LEAPS are options with expiration >= minExpiryMonths (e.g. 11) months from current date
+func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker string, minDelta float64, minExpiryMonths int) (string, *marketdata.OptionSnapshot, error) {
 	if underlyingTicker == "" {
 		return "", nil, fmt.Errorf("underlying ticker cannot be empty")
 	}
@@ -22,9 +23,13 @@ func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker strin
 		return "", nil, fmt.Errorf("minimum delta must be greater than 0")
 	}
 
-	// Calculate expiration date threshold (11 months from now)
-	elevenMonthsFromNow := time.Now().AddDate(0, 11, 0)
-	expirationDateGte := civil.DateOf(elevenMonthsFromNow)
+	if minExpiryMonths <= 0 {
+		return "", nil, fmt.Errorf("minimum expiry months must be greater than 0")
+	}
+
+	// Calculate expiration date threshold (e.g. 11 months from now)
+	minExpiry := time.Now().AddDate(0, minExpiryMonths, 0)
+	expirationDateGte := civil.DateOf(minExpiry)
 
 	// Get option chain for the underlying symbol
 	optionChain, err := m.marketDataClient.GetOptionChain(underlyingTicker, marketdata.GetOptionChainRequest{
@@ -41,6 +46,16 @@ func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker strin
 		return "", nil, fmt.Errorf("no call LEAPS options found for %s", underlyingTicker)
 	}
 
+	return SelectCallLeapsByDelta(optionChain, minDelta)
+}
+
+// SelectCallLeapsByDelta picks, from the earliest expiry with any option at delta >= minDelta,
+// the option with the lowest qualifying delta. The chain is expected to already be limited to LEAPS expiries.
+func SelectCallLeapsByDelta(optionChain map[string]marketdata.OptionSnapshot, minDelta float64) (string, *marketdata.OptionSnapshot, error) {
+	if len(optionChain) == 0 {
+		return "", nil, fmt.Errorf("option chain is empty")
+	}
+
 	// Filter options with delta >= minDelta and sort by strike price
 	var validOptions []struct {
 		symbol   string
@@ -59,7 +74,7 @@ func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker strin
 		// For call options, we want positive delta
 		if delta >= minDelta {
 			// Parse the option symbol to get expiry date
-			option, err := m.ParseOptionTicker(symbol)
+			option, err := ParseOptionTicker(symbol)
 			if err != nil {
 				log.Printf("Failed to parse option ticker %s: %v", symbol, err)
 				continue
@@ -80,7 +95,7 @@ func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker strin
 	}
 
 	if len(validOptions) == 0 {
-		return "", nil, fmt.Errorf("no call LEAPS options found for %s with delta >= %.2f", underlyingTicker, minDelta)
+		return "", nil, fmt.Errorf("no call LEAPS options found with delta >= %.2f", minDelta)
 	}
 
 	// Sort by expiry date (earliest first)
@@ -117,6 +132,25 @@ func (m *Client) GetCallLeapsByDelta(ctx context.Context, underlyingTicker strin
 	return minDeltaOption.symbol, minDeltaOption.snapshot, nil
 }
 
+// GetOptionSnapshot retrieves the latest quote, trade and greeks of an option
+func (m *Client) GetOptionSnapshot(ctx context.Context, optionSymbol string) (*marketdata.OptionSnapshot, error) {
+	if optionSymbol == "" {
+		return nil, fmt.Errorf("option symbol cannot be empty")
+	}
+
+	snapshot, err := m.marketDataClient.GetOptionSnapshot(optionSymbol, marketdata.GetOptionSnapshotRequest{
+		Feed: marketdata.OPRA,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option snapshot for %s: %w", optionSymbol, err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("no option snapshot for %s", optionSymbol)
+	}
+
+	return snapshot, nil
+}
+
 // GetLatestBar retrieves the latest bar for a symbol
 func (m *Client) GetLatestBar(ctx context.Context, symbol string) (*marketdata.Bar, error) {
 	if symbol == "" {
@@ -135,46 +169,46 @@ func (m *Client) GetLatestBar(ctx context.Context, symbol string) (*marketdata.B
 }
 
 // GetLatestQuote retrieves the latest quote for a symbol and returns the ask price
-func (m *Client) GetLatestQuote(ctx context.Context, symbol string) (float64, error) {
+func (m *Client) GetLatestQuote(ctx context.Context, symbol string) (money.Money, error) {
 	if symbol == "" {
-		return 0, fmt.Errorf("symbol cannot be empty")
+		return money.Zero, fmt.Errorf("symbol cannot be empty")
 	}
 
 	quote, err := m.marketDataClient.GetLatestQuote(symbol, marketdata.GetLatestQuoteRequest{
 		Feed: marketdata.SIP,
 	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get latest quote for %s: %w", symbol, err)
+		return money.Zero, fmt.Errorf("failed to get latest quote for %s: %w", symbol, err)
 	}
 
 	log.Printf("Latest quote data: %+v", quote)
 
 	// Return ask price for buying scenarios
-	return quote.AskPrice, nil
+	return money.NewFromFloat(quote.AskPrice), nil
 }
 
 // GetLatestBarMidPrice calculates and returns the mid price from the latest bar
-func (m *Client) GetLatestBarMidPrice(ctx context.Context, symbol string) (float64, error) {
+func (m *Client) GetLatestBarMidPrice(ctx context.Context, symbol string) (money.Money, error) {
 	bar, err := m.GetLatestBar(ctx, symbol)
 	if err != nil {
-		return 0, err
+		return money.Zero, err
 	}
 
 	// Calculate mid price as (high + low) / 2
-	midPrice := (bar.High + bar.Low) / 2
+	midPrice := money.NewFromFloat(bar.High).Add(money.NewFromFloat(bar.Low)).DivInt(2)
 	return midPrice, nil
 }
 
 // GetLastTradingDayClose retrieves the closing price for the last trading day
-func (m *Client) GetLastTradingDayClose(ctx context.Context, symbol string) (float64, error) {
+func (m *Client) GetLastTradingDayClose(ctx context.Context, symbol string) (money.Money, error) {
 	if symbol == "" {
-		return 0, fmt.Errorf("symbol cannot be empty")
+		return money.Zero, fmt.Errorf("symbol cannot be empty")
 	}
 
 	// Get the last trading day using Alpaca calendar API
 	lastTradingDay, err := m.getLastTradingDay(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last trading day: %w", err)
+		return money.Zero, fmt.Errorf("failed to get last trading day: %w", err)
 	}
 
 	log.Printf("Last trading day: %s\n", lastTradingDay.Format("2006-01-02"))
@@ -188,14 +222,14 @@ func (m *Client) GetLastTradingDayClose(ctx context.Context, symbol string) (flo
 		TotalLimit: 1,
 	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get bars for %s: %w", symbol, err)
+		return money.Zero, fmt.Errorf("failed to get bars for %s: %w", symbol, err)
 	}
 
 	if len(bars) == 0 {
-		return 0, fmt.Errorf("no data found for %s on %s", symbol, lastTradingDay.Format("2006-01-02"))
+		return money.Zero, fmt.Errorf("no data found for %s on %s", symbol, lastTradingDay.Format("2006-01-02"))
 	}
 
 	log.Printf("Last trading day bars data: %+v", bars[0])
 
-	return bars[0].Close, nil
+	return money.NewFromFloat(bars[0].Close), nil
 }
@@ -4,18 +4,26 @@ import (
 	"fmt"
 	"strconv"
 	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
 )
 
 type Option struct {
-	Underlying string    // Underlying ticker (e.g., "QQQ")
-	Expiry     time.Time // Expiration date
-	Type       string    // "C" for call, "P" for put
-	Strike     float64   // Strike price
-	Ticker     string    // Full option symbol
+	Underlying string      // Underlying ticker (e.g., "QQQ")
+	Expiry     time.Time   // Expiration date
+	Type       string      // "C" for call, "P" for put
+	Strike     money.Money // Strike price
+	Ticker     string      // Full option symbol
 }
 
 // ParseOptionTicker parses an option ticker symbol and returns structured data
 func (m *Client) ParseOptionTicker(symbol string) (*Option, error) {
+	return ParseOptionTicker(symbol)
+}
+
+// ParseOptionTicker parses an OSI option symbol without needing a client
+func ParseOptionTicker(symbol string) (*Option, error) {
 	if symbol == "" {
 		return nil, fmt.Errorf("symbol cannot be empty")
 	}
@@ -29,13 +37,13 @@ func (m *Client) ParseOptionTicker(symbol string) (*Option, error) {
 	}
 
 	strikeStr := symbol[len(symbol)-8:]
-	strikeFloat, err := strconv.ParseFloat(strikeStr, 64)
+	strikeMills, err := strconv.ParseInt(strikeStr, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid strike price: %w", err)
 	}
 
-	// Convert strike from integer format (e.g., 00420000) to decimal (420.00)
-	strike := strikeFloat / 1000
+	// Convert strike from integer format in thousandths of a dollar (e.g., 00420000) to decimal (420.00)
+	strike := money.New(decimal.New(strikeMills, -3))
 
 	// Remove strike from symbol to get ticker + date + type
 	baseSymbol := symbol[:len(symbol)-8]
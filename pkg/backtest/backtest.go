@@ -0,0 +1,334 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/engine"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/report"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+)
+
+// Exit reasons recorded on closed trades
+const (
+	ExitTakeProfit = "take-profit"
+	ExitStopLoss   = "stop-loss"
+	ExitExpired    = "expired"
+	ExitEndOfTest  = "end-of-test"
+)
+
+// StrategyFactory builds the strategy under test against the simulated broker
+type StrategyFactory func(broker broker.Broker) (strategies.Strategy, error)
+
+// Config controls a single backtest run
+type Config struct {
+	From        time.Time
+	To          time.Time
+	InitialCash money.Money
+	// RunAt is the time after the exchange-local midnight at which the strategy runs each day
+	RunAt    time.Duration
+	Location *time.Location
+	FillRule sim.FillRule
+}
+
+// Result is the outcome of a backtest
+type Result struct {
+	InitialCash money.Money          `json:"initial_cash"`
+	FinalEquity money.Money          `json:"final_equity"`
+	EquityCurve []report.EquityPoint `json:"equity_curve"`
+	Trades      []report.Trade       `json:"trades"`
+	// RunErrors counts the days on which the strategy returned an error
+	RunErrors int `json:"run_errors"`
+}
+
+// Backtester replays historical data through a strategy using the simulated broker
+type Backtester struct {
+	data    *Data
+	config  Config
+	factory StrategyFactory
+
+	broker   *sim.Broker
+	openLots map[string]*report.Trade
+	// lotOrders maps the client order ID of each entry to its broker order ID, to match closing orders
+	lotOrders map[string]string
+	seenFills map[string]bool
+	lastMarks map[string]money.Money
+	result    *Result
+}
+
+// NewBacktester creates a new backtester over data
+func NewBacktester(data *Data, config Config, factory StrategyFactory) *Backtester {
+	return &Backtester{
+		data:    data,
+		config:  config,
+		factory: factory,
+	}
+}
+
+// Run replays every trading day between From and To and returns the trades and equity curve
+func (b *Backtester) Run(ctx context.Context) (*Result, error) {
+	days := b.data.TradingDays(b.config.From, b.config.To)
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no trading days with data between %s and %s",
+			b.config.From.Format("2006-01-02"), b.config.To.Format("2006-01-02"))
+	}
+
+	b.broker = sim.NewBroker(days[0])
+	b.broker.SetBuyingPower(b.config.InitialCash)
+	b.broker.SetFillRule(b.config.FillRule)
+	b.openLots = make(map[string]*report.Trade)
+	b.lotOrders = make(map[string]string)
+	b.seenFills = make(map[string]bool)
+	b.lastMarks = make(map[string]money.Money)
+	b.result = &Result{InitialCash: b.config.InitialCash}
+
+	var sessions []sim.Session
+	for _, day := range b.data.TradingDays(time.Time{}, b.config.To) {
+		sessions = append(sessions, sim.RegularSession(day, b.config.Location))
+	}
+	b.broker.SetCalendar(sessions...)
+	for symbol, bars := range b.data.Daily {
+		b.broker.AddDailyBars(symbol, bars...)
+	}
+
+	strategy, err := b.factory(b.broker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create strategy: %w", err)
+	}
+	eng := engine.NewEngine([]strategies.Strategy{strategy}, b.broker, notification.NewNoopClient())
+	// Simulated runs never block; a wall clock limit would only make results nondeterministic
+	eng.SetStrategyTimeout(0)
+	// Strategy state and client-managed exits carry over from day to day as they do between live runs
+	store := statestore.NewMemoryStore()
+	eng.SetStateStore(store)
+	eng.SetExitMonitor(bracket.NewMonitor(b.broker, store))
+
+	for _, day := range days {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Open of the run: quotes as of RunAt and the day's option chains
+		runAt := day.Add(b.config.RunAt)
+		b.broker.SetTime(runAt)
+		for symbol := range b.data.Daily {
+			if price, ok := b.data.PriceAt(symbol, runAt); ok {
+				b.broker.SetQuote(symbol, price, price)
+			}
+		}
+		b.loadOptions(day)
+
+		runResult, err := eng.Run(ctx)
+		switch {
+		case err != nil:
+			log.Printf("Backtest %s: engine run failed: %v", day.Format("2006-01-02"), err)
+			b.result.RunErrors++
+		case runResult.Failed() > 0:
+			log.Printf("Backtest %s: strategy run failed: %s", day.Format("2006-01-02"), runResult.Strategies[0].Error)
+			b.result.RunErrors++
+		}
+
+		// Close of the session: resting orders get a last chance, DAY orders expire and expiring options settle
+		session := sim.RegularSession(day, b.config.Location)
+		b.broker.SetTime(session.Close.Add(-time.Minute))
+		for symbol := range b.data.Daily {
+			if bar, ok := b.data.DailyBar(symbol, day); ok {
+				b.broker.SetQuote(symbol, bar.Close, bar.Close)
+			}
+		}
+		b.broker.MatchOrders()
+		b.broker.CancelOpenOrders()
+		b.collectFills(day)
+		if err := b.settleExpired(day); err != nil {
+			return nil, err
+		}
+
+		b.result.EquityCurve = append(b.result.EquityCurve, report.EquityPoint{
+			Date:   day,
+			Cash:   b.broker.BuyingPower(),
+			Equity: b.equity(),
+		})
+	}
+
+	// Lots still open at the end are closed at their last known mark
+	lastDay := days[len(days)-1]
+	for _, orderID := range b.sortedOpenLots() {
+		lot := b.openLots[orderID]
+		mark, ok := b.lastMarks[lot.Symbol]
+		if !ok {
+			mark = lot.EntryPrice
+		}
+		b.closeLot(orderID, lastDay, mark, ExitEndOfTest)
+	}
+
+	b.result.FinalEquity = b.result.EquityCurve[len(b.result.EquityCurve)-1].Equity
+	sort.SliceStable(b.result.Trades, func(i, j int) bool {
+		return b.result.Trades[i].EntryDate.Before(b.result.Trades[j].EntryDate)
+	})
+	return b.result, nil
+}
+
+// Report computes the performance report of the backtest
+func (r *Result) Report() *report.Report {
+	return report.Compute(r.EquityCurve, r.Trades)
+}
+
+// loadOptions replaces the simulated option chains with the snapshots recorded for day
+func (b *Backtester) loadOptions(day time.Time) {
+	date := day.Format("2006-01-02")
+	for underlying, byDate := range b.data.Options {
+		b.broker.ClearOptionChain(underlying)
+		for _, row := range byDate[date] {
+			if err := b.broker.SetOption(row.Symbol, row.Snapshot); err != nil {
+				log.Printf("Backtest %s: skipping option: %v", date, err)
+			}
+		}
+	}
+}
+
+// collectFills opens a lot for every newly filled entry and closes lots whose exit leg or
+// client-managed closing order filled
+func (b *Backtester) collectFills(day time.Time) {
+	for _, order := range b.broker.Orders() {
+		if order.Status == "filled" && order.Side == alpaca.Sell && !b.seenFills[order.ID] {
+			b.seenFills[order.ID] = true
+			reason := ExitStopLoss
+			if order.Type == alpaca.Limit {
+				reason = ExitTakeProfit
+			}
+			if entry, ok := bracket.EntryClientOrderID(order.ClientOrderID); ok {
+				b.closeLot(b.lotOrders[entry], day, money.FromPtr(order.FilledAvgPrice), reason)
+			}
+		}
+		if order.Status == "filled" && order.Side == alpaca.Buy && !b.seenFills[order.ID] {
+			b.seenFills[order.ID] = true
+			b.lotOrders[order.ClientOrderID] = order.ID
+			b.openLots[order.ID] = &report.Trade{
+				Symbol:     order.Symbol,
+				OrderID:    order.ID,
+				Quantity:   order.FilledQty.IntPart(),
+				EntryDate:  day,
+				EntryPrice: money.FromPtr(order.FilledAvgPrice),
+			}
+		}
+		for _, leg := range order.Legs {
+			if leg.Status == "filled" && !b.seenFills[leg.ID] {
+				b.seenFills[leg.ID] = true
+				reason := ExitTakeProfit
+				if leg.Type == alpaca.Stop {
+					reason = ExitStopLoss
+				}
+				b.closeLot(order.ID, day, money.FromPtr(leg.FilledAvgPrice), reason)
+			}
+		}
+	}
+
+	for _, position := range b.broker.Positions() {
+		if position.CurrentPrice != nil {
+			b.lastMarks[position.Symbol] = money.New(*position.CurrentPrice)
+		}
+	}
+}
+
+// settleExpired settles every option expiring on or before day at its intrinsic value
+func (b *Backtester) settleExpired(day time.Time) error {
+	for _, position := range b.broker.Positions() {
+		option, err := athenaxalpaca.ParseOptionTicker(position.Symbol)
+		if err != nil {
+			continue
+		}
+		if option.Expiry.Format("2006-01-02") > day.Format("2006-01-02") {
+			continue
+		}
+
+		underlyingBar, ok := b.data.DailyBar(option.Underlying, day)
+		if !ok {
+			return fmt.Errorf("no %s close on %s to settle expiring option %s",
+				option.Underlying, day.Format("2006-01-02"), position.Symbol)
+		}
+		intrinsic := money.NewFromFloat(underlyingBar.Close).Sub(option.Strike)
+		if option.Type == "P" {
+			intrinsic = intrinsic.Neg()
+		}
+		intrinsic = money.Max(intrinsic, money.Zero)
+
+		if err := b.broker.SettlePosition(position.Symbol, intrinsic); err != nil {
+			return err
+		}
+		for _, orderID := range b.sortedOpenLots() {
+			if b.openLots[orderID].Symbol == position.Symbol {
+				b.closeLot(orderID, day, intrinsic, ExitExpired)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Backtester) closeLot(orderID string, day time.Time, price money.Money, reason string) {
+	lot, ok := b.openLots[orderID]
+	if !ok {
+		return
+	}
+	lot.ExitDate = day
+	lot.ExitPrice = price
+	lot.ExitReason = reason
+	b.result.Trades = append(b.result.Trades, *lot)
+	delete(b.openLots, orderID)
+}
+
+func (b *Backtester) sortedOpenLots() []string {
+	ids := make([]string, 0, len(b.openLots))
+	for id := range b.openLots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// equity values cash plus every position at its mark, falling back to the last mark or cost basis
+func (b *Backtester) equity() money.Money {
+	equity := b.broker.BuyingPower()
+	for _, position := range b.broker.Positions() {
+		switch {
+		case position.MarketValue != nil:
+			equity = equity.Add(money.New(*position.MarketValue))
+		case b.lastMarks[position.Symbol].IsPositive():
+			equity = equity.Add(b.lastMarks[position.Symbol].Mul(position.Qty).MulInt(money.ContractMultiplier))
+		default:
+			equity = equity.Add(money.New(position.CostBasis))
+		}
+	}
+	return equity
+}
+
+// WriteEquityCurveCSV writes the equity curve as date,cash,equity rows
+func WriteEquityCurveCSV(w io.Writer, curve []report.EquityPoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"date", "cash", "equity"}); err != nil {
+		return err
+	}
+	for _, point := range curve {
+		if err := writer.Write([]string{
+			point.Date.Format("2006-01-02"),
+			point.Cash.String(),
+			point.Equity.String(),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,150 @@
+package backtest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/backtest"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+)
+
+// The fixture in testdata is a week of QQQ with one call LEAP, QQQ260320C00450000. QQQ gaps down 3% on
+// March 4th and again on the 7th, and 1.2% on the 5th. The LEAP is asked at 50.00 on the 4th and 65.00 on
+// the 7th, bid at 75.00 on the 6th and marked at 69.75 on the last day, the 10th.
+const testOption = "QQQ260320C00450000"
+
+// date returns midnight of a March 2025 day in the fixture's time zone
+func date(day int) time.Time {
+	return time.Date(2025, 3, day, 0, 0, 0, 0, time.UTC)
+}
+
+func loadData(t *testing.T) *backtest.Data {
+	t.Helper()
+	data, err := backtest.LoadData("testdata", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// testConfig backtests the fixture from its second day, when there is a previous close to gap from
+func testConfig() backtest.Config {
+	return backtest.Config{
+		From:        date(4),
+		To:          date(10),
+		InitialCash: money.NewFromInt(100000),
+		RunAt:       9*time.Hour + 45*time.Minute,
+		Location:    time.UTC,
+	}
+}
+
+// gapFactory builds a two-percent-down strategy with its default parameters, overridden by params
+func gapFactory(params map[string]string) (backtest.StrategyFactory, error) {
+	def, err := strategies.Lookup("two-percent-down")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := def.Resolve(params); err != nil {
+		return nil, err
+	}
+	return func(b broker.Broker) (strategies.Strategy, error) {
+		return def.Build("qqq-gap", b, params)
+	}, nil
+}
+
+func TestBacktest(t *testing.T) {
+	factory, err := gapFactory(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := backtest.NewBacktester(loadData(t), testConfig(), factory).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.RunErrors != 0 {
+		t.Errorf("RunErrors = %d, want 0", result.RunErrors)
+	}
+
+	// A fifth of the cash buys four contracts at 99% of the 50.00 ask, which take profit 50% higher, and a
+	// fifth of the cash after that buys three at 99% of the 65.00 ask, open at the end
+	want := []struct {
+		quantity    int64
+		entry, exit int
+		entryPrice  money.Money
+		exitPrice   money.Money
+		reason      string
+		pnl         money.Money
+	}{
+		{quantity: 4, entry: 4, exit: 6, entryPrice: money.Cents(4950), exitPrice: money.Cents(7425), reason: backtest.ExitTakeProfit, pnl: money.NewFromInt(9900)},
+		{quantity: 3, entry: 7, exit: 10, entryPrice: money.Cents(6435), exitPrice: money.Cents(6975), reason: backtest.ExitEndOfTest, pnl: money.NewFromInt(1620)},
+	}
+	if len(result.Trades) != len(want) {
+		t.Fatalf("%d trades, want %d: %+v", len(result.Trades), len(want), result.Trades)
+	}
+	for i, w := range want {
+		trade := result.Trades[i]
+		if trade.Symbol != testOption || trade.Quantity != w.quantity {
+			t.Errorf("trade %d: %d × %s, want %d × %s", i, trade.Quantity, trade.Symbol, w.quantity, testOption)
+		}
+		if !trade.EntryDate.Equal(date(w.entry)) || !trade.EntryPrice.Equal(w.entryPrice) {
+			t.Errorf("trade %d: entered %s @ %s, want March %d @ %s", i, trade.EntryDate.Format("2006-01-02"), trade.EntryPrice, w.entry, w.entryPrice)
+		}
+		if !trade.ExitDate.Equal(date(w.exit)) || !trade.ExitPrice.Equal(w.exitPrice) || trade.ExitReason != w.reason {
+			t.Errorf("trade %d: exited %s @ %s (%s), want March %d @ %s (%s)",
+				i, trade.ExitDate.Format("2006-01-02"), trade.ExitPrice, trade.ExitReason, w.exit, w.exitPrice, w.reason)
+		}
+		if !trade.PnL().Equal(w.pnl) {
+			t.Errorf("trade %d: P&L = %s, want %s", i, trade.PnL(), w.pnl)
+		}
+	}
+
+	// Cash plus the LEAPs at their mid each close
+	curve := []struct{ cash, equity money.Money }{
+		{money.NewFromInt(80200), money.NewFromInt(100100)},
+		{money.NewFromInt(80200), money.NewFromInt(99300)},
+		{money.NewFromInt(109900), money.NewFromInt(109900)},
+		{money.NewFromInt(90595), money.NewFromInt(110020)},
+		{money.NewFromInt(90595), money.NewFromInt(111520)},
+	}
+	if len(result.EquityCurve) != len(curve) {
+		t.Fatalf("%d equity points, want %d", len(result.EquityCurve), len(curve))
+	}
+	for i, point := range result.EquityCurve {
+		if !point.Cash.Equal(curve[i].cash) || !point.Equity.Equal(curve[i].equity) {
+			t.Errorf("%s: cash %s, equity %s; want %s and %s",
+				point.Date.Format("2006-01-02"), point.Cash, point.Equity, curve[i].cash, curve[i].equity)
+		}
+	}
+	if !result.FinalEquity.Equal(money.NewFromInt(111520)) {
+		t.Errorf("FinalEquity = %s, want 111520", result.FinalEquity)
+	}
+
+	r := result.Report()
+	if r.Trades != 2 || r.WinRatePercent != 100 || !r.TotalPnL.Equal(money.NewFromInt(11520)) {
+		t.Errorf("report: %d trades, %.2f%% won, P&L %s; want 2, 100%% and 11520", r.Trades, r.WinRatePercent, r.TotalPnL)
+	}
+	// The return runs from the first close, 100,100, and the drawdown from there to the 5th's 99,300
+	if got := fmt.Sprintf("%.2f %.2f", r.TotalReturnPercent, r.MaxDrawdownPercent); got != "11.41 0.80" {
+		t.Errorf("return and drawdown = %s, want 11.41 0.80", got)
+	}
+	// Exposed on the 4th, 5th and 7th of five days
+	if r.ExposurePercent != 60 {
+		t.Errorf("ExposurePercent = %.2f, want 60", r.ExposurePercent)
+	}
+}
+
+func TestBacktestWithoutTradingDays(t *testing.T) {
+	config := testConfig()
+	config.From, config.To = date(11), date(14)
+	factory, err := gapFactory(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backtest.NewBacktester(loadData(t), config, factory).Run(context.Background()); err == nil {
+		t.Error("Run() succeeded without trading days, want an error")
+	}
+}
@@ -0,0 +1,303 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// Data directory layout, one CSV file per symbol:
+//
+//	daily/<SYMBOL>.csv        date,open,high,low,close,volume
+//	intraday/<SYMBOL>.csv     timestamp,open,high,low,close,volume   (RFC3339 timestamps)
+//	options/<UNDERLYING>.csv  date,symbol,bid,ask,delta[,gamma,theta,vega,rho,implied_volatility]
+//
+// Dates are YYYY-MM-DD and are interpreted in the exchange time zone.
+const (
+	dailyDir    = "daily"
+	intradayDir = "intraday"
+	optionsDir  = "options"
+)
+
+// OptionRow is one end-of-day snapshot of a single option contract
+type OptionRow struct {
+	Symbol   string
+	Snapshot marketdata.OptionSnapshot
+}
+
+// Data holds all historical market data replayed by a backtest
+type Data struct {
+	// Daily bars per symbol, ordered by date
+	Daily map[string][]marketdata.Bar
+	// Intraday bars per symbol and date (YYYY-MM-DD), ordered by timestamp
+	Intraday map[string]map[string][]marketdata.Bar
+	// Option snapshots per underlying and date (YYYY-MM-DD)
+	Options map[string]map[string][]OptionRow
+}
+
+// LoadData reads every CSV file found under dir using the layout described above
+func LoadData(dir string, loc *time.Location) (*Data, error) {
+	data := &Data{
+		Daily:    make(map[string][]marketdata.Bar),
+		Intraday: make(map[string]map[string][]marketdata.Bar),
+		Options:  make(map[string]map[string][]OptionRow),
+	}
+
+	if err := forEachCSV(filepath.Join(dir, dailyDir), func(symbol string, rows []map[string]string) error {
+		for _, row := range rows {
+			date, err := time.ParseInLocation("2006-01-02", row["date"], loc)
+			if err != nil {
+				return fmt.Errorf("invalid date %q: %w", row["date"], err)
+			}
+			bar, err := parseBar(date, row)
+			if err != nil {
+				return err
+			}
+			data.Daily[symbol] = append(data.Daily[symbol], bar)
+		}
+		sort.Slice(data.Daily[symbol], func(i, j int) bool {
+			return data.Daily[symbol][i].Timestamp.Before(data.Daily[symbol][j].Timestamp)
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(data.Daily) == 0 {
+		return nil, fmt.Errorf("no daily bars found in %s", filepath.Join(dir, dailyDir))
+	}
+
+	if err := forEachCSV(filepath.Join(dir, intradayDir), func(symbol string, rows []map[string]string) error {
+		byDate := make(map[string][]marketdata.Bar)
+		for _, row := range rows {
+			timestamp, err := time.Parse(time.RFC3339, row["timestamp"])
+			if err != nil {
+				return fmt.Errorf("invalid timestamp %q: %w", row["timestamp"], err)
+			}
+			bar, err := parseBar(timestamp.In(loc), row)
+			if err != nil {
+				return err
+			}
+			date := bar.Timestamp.Format("2006-01-02")
+			byDate[date] = append(byDate[date], bar)
+		}
+		for date := range byDate {
+			sort.Slice(byDate[date], func(i, j int) bool {
+				return byDate[date][i].Timestamp.Before(byDate[date][j].Timestamp)
+			})
+		}
+		data.Intraday[symbol] = byDate
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := forEachCSV(filepath.Join(dir, optionsDir), func(underlying string, rows []map[string]string) error {
+		byDate := make(map[string][]OptionRow)
+		for _, row := range rows {
+			option, err := parseOptionRow(row)
+			if err != nil {
+				return err
+			}
+			byDate[row["date"]] = append(byDate[row["date"]], option)
+		}
+		data.Options[underlying] = byDate
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// TradingDays returns every date with a daily bar for any symbol between from and to, inclusive
+func (d *Data) TradingDays(from, to time.Time) []time.Time {
+	seen := make(map[string]time.Time)
+	for _, bars := range d.Daily {
+		for _, bar := range bars {
+			if bar.Timestamp.Before(from) || bar.Timestamp.After(to) {
+				continue
+			}
+			seen[bar.Timestamp.Format("2006-01-02")] = bar.Timestamp
+		}
+	}
+
+	days := make([]time.Time, 0, len(seen))
+	for _, day := range seen {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+	return days
+}
+
+// DailyBar returns the daily bar for symbol on day, if any
+func (d *Data) DailyBar(symbol string, day time.Time) (marketdata.Bar, bool) {
+	date := day.Format("2006-01-02")
+	bars := d.Daily[symbol]
+	i := sort.Search(len(bars), func(i int) bool {
+		return bars[i].Timestamp.Format("2006-01-02") >= date
+	})
+	if i < len(bars) && bars[i].Timestamp.Format("2006-01-02") == date {
+		return bars[i], true
+	}
+	return marketdata.Bar{}, false
+}
+
+// PriceAt returns the price of symbol at t: the close of the last intraday bar at or before t,
+// falling back to the day's open when there is no intraday data
+func (d *Data) PriceAt(symbol string, t time.Time) (float64, bool) {
+	var price float64
+	found := false
+	for _, bar := range d.Intraday[symbol][t.Format("2006-01-02")] {
+		if bar.Timestamp.After(t) {
+			break
+		}
+		price = bar.Close
+		found = true
+	}
+	if found {
+		return price, true
+	}
+
+	bar, ok := d.DailyBar(symbol, t)
+	if !ok {
+		return 0, false
+	}
+	return bar.Open, true
+}
+
+// forEachCSV calls fn with the rows of every <SYMBOL>.csv in dir; a missing dir is not an error
+func forEachCSV(dir string, fn func(symbol string, rows []map[string]string) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		rows, err := readCSV(path)
+		if err != nil {
+			return err
+		}
+		symbol := strings.ToUpper(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if err := fn(symbol, rows); err != nil {
+			return fmt.Errorf("failed to load %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// readCSV reads a CSV file with a header row into one map per record keyed by lower-cased column name
+func readCSV(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseBar(timestamp time.Time, row map[string]string) (marketdata.Bar, error) {
+	bar := marketdata.Bar{Timestamp: timestamp}
+	fields := []struct {
+		name  string
+		value *float64
+	}{
+		{"open", &bar.Open},
+		{"high", &bar.High},
+		{"low", &bar.Low},
+		{"close", &bar.Close},
+	}
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(row[field.name], 64)
+		if err != nil {
+			return bar, fmt.Errorf("invalid %s %q: %w", field.name, row[field.name], err)
+		}
+		*field.value = value
+	}
+	if volume, ok := row["volume"]; ok && volume != "" {
+		parsed, err := strconv.ParseFloat(volume, 64)
+		if err != nil {
+			return bar, fmt.Errorf("invalid volume %q: %w", volume, err)
+		}
+		bar.Volume = uint64(parsed)
+	}
+	return bar, nil
+}
+
+func parseOptionRow(row map[string]string) (OptionRow, error) {
+	if row["symbol"] == "" {
+		return OptionRow{}, fmt.Errorf("option row on %s has no symbol", row["date"])
+	}
+
+	values := make(map[string]float64)
+	for _, column := range []string{"bid", "ask", "delta", "gamma", "theta", "vega", "rho", "implied_volatility"} {
+		raw, ok := row[column]
+		if !ok || raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return OptionRow{}, fmt.Errorf("invalid %s %q for %s: %w", column, raw, row["symbol"], err)
+		}
+		values[column] = value
+	}
+
+	snapshot := marketdata.OptionSnapshot{
+		LatestQuote:       &marketdata.OptionQuote{BidPrice: values["bid"], AskPrice: values["ask"]},
+		ImpliedVolatility: values["implied_volatility"],
+	}
+	if _, ok := row["delta"]; ok && row["delta"] != "" {
+		snapshot.Greeks = &marketdata.OptionGreeks{
+			Delta: values["delta"],
+			Gamma: values["gamma"],
+			Theta: values["theta"],
+			Vega:  values["vega"],
+			Rho:   values["rho"],
+		}
+	}
+	return OptionRow{Symbol: row["symbol"], Snapshot: snapshot}, nil
+}
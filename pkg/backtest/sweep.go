@@ -0,0 +1,182 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/report"
+)
+
+// Grid maps a strategy parameter name to the values to try
+type Grid map[string][]string
+
+// ParseGrid parses "name=v1,v2,v3" entries into a grid
+func ParseGrid(entries []string) (Grid, error) {
+	grid := make(Grid)
+	for _, entry := range entries {
+		name, rawValues, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || rawValues == "" {
+			return nil, fmt.Errorf("invalid grid entry %q, expected name=v1,v2,...", entry)
+		}
+		for _, value := range strings.Split(rawValues, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				grid[name] = append(grid[name], value)
+			}
+		}
+	}
+	return grid, nil
+}
+
+// Names returns the parameter names of the grid in sorted order
+func (g Grid) Names() []string {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Combinations returns the cartesian product of the grid, in a deterministic order
+func (g Grid) Combinations() []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, name := range g.Names() {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range g[name] {
+				params := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					params[k] = v
+				}
+				params[name] = value
+				next = append(next, params)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// SweepResult is the outcome of one backtest in a sweep
+type SweepResult struct {
+	Params map[string]string
+	Report *report.Report
+	Err    error
+}
+
+// FactoryBuilder builds the strategy factory for one set of parameters
+type FactoryBuilder func(params map[string]string) (StrategyFactory, error)
+
+// Sweep runs a backtest for every combination in the grid using at most workers concurrent backtests
+func Sweep(ctx context.Context, data *Data, config Config, grid Grid, build FactoryBuilder, workers int) []SweepResult {
+	combinations := grid.Combinations()
+	results := make([]SweepResult, len(combinations))
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = runCombination(ctx, data, config, combinations[i], build)
+			}
+		}()
+	}
+
+	for i := range combinations {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func runCombination(ctx context.Context, data *Data, config Config, params map[string]string, build FactoryBuilder) SweepResult {
+	result := SweepResult{Params: params}
+
+	factory, err := build(params)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	backtestResult, err := NewBacktester(data, config, factory).Run(ctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Report = backtestResult.Report()
+	return result
+}
+
+// RankSweep orders results best first by the given report metric; failed runs go last
+func RankSweep(results []SweepResult, metric string) error {
+	if _, err := (&report.Report{}).Metric(metric); err != nil {
+		return err
+	}
+
+	lowerIsBetter := report.LowerIsBetter(metric)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Report == nil || results[j].Report == nil {
+			return results[j].Report == nil && results[i].Report != nil
+		}
+		a, _ := results[i].Report.Metric(metric)
+		b, _ := results[j].Report.Metric(metric)
+		if lowerIsBetter {
+			return a < b
+		}
+		return a > b
+	})
+	return nil
+}
+
+// WriteSweepCSV writes one row per result with its rank, parameters, every report metric and any error
+func WriteSweepCSV(w io.Writer, grid Grid, results []SweepResult) error {
+	writer := csv.NewWriter(w)
+	names := grid.Names()
+
+	header := append([]string{"rank"}, names...)
+	header = append(header, report.Metrics...)
+	header = append(header, "error")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		row := []string{strconv.Itoa(i + 1)}
+		for _, name := range names {
+			row = append(row, result.Params[name])
+		}
+		for _, metric := range report.Metrics {
+			if result.Report == nil {
+				row = append(row, "")
+				continue
+			}
+			value, _ := result.Report.Metric(metric)
+			row = append(row, strconv.FormatFloat(value, 'f', 4, 64))
+		}
+		errText := ""
+		if result.Err != nil {
+			errText = result.Err.Error()
+		}
+		row = append(row, errText)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,130 @@
+package backtest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/backtest"
+)
+
+func TestParseGrid(t *testing.T) {
+	grid, err := backtest.ParseGrid([]string{"gap_threshold=-1, -2,", "min_delta=0.6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	combinations := grid.Combinations()
+	want := []string{"-1 0.6", "-2 0.6"}
+	if len(combinations) != len(want) {
+		t.Fatalf("%d combinations, want %d", len(combinations), len(want))
+	}
+	for i, combination := range combinations {
+		if got := combination["gap_threshold"] + " " + combination["min_delta"]; got != want[i] {
+			t.Errorf("combination %d = %s, want %s", i, got, want[i])
+		}
+	}
+
+	for _, entry := range []string{"gap_threshold", "=-1", "gap_threshold="} {
+		if _, err := backtest.ParseGrid([]string{entry}); err == nil {
+			t.Errorf("ParseGrid(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	// A -1% threshold also buys the 1.2% gap on the 5th, taking profit at 71.28 on the 6th; a -4%
+	// threshold never buys. "steep" doesn't build.
+	grid := backtest.Grid{"gap_threshold": {"-4", "-2", "-1", "steep"}}
+	results := backtest.Sweep(context.Background(), loadData(t), testConfig(), grid, gapFactory, 3)
+	if len(results) != 4 {
+		t.Fatalf("%d results, want 4", len(results))
+	}
+
+	tests := []struct {
+		metric string
+		// ranking is the gap thresholds best first
+		ranking []string
+	}{
+		{metric: "total_return_percent", ranking: []string{"-1", "-2", "-4", "steep"}},
+		{metric: "total_pnl", ranking: []string{"-1", "-2", "-4", "steep"}},
+		// Buying on the 5th at 47.52, under the 47.75 mid, softens that day's drawdown
+		{metric: "max_drawdown_percent", ranking: []string{"-4", "-1", "-2", "steep"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			if err := backtest.RankSweep(results, tt.metric); err != nil {
+				t.Fatal(err)
+			}
+			var ranking []string
+			for _, result := range results {
+				ranking = append(ranking, result.Params["gap_threshold"])
+			}
+			if strings.Join(ranking, " ") != strings.Join(tt.ranking, " ") {
+				t.Errorf("ranking = %v, want %v", ranking, tt.ranking)
+			}
+		})
+	}
+
+	byThreshold := make(map[string]backtest.SweepResult)
+	for _, result := range results {
+		byThreshold[result.Params["gap_threshold"]] = result
+	}
+	for threshold, want := range map[string]struct {
+		trades      int
+		totalReturn float64
+	}{
+		"-4": {trades: 0, totalReturn: 0},
+		"-2": {trades: 2, totalReturn: 11.41},
+		"-1": {trades: 3, totalReturn: 20.90},
+	} {
+		result := byThreshold[threshold]
+		if result.Err != nil {
+			t.Errorf("gap_threshold %s: error = %v", threshold, result.Err)
+			continue
+		}
+		if result.Report.Trades != want.trades || fmt.Sprintf("%.2f", result.Report.TotalReturnPercent) != fmt.Sprintf("%.2f", want.totalReturn) {
+			t.Errorf("gap_threshold %s: %d trades returning %.2f%%, want %d returning %.2f%%",
+				threshold, result.Report.Trades, result.Report.TotalReturnPercent, want.trades, want.totalReturn)
+		}
+	}
+	if failed := byThreshold["steep"]; failed.Err == nil || failed.Report != nil {
+		t.Errorf("gap_threshold steep: error = %v, want it to fail without a report", failed.Err)
+	}
+
+	if err := backtest.RankSweep(results, "luck"); err == nil {
+		t.Error("RankSweep() by an unknown metric succeeded, want an error")
+	}
+}
+
+func TestWriteSweepCSV(t *testing.T) {
+	grid := backtest.Grid{"gap_threshold": {"-2", "steep"}}
+	results := backtest.Sweep(context.Background(), loadData(t), testConfig(), grid, gapFactory, 1)
+	if err := backtest.RankSweep(results, "total_return_percent"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := backtest.WriteSweepCSV(&buf, grid, results); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("%d rows, want a header and 2 results", len(rows))
+	}
+	header, best, failed := rows[0], rows[1], rows[2]
+	if header[0] != "rank" || header[1] != "gap_threshold" || header[2] != "total_return_percent" || header[len(header)-1] != "error" {
+		t.Errorf("header = %v", header)
+	}
+	if best[0] != "1" || best[1] != "-2" || best[2] != "11.4086" || best[len(best)-1] != "" {
+		t.Errorf("first row = %v, want rank 1 for -2 returning 11.4086 without an error", best)
+	}
+	if failed[0] != "2" || failed[1] != "steep" || failed[2] != "" || failed[len(failed)-1] == "" {
+		t.Errorf("second row = %v, want rank 2 for steep with only an error", failed)
+	}
+}
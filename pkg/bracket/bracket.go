@@ -0,0 +1,144 @@
+package bracket
+
+import (
+	"fmt"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+// StopKind is how a stop-loss decides that a position must be closed
+type StopKind string
+
+const (
+	// StopNone attaches no stop-loss
+	StopNone StopKind = "none"
+	// StopPercent stops out when the option falls Percent percent below its entry price
+	StopPercent StopKind = "percent"
+	// StopPrice stops out when the option falls to Price
+	StopPrice StopKind = "price"
+	// StopUnderlying stops out when the underlying trades at or below UnderlyingPrice. Brokers can't
+	// trigger an option order from another symbol's price, so this stop is always client-managed.
+	StopUnderlying StopKind = "underlying"
+)
+
+// StopKinds lists every stop-loss kind
+var StopKinds = []StopKind{StopNone, StopPercent, StopPrice, StopUnderlying}
+
+// Exit reasons of the positions a bracket closes
+const (
+	ExitTakeProfit = "take-profit"
+	ExitStopLoss   = "stop-loss"
+)
+
+// StopLoss configures the protective exit of a bracket; the zero value has none
+type StopLoss struct {
+	Kind StopKind
+	// Percent is the loss on the entry price that triggers StopPercent, e.g. 30
+	Percent float64
+	// Price is the option price that triggers StopPrice
+	Price money.Money
+	// UnderlyingPrice is the underlying price that triggers StopUnderlying
+	UnderlyingPrice money.Money
+}
+
+// Enabled reports whether the stop-loss closes positions
+func (s StopLoss) Enabled() bool {
+	return s.Kind != "" && s.Kind != StopNone
+}
+
+// Validate checks that the stop-loss settings are usable by its kind
+func (s StopLoss) Validate() error {
+	switch s.Kind {
+	case "", StopNone:
+	case StopPercent:
+		if s.Percent <= 0 || s.Percent >= 100 {
+			return fmt.Errorf("stop-loss percent must be in (0, 100), got %.2f", s.Percent)
+		}
+	case StopPrice:
+		if !s.Price.IsPositive() {
+			return fmt.Errorf("stop-loss price must be greater than 0, got %s", s.Price)
+		}
+	case StopUnderlying:
+		if !s.UnderlyingPrice.IsPositive() {
+			return fmt.Errorf("stop-loss underlying price must be greater than 0, got %s", s.UnderlyingPrice)
+		}
+	default:
+		return fmt.Errorf("unknown stop-loss kind %q", s.Kind)
+	}
+	return nil
+}
+
+// Exits configures the exits attached to an entry order
+type Exits struct {
+	// TakeProfitPercent is the gain on the entry price at which the position is sold; 0 disables it
+	TakeProfitPercent float64
+	StopLoss          StopLoss
+}
+
+// Validate checks that the exits are usable
+func (e Exits) Validate() error {
+	if e.TakeProfitPercent < 0 {
+		return fmt.Errorf("take profit percent must not be negative, got %.2f", e.TakeProfitPercent)
+	}
+	if err := e.StopLoss.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Native reports whether a broker can hold every exit as a leg of a bracket order
+func (e Exits) Native() bool {
+	return e.StopLoss.Kind != StopUnderlying
+}
+
+// Prices are the trigger prices of a bracket's exits; a zero price is an exit that isn't set
+type Prices struct {
+	TakeProfit money.Money `json:"take_profit,omitzero"`
+	// Stop is the option price at or below which the position is stopped out
+	Stop money.Money `json:"stop,omitzero"`
+	// UnderlyingStop is the underlying price at or below which the position is stopped out
+	UnderlyingStop money.Money `json:"underlying_stop,omitzero"`
+}
+
+// Prices returns the exit prices for a position entered at entry, on the option's tick grid: the take
+// profit rounded to the nearest tick and the stop rounded down, so it never triggers early
+func (e Exits) Prices(entry money.Money, rule pricing.TickRule) Prices {
+	var prices Prices
+	if e.TakeProfitPercent > 0 {
+		prices.TakeProfit = pricing.RoundNearest(entry.Add(entry.Percent(e.TakeProfitPercent)), rule)
+	}
+	switch e.StopLoss.Kind {
+	case StopPercent:
+		prices.Stop = pricing.RoundDown(entry.Sub(entry.Percent(e.StopLoss.Percent)), rule)
+	case StopPrice:
+		prices.Stop = pricing.RoundDown(e.StopLoss.Price, rule)
+	case StopUnderlying:
+		prices.UnderlyingStop = e.StopLoss.UnderlyingPrice
+	}
+	return prices
+}
+
+// Any reports whether any exit is set
+func (p Prices) Any() bool {
+	return p.TakeProfit.IsPositive() || p.Stop.IsPositive() || p.UnderlyingStop.IsPositive()
+}
+
+// String describes the exits for logs
+func (p Prices) String() string {
+	s := "take profit "
+	if p.TakeProfit.IsPositive() {
+		s += p.TakeProfit.String()
+	} else {
+		s += "none"
+	}
+	switch {
+	case p.Stop.IsPositive():
+		s += ", stop " + p.Stop.String()
+	case p.UnderlyingStop.IsPositive():
+		s += ", stop when the underlying is at or below " + p.UnderlyingStop.String()
+	default:
+		s += ", no stop"
+	}
+	return s
+}
@@ -0,0 +1,352 @@
+package bracket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// bookKey is the state store key holding the client-managed brackets
+const bookKey = "brackets/oco.json"
+
+// exitClientOrderIDSeparator separates the entry's client order ID from the attempt number in the
+// client order IDs of closing orders
+const exitClientOrderIDSeparator = "-x"
+
+// ExitClientOrderID returns the client order ID of the attempt-th closing order of a bracket, keeping
+// the entry's ID as a prefix so the exit is attributed to the same strategy instance
+func ExitClientOrderID(entryClientOrderID string, attempt int) string {
+	return fmt.Sprintf("%s%s%d", entryClientOrderID, exitClientOrderIDSeparator, attempt)
+}
+
+// EntryClientOrderID returns the client order ID of the entry a closing order exits, or false when
+// clientOrderID isn't one of a closing order
+func EntryClientOrderID(clientOrderID string) (string, bool) {
+	i := strings.LastIndex(clientOrderID, exitClientOrderIDSeparator)
+	if i < 0 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(clientOrderID[i+len(exitClientOrderIDSeparator):]); err != nil {
+		return "", false
+	}
+	return clientOrderID[:i], true
+}
+
+// Event types reported while enforcing client-managed brackets
+const (
+	// EventTriggered means an exit triggered and its closing order was submitted
+	EventTriggered = "triggered"
+	// EventExited means a closing order filled, completely or before it was cancelled
+	EventExited = "exited"
+	// EventDropped means the bracket stopped being enforced without an exit, e.g. its entry never filled
+	EventDropped = "dropped"
+)
+
+// Broker is the set of brokerage operations the monitor depends on
+type Broker interface {
+	GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error)
+	GetOptionSnapshot(ctx context.Context, optionSymbol string) (*marketdata.OptionSnapshot, error)
+	GetLatestQuote(ctx context.Context, symbol string) (money.Money, error)
+	PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error)
+}
+
+// OCO is a bracket whose exits the client enforces because the broker can't hold them: whichever
+// exit triggers first closes the position, and the other is dropped with it
+type OCO struct {
+	// EntryOrderID is the broker ID of the entry order, or of its last replacement
+	EntryOrderID string `json:"entry_order_id"`
+	// ClientOrderID is the client order ID of the entry order, which prefixes those of closing orders
+	ClientOrderID string `json:"client_order_id"`
+	Symbol        string `json:"symbol"`
+	Underlying    string `json:"underlying"`
+	Prices        Prices `json:"prices"`
+	// ExitedQty is the number of contracts closing orders have sold so far
+	ExitedQty decimal.Decimal `json:"exited_qty"`
+	// ExitOrderID is the working closing order, if an exit triggered
+	ExitOrderID string `json:"exit_order_id,omitempty"`
+	ExitReason  string `json:"exit_reason,omitempty"`
+	// ExitAttempts numbers closing orders, so a resubmission gets a new client order ID
+	ExitAttempts int       `json:"exit_attempts,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Event is a step in enforcing a client-managed bracket
+type Event struct {
+	At           time.Time       `json:"at"`
+	Type         string          `json:"type"`
+	EntryOrderID string          `json:"entry_order_id"`
+	ExitOrderID  string          `json:"exit_order_id,omitempty"`
+	Symbol       string          `json:"symbol"`
+	Reason       string          `json:"reason,omitempty"`
+	Qty          decimal.Decimal `json:"qty"`
+	Price        money.Money     `json:"price,omitzero"`
+	Message      string          `json:"message"`
+}
+
+// Monitor enforces client-managed brackets, keeping them in a state store between runs
+type Monitor struct {
+	broker Broker
+	store  statestore.StateStore
+}
+
+// NewMonitor creates a monitor that trades through b and keeps its brackets in store
+func NewMonitor(b Broker, store statestore.StateStore) *Monitor {
+	return &Monitor{broker: b, store: store}
+}
+
+// Add starts enforcing oco
+func (m *Monitor) Add(ctx context.Context, oco OCO) error {
+	book, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	for _, existing := range book {
+		if existing.EntryOrderID == oco.EntryOrderID {
+			return nil
+		}
+	}
+	if oco.CreatedAt.IsZero() {
+		oco.CreatedAt = time.Now()
+	}
+	log.Printf("Enforcing client-managed exits of order %s on %s: %s", oco.EntryOrderID, oco.Symbol, oco.Prices)
+	return m.save(ctx, append(book, oco))
+}
+
+// Drop stops enforcing the brackets on symbol, e.g. once its position was closed another way
+func (m *Monitor) Drop(ctx context.Context, symbol string) error {
+	book, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	var kept []OCO
+	for _, oco := range book {
+		if oco.Symbol == symbol {
+			log.Printf("No longer enforcing client-managed exits of order %s on %s", oco.EntryOrderID, symbol)
+			continue
+		}
+		kept = append(kept, oco)
+	}
+	if len(kept) == len(book) {
+		return nil
+	}
+	return m.save(ctx, kept)
+}
+
+// Brackets returns the brackets being enforced
+func (m *Monitor) Brackets(ctx context.Context) ([]OCO, error) {
+	return m.load(ctx)
+}
+
+// Enforce checks every bracket against the latest quotes, submitting a closing order for each one
+// whose exit triggered and following closing orders until they fill, and calls report with each
+// event. A bracket that can't be checked is kept for the next run and its error returned.
+func (m *Monitor) Enforce(ctx context.Context, report func(Event)) error {
+	book, err := m.load(ctx)
+	if err != nil || len(book) == 0 {
+		return err
+	}
+
+	var kept []OCO
+	var errs []error
+	for _, oco := range book {
+		keep, err := m.enforce(ctx, &oco, func(event Event) {
+			log.Printf("Bracket of order %s %s: %s", event.EntryOrderID, event.Type, event.Message)
+			if report != nil {
+				report(event)
+			}
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to enforce exits of order %s: %w", oco.EntryOrderID, err))
+		}
+		if keep {
+			kept = append(kept, oco)
+		}
+	}
+
+	if err := m.save(ctx, kept); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// enforce moves one bracket forward, updating it in place, and reports whether to keep enforcing it.
+// The contracts an entry has filled are protected as soon as they fill, so a partially filled entry
+// that keeps working, e.g. a DAY limit, is enforced on what it holds and again on each later fill.
+func (m *Monitor) enforce(ctx context.Context, oco *OCO, emit func(Event)) (bool, error) {
+	if oco.ExitOrderID != "" {
+		if _, err := m.followExit(ctx, oco, emit); err != nil || oco.ExitOrderID != "" {
+			return true, err
+		}
+	}
+
+	entry, err := m.entry(ctx, oco)
+	if err != nil {
+		return true, err
+	}
+	working := !isTerminal(entry.Status)
+	open := entry.FilledQty.Sub(oco.ExitedQty)
+	if !open.IsPositive() {
+		if working {
+			return true, nil
+		}
+		// An entry whose contracts were all sold by exits is done; one that never filled is dropped
+		if !oco.ExitedQty.IsPositive() {
+			emit(m.event(EventDropped, oco, fmt.Sprintf("%s entry ended %s with no contracts left to close", oco.Symbol, entry.Status)))
+		}
+		return false, nil
+	}
+
+	reason, limitPrice, message, err := m.triggered(ctx, oco)
+	if err != nil || reason == "" {
+		return true, err
+	}
+
+	oco.ExitAttempts++
+	exit, err := m.broker.PlaceOptionSellOrder(ctx, ExitClientOrderID(oco.ClientOrderID, oco.ExitAttempts), oco.Symbol, open, limitPrice)
+	if err != nil {
+		return true, fmt.Errorf("failed to submit %s exit: %w", reason, err)
+	}
+	oco.ExitOrderID = exit.ID
+	oco.ExitReason = reason
+
+	event := m.event(EventTriggered, oco, fmt.Sprintf("%s %s: %s, selling %s contracts", oco.Symbol, reason, message, open))
+	event.Qty = open
+	event.Price = limitPrice
+	emit(event)
+
+	// A marketable order may have filled on submission; the bracket is kept while the entry may fill more
+	filled, err := m.followExit(ctx, oco, emit)
+	return !filled || working, err
+}
+
+// entry returns the bracket's entry order, following it through any replacements
+func (m *Monitor) entry(ctx context.Context, oco *OCO) (*alpaca.Order, error) {
+	order, err := m.broker.GetOrder(ctx, oco.EntryOrderID)
+	if err != nil {
+		return nil, err
+	}
+	for order.Status == "replaced" && order.ReplacedBy != nil {
+		order, err = m.broker.GetOrder(ctx, *order.ReplacedBy)
+		if err != nil {
+			return nil, err
+		}
+		oco.EntryOrderID = order.ID
+	}
+	return order, nil
+}
+
+// triggered returns the reason and limit price of the exit that triggered, with a zero limit meaning
+// a market order, or an empty reason when neither did. The take profit wins when both trigger.
+func (m *Monitor) triggered(ctx context.Context, oco *OCO) (string, money.Money, string, error) {
+	snapshot, err := m.broker.GetOptionSnapshot(ctx, oco.Symbol)
+	if err != nil {
+		return "", money.Zero, "", err
+	}
+	if snapshot.LatestQuote == nil {
+		return "", money.Zero, "", fmt.Errorf("no quote for %s", oco.Symbol)
+	}
+	bid := money.NewFromFloat(snapshot.LatestQuote.BidPrice)
+
+	prices := oco.Prices
+	switch {
+	case prices.TakeProfit.IsPositive() && bid.GreaterThanOrEqual(prices.TakeProfit):
+		return ExitTakeProfit, prices.TakeProfit, fmt.Sprintf("bid %s reached the take profit %s", bid, prices.TakeProfit), nil
+	case prices.Stop.IsPositive() && bid.LessThanOrEqual(prices.Stop):
+		return ExitStopLoss, money.Zero, fmt.Sprintf("bid %s fell to the stop %s", bid, prices.Stop), nil
+	case prices.UnderlyingStop.IsPositive():
+		price, err := m.broker.GetLatestQuote(ctx, oco.Underlying)
+		if err != nil {
+			return "", money.Zero, "", err
+		}
+		if price.LessThanOrEqual(prices.UnderlyingStop) {
+			return ExitStopLoss, money.Zero, fmt.Sprintf("%s at %s fell to the stop %s", oco.Underlying, price, prices.UnderlyingStop), nil
+		}
+	}
+	return "", money.Zero, "", nil
+}
+
+// followExit checks the working closing order, reporting whether it filled completely. A closing order
+// that ended is cleared, so a partial exit is followed by checking the exits again.
+func (m *Monitor) followExit(ctx context.Context, oco *OCO, emit func(Event)) (bool, error) {
+	exit, err := m.broker.GetOrder(ctx, oco.ExitOrderID)
+	if err != nil {
+		return false, err
+	}
+	if exit.Status != "filled" && !isTerminal(exit.Status) {
+		return false, nil
+	}
+
+	if exit.FilledQty.IsPositive() {
+		oco.ExitedQty = oco.ExitedQty.Add(exit.FilledQty)
+		event := m.event(EventExited, oco, fmt.Sprintf("%s %s exit %s: sold %s @ %s",
+			oco.Symbol, oco.ExitReason, exit.Status, exit.FilledQty, money.FromPtr(exit.FilledAvgPrice)))
+		event.ExitOrderID = exit.ID
+		event.Qty = exit.FilledQty
+		event.Price = money.FromPtr(exit.FilledAvgPrice)
+		emit(event)
+	}
+	if exit.Status != "filled" {
+		log.Printf("Exit order %s of %s ended %s; checking the exits again", exit.ID, oco.Symbol, exit.Status)
+	}
+	oco.ExitOrderID = ""
+	oco.ExitReason = ""
+	return exit.Status == "filled", nil
+}
+
+func (m *Monitor) event(eventType string, oco *OCO, message string) Event {
+	return Event{
+		At:           time.Now(),
+		Type:         eventType,
+		EntryOrderID: oco.EntryOrderID,
+		ExitOrderID:  oco.ExitOrderID,
+		Symbol:       oco.Symbol,
+		Reason:       oco.ExitReason,
+		Message:      message,
+	}
+}
+
+func (m *Monitor) load(ctx context.Context) ([]OCO, error) {
+	data, err := m.store.Get(ctx, bookKey)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client-managed brackets: %w", err)
+	}
+	var book []OCO
+	if err := json.Unmarshal(data, &book); err != nil {
+		return nil, fmt.Errorf("failed to decode client-managed brackets: %w", err)
+	}
+	return book, nil
+}
+
+func (m *Monitor) save(ctx context.Context, book []OCO) error {
+	data, err := json.Marshal(book)
+	if err != nil {
+		return fmt.Errorf("failed to encode client-managed brackets: %w", err)
+	}
+	if err := m.store.Put(ctx, bookKey, data); err != nil {
+		return fmt.Errorf("failed to save client-managed brackets: %w", err)
+	}
+	return nil
+}
+
+// isTerminal reports whether the broker will no longer change an order of this status
+func isTerminal(status string) bool {
+	switch status {
+	case "filled", "canceled", "expired", "rejected", "replaced", "done_for_day":
+		return true
+	}
+	return false
+}
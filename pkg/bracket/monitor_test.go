@@ -0,0 +1,374 @@
+package bracket_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+const leap = "QQQ260320C00450000"
+
+// enterWithClientExits buys 2 contracts at $10.00 with the exits enforced by the client, as when the
+// broker refuses bracket orders, and starts enforcing them with a new monitor
+func enterWithClientExits(t *testing.T, exits bracket.Exits) (*sim.Broker, *bracket.Monitor, *bracket.OCO) {
+	t.Helper()
+	ctx := context.Background()
+	b := sim.NewBroker(time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC))
+	b.SetBuyingPower(money.NewFromInt(25000))
+	b.SetQuote("QQQ", 490, 490)
+	if err := b.SetOption(leap, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}}); err != nil {
+		t.Fatal(err)
+	}
+	b.RejectBracketOrders(true)
+
+	_, oco, err := b.PlaceOptionBracketOrder(ctx, "qqq-gap.20250304.gap-down", money.NewFromInt(2000), leap,
+		&marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, pricing.PercentOfAsk(100), exits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oco == nil {
+		t.Fatal("the exits aren't client-managed")
+	}
+
+	monitor := bracket.NewMonitor(b, statestore.NewMemoryStore())
+	if err := monitor.Add(ctx, *oco); err != nil {
+		t.Fatal(err)
+	}
+	return b, monitor, oco
+}
+
+// enforce runs the monitor once, returning the types of the events it reported
+func enforce(t *testing.T, monitor *bracket.Monitor) []string {
+	t.Helper()
+	var events []string
+	if err := monitor.Enforce(context.Background(), func(event bracket.Event) {
+		events = append(events, event.Type)
+	}); err != nil {
+		t.Fatalf("Enforce() error = %v", err)
+	}
+	return events
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMonitorEnforce(t *testing.T) {
+	percentStop := bracket.Exits{TakeProfitPercent: 50, StopLoss: bracket.StopLoss{Kind: bracket.StopPercent, Percent: 30}}
+	underlyingStop := bracket.Exits{TakeProfitPercent: 50, StopLoss: bracket.StopLoss{Kind: bracket.StopUnderlying, UnderlyingPrice: money.NewFromInt(450)}}
+
+	tests := []struct {
+		name  string
+		exits bracket.Exits
+		// move changes the market after the entry filled
+		move func(t *testing.T, b *sim.Broker)
+		// events are the types of the events reported, in order
+		events []string
+		// reason is the exit the closing order was for, and price its fill, if one filled
+		reason string
+		price  string
+		// kept is whether the bracket is still enforced afterwards
+		kept bool
+	}{
+		{
+			name:  "neither exit triggered",
+			exits: percentStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				setOptionQuote(t, b, 11.00, 11.10)
+			},
+			kept: true,
+		},
+		{
+			name:  "take profit",
+			exits: percentStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				setOptionQuote(t, b, 15.10, 15.30)
+			},
+			events: []string{bracket.EventTriggered, bracket.EventExited},
+			reason: bracket.ExitTakeProfit,
+			price:  "15.00",
+		},
+		{
+			name:  "stop at market",
+			exits: percentStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				setOptionQuote(t, b, 6.80, 7.00)
+			},
+			events: []string{bracket.EventTriggered, bracket.EventExited},
+			reason: bracket.ExitStopLoss,
+			price:  "6.80",
+		},
+		{
+			name:  "take profit wins when both trigger",
+			exits: underlyingStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				b.SetQuote("QQQ", 440, 440)
+				setOptionQuote(t, b, 15.10, 15.30)
+			},
+			events: []string{bracket.EventTriggered, bracket.EventExited},
+			reason: bracket.ExitTakeProfit,
+			price:  "15.00",
+		},
+		{
+			name:  "underlying above its stop",
+			exits: underlyingStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				b.SetQuote("QQQ", 451, 451)
+				setOptionQuote(t, b, 8.00, 8.10)
+			},
+			kept: true,
+		},
+		{
+			name:  "underlying stop",
+			exits: underlyingStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				b.SetQuote("QQQ", 450, 450)
+				setOptionQuote(t, b, 8.00, 8.10)
+			},
+			events: []string{bracket.EventTriggered, bracket.EventExited},
+			reason: bracket.ExitStopLoss,
+			price:  "8.00",
+		},
+		{
+			name:  "closing order not filled",
+			exits: percentStop,
+			move: func(t *testing.T, b *sim.Broker) {
+				b.SetFillRule(sim.FillNever)
+				setOptionQuote(t, b, 15.10, 15.30)
+			},
+			events: []string{bracket.EventTriggered},
+			kept:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, monitor, _ := enterWithClientExits(t, tt.exits)
+			if tt.move != nil {
+				tt.move(t, b)
+			}
+
+			var exits []bracket.Event
+			var events []string
+			if err := monitor.Enforce(context.Background(), func(event bracket.Event) {
+				events = append(events, event.Type)
+				if event.Type == bracket.EventExited {
+					exits = append(exits, event)
+				}
+			}); err != nil {
+				t.Fatalf("Enforce() error = %v", err)
+			}
+			if !equal(events, tt.events) {
+				t.Errorf("events = %v, want %v", events, tt.events)
+			}
+			if tt.reason != "" {
+				if len(exits) != 1 {
+					t.Fatalf("%d exits, want 1", len(exits))
+				}
+				if exits[0].Reason != tt.reason || !exits[0].Price.Equal(mustParse(t, tt.price)) || !exits[0].Qty.Equal(decimal.NewFromInt(2)) {
+					t.Errorf("exit = %s of %s @ %s, want %s of 2 @ %s", exits[0].Reason, exits[0].Qty, exits[0].Price, tt.reason, tt.price)
+				}
+				if positions := b.Positions(); len(positions) != 0 {
+					t.Errorf("%d positions left, want none", len(positions))
+				}
+			}
+
+			brackets, err := monitor.Brackets(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := len(brackets) == 1; kept != tt.kept {
+				t.Errorf("bracket kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestMonitorFollowsClosingOrderAcrossRuns(t *testing.T) {
+	b, monitor, _ := enterWithClientExits(t, bracket.Exits{TakeProfitPercent: 50})
+	b.SetFillRule(sim.FillWhenMarketable)
+	setOptionQuote(t, b, 14.00, 14.20)
+	if events := enforce(t, monitor); len(events) != 0 {
+		t.Fatalf("events = %v before the take profit, want none", events)
+	}
+
+	// The take profit triggers, but its closing limit order rests unfilled
+	setOptionQuote(t, b, 15.00, 15.20)
+	b.SetFillRule(sim.FillNever)
+	if events := enforce(t, monitor); !equal(events, []string{bracket.EventTriggered}) {
+		t.Fatalf("events = %v, want the trigger", events)
+	}
+	// Another run follows the working closing order instead of submitting a second one
+	if events := enforce(t, monitor); len(events) != 0 {
+		t.Fatalf("events = %v while the closing order works, want none", events)
+	}
+	b.SetFillRule(sim.FillWhenMarketable)
+	b.MatchOrders()
+	if events := enforce(t, monitor); !equal(events, []string{bracket.EventExited}) {
+		t.Fatalf("events = %v, want the exit", events)
+	}
+	if sells := countSells(b); sells != 1 {
+		t.Errorf("%d closing orders, want 1", sells)
+	}
+}
+
+func TestMonitorDropsUnfilledEntry(t *testing.T) {
+	ctx := context.Background()
+	b := sim.NewBroker(time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC))
+	b.SetBuyingPower(money.NewFromInt(25000))
+	if err := b.SetOption(leap, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}}); err != nil {
+		t.Fatal(err)
+	}
+	b.SetFillRule(sim.FillNever)
+	b.RejectBracketOrders(true)
+	entry, oco, err := b.PlaceOptionBracketOrder(ctx, "qqq-gap.20250304.gap-down", money.NewFromInt(2000), leap,
+		&marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, pricing.PercentOfAsk(100), bracket.Exits{TakeProfitPercent: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor := bracket.NewMonitor(b, statestore.NewMemoryStore())
+	if err := monitor.Add(ctx, *oco); err != nil {
+		t.Fatal(err)
+	}
+
+	// A working entry is kept until it fills
+	if events := enforce(t, monitor); len(events) != 0 {
+		t.Fatalf("events = %v while the entry works, want none", events)
+	}
+	if err := b.CancelOrder(ctx, entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if events := enforce(t, monitor); !equal(events, []string{bracket.EventDropped}) {
+		t.Fatalf("events = %v, want the bracket dropped", events)
+	}
+	if brackets, _ := monitor.Brackets(ctx); len(brackets) != 0 {
+		t.Errorf("%d brackets left, want none", len(brackets))
+	}
+}
+
+// partialBroker reports the entry order as filled up to filled contracts, still working until done
+type partialBroker struct {
+	*sim.Broker
+	entryID string
+	filled  int64
+	done    bool
+}
+
+func (b *partialBroker) GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error) {
+	if orderID != b.entryID {
+		return b.Broker.GetOrder(ctx, orderID)
+	}
+	status := "partially_filled"
+	if b.done {
+		status = "filled"
+	}
+	return &alpaca.Order{ID: orderID, Status: status, FilledQty: decimal.NewFromInt(b.filled)}, nil
+}
+
+func TestMonitorProtectsPartiallyFilledEntry(t *testing.T) {
+	ctx := context.Background()
+	b := sim.NewBroker(time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC))
+	if err := b.SetOption(leap, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 15.10, AskPrice: 15.30}}); err != nil {
+		t.Fatal(err)
+	}
+	b.SetPosition(alpaca.Position{Symbol: leap, Qty: decimal.NewFromInt(1), CostBasis: decimal.NewFromInt(1000)})
+	partial := &partialBroker{Broker: b, entryID: "entry", filled: 1}
+
+	monitor := bracket.NewMonitor(partial, statestore.NewMemoryStore())
+	if err := monitor.Add(ctx, bracket.OCO{EntryOrderID: "entry", ClientOrderID: "qqq-gap.20250304.gap-down",
+		Symbol: leap, Underlying: "QQQ", Prices: bracket.Prices{TakeProfit: money.NewFromInt(15)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The filled contract is sold while the rest of the entry still works, and the bracket kept
+	var sold []decimal.Decimal
+	report := func(event bracket.Event) {
+		if event.Type == bracket.EventExited {
+			sold = append(sold, event.Qty)
+		}
+	}
+	if err := monitor.Enforce(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	if len(sold) != 1 || !sold[0].Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("sold %v, want 1 contract", sold)
+	}
+	if brackets, _ := monitor.Brackets(ctx); len(brackets) != 1 {
+		t.Fatalf("%d brackets, want the partially filled one kept", len(brackets))
+	}
+
+	// The entry's second contract fills later and is sold too, after which the bracket is done
+	partial.filled, partial.done = 2, true
+	b.SetPosition(alpaca.Position{Symbol: leap, Qty: decimal.NewFromInt(1), CostBasis: decimal.NewFromInt(1000)})
+	if err := monitor.Enforce(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	if len(sold) != 2 || !sold[1].Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("sold %v, want 1 contract more", sold)
+	}
+	if err := monitor.Enforce(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	if brackets, _ := monitor.Brackets(ctx); len(brackets) != 0 {
+		t.Errorf("%d brackets, want none once every contract was sold", len(brackets))
+	}
+	if sells := countSells(b); sells != 2 {
+		t.Errorf("%d closing orders, want 2", sells)
+	}
+}
+
+func TestExitClientOrderID(t *testing.T) {
+	id := bracket.ExitClientOrderID("qqq-gap.20250304.gap-down", 2)
+	if id != "qqq-gap.20250304.gap-down-x2" {
+		t.Errorf("ExitClientOrderID = %q", id)
+	}
+	if entry, ok := bracket.EntryClientOrderID(id); !ok || entry != "qqq-gap.20250304.gap-down" {
+		t.Errorf("EntryClientOrderID(%q) = %q, %v", id, entry, ok)
+	}
+	if _, ok := bracket.EntryClientOrderID("qqq-gap.20250304.gap-down"); ok {
+		t.Error("an entry's client order ID parsed as a closing order's")
+	}
+}
+
+func setOptionQuote(t *testing.T, b *sim.Broker, bid, ask float64) {
+	t.Helper()
+	if err := b.SetOptionQuote(leap, bid, ask); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustParse(t *testing.T, s string) money.Money {
+	t.Helper()
+	m, err := money.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func countSells(b *sim.Broker) int {
+	var n int
+	for _, order := range b.Orders() {
+		if order.Side == alpaca.Sell {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,104 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+// Broker is the set of brokerage operations the engine and strategies depend on
+type Broker interface {
+	// Now returns the broker's current time; the simulated broker returns its simulated clock
+	Now() time.Time
+
+	// IsMarketOpen checks if the market is currently open
+	IsMarketOpen(ctx context.Context) (bool, error)
+
+	// GetLastTradingDayClose retrieves the closing price for the last trading day
+	GetLastTradingDayClose(ctx context.Context, symbol string) (money.Money, error)
+
+	// GetLatestQuote retrieves the latest ask price for a symbol
+	GetLatestQuote(ctx context.Context, symbol string) (money.Money, error)
+
+	// GetOptionsPositions retrieves all option positions for a specific underlying ticker
+	GetOptionsPositions(ctx context.Context, underlyingTicker string) ([]alpaca.Position, error)
+
+	// GetCallLeapsByDelta finds a call LEAPS option for the underlying with delta >= minDelta
+	// expiring at least minExpiryMonths from now
+	GetCallLeapsByDelta(ctx context.Context, underlyingTicker string, minDelta float64, minExpiryMonths int) (string, *marketdata.OptionSnapshot, error)
+
+	// GetOptionSnapshot retrieves the latest quote, trade and greeks of an option
+	GetOptionSnapshot(ctx context.Context, optionSymbol string) (*marketdata.OptionSnapshot, error)
+
+	// GetNonMarginableBuyingPower retrieves the non-marginable buying power in the account
+	GetNonMarginableBuyingPower(ctx context.Context) (money.Money, error)
+
+	// GetEquity retrieves the account's equity now and as of the previous trading day's close
+	GetEquity(ctx context.Context) (equity, lastEquity money.Money, err error)
+
+	// GetAllPositions retrieves every position in the account
+	GetAllPositions(ctx context.Context) ([]alpaca.Position, error)
+
+	// GetInstanceOptionsPositions retrieves the option positions on a ticker opened by orders
+	// of the given strategy instance
+	GetInstanceOptionsPositions(ctx context.Context, instanceID, underlyingTicker string) ([]alpaca.Position, error)
+
+	// GetOrder retrieves an order and its bracket legs by broker order ID
+	GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error)
+
+	// GetOrderByClientOrderID retrieves the order submitted with a client order ID, or nil if there is none
+	GetOrderByClientOrderID(ctx context.Context, clientOrderID string) (*alpaca.Order, error)
+
+	// GetOpenOrders retrieves the open orders in a symbol, with the legs of bracket orders listed on their own
+	GetOpenOrders(ctx context.Context, symbol string) ([]alpaca.Order, error)
+
+	// CancelOrder cancels an open order and its bracket legs
+	CancelOrder(ctx context.Context, orderID string) error
+
+	// ReplaceOrder replaces an open limit order with one at limitPrice, submitted under clientOrderID.
+	// The replacement gets a new broker order ID; the original ends in status replaced.
+	ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error)
+
+	// PlaceOptionBracketOrder places a limit order for an option, priced from its quote by policy,
+	// with exits attached. clientOrderID tags the order with its strategy instance. When the broker
+	// can't hold the exits the entry is placed alone and the returned OCO carries them instead.
+	PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error)
+
+	// PlaceOptionBuyOrder buys qty contracts of an option to open a position, at limitPrice
+	PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error)
+
+	// PlaceOptionSellOrder sells qty contracts of an option to close a position, at limitPrice or
+	// at market when limitPrice is zero
+	PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error)
+}
+
+// OrderClassMultiLeg is the class of orders whose legs execute together or not at all
+const OrderClassMultiLeg = alpaca.MLeg
+
+// ErrMultiLegUnsupported is returned for a multi-leg order when the broker can't place one
+var ErrMultiLegUnsupported = errors.New("multi-leg orders are not supported")
+
+// MultiLegBroker is implemented by brokers that can roll an option position in one multi-leg order
+type MultiLegBroker interface {
+	// PlaceOptionRollOrder sells qty contracts of closeSymbol and buys qty contracts of openSymbol in one
+	// order, at a net price per contract of at most netDebit; a negative netDebit requires a credit
+	PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error)
+}
+
+// MultiLeg returns b as a MultiLegBroker, or an error wrapping ErrMultiLegUnsupported when it can't place
+// multi-leg orders. Wrappers use it to pass rolls through to the broker they wrap.
+func MultiLeg(b Broker) (MultiLegBroker, error) {
+	multiLeg, ok := b.(MultiLegBroker)
+	if !ok {
+		return nil, fmt.Errorf("%T: %w", b, ErrMultiLegUnsupported)
+	}
+	return multiLeg, nil
+}
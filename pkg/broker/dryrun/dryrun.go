@@ -0,0 +1,175 @@
+package dryrun
+
+import (
+	"context"
+	"log"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+// Status is the status of orders computed but never submitted
+const Status = "dry_run"
+
+// Broker wraps a broker so that every read goes through to it while orders are only computed and logged
+type Broker struct {
+	broker.Broker
+}
+
+// Ensure Broker satisfies the broker interfaces
+var (
+	_ broker.Broker         = (*Broker)(nil)
+	_ broker.MultiLegBroker = (*Broker)(nil)
+)
+
+// NewBroker creates a dry-run broker around b
+func NewBroker(b broker.Broker) *Broker {
+	return &Broker{Broker: b}
+}
+
+// PlaceOptionBracketOrder computes the bracket order exactly like the Alpaca client and
+// returns it with status dry_run instead of submitting it
+func (b *Broker) PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error) {
+	bracketOrder, err := athenaxalpaca.BuildOptionBracketOrder(clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := bracketOrder.Request
+
+	order := &alpaca.Order{
+		ID:            "dry-run-" + req.ClientOrderID,
+		ClientOrderID: req.ClientOrderID,
+		Symbol:        req.Symbol,
+		AssetClass:    "us_option",
+		OrderClass:    req.OrderClass,
+		Type:          req.Type,
+		Side:          req.Side,
+		TimeInForce:   req.TimeInForce,
+		Status:        Status,
+		Qty:           req.Qty,
+		LimitPrice:    req.LimitPrice,
+	}
+	if req.TakeProfit != nil {
+		order.Legs = append(order.Legs, alpaca.Order{
+			Symbol:      req.Symbol,
+			AssetClass:  "us_option",
+			Type:        alpaca.Limit,
+			Side:        alpaca.Sell,
+			TimeInForce: req.TimeInForce,
+			Status:      Status,
+			Qty:         req.Qty,
+			LimitPrice:  req.TakeProfit.LimitPrice,
+		})
+	}
+	if req.StopLoss != nil {
+		order.Legs = append(order.Legs, alpaca.Order{
+			Symbol:      req.Symbol,
+			AssetClass:  "us_option",
+			Type:        alpaca.Stop,
+			Side:        alpaca.Sell,
+			TimeInForce: req.TimeInForce,
+			Status:      Status,
+			Qty:         req.Qty,
+			StopPrice:   req.StopLoss.StopPrice,
+		})
+	}
+
+	log.Printf("DRY RUN: not submitting bracket order: clientOrderID=%s, symbol=%s, quantity=%s, limitPrice=%s, exits: %s (client-managed: %t)",
+		req.ClientOrderID, req.Symbol, req.Qty, req.LimitPrice, bracketOrder.Prices, bracketOrder.ClientManaged)
+	return order, bracketOrder.OCO(order), nil
+}
+
+// PlaceOptionBuyOrder logs the buy order instead of submitting it and returns the order it would create
+func (b *Broker) PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	log.Printf("DRY RUN: not buying %s contracts of %s: clientOrderID=%s, limitPrice=%s", qty, optionSymbol, clientOrderID, limitPrice)
+	return &alpaca.Order{
+		ID:            "dry-run-" + clientOrderID,
+		ClientOrderID: clientOrderID,
+		Symbol:        optionSymbol,
+		AssetClass:    "us_option",
+		Type:          alpaca.Limit,
+		Side:          alpaca.Buy,
+		Status:        Status,
+		Qty:           &qty,
+		LimitPrice:    limitPrice.Ptr(),
+	}, nil
+}
+
+// PlaceOptionSellOrder logs the sell order instead of submitting it and returns the order it would create
+func (b *Broker) PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	log.Printf("DRY RUN: not selling %s contracts of %s: clientOrderID=%s, limitPrice=%s", qty, optionSymbol, clientOrderID, limitPrice)
+	order := &alpaca.Order{
+		ID:            "dry-run-" + clientOrderID,
+		ClientOrderID: clientOrderID,
+		Symbol:        optionSymbol,
+		AssetClass:    "us_option",
+		Type:          alpaca.Market,
+		Side:          alpaca.Sell,
+		Status:        Status,
+		Qty:           &qty,
+	}
+	if limitPrice.IsPositive() {
+		order.Type = alpaca.Limit
+		order.LimitPrice = limitPrice.Ptr()
+	}
+	return order, nil
+}
+
+// PlaceOptionRollOrder logs the roll order instead of submitting it and returns the order it would
+// create, or an error wrapping broker.ErrMultiLegUnsupported when the wrapped broker couldn't place it
+func (b *Broker) PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error) {
+	if _, err := broker.MultiLeg(b.Broker); err != nil {
+		return nil, err
+	}
+	log.Printf("DRY RUN: not rolling %s contracts of %s into %s: clientOrderID=%s, netDebit=%s", qty, closeSymbol, openSymbol, clientOrderID, netDebit)
+	order := &alpaca.Order{
+		ID:            "dry-run-" + clientOrderID,
+		ClientOrderID: clientOrderID,
+		AssetClass:    "us_option",
+		OrderClass:    broker.OrderClassMultiLeg,
+		Type:          alpaca.Limit,
+		Side:          alpaca.Buy,
+		Status:        Status,
+		Qty:           &qty,
+		LimitPrice:    netDebit.Ptr(),
+	}
+	for _, leg := range []struct {
+		symbol string
+		side   alpaca.Side
+	}{{closeSymbol, alpaca.Sell}, {openSymbol, alpaca.Buy}} {
+		order.Legs = append(order.Legs, alpaca.Order{
+			Symbol:     leg.symbol,
+			AssetClass: "us_option",
+			Type:       alpaca.Market,
+			Side:       leg.side,
+			Status:     Status,
+			Qty:        &qty,
+		})
+	}
+	return order, nil
+}
+
+// CancelOrder logs the cancellation instead of sending it
+func (b *Broker) CancelOrder(ctx context.Context, orderID string) error {
+	log.Printf("DRY RUN: not cancelling order %s", orderID)
+	return nil
+}
+
+// ReplaceOrder logs the replacement instead of sending it and returns the order it would create
+func (b *Broker) ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error) {
+	log.Printf("DRY RUN: not replacing order %s: clientOrderID=%s, limitPrice=%s", orderID, clientOrderID, limitPrice)
+	return &alpaca.Order{
+		ID:            "dry-run-" + clientOrderID,
+		ClientOrderID: clientOrderID,
+		Replaces:      &orderID,
+		Type:          alpaca.Limit,
+		Status:        Status,
+		LimitPrice:    limitPrice.Ptr(),
+	}, nil
+}
@@ -0,0 +1,217 @@
+package dryrun_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+const (
+	leap    = "QQQ260320C00450000"
+	rollTo  = "QQQ270115C00450000"
+	entryID = "qqq-gap.20250304.gap-down"
+)
+
+var now = time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC)
+
+// guardedBroker is a simulated account that fails the test whenever an order reaches it
+type guardedBroker struct {
+	*sim.Broker
+	t *testing.T
+}
+
+func (b *guardedBroker) reached(method string) error {
+	b.t.Errorf("dry run reached the broker's %s", method)
+	return errors.New("reached the broker")
+}
+
+func (b *guardedBroker) PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error) {
+	return nil, nil, b.reached("PlaceOptionBracketOrder")
+}
+
+func (b *guardedBroker) PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	return nil, b.reached("PlaceOptionBuyOrder")
+}
+
+func (b *guardedBroker) PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	return nil, b.reached("PlaceOptionSellOrder")
+}
+
+func (b *guardedBroker) PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error) {
+	return nil, b.reached("PlaceOptionRollOrder")
+}
+
+func (b *guardedBroker) CancelOrder(ctx context.Context, orderID string) error {
+	return b.reached("CancelOrder")
+}
+
+func (b *guardedBroker) ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error) {
+	return nil, b.reached("ReplaceOrder")
+}
+
+// singleLegBroker hides the simulated broker's multi-leg orders
+type singleLegBroker struct {
+	broker.Broker
+}
+
+// newAccount returns a simulated account holding 2 contracts of leap with an order resting to sell them
+func newAccount(t *testing.T) (*sim.Broker, *alpaca.Order) {
+	t.Helper()
+	account := sim.NewBroker(now)
+	account.SetCalendar(sim.RegularSession(now, time.UTC))
+	account.SetBuyingPower(money.NewFromInt(20000))
+	for _, symbol := range []string{leap, rollTo} {
+		if err := account.SetOption(symbol, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	account.SetPosition(alpaca.Position{Symbol: leap, Qty: decimal.NewFromInt(2), CostBasis: decimal.NewFromInt(1600)})
+	account.SetFillRule(sim.FillNever)
+	resting, err := account.PlaceOptionSellOrder(context.Background(), "qqq-gap.20250303.take-profit", leap, decimal.NewFromInt(2), money.NewFromInt(15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account, resting
+}
+
+func TestDryRunNeverReachesTheBroker(t *testing.T) {
+	ctx := context.Background()
+	account, resting := newAccount(t)
+	b := dryrun.NewBroker(&guardedBroker{Broker: account, t: t})
+
+	orders := []struct {
+		name  string
+		place func() (*alpaca.Order, error)
+		// want is the order the dry run would have created
+		want alpaca.Order
+	}{
+		{
+			// $2,000 at the 10.00 ask buys 2 contracts, with a take profit at 15.00
+			name: "bracket",
+			place: func() (*alpaca.Order, error) {
+				order, oco, err := b.PlaceOptionBracketOrder(ctx, entryID, money.NewFromInt(2000), leap,
+					&marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, pricing.PercentOfAsk(100), bracket.Exits{TakeProfitPercent: 50})
+				if err == nil && (len(order.Legs) != 1 || !order.Legs[0].LimitPrice.Equal(decimal.NewFromInt(15)) || oco != nil) {
+					t.Errorf("bracket: legs %+v and OCO %+v, want a take profit leg at 15.00 held by the broker", order.Legs, oco)
+				}
+				return order, err
+			},
+			want: alpaca.Order{ClientOrderID: entryID, Symbol: leap, Side: alpaca.Buy, Type: alpaca.Limit, Qty: qty(2), LimitPrice: price("10")},
+		},
+		{
+			name: "buy",
+			place: func() (*alpaca.Order, error) {
+				return b.PlaceOptionBuyOrder(ctx, entryID, leap, decimal.NewFromInt(1), money.Cents(995))
+			},
+			want: alpaca.Order{ClientOrderID: entryID, Symbol: leap, Side: alpaca.Buy, Type: alpaca.Limit, Qty: qty(1), LimitPrice: price("9.95")},
+		},
+		{
+			name: "sell",
+			place: func() (*alpaca.Order, error) {
+				return b.PlaceOptionSellOrder(ctx, "qqq-gap.20250304.trim", leap, decimal.NewFromInt(1), money.Cents(990))
+			},
+			want: alpaca.Order{ClientOrderID: "qqq-gap.20250304.trim", Symbol: leap, Side: alpaca.Sell, Type: alpaca.Limit, Qty: qty(1), LimitPrice: price("9.90")},
+		},
+		{
+			name: "sell at market",
+			place: func() (*alpaca.Order, error) {
+				return b.PlaceOptionSellOrder(ctx, "qqq-gap.20250304.expiry", leap, decimal.NewFromInt(2), money.Zero)
+			},
+			want: alpaca.Order{ClientOrderID: "qqq-gap.20250304.expiry", Symbol: leap, Side: alpaca.Sell, Type: alpaca.Market, Qty: qty(2)},
+		},
+		{
+			name: "roll",
+			place: func() (*alpaca.Order, error) {
+				return b.PlaceOptionRollOrder(ctx, "qqq-gap.20250304.roll", leap, rollTo, decimal.NewFromInt(2), money.Cents(50))
+			},
+			want: alpaca.Order{ClientOrderID: "qqq-gap.20250304.roll", Side: alpaca.Buy, Type: alpaca.Limit, Qty: qty(2), LimitPrice: price("0.50")},
+		},
+		{
+			name: "replace",
+			place: func() (*alpaca.Order, error) {
+				return b.ReplaceOrder(ctx, resting.ID, "qqq-gap.20250303.take-profit-r1", money.NewFromInt(14))
+			},
+			want: alpaca.Order{ClientOrderID: "qqq-gap.20250303.take-profit-r1", Type: alpaca.Limit, LimitPrice: price("14")},
+		},
+	}
+	for _, tt := range orders {
+		order, err := tt.place()
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if order.ID != "dry-run-"+tt.want.ClientOrderID || order.Status != dryrun.Status {
+			t.Errorf("%s: order %s is %s, want dry-run-%s with status %s", tt.name, order.ID, order.Status, tt.want.ClientOrderID, dryrun.Status)
+		}
+		if order.ClientOrderID != tt.want.ClientOrderID || order.Symbol != tt.want.Symbol || order.Side != tt.want.Side || order.Type != tt.want.Type {
+			t.Errorf("%s: order %s %s %s %s, want %s %s %s %s", tt.name, order.ClientOrderID, order.Symbol, order.Side, order.Type,
+				tt.want.ClientOrderID, tt.want.Symbol, tt.want.Side, tt.want.Type)
+		}
+		if !equal(order.Qty, tt.want.Qty) || !equal(order.LimitPrice, tt.want.LimitPrice) {
+			t.Errorf("%s: %v @ %v, want %v @ %v", tt.name, order.Qty, order.LimitPrice, tt.want.Qty, tt.want.LimitPrice)
+		}
+	}
+	if err := b.CancelOrder(ctx, resting.ID); err != nil {
+		t.Errorf("cancel: error = %v", err)
+	}
+
+	// Reads still go through to the account, which is just as it was
+	order, err := b.GetOrder(ctx, resting.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "new" || !order.LimitPrice.Equal(decimal.NewFromInt(15)) {
+		t.Errorf("resting order is %s @ %s, want new @ 15", order.Status, order.LimitPrice)
+	}
+	if orders := account.Orders(); len(orders) != 1 {
+		t.Errorf("account has %d orders, want only the resting one", len(orders))
+	}
+	positions, err := b.GetAllPositions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 || !positions[0].Qty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("positions = %+v, want the 2 contracts of %s", positions, leap)
+	}
+	if buyingPower, err := b.GetNonMarginableBuyingPower(ctx); err != nil || !buyingPower.Equal(money.NewFromInt(20000)) {
+		t.Errorf("buying power = %s (%v), want 20000", buyingPower, err)
+	}
+}
+
+func TestDryRunRollNeedsMultiLeg(t *testing.T) {
+	account, _ := newAccount(t)
+	b := dryrun.NewBroker(singleLegBroker{account})
+	_, err := b.PlaceOptionRollOrder(context.Background(), "qqq-gap.20250304.roll", leap, rollTo, decimal.NewFromInt(2), money.Cents(50))
+	if !errors.Is(err, broker.ErrMultiLegUnsupported) {
+		t.Errorf("roll error = %v, want %v", err, broker.ErrMultiLegUnsupported)
+	}
+}
+
+func qty(n int64) *decimal.Decimal {
+	d := decimal.NewFromInt(n)
+	return &d
+}
+
+func price(s string) *decimal.Decimal {
+	d := decimal.RequireFromString(s)
+	return &d
+}
+
+// equal reports whether two optional decimals are both missing or equal
+func equal(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
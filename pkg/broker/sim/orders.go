@@ -0,0 +1,600 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+// FillRule decides when the simulated broker fills a resting limit order
+type FillRule int
+
+const (
+	// FillAtLimit fills every entry order at its limit price as soon as it is submitted
+	FillAtLimit FillRule = iota
+	// FillWhenMarketable fills a buy limit once the ask is at or below the limit price
+	// and a sell limit once the bid is at or above it
+	FillWhenMarketable
+	// FillNever leaves every order open
+	FillNever
+)
+
+// Order statuses used by the simulated broker, matching Alpaca's values
+const (
+	statusNew      = "new"
+	statusHeld     = "held"
+	statusFilled   = "filled"
+	statusCanceled = "canceled"
+	statusReplaced = "replaced"
+)
+
+// SetFillRule sets the rule used to fill orders
+func (b *Broker) SetFillRule(rule FillRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fillRule = rule
+}
+
+// FailOrders makes every following order submission return err; pass nil to accept orders again
+func (b *Broker) FailOrders(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.orderError = err
+}
+
+// Orders returns a copy of every order submitted so far, in submission order
+func (b *Broker) Orders() []alpaca.Order {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	orders := make([]alpaca.Order, 0, len(b.orders))
+	for _, order := range b.orders {
+		orders = append(orders, *order)
+	}
+	return orders
+}
+
+// GetOrder returns a copy of the order with the given ID
+func (b *Broker) GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if order := b.findOrder(orderID); order != nil {
+		result := *order
+		result.Legs = append([]alpaca.Order(nil), order.Legs...)
+		return &result, nil
+	}
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+// GetOrderByClientOrderID returns a copy of the order submitted with a client order ID, or nil if there is none
+func (b *Broker) GetOrderByClientOrderID(ctx context.Context, clientOrderID string) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if order := b.findClientOrder(clientOrderID); order != nil {
+		result := *order
+		result.Legs = append([]alpaca.Order(nil), order.Legs...)
+		return &result, nil
+	}
+	return nil, nil
+}
+
+// RejectBracketOrders makes the broker refuse orders with exit legs, like an account Alpaca won't
+// accept brackets from, so exits fall back to being client-managed
+func (b *Broker) RejectBracketOrders(reject bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rejectBrackets = reject
+}
+
+// GetOpenOrders returns copies of the open orders and active legs in a symbol, legs listed on their own
+func (b *Broker) GetOpenOrders(ctx context.Context, symbol string) ([]alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var open []alpaca.Order
+	for _, order := range b.orders {
+		if order.Symbol == symbol && order.Status == statusNew {
+			result := *order
+			result.Legs = nil
+			open = append(open, result)
+		}
+		for _, leg := range order.Legs {
+			if leg.Symbol == symbol && leg.Status == statusNew {
+				open = append(open, leg)
+			}
+		}
+	}
+	return open, nil
+}
+
+// PlaceOptionBracketOrder sizes and prices the order exactly like the Alpaca client,
+// records it, and fills it according to the current fill rule
+func (b *Broker) PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error) {
+	bracketOrder, err := athenaxalpaca.BuildOptionBracketOrder(clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.orderError != nil {
+		return nil, nil, fmt.Errorf("failed to place bracket order: %w", b.orderError)
+	}
+	if b.rejectBrackets && bracketOrder.HasLegs() {
+		bracketOrder.WithClientManagedExits()
+	}
+	req := bracketOrder.Request
+	// Alpaca rejects a client order ID that was used before
+	if req.ClientOrderID != "" && b.findClientOrder(req.ClientOrderID) != nil {
+		return nil, nil, fmt.Errorf("failed to place bracket order: client_order_id must be unique")
+	}
+
+	order := b.newOrder(req.Symbol, req.Side, *req.Qty, *req.LimitPrice)
+	if req.ClientOrderID != "" {
+		order.ClientOrderID = req.ClientOrderID
+	}
+	order.OrderClass = req.OrderClass
+	if req.TakeProfit != nil {
+		leg := b.newOrder(req.Symbol, alpaca.Sell, *req.Qty, *req.TakeProfit.LimitPrice)
+		leg.Status = statusHeld
+		order.Legs = append(order.Legs, *leg)
+	}
+	if req.StopLoss != nil {
+		leg := b.newOrder(req.Symbol, alpaca.Sell, *req.Qty, decimal.Zero)
+		leg.Type = alpaca.Stop
+		leg.LimitPrice = nil
+		leg.StopPrice = req.StopLoss.StopPrice
+		leg.Status = statusHeld
+		order.Legs = append(order.Legs, *leg)
+	}
+	b.orders = append(b.orders, order)
+
+	if b.fillRule == FillAtLimit {
+		b.fill(order, *req.LimitPrice)
+	} else {
+		b.matchOrders()
+	}
+
+	result := *order
+	return &result, bracketOrder.OCO(order), nil
+}
+
+// PlaceOptionSellOrder records an order closing qty contracts of a position, at limitPrice or at
+// market when it is zero, and matches it against the current quotes
+func (b *Broker) PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.orderError != nil {
+		return nil, fmt.Errorf("failed to place sell order for %s: %w", optionSymbol, b.orderError)
+	}
+	if clientOrderID != "" && b.findClientOrder(clientOrderID) != nil {
+		return nil, fmt.Errorf("failed to place sell order for %s: client_order_id must be unique", optionSymbol)
+	}
+	position, ok := b.positions[optionSymbol]
+	if !ok || position.Qty.LessThan(qty) {
+		return nil, fmt.Errorf("failed to place sell order for %s: insufficient qty available", optionSymbol)
+	}
+
+	order := b.newOrder(optionSymbol, alpaca.Sell, qty, limitPrice.Decimal())
+	if clientOrderID != "" {
+		order.ClientOrderID = clientOrderID
+	}
+	if !limitPrice.IsPositive() {
+		order.Type = alpaca.Market
+		order.LimitPrice = nil
+	}
+	b.orders = append(b.orders, order)
+	b.matchOrders()
+
+	result := *order
+	return &result, nil
+}
+
+// PlaceOptionBuyOrder records a limit order opening qty contracts of an option and fills it according to
+// the current fill rule
+func (b *Broker) PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.orderError != nil {
+		return nil, fmt.Errorf("failed to place buy order for %s: %w", optionSymbol, b.orderError)
+	}
+	if clientOrderID != "" && b.findClientOrder(clientOrderID) != nil {
+		return nil, fmt.Errorf("failed to place buy order for %s: client_order_id must be unique", optionSymbol)
+	}
+	if !limitPrice.IsPositive() {
+		return nil, fmt.Errorf("failed to place buy order for %s: limit price must be greater than 0", optionSymbol)
+	}
+
+	order := b.newOrder(optionSymbol, alpaca.Buy, qty, limitPrice.Decimal())
+	if clientOrderID != "" {
+		order.ClientOrderID = clientOrderID
+	}
+	b.orders = append(b.orders, order)
+	b.matchOrders()
+
+	result := *order
+	return &result, nil
+}
+
+// PlaceOptionRollOrder records a multi-leg order selling closeSymbol and buying openSymbol, which fills
+// both legs at the bid and the ask once the net debit is within netDebit
+func (b *Broker) PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.orderError != nil {
+		return nil, fmt.Errorf("failed to place roll order for %s: %w", closeSymbol, b.orderError)
+	}
+	if clientOrderID != "" && b.findClientOrder(clientOrderID) != nil {
+		return nil, fmt.Errorf("failed to place roll order for %s: client_order_id must be unique", closeSymbol)
+	}
+	position, ok := b.positions[closeSymbol]
+	if !ok || position.Qty.LessThan(qty) {
+		return nil, fmt.Errorf("failed to place roll order for %s: insufficient qty available", closeSymbol)
+	}
+
+	order := b.newOrder("", alpaca.Buy, qty, netDebit.Decimal())
+	if clientOrderID != "" {
+		order.ClientOrderID = clientOrderID
+	}
+	order.OrderClass = broker.OrderClassMultiLeg
+	for _, leg := range []struct {
+		symbol string
+		side   alpaca.Side
+	}{{closeSymbol, alpaca.Sell}, {openSymbol, alpaca.Buy}} {
+		legOrder := b.newOrder(leg.symbol, leg.side, qty, decimal.Zero)
+		legOrder.Type = alpaca.Market
+		legOrder.LimitPrice = nil
+		legOrder.Status = statusHeld
+		order.Legs = append(order.Legs, *legOrder)
+	}
+	b.orders = append(b.orders, order)
+	b.matchOrders()
+
+	result := *order
+	return &result, nil
+}
+
+// CancelOrder cancels an open order and its legs, or a single active leg
+func (b *Broker) CancelOrder(ctx context.Context, orderID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	order := b.findOrder(orderID)
+	if order == nil {
+		return fmt.Errorf("failed to cancel order %s: order not found", orderID)
+	}
+	if order.Status != statusNew {
+		return fmt.Errorf("failed to cancel order %s: order is %s", orderID, order.Status)
+	}
+	b.cancel(order)
+	return nil
+}
+
+// ReplaceOrder replaces an open entry order with a copy at limitPrice under a new ID, like Alpaca's
+// cancel/replace, and matches the replacement against the current fill rule
+func (b *Broker) ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	original := b.findOrder(orderID)
+	if original == nil {
+		return nil, fmt.Errorf("failed to replace order %s: order not found", orderID)
+	}
+	if original.Status != statusNew {
+		return nil, fmt.Errorf("failed to replace order %s: order is %s", orderID, original.Status)
+	}
+	if clientOrderID != "" && b.findClientOrder(clientOrderID) != nil {
+		return nil, fmt.Errorf("failed to replace order %s: client_order_id must be unique", orderID)
+	}
+
+	replacement := b.newOrder(original.Symbol, original.Side, *original.Qty, limitPrice.Decimal())
+	if clientOrderID != "" {
+		replacement.ClientOrderID = clientOrderID
+	}
+	replacement.OrderClass = original.OrderClass
+	replacement.Replaces = &original.ID
+	replacement.Legs = append([]alpaca.Order(nil), original.Legs...)
+
+	now := b.now
+	original.Status = statusReplaced
+	original.ReplacedAt = &now
+	original.ReplacedBy = &replacement.ID
+	original.UpdatedAt = now
+	b.orders = append(b.orders, replacement)
+
+	if b.fillRule == FillAtLimit {
+		b.fill(replacement, limitPrice.Decimal())
+	} else {
+		b.matchOrders()
+	}
+
+	result := *replacement
+	return &result, nil
+}
+
+// MatchOrders re-evaluates every open order and active exit leg against the current quotes.
+// Call it after moving the clock or changing quotes to let resting orders fill.
+func (b *Broker) MatchOrders() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.matchOrders()
+}
+
+// CancelOpenOrders cancels every entry order that has not filled, e.g. DAY orders at the close
+func (b *Broker) CancelOpenOrders() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, order := range b.orders {
+		if order.Status == statusNew {
+			b.cancel(order)
+		}
+	}
+}
+
+// SettlePosition closes a position at price per share, e.g. at expiry for its intrinsic value,
+// cancelling any exit legs and sell orders still working against it
+func (b *Broker) SettlePosition(symbol string, price money.Money) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	position, ok := b.positions[symbol]
+	if !ok {
+		return fmt.Errorf("no position in %s", symbol)
+	}
+
+	b.buyingPower = b.buyingPower.Add(price.Mul(position.Qty).MulInt(money.ContractMultiplier))
+	delete(b.positions, symbol)
+
+	for _, order := range b.orders {
+		if order.Symbol == symbol && order.Side == alpaca.Sell && order.Status == statusNew {
+			b.cancel(order)
+		}
+		for i := range order.Legs {
+			if order.Legs[i].Symbol == symbol && order.Legs[i].Status == statusNew {
+				b.cancel(&order.Legs[i])
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Broker) matchOrders() {
+	for _, order := range b.orders {
+		switch order.Status {
+		case statusNew:
+			if order.OrderClass == broker.OrderClassMultiLeg {
+				b.fillMultiLeg(order)
+				continue
+			}
+			if price, ok := b.fillPrice(order); ok {
+				b.fill(order, price)
+			}
+		case statusFilled:
+			for i := range order.Legs {
+				leg := &order.Legs[i]
+				if leg.Status != statusNew {
+					continue
+				}
+				price, ok := b.fillPrice(leg)
+				if !ok {
+					continue
+				}
+				b.fill(leg, price)
+				// The legs are one-cancels-other
+				for j := range order.Legs {
+					if j != i && (order.Legs[j].Status == statusNew || order.Legs[j].Status == statusHeld) {
+						b.cancel(&order.Legs[j])
+					}
+				}
+				break
+			}
+		}
+	}
+}
+
+// fillPrice returns the price an open order fills at under the current quotes and fill rule,
+// or false when it doesn't fill yet
+func (b *Broker) fillPrice(order *alpaca.Order) (decimal.Decimal, bool) {
+	if b.fillRule == FillNever {
+		return decimal.Zero, false
+	}
+	quote := b.optionQuote(order.Symbol)
+	if quote == nil {
+		return decimal.Zero, false
+	}
+	bid := money.NewFromFloat(quote.BidPrice)
+	ask := money.NewFromFloat(quote.AskPrice)
+
+	switch {
+	case order.Side == alpaca.Buy:
+		if !ask.IsPositive() {
+			return decimal.Zero, false
+		}
+		if b.fillRule == FillAtLimit || ask.LessThanOrEqual(money.FromPtr(order.LimitPrice)) {
+			return *order.LimitPrice, true
+		}
+	case order.Type == alpaca.Stop:
+		// A stop becomes a market order once the bid falls to its stop price
+		if bid.IsPositive() && bid.LessThanOrEqual(money.FromPtr(order.StopPrice)) {
+			return bid.Decimal(), true
+		}
+	case order.Type == alpaca.Market:
+		if bid.IsPositive() {
+			return bid.Decimal(), true
+		}
+	default:
+		if bid.GreaterThanOrEqual(money.FromPtr(order.LimitPrice)) {
+			return *order.LimitPrice, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// fillMultiLeg fills every leg of a multi-leg order, sells at the bid and buys at the ask, once all legs
+// are quoted and the net debit is within the order's limit
+func (b *Broker) fillMultiLeg(order *alpaca.Order) {
+	if b.fillRule == FillNever {
+		return
+	}
+	prices := make([]decimal.Decimal, len(order.Legs))
+	net := money.Zero
+	for i, leg := range order.Legs {
+		quote := b.optionQuote(leg.Symbol)
+		if quote == nil || quote.BidPrice <= 0 || quote.AskPrice <= 0 {
+			return
+		}
+		if leg.Side == alpaca.Buy {
+			prices[i] = decimal.NewFromFloat(quote.AskPrice)
+			net = net.Add(money.New(prices[i]))
+		} else {
+			prices[i] = decimal.NewFromFloat(quote.BidPrice)
+			net = net.Sub(money.New(prices[i]))
+		}
+	}
+	if b.fillRule != FillAtLimit && net.GreaterThan(money.FromPtr(order.LimitPrice)) {
+		return
+	}
+
+	for i := range order.Legs {
+		b.fill(&order.Legs[i], prices[i])
+	}
+	now := b.now
+	netPrice := net.Decimal()
+	order.Status = statusFilled
+	order.FilledAt = &now
+	order.UpdatedAt = now
+	order.FilledQty = *order.Qty
+	order.FilledAvgPrice = &netPrice
+}
+
+// findOrder returns the order or bracket leg with the given ID
+func (b *Broker) findOrder(orderID string) *alpaca.Order {
+	for _, order := range b.orders {
+		if order.ID == orderID {
+			return order
+		}
+		for i := range order.Legs {
+			if order.Legs[i].ID == orderID {
+				return &order.Legs[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Broker) findClientOrder(clientOrderID string) *alpaca.Order {
+	for _, order := range b.orders {
+		if order.ClientOrderID == clientOrderID {
+			return order
+		}
+	}
+	return nil
+}
+
+func (b *Broker) newOrder(symbol string, side alpaca.Side, qty, limitPrice decimal.Decimal) *alpaca.Order {
+	b.nextID++
+	return &alpaca.Order{
+		ID:            fmt.Sprintf("sim-%d", b.nextID),
+		ClientOrderID: fmt.Sprintf("sim-client-%d", b.nextID),
+		CreatedAt:     b.now,
+		UpdatedAt:     b.now,
+		SubmittedAt:   b.now,
+		Symbol:        symbol,
+		AssetClass:    "us_option",
+		OrderClass:    alpaca.Simple,
+		Type:          alpaca.Limit,
+		Side:          side,
+		TimeInForce:   alpaca.Day,
+		Status:        statusNew,
+		Qty:           &qty,
+		LimitPrice:    &limitPrice,
+	}
+}
+
+// fill executes an order at price, moving cash and positions, and activates its exit legs
+func (b *Broker) fill(order *alpaca.Order, price decimal.Decimal) {
+	now := b.now
+	order.Status = statusFilled
+	order.FilledAt = &now
+	order.UpdatedAt = now
+	order.FilledQty = *order.Qty
+	order.FilledAvgPrice = &price
+
+	notional := order.Qty.Mul(price).Mul(decimal.NewFromInt(money.ContractMultiplier))
+	position := b.positions[order.Symbol]
+
+	switch order.Side {
+	case alpaca.Buy:
+		b.buyingPower = b.buyingPower.Sub(money.New(notional))
+		if position == nil {
+			position = &alpaca.Position{
+				Symbol:     order.Symbol,
+				AssetClass: "us_option",
+				Side:       "long",
+			}
+			b.positions[order.Symbol] = position
+		}
+		position.CostBasis = position.CostBasis.Add(notional)
+		position.Qty = position.Qty.Add(*order.Qty)
+		position.QtyAvailable = position.Qty
+		position.AvgEntryPrice = position.CostBasis.Div(position.Qty.Mul(decimal.NewFromInt(money.ContractMultiplier)))
+	case alpaca.Sell:
+		b.buyingPower = b.buyingPower.Add(money.New(notional))
+		if position != nil {
+			remaining := position.Qty.Sub(*order.Qty)
+			if remaining.LessThanOrEqual(decimal.Zero) {
+				delete(b.positions, order.Symbol)
+			} else {
+				position.CostBasis = position.AvgEntryPrice.Mul(remaining).Mul(decimal.NewFromInt(money.ContractMultiplier))
+				position.Qty = remaining
+				position.QtyAvailable = remaining
+			}
+		}
+	}
+
+	for i := range order.Legs {
+		if order.Legs[i].Status == statusHeld {
+			order.Legs[i].Status = statusNew
+			order.Legs[i].UpdatedAt = now
+		}
+	}
+}
+
+func (b *Broker) cancel(order *alpaca.Order) {
+	now := b.now
+	order.Status = statusCanceled
+	order.CanceledAt = &now
+	order.UpdatedAt = now
+	for i := range order.Legs {
+		if order.Legs[i].Status == statusHeld || order.Legs[i].Status == statusNew {
+			b.cancel(&order.Legs[i])
+		}
+	}
+}
+
+func (b *Broker) optionQuote(optionSymbol string) *marketdata.OptionQuote {
+	option, err := athenaxalpaca.ParseOptionTicker(optionSymbol)
+	if err != nil {
+		return nil
+	}
+	snapshot, ok := b.optionChains[option.Underlying][optionSymbol]
+	if !ok {
+		return nil
+	}
+	return snapshot.LatestQuote
+}
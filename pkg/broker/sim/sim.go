@@ -0,0 +1,389 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// Session is a single trading day in the simulated market calendar
+type Session struct {
+	Open  time.Time
+	Close time.Time
+}
+
+// Broker is a fully in-memory broker.Broker used for deterministic strategy tests and simulations.
+// All state is set explicitly through its setters; nothing touches the network or the wall clock.
+type Broker struct {
+	mu sync.Mutex
+
+	now      time.Time
+	calendar []Session
+
+	dailyBars map[string][]marketdata.Bar
+	quotes    map[string]marketdata.Quote
+	// optionChains maps underlying ticker -> option symbol -> snapshot
+	optionChains map[string]map[string]marketdata.OptionSnapshot
+
+	positions   map[string]*alpaca.Position
+	buyingPower money.Money
+	lastEquity  money.Money
+
+	orders         []*alpaca.Order
+	fillRule       FillRule
+	orderError     error
+	rejectBrackets bool
+	nextID         int
+}
+
+// Ensure Broker satisfies the broker interfaces
+var (
+	_ broker.Broker         = (*Broker)(nil)
+	_ broker.MultiLegBroker = (*Broker)(nil)
+)
+
+// NewBroker creates an empty simulated broker whose clock is set to now
+func NewBroker(now time.Time) *Broker {
+	return &Broker{
+		now:          now,
+		dailyBars:    make(map[string][]marketdata.Bar),
+		quotes:       make(map[string]marketdata.Quote),
+		optionChains: make(map[string]map[string]marketdata.OptionSnapshot),
+		positions:    make(map[string]*alpaca.Position),
+		fillRule:     FillAtLimit,
+	}
+}
+
+// RegularSession returns the regular 9:30-16:00 session for the given date in the given location
+func RegularSession(date time.Time, loc *time.Location) Session {
+	y, m, d := date.Date()
+	return Session{
+		Open:  time.Date(y, m, d, 9, 30, 0, 0, loc),
+		Close: time.Date(y, m, d, 16, 0, 0, 0, loc),
+	}
+}
+
+// Now returns the current simulated time
+func (b *Broker) Now() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.now
+}
+
+// SetTime moves the simulated clock to t
+func (b *Broker) SetTime(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = t
+}
+
+// Advance moves the simulated clock forward by d
+func (b *Broker) Advance(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = b.now.Add(d)
+}
+
+// SetCalendar replaces the market calendar with the given sessions
+func (b *Broker) SetCalendar(sessions ...Session) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calendar = append([]Session(nil), sessions...)
+	sort.Slice(b.calendar, func(i, j int) bool {
+		return b.calendar[i].Open.Before(b.calendar[j].Open)
+	})
+}
+
+// AddDailyBars adds daily bars for a symbol, keeping them ordered by timestamp
+func (b *Broker) AddDailyBars(symbol string, bars ...marketdata.Bar) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dailyBars[symbol] = append(b.dailyBars[symbol], bars...)
+	sort.Slice(b.dailyBars[symbol], func(i, j int) bool {
+		return b.dailyBars[symbol][i].Timestamp.Before(b.dailyBars[symbol][j].Timestamp)
+	})
+}
+
+// SetQuote sets the latest bid and ask for an equity symbol
+func (b *Broker) SetQuote(symbol string, bid, ask float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.quotes[symbol] = marketdata.Quote{Timestamp: b.now, BidPrice: bid, AskPrice: ask}
+}
+
+// SetOption adds or replaces a single option in the chain of its underlying
+func (b *Broker) SetOption(optionSymbol string, snapshot marketdata.OptionSnapshot) error {
+	option, err := athenaxalpaca.ParseOptionTicker(optionSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to parse option symbol %s: %w", optionSymbol, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.optionChains[option.Underlying] == nil {
+		b.optionChains[option.Underlying] = make(map[string]marketdata.OptionSnapshot)
+	}
+	b.optionChains[option.Underlying][optionSymbol] = snapshot
+	return nil
+}
+
+// SetOptionQuote updates the bid and ask of an option already present in a chain
+func (b *Broker) SetOptionQuote(optionSymbol string, bid, ask float64) error {
+	option, err := athenaxalpaca.ParseOptionTicker(optionSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to parse option symbol %s: %w", optionSymbol, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot, ok := b.optionChains[option.Underlying][optionSymbol]
+	if !ok {
+		return fmt.Errorf("option %s is not in the chain", optionSymbol)
+	}
+	snapshot.LatestQuote = &marketdata.OptionQuote{Timestamp: b.now, BidPrice: bid, AskPrice: ask}
+	b.optionChains[option.Underlying][optionSymbol] = snapshot
+	return nil
+}
+
+// ClearOptionChain removes every option for an underlying
+func (b *Broker) ClearOptionChain(underlyingTicker string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.optionChains, underlyingTicker)
+}
+
+// SetPosition adds or replaces a position
+func (b *Broker) SetPosition(position alpaca.Position) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.positions[position.Symbol] = &position
+}
+
+// SetBuyingPower sets the non-marginable buying power
+func (b *Broker) SetBuyingPower(buyingPower money.Money) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buyingPower = buyingPower
+}
+
+// SetLastEquity sets the equity at the previous close, which GetEquity otherwise reports as the current equity
+func (b *Broker) SetLastEquity(equity money.Money) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastEquity = equity
+}
+
+// BuyingPower returns the current non-marginable buying power
+func (b *Broker) BuyingPower() money.Money {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buyingPower
+}
+
+// Positions returns a copy of all open positions ordered by symbol
+func (b *Broker) Positions() []alpaca.Position {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sortedPositions()
+}
+
+// IsMarketOpen reports whether the simulated clock falls inside a calendar session
+func (b *Broker) IsMarketOpen(ctx context.Context) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, session := range b.calendar {
+		if !b.now.Before(session.Open) && b.now.Before(session.Close) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetLastTradingDayClose returns the close of the most recent daily bar dated before the simulated day
+func (b *Broker) GetLastTradingDayClose(ctx context.Context, symbol string) (money.Money, error) {
+	if symbol == "" {
+		return money.Zero, fmt.Errorf("symbol cannot be empty")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bars := b.dailyBars[symbol]
+	today := dateOf(b.now)
+	for i := len(bars) - 1; i >= 0; i-- {
+		if dateOf(bars[i].Timestamp).Before(today) {
+			return money.NewFromFloat(bars[i].Close), nil
+		}
+	}
+	return money.Zero, fmt.Errorf("no data found for %s before %s", symbol, today.Format("2006-01-02"))
+}
+
+// GetLatestQuote returns the ask price of the latest quote for a symbol
+func (b *Broker) GetLatestQuote(ctx context.Context, symbol string) (money.Money, error) {
+	if symbol == "" {
+		return money.Zero, fmt.Errorf("symbol cannot be empty")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	quote, ok := b.quotes[symbol]
+	if !ok {
+		return money.Zero, fmt.Errorf("no quote available for %s", symbol)
+	}
+	return money.NewFromFloat(quote.AskPrice), nil
+}
+
+// GetOptionsPositions returns all option positions on the given underlying
+func (b *Broker) GetOptionsPositions(ctx context.Context, underlyingTicker string) ([]alpaca.Position, error) {
+	if underlyingTicker == "" {
+		return nil, fmt.Errorf("underlying ticker cannot be empty")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var optionPositions []alpaca.Position
+	for _, position := range b.sortedPositions() {
+		option, err := athenaxalpaca.ParseOptionTicker(position.Symbol)
+		if err != nil {
+			continue
+		}
+		if option.Underlying == underlyingTicker {
+			optionPositions = append(optionPositions, position)
+		}
+	}
+	return optionPositions, nil
+}
+
+// GetInstanceOptionsPositions returns the option positions on the underlying opened by the given
+// strategy instance, attributed from its orders like the Alpaca client does
+func (b *Broker) GetInstanceOptionsPositions(ctx context.Context, instanceID, underlyingTicker string) ([]alpaca.Position, error) {
+	positions, err := b.GetOptionsPositions(ctx, underlyingTicker)
+	if err != nil {
+		return nil, err
+	}
+	return athenaxalpaca.AttributePositions(positions, b.Orders(), instanceID), nil
+}
+
+// GetCallLeapsByDelta selects a call LEAP from the simulated chain using the same rules as the Alpaca client
+func (b *Broker) GetCallLeapsByDelta(ctx context.Context, underlyingTicker string, minDelta float64, minExpiryMonths int) (string, *marketdata.OptionSnapshot, error) {
+	if underlyingTicker == "" {
+		return "", nil, fmt.Errorf("underlying ticker cannot be empty")
+	}
+
+	if minDelta <= 0 {
+		return "", nil, fmt.Errorf("minimum delta must be greater than 0")
+	}
+
+	if minExpiryMonths <= 0 {
+		return "", nil, fmt.Errorf("minimum expiry months must be greater than 0")
+	}
+
+	b.mu.Lock()
+	minExpiry := dateOf(b.now.AddDate(0, minExpiryMonths, 0))
+	leaps := make(map[string]marketdata.OptionSnapshot)
+	for symbol, snapshot := range b.optionChains[underlyingTicker] {
+		option, err := athenaxalpaca.ParseOptionTicker(symbol)
+		if err != nil || option.Type != "C" || option.Expiry.Before(minExpiry) {
+			continue
+		}
+		leaps[symbol] = snapshot
+	}
+	b.mu.Unlock()
+
+	if len(leaps) == 0 {
+		return "", nil, fmt.Errorf("no call LEAPS options found for %s", underlyingTicker)
+	}
+
+	return athenaxalpaca.SelectCallLeapsByDelta(leaps, minDelta)
+}
+
+// GetOptionSnapshot returns the option's snapshot from the simulated chain
+func (b *Broker) GetOptionSnapshot(ctx context.Context, optionSymbol string) (*marketdata.OptionSnapshot, error) {
+	option, err := athenaxalpaca.ParseOptionTicker(optionSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot, ok := b.optionChains[option.Underlying][optionSymbol]
+	if !ok {
+		return nil, fmt.Errorf("no option snapshot for %s", optionSymbol)
+	}
+	return &snapshot, nil
+}
+
+// GetNonMarginableBuyingPower returns the simulated buying power
+func (b *Broker) GetNonMarginableBuyingPower(ctx context.Context) (money.Money, error) {
+	return b.BuyingPower(), nil
+}
+
+// GetEquity returns the buying power plus the marked value of every position, and the last equity set
+// with SetLastEquity
+func (b *Broker) GetEquity(ctx context.Context) (money.Money, money.Money, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	equity := b.buyingPower
+	for _, position := range b.sortedPositions() {
+		if position.MarketValue != nil {
+			equity = equity.Add(money.FromPtr(position.MarketValue))
+		} else {
+			equity = equity.Add(money.New(position.CostBasis))
+		}
+	}
+	if b.lastEquity.IsZero() {
+		return equity, equity, nil
+	}
+	return equity, b.lastEquity, nil
+}
+
+// GetAllPositions returns every open position ordered by symbol
+func (b *Broker) GetAllPositions(ctx context.Context) ([]alpaca.Position, error) {
+	return b.Positions(), nil
+}
+
+func (b *Broker) sortedPositions() []alpaca.Position {
+	positions := make([]alpaca.Position, 0, len(b.positions))
+	for _, position := range b.positions {
+		positions = append(positions, b.markToMarket(*position))
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Symbol < positions[j].Symbol
+	})
+	return positions
+}
+
+// markToMarket fills in the position's current price and value from the option's mid quote, when known
+func (b *Broker) markToMarket(position alpaca.Position) alpaca.Position {
+	quote := b.optionQuote(position.Symbol)
+	if quote == nil || quote.BidPrice <= 0 || quote.AskPrice <= 0 {
+		return position
+	}
+
+	currentPrice := money.NewFromFloat(quote.BidPrice).Add(money.NewFromFloat(quote.AskPrice)).DivInt(2).Decimal()
+	marketValue := currentPrice.Mul(position.Qty).Mul(decimal.NewFromInt(money.ContractMultiplier))
+	unrealizedPL := marketValue.Sub(position.CostBasis)
+	position.CurrentPrice = &currentPrice
+	position.MarketValue = &marketValue
+	position.UnrealizedPL = &unrealizedPL
+	return position
+}
+
+// dateOf truncates t to midnight UTC of its calendar date
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,713 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/engine"
+	"github.com/vignesh-goutham/AthenaX/pkg/halt"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
+	"github.com/vignesh-goutham/AthenaX/pkg/positions"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv is the environment variable naming the config file when none is given explicitly
+const PathEnv = "ATHENAX_CONFIG"
+
+// Config is the declarative runtime configuration of AthenaX
+type Config struct {
+	Broker       Broker           `yaml:"broker" json:"broker"`
+	Notification Notification     `yaml:"notification" json:"notification"`
+	Risk         Risk             `yaml:"risk" json:"risk"`
+	Engine       Engine           `yaml:"engine" json:"engine"`
+	Orders       Orders           `yaml:"orders" json:"orders"`
+	Positions    Positions        `yaml:"positions" json:"positions"`
+	State        State            `yaml:"state" json:"state"`
+	Journal      Journal          `yaml:"journal" json:"journal"`
+	Strategies   []StrategyConfig `yaml:"strategies" json:"strategies"`
+}
+
+// Environment variables guarding live trading
+const (
+	// LiveConfirmationEnv must hold LiveConfirmationToken to trade live without the CLI's --confirm-live flag
+	LiveConfirmationEnv = "ATHENAX_CONFIRM_LIVE"
+	// LiveConfirmationToken is the value LiveConfirmationEnv must be set to
+	LiveConfirmationToken = "trade-real-money"
+)
+
+// Broker holds the Alpaca environment and the credentials of each environment
+type Broker struct {
+	// Environment is "paper" (the default) or "live"
+	Environment string      `yaml:"environment" json:"environment"`
+	Paper       Credentials `yaml:"paper" json:"paper"`
+	Live        Credentials `yaml:"live" json:"live"`
+}
+
+// Credentials are one environment's Alpaca API keys
+type Credentials struct {
+	APIKey    string `yaml:"api_key" json:"api_key"`
+	SecretKey string `yaml:"secret_key" json:"secret_key"`
+}
+
+// Env returns the selected environment
+func (b Broker) Env() alpaca.Environment {
+	return alpaca.Environment(b.Environment)
+}
+
+// Credentials returns the credentials of the selected environment
+func (b Broker) Credentials() Credentials {
+	if b.Env() == alpaca.Live {
+		return b.Live
+	}
+	return b.Paper
+}
+
+// ConfirmLive refuses to trade live unless confirmed, either by the caller (e.g. a --confirm-live flag)
+// or by LiveConfirmationEnv holding LiveConfirmationToken. Paper trading needs no confirmation.
+func (b Broker) ConfirmLive(confirmed bool) error {
+	if b.Env() != alpaca.Live {
+		return nil
+	}
+	if confirmed || os.Getenv(LiveConfirmationEnv) == LiveConfirmationToken {
+		return nil
+	}
+	return fmt.Errorf("broker.environment is live: confirm live trading with --confirm-live or %s=%s",
+		LiveConfirmationEnv, LiveConfirmationToken)
+}
+
+// Notification holds the webhook settings
+type Notification struct {
+	// Method is "generic" or "discord"
+	Method           string `yaml:"method" json:"method"`
+	NoisyWebhookURL  string `yaml:"noisy_webhook_url" json:"noisy_webhook_url"`
+	NormalWebhookURL string `yaml:"normal_webhook_url" json:"normal_webhook_url"`
+}
+
+// Risk holds account-wide limits. Apart from MaxActiveOptions and HaltAfterOrderFailures they are enforced
+// on every opening order by the risk manager; a zero value disables the limit.
+type Risk struct {
+	// MaxActiveOptions is the default max_active_options of every strategy that doesn't set its own
+	MaxActiveOptions int `yaml:"max_active_options" json:"max_active_options"`
+	// MaxTradeNotional is the most premium a single order may commit, in dollars
+	MaxTradeNotional float64 `yaml:"max_trade_notional" json:"max_trade_notional"`
+	// MaxUnderlyingPercent is the most option premium held on one underlying, as a percent of equity
+	MaxUnderlyingPercent float64 `yaml:"max_underlying_percent" json:"max_underlying_percent"`
+	// MaxTotalPremium is the most option premium held across the account, in dollars
+	MaxTotalPremium float64 `yaml:"max_total_premium" json:"max_total_premium"`
+	// MaxPortfolioDelta is the largest share-equivalent delta of the option positions
+	MaxPortfolioDelta float64 `yaml:"max_portfolio_delta" json:"max_portfolio_delta"`
+	// DailyLossLimit stops opening orders once equity fell this many dollars since the previous close
+	DailyLossLimit float64 `yaml:"daily_loss_limit" json:"daily_loss_limit"`
+	// MaxOrdersPerDay is the most opening orders submitted in a trading day
+	MaxOrdersPerDay int `yaml:"max_orders_per_day" json:"max_orders_per_day"`
+	// HaltAfterOrderFailures halts trading after this many order submissions fail in a row
+	HaltAfterOrderFailures int `yaml:"halt_after_order_failures" json:"halt_after_order_failures"`
+}
+
+// Limits returns the limits enforced by the risk manager
+func (r Risk) Limits() risk.Limits {
+	return risk.Limits{
+		MaxTradeNotional:     money.NewFromFloat(r.MaxTradeNotional),
+		MaxUnderlyingPercent: r.MaxUnderlyingPercent,
+		MaxTotalPremium:      money.NewFromFloat(r.MaxTotalPremium),
+		MaxPortfolioDelta:    r.MaxPortfolioDelta,
+		DailyLossLimit:       money.NewFromFloat(r.DailyLossLimit),
+		MaxOrdersPerDay:      r.MaxOrdersPerDay,
+	}
+}
+
+// Engine holds the engine settings
+type Engine struct {
+	// StrategyTimeout bounds each strategy run, as a Go duration such as "90s"; "0" disables the limit
+	StrategyTimeout string `yaml:"strategy_timeout" json:"strategy_timeout"`
+}
+
+// Timeout returns the parsed strategy timeout, or the engine default when unset
+func (e Engine) Timeout() time.Duration {
+	if e.StrategyTimeout == "" {
+		return engine.DefaultStrategyTimeout
+	}
+	timeout, _ := time.ParseDuration(e.StrategyTimeout)
+	return timeout
+}
+
+// Orders configures how submitted orders are followed until they fill
+type Orders struct {
+	// FillTimeout is how long an order may rest at a limit price before OnTimeout applies, as a Go duration
+	// such as "2m"; empty or "0" leaves orders resting untracked
+	FillTimeout string `yaml:"fill_timeout" json:"fill_timeout"`
+	// PollInterval is how often a tracked order's status is checked; it defaults to 5s
+	PollInterval string `yaml:"poll_interval" json:"poll_interval"`
+	// OnTimeout is "cancel" (the default), or "walk" to cancel and replace the order at a limit walked toward the ask
+	OnTimeout string `yaml:"on_timeout" json:"on_timeout"`
+	// MaxSteps is how many times "walk" reprices an order before cancelling it; it defaults to 3
+	MaxSteps int `yaml:"max_steps" json:"max_steps"`
+}
+
+// Tracker returns the order tracker configuration, or false when orders aren't tracked
+func (o Orders) Tracker() (ordertracker.Config, bool) {
+	timeout, _ := time.ParseDuration(o.FillTimeout)
+	if timeout <= 0 {
+		return ordertracker.Config{}, false
+	}
+	pollInterval, _ := time.ParseDuration(o.PollInterval)
+	return ordertracker.Config{
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+		OnTimeout:    ordertracker.Action(o.OnTimeout),
+		MaxSteps:     o.MaxSteps,
+	}, true
+}
+
+// Positions configures the position manager's exit rules; a zero value disables the rule
+type Positions struct {
+	// Underlyings are the tickers whose option positions the manager checks
+	Underlyings []string `yaml:"underlyings" json:"underlyings"`
+	// MaxHoldDays sells positions held this many calendar days
+	MaxHoldDays int `yaml:"max_hold_days" json:"max_hold_days"`
+	// MinDaysToExpiry sells positions this many calendar days before they expire, e.g. 90
+	MinDaysToExpiry int `yaml:"min_days_to_expiry" json:"min_days_to_expiry"`
+	// TrailingStopPercent sells positions that fall this far below their high-water mark
+	TrailingStopPercent float64 `yaml:"trailing_stop_percent" json:"trailing_stop_percent"`
+	// MinDelta sells positions whose delta decays below it
+	MinDelta float64 `yaml:"min_delta" json:"min_delta"`
+	// ProfitLocks sell positions whose gain falls back to the floor locked in by the highest rung reached
+	ProfitLocks []positions.ProfitLock `yaml:"profit_locks" json:"profit_locks"`
+	// Roll rolls calls into a later expiry instead of letting them age into short-dated options
+	Roll Roll `yaml:"roll" json:"roll"`
+}
+
+// Roll configures rolling calls into a later expiry; a zero days_to_expiry disables it
+type Roll struct {
+	// DaysToExpiry rolls calls this many calendar days or fewer before they expire, e.g. 120
+	DaysToExpiry int `yaml:"days_to_expiry" json:"days_to_expiry"`
+	// MinDelta is the minimum delta of the replacement; it defaults to 0.60
+	MinDelta float64 `yaml:"min_delta" json:"min_delta"`
+	// MinExpiryMonths is the minimum months to expiry of the replacement; it defaults to 11
+	MinExpiryMonths int `yaml:"min_expiry_months" json:"min_expiry_months"`
+	// FillTimeout is how long each order of a roll may rest before it is cancelled, as a Go duration; it defaults to 2m
+	FillTimeout string `yaml:"fill_timeout" json:"fill_timeout"`
+}
+
+// Rules returns the configured exit rules
+func (p Positions) Rules() positions.Rules {
+	return positions.Rules{
+		MaxHoldDays:         p.MaxHoldDays,
+		MinDaysToExpiry:     p.MinDaysToExpiry,
+		TrailingStopPercent: p.TrailingStopPercent,
+		MinDelta:            p.MinDelta,
+		ProfitLocks:         p.ProfitLocks,
+	}
+}
+
+// RollConfig returns the roll settings, with defaults for those unset
+func (p Positions) RollConfig() positions.RollConfig {
+	config := positions.RollConfig{
+		DaysToExpiry:    p.Roll.DaysToExpiry,
+		MinDelta:        p.Roll.MinDelta,
+		MinExpiryMonths: p.Roll.MinExpiryMonths,
+		FillTimeout:     positions.DefaultRollFillTimeout,
+	}
+	if config.MinDelta == 0 {
+		config.MinDelta = positions.DefaultRollMinDelta
+	}
+	if config.MinExpiryMonths == 0 {
+		config.MinExpiryMonths = positions.DefaultRollMinExpiryMonths
+	}
+	if p.Roll.FillTimeout != "" {
+		config.FillTimeout, _ = time.ParseDuration(p.Roll.FillTimeout)
+	}
+	return config
+}
+
+// State selects where the journal, idempotency keys and strategy state are kept
+type State struct {
+	// Backend is "local" (the default) or "s3"
+	Backend string `yaml:"backend" json:"backend"`
+	// Path is the directory of the local backend
+	Path string  `yaml:"path" json:"path"`
+	S3   S3State `yaml:"s3" json:"s3"`
+}
+
+// S3State configures the S3-compatible backend. Credentials default to the standard AWS environment variables.
+type S3State struct {
+	// Endpoint is the URL of an S3-compatible service such as MinIO; empty uses AWS S3
+	Endpoint        string `yaml:"endpoint" json:"endpoint"`
+	Region          string `yaml:"region" json:"region"`
+	Bucket          string `yaml:"bucket" json:"bucket"`
+	Prefix          string `yaml:"prefix" json:"prefix"`
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key"`
+	SessionToken    string `yaml:"session_token" json:"session_token"`
+}
+
+// OpenStateStore creates the configured state store
+func (c *Config) OpenStateStore() (statestore.StateStore, error) {
+	switch c.State.Backend {
+	case "s3":
+		return statestore.NewS3Store(statestore.S3Config{
+			Endpoint:        c.State.S3.Endpoint,
+			Region:          c.State.S3.Region,
+			Bucket:          c.State.S3.Bucket,
+			Prefix:          c.State.S3.Prefix,
+			AccessKeyID:     c.State.S3.AccessKeyID,
+			SecretAccessKey: c.State.S3.SecretAccessKey,
+			SessionToken:    c.State.S3.SessionToken,
+		})
+	default:
+		return statestore.NewLocalStore(c.State.Path)
+	}
+}
+
+// Journal holds the trade journal settings
+type Journal struct {
+	// Path is the state store key of the SQLite journal, a file under state.path with the local backend
+	Path string `yaml:"path" json:"path"`
+	// Disabled turns journaling off
+	Disabled bool `yaml:"disabled" json:"disabled"`
+}
+
+// OpenJournal opens the configured journal in store tagged with the trading environment, or returns nil when disabled
+func (c *Config) OpenJournal(ctx context.Context, store statestore.StateStore) (*journal.Journal, error) {
+	if c.Journal.Disabled {
+		return nil, nil
+	}
+	j, err := journal.OpenStore(ctx, store, c.Journal.Path)
+	if err != nil {
+		return nil, err
+	}
+	j.SetEnvironment(c.Broker.Environment)
+	return j, nil
+}
+
+// RiskManager wraps b in the risk manager when any risk limit is set, counting orders in store and
+// recording rejections in j, and returns b unchanged otherwise
+func (c *Config) RiskManager(b broker.Broker, store statestore.StateStore, j *journal.Journal) broker.Broker {
+	limits := c.Risk.Limits()
+	if !limits.Enabled() {
+		return b
+	}
+	manager := risk.NewBroker(b, limits, store)
+	manager.SetJournal(j)
+	return manager
+}
+
+// HaltSwitch returns the halt flag kept in store, tripping after risk.halt_after_order_failures failed orders
+func (c *Config) HaltSwitch(store statestore.StateStore) *halt.Switch {
+	return halt.NewSwitch(store, c.Risk.HaltAfterOrderFailures)
+}
+
+// Gate wraps b in the risk manager and then the halt switch, so that no order is sent while trading
+// is halted and every opening order is checked against the risk limits
+func (c *Config) Gate(b broker.Broker, sw *halt.Switch, store statestore.StateStore, j *journal.Journal) broker.Broker {
+	return halt.NewBroker(c.RiskManager(b, store, j), sw)
+}
+
+// StrategyConfig configures one strategy instance
+type StrategyConfig struct {
+	// ID uniquely identifies the instance; it defaults to the strategy name
+	ID     string         `yaml:"id" json:"id"`
+	Name   string         `yaml:"name" json:"name"`
+	Params map[string]any `yaml:"params" json:"params"`
+	// DryRun runs the instance in shadow mode: orders are computed, logged and notified but never sent
+	DryRun bool `yaml:"dry_run" json:"dry_run"`
+}
+
+// InstanceID returns the instance's ID, defaulting to the strategy name
+func (s StrategyConfig) InstanceID() string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return s.Name
+}
+
+// Load reads the config file at path, applies environment variable overrides and validates the result.
+// An empty path yields a config built from defaults and the environment only.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path != "" {
+		if err := cfg.read(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.applyDefaults()
+	cfg.applyEnv()
+
+	if err := cfg.Validate(); err != nil {
+		if path != "" {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// read decodes the file, as JSON for a .json extension and YAML otherwise, rejecting unknown fields
+func (c *Config) read(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			return fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.Broker.Environment == "" {
+		c.Broker.Environment = string(alpaca.Paper)
+	}
+	if c.Notification.Method == "" {
+		c.Notification.Method = "generic"
+	}
+	if c.State.Backend == "" {
+		c.State.Backend = "local"
+	}
+	if c.State.Path == "" {
+		c.State.Path = "."
+	}
+	if c.Journal.Path == "" {
+		c.Journal.Path = journal.DefaultPath
+	}
+}
+
+// applyEnv lets environment variables override values from the file.
+// Strategy parameters tied to an environment variable are resolved in Overrides.
+func (c *Config) applyEnv() {
+	overrideString(&c.Broker.Environment, "ALPACA_ENVIRONMENT")
+	// The unprefixed variables predate environments and only ever apply to paper trading
+	overrideString(&c.Broker.Paper.APIKey, "ALPACA_API_KEY")
+	overrideString(&c.Broker.Paper.SecretKey, "ALPACA_SECRET_KEY")
+	overrideString(&c.Broker.Paper.APIKey, "ALPACA_PAPER_API_KEY")
+	overrideString(&c.Broker.Paper.SecretKey, "ALPACA_PAPER_SECRET_KEY")
+	overrideString(&c.Broker.Live.APIKey, "ALPACA_LIVE_API_KEY")
+	overrideString(&c.Broker.Live.SecretKey, "ALPACA_LIVE_SECRET_KEY")
+	overrideString(&c.Notification.Method, "NOTIFY_METHOD")
+	overrideString(&c.Notification.NoisyWebhookURL, "NOTIFY_NOISY_WEBHOOK_URL")
+	overrideString(&c.Notification.NormalWebhookURL, "NOTIFY_NORMAL_WEBHOOK_URL")
+	overrideString(&c.Orders.FillTimeout, "ATHENAX_ORDER_FILL_TIMEOUT")
+	overrideString(&c.Orders.OnTimeout, "ATHENAX_ORDER_ON_TIMEOUT")
+	overrideString(&c.Journal.Path, "ATHENAX_JOURNAL")
+	overrideString(&c.State.Backend, "ATHENAX_STATE_BACKEND")
+	overrideString(&c.State.Path, "ATHENAX_STATE_PATH")
+	overrideString(&c.State.S3.Endpoint, "ATHENAX_S3_ENDPOINT")
+	overrideString(&c.State.S3.Bucket, "ATHENAX_S3_BUCKET")
+	overrideString(&c.State.S3.Prefix, "ATHENAX_S3_PREFIX")
+	// The standard AWS variables only fill in what the file leaves out, since Lambda always sets them
+	// to the execution role's credentials
+	defaultString(&c.State.S3.Region, "AWS_REGION")
+	defaultString(&c.State.S3.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	defaultString(&c.State.S3.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	defaultString(&c.State.S3.SessionToken, "AWS_SESSION_TOKEN")
+}
+
+// Validate checks the broker environment, the notification method and every strategy instance
+// against its registered schema
+func (c *Config) Validate() error {
+	if _, err := alpaca.ParseEnvironment(c.Broker.Environment); err != nil {
+		return fmt.Errorf("broker.environment: %w", err)
+	}
+
+	switch c.Notification.Method {
+	case "generic", "discord":
+	default:
+		return fmt.Errorf("notification.method must be \"generic\" or \"discord\", got %q", c.Notification.Method)
+	}
+
+	if c.Engine.StrategyTimeout != "" {
+		timeout, err := time.ParseDuration(c.Engine.StrategyTimeout)
+		if err != nil {
+			return fmt.Errorf("engine.strategy_timeout: %w", err)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("engine.strategy_timeout must not be negative, got %s", c.Engine.StrategyTimeout)
+		}
+	}
+
+	if err := c.Orders.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Positions.Rules().Validate(); err != nil {
+		return fmt.Errorf("positions: %w", err)
+	}
+	if c.Positions.Roll.FillTimeout != "" {
+		if _, err := time.ParseDuration(c.Positions.Roll.FillTimeout); err != nil {
+			return fmt.Errorf("positions.roll.fill_timeout: %w", err)
+		}
+	}
+	if err := c.Positions.RollConfig().Validate(); err != nil {
+		return fmt.Errorf("positions: %w", err)
+	}
+	for i, underlying := range c.Positions.Underlyings {
+		if underlying == "" {
+			return fmt.Errorf("positions.underlyings[%d] must not be empty", i)
+		}
+	}
+
+	switch c.State.Backend {
+	case "local":
+	case "s3":
+		if c.State.S3.Bucket == "" {
+			return fmt.Errorf("state.s3.bucket is required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("state.backend must be \"local\" or \"s3\", got %q", c.State.Backend)
+	}
+	if err := statestore.ValidateKey(c.Journal.Path); err != nil {
+		return fmt.Errorf("journal.path: %w", err)
+	}
+
+	if c.Risk.MaxActiveOptions < 0 {
+		return fmt.Errorf("risk.max_active_options must not be negative, got %d", c.Risk.MaxActiveOptions)
+	}
+	if c.Risk.HaltAfterOrderFailures < 0 {
+		return fmt.Errorf("risk.halt_after_order_failures must not be negative, got %d", c.Risk.HaltAfterOrderFailures)
+	}
+	if err := c.Risk.Limits().Validate(); err != nil {
+		return fmt.Errorf("risk: %w", err)
+	}
+
+	ids := make(map[string]int, len(c.Strategies))
+	// envIDs maps the form an instance ID takes in ParamEnv back to the instance
+	envIDs := make(map[string]int, len(c.Strategies))
+	for i, instance := range c.Strategies {
+		if instance.Name == "" {
+			return fmt.Errorf("strategies[%d]: name is required", i)
+		}
+		def, err := strategies.Lookup(instance.Name)
+		if err != nil {
+			return fmt.Errorf("strategies[%d]: %w", i, err)
+		}
+		id := instance.InstanceID()
+		if err := alpaca.ValidateInstanceID(id); err != nil {
+			return fmt.Errorf("strategies[%d] (%s): %w", i, instance.Name, err)
+		}
+		if id == positions.InstanceID {
+			return fmt.Errorf("strategies[%d] (%s): id %q is reserved for the position manager", i, instance.Name, id)
+		}
+		if first, ok := ids[id]; ok {
+			return fmt.Errorf("strategies[%d] (%s): id %q is already used by strategies[%d]; give each instance a unique id", i, instance.Name, id, first)
+		}
+		ids[id] = i
+		if first, ok := envIDs[envName(id)]; ok {
+			return fmt.Errorf("strategies[%d] (%s): id %q shares its environment variables %s with strategies[%d]; give each instance a distinct id",
+				i, instance.Name, id, ParamEnv(id, "<PARAM>"), first)
+		}
+		envIDs[envName(id)] = i
+		overrides, err := c.Overrides(instance)
+		if err != nil {
+			return fmt.Errorf("strategies[%d] (%s): %w", i, instance.Name, err)
+		}
+		if _, err := def.Resolve(overrides); err != nil {
+			return fmt.Errorf("strategies[%d] (%s): %w", i, instance.Name, err)
+		}
+	}
+	return nil
+}
+
+func (o Orders) validate() error {
+	for _, setting := range []struct{ name, value string }{
+		{"orders.fill_timeout", o.FillTimeout},
+		{"orders.poll_interval", o.PollInterval},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(setting.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", setting.name, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", setting.name, setting.value)
+		}
+	}
+	switch ordertracker.Action(o.OnTimeout) {
+	case "", ordertracker.ActionCancel, ordertracker.ActionWalk:
+	default:
+		return fmt.Errorf("orders.on_timeout must be \"cancel\" or \"walk\", got %q", o.OnTimeout)
+	}
+	if o.MaxSteps < 0 {
+		return fmt.Errorf("orders.max_steps must not be negative, got %d", o.MaxSteps)
+	}
+	return nil
+}
+
+// Instances selects strategy instances: the one with the given id, every instance of the named strategy
+// (a bare instance using defaults when the config has none), or every configured instance when both are empty
+func (c *Config) Instances(name, id string) ([]StrategyConfig, error) {
+	if id != "" {
+		for _, instance := range c.Strategies {
+			if instance.InstanceID() == id {
+				if name != "" && instance.Name != name {
+					return nil, fmt.Errorf("strategy instance %s is a %s strategy, not %s", id, instance.Name, name)
+				}
+				return []StrategyConfig{instance}, nil
+			}
+		}
+		return nil, fmt.Errorf("no strategy instance with id %s in config", id)
+	}
+
+	if name == "" {
+		if len(c.Strategies) == 0 {
+			return nil, fmt.Errorf("no strategies configured; pass a strategy name or add strategies to the config")
+		}
+		return c.Strategies, nil
+	}
+
+	var instances []StrategyConfig
+	for _, instance := range c.Strategies {
+		if instance.Name == name {
+			instances = append(instances, instance)
+		}
+	}
+	if len(instances) == 0 {
+		if _, err := strategies.Lookup(name); err != nil {
+			return nil, err
+		}
+		instances = append(instances, StrategyConfig{Name: name})
+	}
+	return instances, nil
+}
+
+// Build creates a strategy for each instance. Instances configured for dry runs, or all of them when
+// dryRun is set, get a broker that never submits orders.
+func (c *Config) Build(instances []StrategyConfig, broker broker.Broker, dryRun bool) ([]strategies.Strategy, error) {
+	built := make([]strategies.Strategy, 0, len(instances))
+	for _, instance := range instances {
+		instanceBroker := broker
+		if dryRun || instance.DryRun {
+			instanceBroker = dryrun.NewBroker(broker)
+		}
+
+		def, err := strategies.Lookup(instance.Name)
+		if err != nil {
+			return nil, err
+		}
+		overrides, err := c.Overrides(instance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config for strategy instance %s: %w", instance.InstanceID(), err)
+		}
+		strategy, err := def.Build(instance.InstanceID(), instanceBroker, overrides)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create strategy instance %s: %w", instance.InstanceID(), err)
+		}
+		built = append(built, strategy)
+	}
+	return built, nil
+}
+
+// Overrides returns the instance's parameters as strategy overrides. In increasing precedence each
+// parameter takes the account-wide risk default, its strategy's environment variable, the instance's
+// value and the instance's own environment variable (see ParamEnv). The strategy's variable is shared by
+// every instance of the strategy, so it only fills in what an instance doesn't set itself.
+func (c *Config) Overrides(instance StrategyConfig) (map[string]string, error) {
+	def, err := strategies.Lookup(instance.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]string, len(instance.Params)+1)
+	if c.Risk.MaxActiveOptions > 0 {
+		if _, ok := def.Param("max_active_options"); ok {
+			overrides["max_active_options"] = strconv.Itoa(c.Risk.MaxActiveOptions)
+		}
+	}
+
+	for _, param := range def.Params {
+		if param.Env == "" {
+			continue
+		}
+		if value := os.Getenv(param.Env); value != "" {
+			overrides[param.Name] = value
+		}
+	}
+
+	for name, value := range instance.Params {
+		raw, err := scalarString(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		overrides[name] = raw
+	}
+
+	for _, param := range def.Params {
+		if value := os.Getenv(ParamEnv(instance.InstanceID(), param.Name)); value != "" {
+			overrides[param.Name] = value
+		}
+	}
+	return overrides, nil
+}
+
+// ParamEnv returns the environment variable overriding a parameter of one strategy instance, such as
+// ATHENAX_QQQ_GAP_MAX_ACTIVE_OPTIONS for max_active_options of the instance qqq-gap
+func ParamEnv(instanceID, param string) string {
+	return "ATHENAX_" + envName(instanceID) + "_" + envName(param)
+}
+
+// envName upper-cases name and replaces the dashes environment variable names can't hold
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func overrideString(field *string, env string) {
+	if value := os.Getenv(env); value != "" {
+		*field = value
+	}
+}
+
+// defaultString sets an empty field from env
+func defaultString(field *string, env string) {
+	if *field == "" {
+		*field = os.Getenv(env)
+	}
+}
+
+// scalarString renders a decoded YAML/JSON scalar as the string form strategy parameters parse
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("must be a string, number or boolean, got %T", value)
+	}
+}
@@ -0,0 +1,399 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/config"
+)
+
+// configEnv lists every environment variable the config reads
+var configEnv = []string{
+	"ALPACA_ENVIRONMENT", "ALPACA_API_KEY", "ALPACA_SECRET_KEY", "ALPACA_PAPER_API_KEY", "ALPACA_PAPER_SECRET_KEY",
+	"ALPACA_LIVE_API_KEY", "ALPACA_LIVE_SECRET_KEY", "NOTIFY_METHOD", "NOTIFY_NOISY_WEBHOOK_URL", "NOTIFY_NORMAL_WEBHOOK_URL",
+	"ATHENAX_ORDER_FILL_TIMEOUT", "ATHENAX_ORDER_ON_TIMEOUT", "ATHENAX_JOURNAL", "ATHENAX_STATE_BACKEND", "ATHENAX_STATE_PATH", "ATHENAX_S3_ENDPOINT", "ATHENAX_S3_BUCKET",
+	"ATHENAX_S3_PREFIX", "AWS_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "MAX_ACTIVE_OPTIONS",
+	config.LiveConfirmationEnv,
+}
+
+// setEnv clears the environment the config reads, then sets env for the rest of the test
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range configEnv {
+		t.Setenv(name, "")
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+// writeConfig writes content to a config file with the given name, returning its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		// wantErr is part of the error, empty when the config is valid
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `
+broker: {environment: paper}
+engine: {strategy_timeout: 90s}
+risk: {max_active_options: 5, max_trade_notional: 5000}
+positions: {underlyings: [QQQ], min_days_to_expiry: 90}
+strategies:
+  - name: two-percent-down
+    id: qqq-gap
+    params: {ticker: QQQ, gap_threshold: -2.0, max_active_options: 3}
+  - name: two-percent-down
+    id: spy-gap
+    params: {ticker: SPY}
+  - name: delta-target
+    params: {ticker: QQQ, target_delta: 200, band: 60}
+`,
+		},
+		{
+			name:    "valid JSON",
+			file:    "athenax.json",
+			content: `{"strategies": [{"name": "two-percent-down", "params": {"gap_threshold": -1.5}}]}`,
+		},
+		{
+			name:    "unknown key",
+			content: "brokr: {environment: paper}\n",
+			wantErr: "field brokr not found",
+		},
+		{
+			name:    "unknown JSON key",
+			file:    "athenax.json",
+			content: `{"brokr": {"environment": "paper"}}`,
+			wantErr: `unknown field "brokr"`,
+		},
+		{
+			name:    "broker environment",
+			content: "broker: {environment: sandbox}\n",
+			wantErr: "broker.environment",
+		},
+		{
+			name:    "notification method",
+			content: "notification: {method: slack}\n",
+			wantErr: `notification.method must be "generic" or "discord", got "slack"`,
+		},
+		{
+			name:    "negative strategy timeout",
+			content: "engine: {strategy_timeout: -1s}\n",
+			wantErr: "engine.strategy_timeout must not be negative, got -1s",
+		},
+		{
+			name:    "order timeout action",
+			content: "orders: {on_timeout: market}\n",
+			wantErr: `orders.on_timeout must be "cancel" or "walk", got "market"`,
+		},
+		{
+			name:    "S3 without a bucket",
+			content: "state: {backend: s3}\n",
+			wantErr: "state.s3.bucket is required",
+		},
+		{
+			name:    "journal path",
+			content: "journal: {path: ../athenax.db}\n",
+			wantErr: "journal.path",
+		},
+		{
+			name:    "negative risk limit",
+			content: "risk: {max_active_options: -1}\n",
+			wantErr: "risk.max_active_options must not be negative, got -1",
+		},
+		{
+			name:    "strategy without a name",
+			content: "strategies: [{id: qqq-gap}]\n",
+			wantErr: "strategies[0]: name is required",
+		},
+		{
+			name:    "unknown strategy",
+			content: "strategies: [{name: three-percent-up}]\n",
+			wantErr: "strategies[0]: unknown strategy: three-percent-up",
+		},
+		{
+			name:    "invalid instance id",
+			content: "strategies: [{name: two-percent-down, id: qqq gap}]\n",
+			wantErr: `strategies[0] (two-percent-down): instance ID "qqq gap" must be 1-48 letters`,
+		},
+		{
+			name:    "reserved instance id",
+			content: "strategies: [{name: two-percent-down, id: position-manager}]\n",
+			wantErr: `id "position-manager" is reserved for the position manager`,
+		},
+		{
+			name:    "duplicate instance id",
+			content: "strategies: [{name: two-percent-down}, {name: two-percent-down}]\n",
+			wantErr: `strategies[1] (two-percent-down): id "two-percent-down" is already used by strategies[0]`,
+		},
+		{
+			name:    "instance ids sharing environment variables",
+			content: "strategies: [{name: two-percent-down, id: qqq-gap}, {name: two-percent-down, id: qqq_gap}]\n",
+			wantErr: `strategies[1] (two-percent-down): id "qqq_gap" shares its environment variables ATHENAX_QQQ_GAP_<PARAM> with strategies[0]`,
+		},
+		{
+			name:    "unknown parameter",
+			content: "strategies: [{name: two-percent-down, params: {gap: -2}}]\n",
+			wantErr: `strategies[0] (two-percent-down): strategy two-percent-down has no parameter "gap"`,
+		},
+		{
+			name:    "parameter of the wrong type",
+			content: "strategies: [{name: two-percent-down, params: {gap_threshold: steep}}]\n",
+			wantErr: "invalid float for two-percent-down parameter gap_threshold",
+		},
+		{
+			name:    "parameter that isn't a scalar",
+			content: "strategies: [{name: two-percent-down, params: {ticker: [QQQ, SPY]}}]\n",
+			wantErr: "strategies[0] (two-percent-down): parameter ticker: must be a string, number or boolean",
+		},
+		{
+			name:    "parameter out of range",
+			content: "strategies: [{name: two-percent-down, params: {gap_threshold: 2}}]\n",
+			wantErr: "invalid two-percent-down parameters: gap_threshold must be negative, got 2",
+		},
+		{
+			name:    "instance variable of the wrong type",
+			content: "strategies: [{name: two-percent-down, id: qqq-gap}]\n",
+			env:     map[string]string{"ATHENAX_QQQ_GAP_MAX_ACTIVE_OPTIONS": "many"},
+			wantErr: "invalid int for two-percent-down parameter max_active_options",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			file := tt.file
+			if file == "" {
+				file = "athenax.yaml"
+			}
+			path := writeConfig(t, file, tt.content)
+
+			_, err := config.Load(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Load() succeeded, want an error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path) {
+				t.Errorf("Load() error = %q, want it to name %s and contain %q", err, path, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setEnv(t, map[string]string{
+		"ALPACA_ENVIRONMENT":         "live",
+		"ALPACA_API_KEY":             "legacy-key",
+		"ALPACA_PAPER_API_KEY":       "env-paper-key",
+		"ALPACA_LIVE_SECRET_KEY":     "env-live-secret",
+		"NOTIFY_NOISY_WEBHOOK_URL":   "https://example.com/env-noisy",
+		"ATHENAX_ORDER_FILL_TIMEOUT": "30s",
+		"ATHENAX_S3_BUCKET":          "env-bucket",
+		"AWS_REGION":                 "eu-west-1",
+		"AWS_ACCESS_KEY_ID":          "role-key",
+	})
+	path := writeConfig(t, "athenax.yaml", `
+broker:
+  environment: paper
+  paper: {api_key: file-paper-key, secret_key: file-paper-secret}
+  live: {api_key: file-live-key, secret_key: file-live-secret}
+notification: {noisy_webhook_url: "https://example.com/file-noisy", normal_webhook_url: "https://example.com/file-normal"}
+orders: {fill_timeout: 2m, on_timeout: walk}
+state:
+  backend: s3
+  s3: {bucket: file-bucket, region: us-west-2}
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range []struct{ name, got, want string }{
+		// The environment overrides the file
+		{"broker.environment", cfg.Broker.Environment, "live"},
+		{"broker.paper.api_key", cfg.Broker.Paper.APIKey, "env-paper-key"},
+		{"broker.live.secret_key", cfg.Broker.Live.SecretKey, "env-live-secret"},
+		{"notification.noisy_webhook_url", cfg.Notification.NoisyWebhookURL, "https://example.com/env-noisy"},
+		{"orders.fill_timeout", cfg.Orders.FillTimeout, "30s"},
+		{"state.s3.bucket", cfg.State.S3.Bucket, "env-bucket"},
+		// The file's values stand where the environment is silent
+		{"broker.paper.secret_key", cfg.Broker.Paper.SecretKey, "file-paper-secret"},
+		{"broker.live.api_key", cfg.Broker.Live.APIKey, "file-live-key"},
+		{"notification.normal_webhook_url", cfg.Notification.NormalWebhookURL, "https://example.com/file-normal"},
+		{"orders.on_timeout", cfg.Orders.OnTimeout, "walk"},
+		// The AWS variables only fill in what the file leaves out
+		{"state.s3.region", cfg.State.S3.Region, "us-west-2"},
+		{"state.s3.access_key_id", cfg.State.S3.AccessKeyID, "role-key"},
+		// Defaults fill in the rest
+		{"notification.method", cfg.Notification.Method, "generic"},
+		{"journal.path", cfg.Journal.Path, "athenax.db"},
+	} {
+		if check.got != check.want {
+			t.Errorf("%s = %q, want %q", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	setEnv(t, map[string]string{"ALPACA_API_KEY": "legacy-key", "NOTIFY_METHOD": "discord"})
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Broker.Environment != "paper" || cfg.Broker.Paper.APIKey != "legacy-key" || cfg.Broker.Live.APIKey != "" {
+		t.Errorf("broker = %+v, want paper with the unprefixed key for paper only", cfg.Broker)
+	}
+	if cfg.Notification.Method != "discord" || cfg.State.Backend != "local" || cfg.State.Path != "." {
+		t.Errorf("notification method %q, state %+v; want discord and the local default", cfg.Notification.Method, cfg.State)
+	}
+
+	setEnv(t, map[string]string{"NOTIFY_METHOD": "slack"})
+	if _, err := config.Load(""); err == nil || !strings.HasPrefix(err.Error(), "invalid config: notification.method") {
+		t.Errorf("Load() error = %v, want the invalid notification method", err)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	tests := []struct {
+		name string
+		risk config.Risk
+		// params are qqq-gap's; spy-gap always sets max_active_options to 3
+		params map[string]any
+		env    map[string]string
+		// want are qqq-gap's overrides
+		want map[string]string
+	}{
+		{
+			name: "strategy default",
+			want: map[string]string{},
+		},
+		{
+			name: "risk default",
+			risk: config.Risk{MaxActiveOptions: 4},
+			want: map[string]string{"max_active_options": "4"},
+		},
+		{
+			name: "strategy variable over the risk default",
+			risk: config.Risk{MaxActiveOptions: 4},
+			env:  map[string]string{"MAX_ACTIVE_OPTIONS": "6"},
+			want: map[string]string{"max_active_options": "6"},
+		},
+		{
+			name:   "instance value over the strategy variable",
+			params: map[string]any{"max_active_options": 2, "gap_threshold": -1.5, "ticker": "QQQ"},
+			env:    map[string]string{"MAX_ACTIVE_OPTIONS": "6"},
+			want:   map[string]string{"max_active_options": "2", "gap_threshold": "-1.5", "ticker": "QQQ"},
+		},
+		{
+			name:   "instance variable over the instance value",
+			params: map[string]any{"max_active_options": 2},
+			env:    map[string]string{"MAX_ACTIVE_OPTIONS": "6", "ATHENAX_QQQ_GAP_MAX_ACTIVE_OPTIONS": "1"},
+			want:   map[string]string{"max_active_options": "1"},
+		},
+		{
+			name:   "instance variable of any parameter",
+			params: map[string]any{"gap_threshold": -1.5},
+			env:    map[string]string{"ATHENAX_QQQ_GAP_GAP_THRESHOLD": "-3", "ATHENAX_QQQ_GAP_TICKER": "SPY"},
+			want:   map[string]string{"gap_threshold": "-3", "ticker": "SPY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			qqq := config.StrategyConfig{Name: "two-percent-down", ID: "qqq-gap", Params: tt.params}
+			spy := config.StrategyConfig{Name: "two-percent-down", ID: "spy-gap", Params: map[string]any{"max_active_options": 3}}
+			cfg := &config.Config{Risk: tt.risk, Strategies: []config.StrategyConfig{qqq, spy}}
+
+			got, err := cfg.Overrides(qqq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Overrides() = %v, want %v", got, tt.want)
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s = %q, want %q", name, got[name], want)
+				}
+			}
+
+			// One instance's variables never reach another instance
+			other, err := cfg.Overrides(spy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other["max_active_options"] != "3" || other["gap_threshold"] != "" || other["ticker"] != "" {
+				t.Errorf("spy-gap overrides = %v, want only its own max_active_options of 3", other)
+			}
+		})
+	}
+}
+
+func TestParamEnv(t *testing.T) {
+	if got := config.ParamEnv("qqq-gap", "max_active_options"); got != "ATHENAX_QQQ_GAP_MAX_ACTIVE_OPTIONS" {
+		t.Errorf("ParamEnv() = %s, want ATHENAX_QQQ_GAP_MAX_ACTIVE_OPTIONS", got)
+	}
+}
+
+func TestConfirmLive(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		confirmed   bool
+		env         string
+		wantErr     bool
+	}{
+		{name: "paper needs no confirmation", environment: "paper"},
+		{name: "live refused unconfirmed", environment: "live", wantErr: true},
+		{name: "live confirmed by flag", environment: "live", confirmed: true},
+		{name: "live confirmed by env", environment: "live", env: config.LiveConfirmationToken},
+		{name: "live refused with another env value", environment: "live", env: "true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, map[string]string{"ALPACA_ENVIRONMENT": tt.environment, config.LiveConfirmationEnv: tt.env})
+			cfg, err := config.Load("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.Broker.ConfirmLive(tt.confirmed)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("ConfirmLive() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("ConfirmLive() succeeded, want live trading refused")
+			}
+			// The error says how to confirm
+			for _, want := range []string{"--confirm-live", config.LiveConfirmationEnv + "=" + config.LiveConfirmationToken} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ConfirmLive() error = %q, want it to mention %s", err, want)
+				}
+			}
+		})
+	}
+}
@@ -2,44 +2,647 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"strings"
+	"time"
 
-	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/halt"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
 	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
 	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
 )
 
+// DefaultStrategyTimeout bounds a single strategy run unless overridden with SetStrategyTimeout
+const DefaultStrategyTimeout = 2 * time.Minute
+
+// DefaultStrategyGrace is how long a strategy past its timeout is waited for to stop, unless overridden
+// with SetStrategyGrace
+const DefaultStrategyGrace = 10 * time.Second
+
 type Engine struct {
-	strategies []strategies.Strategy
-	broker     *alpaca.Client
-	notifier   *notification.Client
+	strategies      []strategies.Strategy
+	broker          broker.Broker
+	notifier        *notification.Client
+	strategyTimeout time.Duration
+	strategyGrace   time.Duration
+	journal         *journal.Journal
+	stateStore      statestore.StateStore
+	tracker         *ordertracker.Tracker
+	exitMonitor     *bracket.Monitor
+	halt            *halt.Switch
+}
+
+// StrategyResult is the outcome of one strategy instance in an engine run
+type StrategyResult struct {
+	ID       string             `json:"id"`
+	Strategy string             `json:"strategy"`
+	Outcome  strategies.Outcome `json:"outcome"`
+	strategies.Result
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// RunResult aggregates the outcome of every strategy in an engine run
+type RunResult struct {
+	MarketOpen bool             `json:"market_open"`
+	Strategies []StrategyResult `json:"strategies"`
 }
 
-func NewEngine(strategies []strategies.Strategy, broker *alpaca.Client, notifier *notification.Client) *Engine {
+func NewEngine(strategies []strategies.Strategy, broker broker.Broker, notifier *notification.Client) *Engine {
 	return &Engine{
-		strategies: strategies,
-		broker:     broker,
-		notifier:   notifier,
+		strategies:      strategies,
+		broker:          broker,
+		notifier:        notifier,
+		strategyTimeout: DefaultStrategyTimeout,
+		strategyGrace:   DefaultStrategyGrace,
 	}
 }
 
-func (e *Engine) Run(ctx context.Context) error {
+// SetStrategyTimeout sets how long each strategy may run before it is abandoned; 0 disables the limit
+func (e *Engine) SetStrategyTimeout(timeout time.Duration) {
+	e.strategyTimeout = timeout
+}
+
+// SetStrategyGrace sets how long a strategy past its timeout is waited for to stop before the engine
+// moves on without it
+func (e *Engine) SetStrategyGrace(grace time.Duration) {
+	e.strategyGrace = grace
+}
+
+// SetJournal records every run, and the fills and exits of journaled orders, in j, and lets
+// journal-aware strategies check it for orders they already placed
+func (e *Engine) SetJournal(j *journal.Journal) {
+	e.journal = j
+	if j == nil {
+		return
+	}
+	for _, strategy := range e.strategies {
+		if aware, ok := strategy.(strategies.JournalAware); ok {
+			aware.SetJournal(j)
+		}
+	}
+}
+
+// SetStateStore persists the state of stateful strategies in store between runs, and lets claim-aware
+// strategies claim their orders' client order IDs in it. Without a store they start every run with
+// empty state and submit without claiming.
+func (e *Engine) SetStateStore(store statestore.StateStore) {
+	e.stateStore = store
+	if store == nil {
+		return
+	}
+	for _, strategy := range e.strategies {
+		if aware, ok := strategy.(strategies.ClaimAware); ok {
+			aware.SetClaimStore(store)
+		}
+	}
+}
+
+// SetOrderTracker follows every submitted order with tracker until it fills or is closed, before the
+// next strategy runs. Without a tracker orders are left resting once submitted.
+func (e *Engine) SetOrderTracker(tracker *ordertracker.Tracker) {
+	e.tracker = tracker
+}
+
+// SetExitMonitor enforces client-managed exits with monitor on every run before the strategies run,
+// and hands it the exits of every order placed without them. Exit-aware strategies drop the exits of
+// positions they sell with it. Without a monitor those exits are ignored.
+func (e *Engine) SetExitMonitor(monitor *bracket.Monitor) {
+	e.exitMonitor = monitor
+	if monitor == nil {
+		return
+	}
+	for _, strategy := range e.strategies {
+		if aware, ok := strategy.(strategies.ExitAware); ok {
+			aware.SetExitMonitor(monitor)
+		}
+	}
+}
+
+// SetHaltSwitch checks sw before enforcing exits and before every strategy, skipping them while trading
+// is halted. Without a switch the engine never halts.
+func (e *Engine) SetHaltSwitch(sw *halt.Switch) {
+	e.halt = sw
+}
+
+// Run runs every strategy in turn. A strategy that fails, panics or times out is recorded as failed
+// and does not stop the ones after it, even if it ignores its cancelled context. The returned error only reports failures of the engine itself.
+func (e *Engine) Run(ctx context.Context) (*RunResult, error) {
+	result := &RunResult{}
+	startedAt := time.Now()
+	e.syncJournal(ctx)
+
 	// Check if market is open first
 	isOpen, err := e.broker.IsMarketOpen(ctx)
 	if err != nil {
-		return e.notifier.Failure(fmt.Sprintf("failed to check if market is open: %w", err))
+		return nil, e.notifier.Failure(fmt.Sprintf("failed to check if market is open: %v", err))
 	}
 	if !isOpen {
 		log.Println("Market is closed, exiting...")
-		return e.notifier.MarketClosed()
+		for _, strategy := range e.strategies {
+			result.Strategies = append(result.Strategies, StrategyResult{
+				ID:       strategy.ID(),
+				Strategy: strategy.Name(),
+				Outcome:  strategies.OutcomeSkipped,
+				Result:   strategies.Result{Message: "market closed"},
+			})
+		}
+		e.recordRun(ctx, startedAt, result)
+		return result, e.notifier.MarketClosed()
+	}
+	result.MarketOpen = true
+	if err := e.checkHalt(ctx); err != nil {
+		log.Printf("Not enforcing client-managed exits: %v", err)
+	} else {
+		e.enforceExits(ctx)
 	}
 
 	// Run strategies only if market is open
+	var late []lateStrategy
 	for _, strategy := range e.strategies {
-		if err := strategy.Run(ctx); err != nil {
-			return err
+		// The halt is checked before every strategy, since an earlier one's orders may have tripped it
+		if err := e.checkHalt(ctx); err != nil {
+			strategyResult := StrategyResult{
+				ID:       strategy.ID(),
+				Strategy: strategy.Name(),
+				Outcome:  strategies.OutcomeSkipped,
+				Result:   strategies.Result{Decision: strategies.DecisionSkippedHalted, Message: err.Error()},
+			}
+			log.Printf("Strategy instance %s not run: %v", strategy.ID(), err)
+			e.notify(strategyResult)
+			result.Strategies = append(result.Strategies, strategyResult)
+			continue
+		}
+
+		log.Printf("Running strategy instance %s", strategy.ID())
+		start := time.Now()
+		state, err := e.loadState(ctx, strategy)
+		var runResult *strategies.Result
+		if err == nil {
+			var running <-chan runOutcome
+			runResult, running, err = e.runStrategy(ctx, strategy)
+			if running == nil {
+				e.saveState(ctx, strategy, state)
+			} else {
+				// Its state is still changing, so it isn't saved; the next run starts from the last saved state
+				late = append(late, lateStrategy{index: len(result.Strategies), done: running})
+			}
+		}
+
+		strategyResult := StrategyResult{ID: strategy.ID(), Strategy: strategy.Name(), Duration: time.Since(start)}
+		if runResult != nil {
+			strategyResult.Result = *runResult
+		}
+		if errors.Is(err, halt.ErrHalted) {
+			strategyResult.Decision = strategies.DecisionSkippedHalted
+			strategyResult.Message = err.Error()
+			log.Printf("Strategy instance %s blocked: %v", strategy.ID(), err)
+		} else if err != nil {
+			strategyResult.Decision = strategies.DecisionError
+			strategyResult.Error = err.Error()
+			log.Printf("Strategy instance %s failed: %v", strategy.ID(), err)
+		} else {
+			log.Printf("Strategy instance %s finished: %s", strategy.ID(), strategyResult.Decision)
+		}
+		strategyResult.Outcome = strategyResult.Decision.Outcome()
+
+		e.notify(strategyResult)
+		if err != nil {
+			e.notifyLateOrders(strategyResult)
+		}
+		e.trackOrders(ctx, &strategyResult)
+		e.monitorExits(ctx, &strategyResult)
+		result.Strategies = append(result.Strategies, strategyResult)
+	}
+	e.collectLate(ctx, result, late)
+	e.recordRun(ctx, startedAt, result)
+	return result, nil
+}
+
+// lateStrategy is a strategy still running after its timeout and grace period
+type lateStrategy struct {
+	// index is the strategy's position in RunResult.Strategies
+	index int
+	done  <-chan runOutcome
+}
+
+// collectLate adds the orders of strategies that finished after the engine moved on to their results,
+// so that they are journaled and their exits monitored, and reports them. A strategy still running is
+// reported since any order it places won't be journaled.
+func (e *Engine) collectLate(ctx context.Context, result *RunResult, late []lateStrategy) {
+	for _, l := range late {
+		strategyResult := &result.Strategies[l.index]
+		select {
+		case outcome := <-l.done:
+			log.Printf("Strategy instance %s finished after the engine moved on", strategyResult.ID)
+			if outcome.result == nil || len(outcome.result.Orders) == 0 {
+				continue
+			}
+			strategyResult.Orders = append(strategyResult.Orders, outcome.result.Orders...)
+			e.notifyLateOrders(*strategyResult)
+			e.monitorExits(ctx, strategyResult)
+		default:
+			log.Printf("Strategy instance %s is still running", strategyResult.ID)
+			_ = e.notifier.ActionNeeded(fmt.Sprintf("[%s] Strategy is still running after its timeout; "+
+				"check the broker for orders it places, which won't be journaled", strategyResult.ID), nil)
+		}
+	}
+}
+
+// notifyLateOrders reports the orders of a strategy that failed after placing them, such as one that
+// finished after its timeout, since they are neither tracked nor reported as ordered
+func (e *Engine) notifyLateOrders(result StrategyResult) {
+	var placed []string
+	for _, order := range result.Orders {
+		if !order.DryRun {
+			placed = append(placed, fmt.Sprintf("%s %g x %s @ %s (order %s)", order.Side, order.Qty, order.Symbol, order.LimitPrice, order.ID))
+		}
+	}
+	if len(placed) == 0 {
+		return
+	}
+	_ = e.notifier.ActionNeeded(fmt.Sprintf("[%s] Strategy failed after placing orders, check them with the broker:\n%s",
+		result.ID, strings.Join(placed, "\n")), nil)
+}
+
+// checkHalt returns an error wrapping halt.ErrHalted when trading is halted
+func (e *Engine) checkHalt(ctx context.Context) error {
+	if e.halt == nil {
+		return nil
+	}
+	return e.halt.Check(ctx)
+}
+
+// syncJournal brings the fills and exits of journaled orders up to date. A failure is logged but
+// doesn't stop the run, since the next run retries it.
+func (e *Engine) syncJournal(ctx context.Context) {
+	if e.journal == nil {
+		return
+	}
+	if err := e.journal.Sync(ctx, e.broker); err != nil {
+		log.Printf("Failed to sync journal with broker orders: %v", err)
+	}
+}
+
+// recordRun journals the run. A failure is reported but doesn't fail the run: its orders are already placed.
+func (e *Engine) recordRun(ctx context.Context, startedAt time.Time, result *RunResult) {
+	if e.journal == nil {
+		return
+	}
+	run := journal.Run{StartedAt: startedAt, FinishedAt: time.Now(), MarketOpen: result.MarketOpen}
+	// The account's value is recorded for reports; a run is journaled even when it can't be read
+	if equity, _, err := e.broker.GetEquity(ctx); err != nil {
+		log.Printf("Failed to read account equity for the journal: %v", err)
+	} else if cash, err := e.broker.GetNonMarginableBuyingPower(ctx); err != nil {
+		log.Printf("Failed to read buying power for the journal: %v", err)
+	} else {
+		run.Equity, run.Cash = equity, cash
+	}
+	for _, strategy := range result.Strategies {
+		run.Strategies = append(run.Strategies, strategy.journalRun())
+	}
+	if _, err := e.journal.RecordRun(ctx, run); err != nil {
+		log.Printf("Failed to record run in the journal: %v", err)
+		_ = e.notifier.ActionNeeded(fmt.Sprintf("Failed to record run in the journal: %v", err), err)
+	}
+}
+
+// journalRun converts the result into its journal record
+func (r StrategyResult) journalRun() journal.StrategyRun {
+	run := journal.StrategyRun{
+		InstanceID:  r.ID,
+		Strategy:    r.Strategy,
+		Decision:    string(r.Decision),
+		Outcome:     string(r.Outcome),
+		Message:     r.Message,
+		Error:       r.Error,
+		DryRun:      r.DryRun(),
+		Duration:    r.Duration,
+		Diagnostics: r.Diagnostics,
+	}
+	for _, signal := range r.Signals {
+		run.Signals = append(run.Signals, journal.Signal(signal))
+	}
+	for _, order := range r.Orders {
+		record := journal.Order{
+			AlpacaOrderID:   order.ID,
+			ClientOrderID:   order.ClientOrderID,
+			Symbol:          order.Symbol,
+			Side:            order.Side,
+			Qty:             order.Qty,
+			LimitPrice:      order.LimitPrice,
+			TakeProfitPrice: order.TakeProfitPrice,
+			StopPrice:       order.StopPrice,
+			DryRun:          order.DryRun,
+			Events:          journalEvents(order.Events),
+		}
+		// Exits the broker doesn't hold aren't legs of the order; record the ones the monitor enforces
+		if order.ClientExits != nil {
+			prices := order.ClientExits.Prices
+			record.TakeProfitPrice = prices.TakeProfit
+			record.StopPrice = prices.Stop
+			record.UnderlyingStopPrice = prices.UnderlyingStop
+		}
+		run.Orders = append(run.Orders, record)
+	}
+	return run
+}
+
+func journalEvents(events []strategies.OrderEvent) []journal.OrderEvent {
+	var converted []journal.OrderEvent
+	for _, event := range events {
+		converted = append(converted, journal.OrderEvent(event))
+	}
+	return converted
+}
+
+// stateKey is the state store key holding a strategy instance's state
+func stateKey(instanceID string) string {
+	return "strategies/" + instanceID + "/state.json"
+}
+
+// loadState hands a stateful strategy its persisted state
+func (e *Engine) loadState(ctx context.Context, strategy strategies.Strategy) (*strategies.State, error) {
+	stateful, ok := strategy.(strategies.Stateful)
+	if !ok {
+		return nil, nil
+	}
+
+	values := map[string]string{}
+	if e.stateStore != nil {
+		data, err := e.stateStore.Get(ctx, stateKey(strategy.ID()))
+		switch {
+		case errors.Is(err, statestore.ErrNotFound):
+		case err != nil:
+			return nil, fmt.Errorf("failed to load strategy state: %w", err)
+		default:
+			if err := json.Unmarshal(data, &values); err != nil {
+				return nil, fmt.Errorf("failed to decode strategy state: %w", err)
+			}
+		}
+	}
+
+	state := strategies.NewState(values)
+	stateful.SetState(state)
+	return state, nil
+}
+
+// saveState persists a strategy's state if the run changed it. A failure is reported but doesn't fail
+// the run, whose orders are already placed.
+func (e *Engine) saveState(ctx context.Context, strategy strategies.Strategy, state *strategies.State) {
+	if state == nil || !state.Changed() || e.stateStore == nil {
+		return
+	}
+	data, err := json.Marshal(state.Values())
+	if err == nil {
+		err = e.stateStore.Put(ctx, stateKey(strategy.ID()), data)
+	}
+	if err != nil {
+		log.Printf("Failed to save state of strategy instance %s: %v", strategy.ID(), err)
+		_ = e.notifier.ActionNeeded(fmt.Sprintf("[%s] Failed to save strategy state: %v", strategy.ID(), err), err)
+	}
+}
+
+// notify sends the notification matching a strategy's decision
+func (e *Engine) notify(result StrategyResult) {
+	message := fmt.Sprintf("[%s] %s", result.ID, result.Message)
+	switch result.Decision {
+	case strategies.DecisionOrdered:
+		if !result.DryRun() {
+			_ = e.notifier.OrderPlaced(message)
+			return
+		}
+		for _, order := range result.Orders {
+			message += fmt.Sprintf("\nWould %s %g x %s @ %s", order.Side, order.Qty, order.Symbol, order.LimitPrice)
+			if order.TakeProfitPrice.IsPositive() {
+				message += fmt.Sprintf(", take profit @ %s", order.TakeProfitPrice)
+			}
+			if order.StopPrice.IsPositive() {
+				message += fmt.Sprintf(", stop @ %s", order.StopPrice)
+			}
+		}
+		_ = e.notifier.DryRunOrder(message)
+	case strategies.DecisionSkippedMaxPositions:
+		_ = e.notifier.MaxActiveOptions(message)
+	case strategies.DecisionSkippedNoSize:
+		_ = e.notifier.NoSize(message)
+	case strategies.DecisionSkippedAlreadyActed:
+		_ = e.notifier.AlreadyActed(message)
+	case strategies.DecisionSkippedWideSpread:
+		_ = e.notifier.WideSpread(message)
+	case strategies.DecisionSkippedRiskLimit:
+		_ = e.notifier.RiskRejected(message)
+	case strategies.DecisionSkippedHalted:
+		_ = e.notifier.Halted(message + "\nRun athenax resume to trade again.")
+	case strategies.DecisionNoSignal:
+		_ = e.notifier.NoSignal(message)
+	case strategies.DecisionError:
+		_ = e.notifier.Failure(fmt.Sprintf("[%s] %s", result.ID, result.Error))
+	}
+}
+
+// trackOrders follows each order a strategy submitted, notifying every transition and replacing the
+// order in the result with its final state. A tracking failure is reported; the order stays with the broker.
+func (e *Engine) trackOrders(ctx context.Context, result *StrategyResult) {
+	if e.tracker == nil || result.Decision != strategies.DecisionOrdered {
+		return
+	}
+	for i, order := range result.Orders {
+		if order.DryRun {
+			continue
+		}
+		final, events, err := e.tracker.Track(ctx, order.ID, func(event ordertracker.Event) {
+			e.notifyOrderEvent(result.ID, event)
+		})
+		if final != nil && final.ID != order.ID {
+			result.Orders[i].ID = final.ID
+			result.Orders[i].ClientOrderID = final.ClientOrderID
+			if final.LimitPrice != nil {
+				result.Orders[i].LimitPrice = money.FromPtr(final.LimitPrice)
+			}
+		}
+		for _, event := range events {
+			result.Orders[i].Events = append(result.Orders[i].Events, strategies.OrderEvent(event))
+		}
+		if err != nil {
+			log.Printf("Failed to track order %s of strategy instance %s: %v", order.ID, result.ID, err)
+			_ = e.notifier.ActionNeeded(fmt.Sprintf("[%s] Failed to track order %s, check it with the broker: %v", result.ID, order.ID, err), err)
+		}
+	}
+}
+
+// enforceExits closes the positions whose client-managed exits triggered, notifying every step and
+// journaling closed positions. A failure is reported; the positions stay open until the next run.
+func (e *Engine) enforceExits(ctx context.Context) {
+	if e.exitMonitor == nil {
+		return
+	}
+	err := e.exitMonitor.Enforce(ctx, func(event bracket.Event) {
+		_ = e.notifier.PositionExit(event.Message)
+		if event.Type == bracket.EventExited {
+			e.journalExit(ctx, event)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to enforce client-managed exits: %v", err)
+		_ = e.notifier.ActionNeeded(fmt.Sprintf("Failed to enforce client-managed exits, check the positions with the broker: %v", err), err)
+	}
+}
+
+// journalExit records a position closed by a client-managed exit against its entry order
+func (e *Engine) journalExit(ctx context.Context, event bracket.Event) {
+	if e.journal == nil {
+		return
+	}
+	err := e.journal.RecordExit(ctx, journal.Exit{
+		AlpacaOrderID: event.EntryOrderID,
+		ExitOrderID:   event.ExitOrderID,
+		Qty:           event.Qty.InexactFloat64(),
+		Price:         event.Price,
+		ExitedAt:      event.At,
+		Reason:        event.Reason,
+	})
+	if err != nil {
+		log.Printf("Failed to journal exit of order %s: %v", event.EntryOrderID, err)
+	}
+}
+
+// monitorExits hands the client-managed exits of each submitted order to the exit monitor, keyed by
+// the order's final ID. A failure is reported since the position would be left without its exits.
+func (e *Engine) monitorExits(ctx context.Context, result *StrategyResult) {
+	if e.exitMonitor == nil {
+		return
+	}
+	for i, order := range result.Orders {
+		if order.DryRun || order.ClientExits == nil {
+			continue
+		}
+		oco := *order.ClientExits
+		oco.EntryOrderID = order.ID
+		oco.ClientOrderID = order.ClientOrderID
+		result.Orders[i].ClientExits = &oco
+		if err := e.exitMonitor.Add(ctx, oco); err != nil {
+			log.Printf("Failed to monitor exits of order %s of strategy instance %s: %v", order.ID, result.ID, err)
+			_ = e.notifier.ActionNeeded(fmt.Sprintf("[%s] Order %s has no exits: failed to monitor them: %v", result.ID, order.ID, err), err)
+		}
+	}
+}
+
+// notifyOrderEvent sends the notification matching a tracked order's transition
+func (e *Engine) notifyOrderEvent(instanceID string, event ordertracker.Event) {
+	message := fmt.Sprintf("[%s] %s", instanceID, event.Message)
+	switch event.Type {
+	case ordertracker.EventFilled:
+		_ = e.notifier.OrderFilled(message)
+	case ordertracker.EventRepriced:
+		_ = e.notifier.OrderRepriced(message)
+	case ordertracker.EventCanceled, ordertracker.EventClosed:
+		_ = e.notifier.OrderNotFilled(message)
+	}
+}
+
+// runOutcome is what a strategy run returned
+type runOutcome struct {
+	result *strategies.Result
+	err    error
+}
+
+// runStrategy runs a strategy under its own timeout, converting panics into errors. At the timeout the
+// strategy's context is cancelled and the strategy is waited for, up to the grace period, so that no
+// order it submits goes unreported and its state isn't saved while it still changes it. A strategy past
+// its timeout always fails, keeping the orders it placed. One that hasn't stopped by the end of the grace
+// period is returned with no result and the channel its outcome will arrive on.
+func (e *Engine) runStrategy(ctx context.Context, strategy strategies.Strategy) (*strategies.Result, <-chan runOutcome, error) {
+	if e.strategyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.strategyTimeout)
+		defer cancel()
+	}
+
+	done := make(chan runOutcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Strategy instance %s panicked: %v\n%s", strategy.ID(), r, debug.Stack())
+				done <- runOutcome{err: fmt.Errorf("strategy panicked: %v", r)}
+			}
+		}()
+		result, err := strategy.Run(ctx)
+		done <- runOutcome{result: result, err: err}
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome.result, nil, outcome.err
+	case <-ctx.Done():
+	}
+
+	timeoutErr := fmt.Errorf("strategy did not finish in time: %w", ctx.Err())
+	log.Printf("Strategy instance %s did not finish in time (%v); waiting up to %v for it to stop", strategy.ID(), ctx.Err(), e.strategyGrace)
+	grace := time.NewTimer(e.strategyGrace)
+	defer grace.Stop()
+	select {
+	case outcome := <-done:
+		if outcome.err != nil {
+			return outcome.result, nil, errors.Join(timeoutErr, outcome.err)
+		}
+		log.Printf("Strategy instance %s finished after its timeout", strategy.ID())
+		return outcome.result, nil, timeoutErr
+	case <-grace.C:
+		log.Printf("Strategy instance %s did not stop within %v; moving on without it", strategy.ID(), e.strategyGrace)
+		return nil, done, fmt.Errorf("%w, and did not stop within %v", timeoutErr, e.strategyGrace)
+	}
+}
+
+// Failed returns the number of strategies that failed
+func (r *RunResult) Failed() int {
+	return r.Count(strategies.OutcomeFailed)
+}
+
+// Count returns the number of strategies with the given outcome
+func (r *RunResult) Count(outcome strategies.Outcome) int {
+	count := 0
+	for _, strategy := range r.Strategies {
+		if strategy.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary describes the run in one line, e.g. "3 strategies: 1 ran, 0 skipped, 1 ordered, 1 failed"
+func (r *RunResult) Summary() string {
+	summary := fmt.Sprintf("%d strategies: %d ran, %d skipped, %d ordered, %d failed",
+		len(r.Strategies),
+		r.Count(strategies.OutcomeRan), r.Count(strategies.OutcomeSkipped),
+		r.Count(strategies.OutcomeOrdered), r.Count(strategies.OutcomeFailed))
+	if !r.MarketOpen {
+		summary += " (market closed)"
+	}
+	return summary
+}
+
+// Breakdown describes each strategy's outcome on its own line
+func (r *RunResult) Breakdown() string {
+	var b strings.Builder
+	for _, strategy := range r.Strategies {
+		fmt.Fprintf(&b, "%s: %s", strategy.ID, strategy.Outcome)
+		switch {
+		case strategy.Error != "":
+			fmt.Fprintf(&b, " (%s)", strategy.Error)
+		case strategy.Message != "":
+			fmt.Fprintf(&b, " - %s", strategy.Message)
 		}
+		b.WriteString("\n")
 	}
-	return nil
+	return b.String()
 }
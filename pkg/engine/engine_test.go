@@ -0,0 +1,349 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/engine"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/strategies"
+)
+
+// testStrategy runs the given function
+type testStrategy struct {
+	id  string
+	run func(ctx context.Context) (*strategies.Result, error)
+}
+
+func (s *testStrategy) ID() string   { return s.id }
+func (s *testStrategy) Name() string { return "test" }
+func (s *testStrategy) Run(ctx context.Context) (*strategies.Result, error) {
+	return s.run(ctx)
+}
+
+// ordered is the result of a strategy that bought one contract as order id
+func ordered(id string) *strategies.Result {
+	return &strategies.Result{
+		Decision: strategies.DecisionOrdered,
+		Message:  "bought",
+		Orders: []strategies.Order{{
+			ID: id, ClientOrderID: "late." + id, Symbol: "QQQ260320C00450000", Side: "buy", Qty: 1, LimitPrice: money.NewFromInt(10),
+		}},
+	}
+}
+
+// notifications collects the messages sent to a webhook
+type notifications struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (n *notifications) contains(substrings ...string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, message := range n.messages {
+		found := true
+		for _, s := range substrings {
+			found = found && strings.Contains(message, s)
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// testDay is the time of the simulated session the engine runs in
+var testDay = time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC)
+
+// newBroker returns a simulated broker during the session on testDay
+func newBroker() *sim.Broker {
+	b := sim.NewBroker(testDay)
+	b.SetCalendar(sim.RegularSession(testDay, time.UTC))
+	return b
+}
+
+// newEngine returns an engine running the strategies against b with the given timeout and grace period,
+// a journal and a notifier sending to a test webhook
+func newEngine(t *testing.T, b *sim.Broker, timeout, grace time.Duration, strategyList ...strategies.Strategy) (*engine.Engine, *journal.Journal, *notifications) {
+	t.Helper()
+
+	sent := &notifications{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		sent.mu.Lock()
+		sent.messages = append(sent.messages, payload.Type+": "+payload.Message)
+		sent.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	notifier, err := notification.NewClient("generic", "", server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = j.Close() })
+
+	e := engine.NewEngine(strategyList, b, notifier)
+	e.SetStrategyTimeout(timeout)
+	e.SetStrategyGrace(grace)
+	e.SetJournal(j)
+	return e, j, sent
+}
+
+// journaled reports whether the journal holds an order with the given ID
+func journaled(t *testing.T, j *journal.Journal, orderID string) bool {
+	t.Helper()
+	trades, err := j.Trades(context.Background(), journal.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trade := range trades {
+		if trade.AlpacaOrderID == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTimedOutStrategyFails(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) (*strategies.Result, error)
+		// order is the ID of an order the strategy placed, which must be journaled and reported
+		order string
+	}{
+		{
+			name: "stops when cancelled",
+			run: func(ctx context.Context) (*strategies.Result, error) {
+				<-ctx.Done()
+				return strategies.NewResult(), ctx.Err()
+			},
+		},
+		{
+			name: "finishes within the grace period",
+			run: func(ctx context.Context) (*strategies.Result, error) {
+				<-ctx.Done()
+				time.Sleep(20 * time.Millisecond)
+				return ordered("late-1"), nil
+			},
+			order: "late-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := false
+			e, j, sent := newEngine(t, newBroker(), 20*time.Millisecond, time.Second,
+				&testStrategy{id: "slow", run: tt.run},
+				&testStrategy{id: "next", run: func(ctx context.Context) (*strategies.Result, error) {
+					next = true
+					return &strategies.Result{Decision: strategies.DecisionNoSignal}, nil
+				}},
+			)
+
+			result, err := e.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			slow := result.Strategies[0]
+			if slow.Outcome != strategies.OutcomeFailed || !strings.Contains(slow.Error, context.DeadlineExceeded.Error()) {
+				t.Errorf("slow strategy: %s (%s), want failed with the timeout", slow.Outcome, slow.Error)
+			}
+			if !next || result.Strategies[1].Outcome != strategies.OutcomeRan {
+				t.Errorf("next strategy: ran = %v, outcome %s", next, result.Strategies[1].Outcome)
+			}
+			if tt.order == "" {
+				return
+			}
+			if len(slow.Orders) != 1 || slow.Orders[0].ID != tt.order {
+				t.Errorf("orders = %+v, want %s kept", slow.Orders, tt.order)
+			}
+			if !journaled(t, j, tt.order) {
+				t.Errorf("order %s not journaled", tt.order)
+			}
+			if !sent.contains("Action needed", tt.order) {
+				t.Errorf("order %s not reported in %q", tt.order, sent.messages)
+			}
+		})
+	}
+}
+
+func TestStrategyIgnoringCancellationIsLeftBehind(t *testing.T) {
+	release := make(chan struct{})
+	stubborn := &testStrategy{id: "stubborn", run: func(ctx context.Context) (*strategies.Result, error) {
+		<-release
+		return ordered("late-2"), nil
+	}}
+	e, j, sent := newEngine(t, newBroker(), 200*time.Millisecond, 20*time.Millisecond,
+		stubborn,
+		&testStrategy{id: "next", run: func(ctx context.Context) (*strategies.Result, error) {
+			// The stubborn strategy finishes while the engine runs this one
+			close(release)
+			time.Sleep(50 * time.Millisecond)
+			return &strategies.Result{Decision: strategies.DecisionNoSignal}, nil
+		}},
+	)
+
+	result, err := e.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := result.Strategies[0]
+	if left.Outcome != strategies.OutcomeFailed || !strings.Contains(left.Error, "did not stop") {
+		t.Errorf("stubborn strategy: %s (%s), want failed for not stopping", left.Outcome, left.Error)
+	}
+	if result.Strategies[1].Outcome != strategies.OutcomeRan {
+		t.Errorf("next strategy: %s, want ran", result.Strategies[1].Outcome)
+	}
+	if len(left.Orders) != 1 || !journaled(t, j, "late-2") {
+		t.Errorf("late order not collected: orders = %+v", left.Orders)
+	}
+	if !sent.contains("Action needed", "late-2") {
+		t.Errorf("late order not reported in %q", sent.messages)
+	}
+}
+
+func TestStrategyStillRunningIsReported(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	e, _, sent := newEngine(t, newBroker(), 20*time.Millisecond, 20*time.Millisecond, &testStrategy{id: "stuck", run: func(ctx context.Context) (*strategies.Result, error) {
+		<-release
+		return nil, errors.New("released")
+	}})
+
+	finished := make(chan *engine.RunResult, 1)
+	go func() {
+		result, _ := e.Run(context.Background())
+		finished <- result
+	}()
+	select {
+	case result := <-finished:
+		if result.Strategies[0].Outcome != strategies.OutcomeFailed {
+			t.Errorf("stuck strategy: %s, want failed", result.Strategies[0].Outcome)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() waited for a strategy ignoring its cancelled context")
+	}
+	if !sent.contains("Action needed", "still running") {
+		t.Errorf("still running strategy not reported in %q", sent.messages)
+	}
+}
+
+func TestTrackedOrderEvents(t *testing.T) {
+	const option = "QQQ260320C00450000"
+	tests := []struct {
+		name    string
+		rule    sim.FillRule
+		tracker ordertracker.Config
+		// events are the types of the journaled events; notified are the notifications sent for them
+		events   []string
+		notified []string
+		// limit is the limit of the journaled order, the last replacement's
+		limit money.Money
+	}{
+		{
+			// 9.40 walks halfway to the 10.00 ask, then to the ask, where it fills
+			name:    "repriced until filled",
+			rule:    sim.FillWhenMarketable,
+			tracker: ordertracker.Config{Timeout: 10 * time.Millisecond, OnTimeout: ordertracker.ActionWalk, MaxSteps: 2},
+			events:  []string{ordertracker.EventRepriced, ordertracker.EventRepriced, ordertracker.EventFilled},
+			notified: []string{
+				"Order repriced: [qqq-gap] " + option + " repriced from 9.40 to 9.70 (step 1/2)",
+				"Order repriced: [qqq-gap] " + option + " repriced from 9.70 to 10.00 (step 2/2)",
+				"Order filled: [qqq-gap] " + option + " filled 2 @ 10.00",
+			},
+			limit: money.NewFromInt(10),
+		},
+		{
+			name:     "cancelled at the timeout",
+			rule:     sim.FillNever,
+			tracker:  ordertracker.Config{Timeout: 10 * time.Millisecond},
+			events:   []string{ordertracker.EventCanceled},
+			notified: []string{"Order not filled: [qqq-gap] " + option + " cancelled: not filled within 10ms at 9.40"},
+			limit:    money.Cents(940),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newBroker()
+			if err := b.SetOption(option, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}}); err != nil {
+				t.Fatal(err)
+			}
+			b.SetBuyingPower(money.NewFromInt(10000))
+			b.SetFillRule(tt.rule)
+
+			clientOrderID := alpaca.NewClientOrderID("qqq-gap", testDay, "entry")
+			e, j, sent := newEngine(t, b, time.Minute, time.Second, &testStrategy{id: "qqq-gap", run: func(ctx context.Context) (*strategies.Result, error) {
+				// $2,000 at 94% of the ask buys 2 contracts at 9.40
+				order, _, err := b.PlaceOptionBracketOrder(ctx, clientOrderID, money.NewFromInt(2000), option,
+					&marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, pricing.PercentOfAsk(94), bracket.Exits{TakeProfitPercent: 50})
+				if err != nil {
+					return nil, err
+				}
+				return &strategies.Result{Decision: strategies.DecisionOrdered, Message: "bought", Orders: []strategies.Order{{
+					ID: order.ID, ClientOrderID: order.ClientOrderID, Symbol: option, Side: "buy", Qty: 2, LimitPrice: money.Cents(940),
+				}}}, nil
+			}})
+			tt.tracker.PollInterval = 2 * time.Millisecond
+			e.SetOrderTracker(ordertracker.New(b, tt.tracker))
+
+			if _, err := e.Run(ctx); err != nil {
+				t.Fatal(err)
+			}
+			for _, notification := range tt.notified {
+				if !sent.contains(notification) {
+					t.Errorf("%q not notified in %q", notification, sent.messages)
+				}
+			}
+
+			trades, err := j.Trades(ctx, journal.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trades) != 1 {
+				t.Fatalf("%d trades journaled, want 1", len(trades))
+			}
+			trade := trades[0]
+			orders := b.Orders()
+			last := orders[len(orders)-1]
+			if trade.AlpacaOrderID != last.ID || trade.ClientOrderID != last.ClientOrderID || !trade.LimitPrice.Equal(tt.limit) {
+				t.Errorf("journaled order %s (%s) @ %s, want the last order %s (%s) @ %s",
+					trade.AlpacaOrderID, trade.ClientOrderID, trade.LimitPrice, last.ID, last.ClientOrderID, tt.limit)
+			}
+			var events []string
+			for _, event := range trade.Events {
+				events = append(events, event.Type)
+			}
+			if strings.Join(events, ",") != strings.Join(tt.events, ",") {
+				t.Errorf("journaled events = %q, want %q", events, tt.events)
+			}
+			if final := trade.Events[len(trade.Events)-1]; final.OrderID != last.ID || final.Status != last.Status {
+				t.Errorf("last journaled event is order %s %s, want %s %s", final.OrderID, final.Status, last.ID, last.Status)
+			}
+		})
+	}
+}
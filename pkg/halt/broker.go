@@ -0,0 +1,122 @@
+package halt
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+)
+
+// Broker wraps a broker so that no order is submitted while trading is halted, buying or selling.
+// Cancellations still go through. It counts the orders the wrapped broker fails to submit, tripping the
+// halt after the switch's limit of consecutive failures, and trips it at once when the risk manager
+// beneath refuses an order for the daily loss limit. Wrap it around the risk manager.
+type Broker struct {
+	broker.Broker
+	halt *Switch
+}
+
+// Ensure Broker satisfies the broker interfaces
+var (
+	_ broker.Broker         = (*Broker)(nil)
+	_ broker.MultiLegBroker = (*Broker)(nil)
+)
+
+// NewBroker creates a broker around b that refuses every order while halt is set
+func NewBroker(b broker.Broker, halt *Switch) *Broker {
+	return &Broker{Broker: b, halt: halt}
+}
+
+// PlaceOptionBracketOrder places the order unless trading is halted
+func (b *Broker) PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error) {
+	if err := b.halt.Check(ctx); err != nil {
+		return nil, nil, err
+	}
+	order, oco, err := b.Broker.PlaceOptionBracketOrder(ctx, clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits)
+	if err != nil {
+		// An order that can't be built, e.g. on a wide spread, never reached the broker and isn't a failed submission
+		if _, buildErr := athenaxalpaca.BuildOptionBracketOrder(clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits); buildErr != nil {
+			return nil, nil, err
+		}
+	}
+	b.count(ctx, optionSymbol, err)
+	return order, oco, err
+}
+
+// PlaceOptionBuyOrder places the order unless trading is halted
+func (b *Broker) PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	if err := b.halt.Check(ctx); err != nil {
+		return nil, err
+	}
+	order, err := b.Broker.PlaceOptionBuyOrder(ctx, clientOrderID, optionSymbol, qty, limitPrice)
+	b.count(ctx, optionSymbol, err)
+	return order, err
+}
+
+// PlaceOptionSellOrder places the order unless trading is halted
+func (b *Broker) PlaceOptionSellOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	if err := b.halt.Check(ctx); err != nil {
+		return nil, err
+	}
+	order, err := b.Broker.PlaceOptionSellOrder(ctx, clientOrderID, optionSymbol, qty, limitPrice)
+	b.count(ctx, optionSymbol, err)
+	return order, err
+}
+
+// PlaceOptionRollOrder places the roll order unless trading is halted. A wrapped broker that can't place
+// multi-leg orders returns an error wrapping broker.ErrMultiLegUnsupported, which isn't a failed submission.
+func (b *Broker) PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error) {
+	if err := b.halt.Check(ctx); err != nil {
+		return nil, err
+	}
+	multiLeg, err := broker.MultiLeg(b.Broker)
+	if err != nil {
+		return nil, err
+	}
+	order, err := multiLeg.PlaceOptionRollOrder(ctx, clientOrderID, closeSymbol, openSymbol, qty, netDebit)
+	if errors.Is(err, broker.ErrMultiLegUnsupported) {
+		return nil, err
+	}
+	b.count(ctx, closeSymbol, err)
+	return order, err
+}
+
+// ReplaceOrder replaces the order unless trading is halted
+func (b *Broker) ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error) {
+	if err := b.halt.Check(ctx); err != nil {
+		return nil, err
+	}
+	order, err := b.Broker.ReplaceOrder(ctx, orderID, clientOrderID, limitPrice)
+	b.count(ctx, orderID, err)
+	return order, err
+}
+
+// count records the outcome of an order submission with the switch. A risk rejection isn't a failed
+// submission, but one for the daily loss limit trips the halt. A failure to update the switch is
+// logged: the order's own outcome stands.
+func (b *Broker) count(ctx context.Context, symbol string, orderErr error) {
+	var rejection *risk.Rejection
+	var err error
+	switch {
+	case errors.As(orderErr, &rejection):
+		if rejection.Limit == risk.LimitDailyLoss {
+			_, err = b.halt.Halt(ctx, SourceDailyLoss, rejection.Reason, b.Now())
+		}
+	case orderErr != nil:
+		err = b.halt.OrderFailed(ctx, b.Now(), orderErr)
+	default:
+		err = b.halt.OrderSucceeded(ctx)
+	}
+	if err != nil {
+		log.Printf("Failed to update the halt flag after an order in %s: %v", symbol, err)
+	}
+}
@@ -0,0 +1,196 @@
+package halt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// Key is the state store key of the halt flag
+const Key = "halt/halt.json"
+
+// Sources of a halt
+const (
+	// SourceManual is a halt set with athenax halt
+	SourceManual = "manual"
+	// SourceOrderFailures is a halt tripped by consecutive failed order submissions
+	SourceOrderFailures = "order-failures"
+	// SourceDailyLoss is a halt tripped by an order the risk manager refused for the daily loss limit
+	SourceDailyLoss = "daily-loss"
+)
+
+// ErrHalted is wrapped by the error of every order refused, and every run blocked, while trading is halted
+var ErrHalted = errors.New("trading halted")
+
+// Status is the halt flag as persisted in the state store
+type Status struct {
+	Halted bool      `json:"halted"`
+	Reason string    `json:"reason,omitempty"`
+	Source string    `json:"source,omitempty"`
+	At     time.Time `json:"at"`
+	// Failures counts the order submissions that failed since the last one that succeeded
+	Failures int `json:"failures"`
+}
+
+// String describes the halt, e.g. "halted (manual) at 2024-03-01T14:35:00Z: bad fills"
+func (s Status) String() string {
+	if !s.Halted {
+		return fmt.Sprintf("not halted (%d consecutive order failures)", s.Failures)
+	}
+	return fmt.Sprintf("halted (%s) at %s: %s", s.Source, s.At.UTC().Format(time.RFC3339), s.Reason)
+}
+
+// Switch reads and sets the halt flag. Every process trading the account shares it through the state
+// store, so a halt set from the CLI stops the Lambda on its next order without a redeploy.
+type Switch struct {
+	store statestore.StateStore
+	// maxFailures is the number of consecutive failed order submissions that trips the halt; 0 never trips
+	maxFailures int
+}
+
+// NewSwitch creates the halt switch kept in store, tripping after maxFailures consecutive failed orders
+func NewSwitch(store statestore.StateStore, maxFailures int) *Switch {
+	return &Switch{store: store, maxFailures: maxFailures}
+}
+
+// Status returns the current halt flag
+func (s *Switch) Status(ctx context.Context) (Status, error) {
+	data, err := s.store.Get(ctx, Key)
+	if err != nil && !errors.Is(err, statestore.ErrNotFound) {
+		return Status{}, fmt.Errorf("failed to load the halt flag: %w", err)
+	}
+	return decode(data)
+}
+
+func decode(data []byte) (Status, error) {
+	var status Status
+	if data == nil {
+		return status, nil
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return Status{}, fmt.Errorf("failed to decode the halt flag: %w", err)
+	}
+	return status, nil
+}
+
+// Check returns an error wrapping ErrHalted when trading is halted. A flag that can't be read counts as
+// halted, so that a broken state store never lets orders through unchecked.
+func (s *Switch) Check(ctx context.Context) error {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrHalted, err)
+	}
+	if status.Halted {
+		return fmt.Errorf("%w since %s (%s): %s", ErrHalted, status.At.UTC().Format(time.RFC3339), status.Source, status.Reason)
+	}
+	return nil
+}
+
+// Halt stops trading for reason. An existing halt is kept with its original reason.
+func (s *Switch) Halt(ctx context.Context, source, reason string, at time.Time) (Status, error) {
+	var status Status
+	halted := false
+	err := s.update(ctx, func(current Status) *Status {
+		status, halted = current, false
+		if status.Halted {
+			return nil
+		}
+		status.Halted, status.Source, status.Reason, status.At = true, source, reason, at
+		halted = true
+		return &status
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	if halted {
+		log.Printf("TRADING HALTED (%s): %s", source, reason)
+	}
+	return status, nil
+}
+
+// Resume lifts the halt and clears the failure count, returning the status it replaced
+func (s *Switch) Resume(ctx context.Context) (Status, error) {
+	previous, err := s.Status(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	if err := s.save(ctx, Status{}); err != nil {
+		return Status{}, err
+	}
+	return previous, nil
+}
+
+// OrderFailed counts a failed order submission, tripping the halt once maxFailures failed in a row. The
+// count is updated with a conditional write, so failures in overlapping runs are all counted.
+func (s *Switch) OrderFailed(ctx context.Context, at time.Time, orderErr error) error {
+	var status Status
+	tripped := false
+	err := s.update(ctx, func(current Status) *Status {
+		status, tripped = current, false
+		status.Failures++
+		if s.maxFailures > 0 && status.Failures >= s.maxFailures && !status.Halted {
+			status.Halted, status.Source, status.At = true, SourceOrderFailures, at
+			status.Reason = fmt.Sprintf("%d consecutive order submissions failed, the last with: %v", status.Failures, orderErr)
+			tripped = true
+		}
+		return &status
+	})
+	if err != nil {
+		return err
+	}
+	if tripped {
+		log.Printf("TRADING HALTED (%s): %s", status.Source, status.Reason)
+	}
+	return nil
+}
+
+// OrderSucceeded resets the count of consecutive failed order submissions
+func (s *Switch) OrderSucceeded(ctx context.Context) error {
+	return s.update(ctx, func(status Status) *Status {
+		if status.Failures == 0 {
+			return nil
+		}
+		status.Failures = 0
+		return &status
+	})
+}
+
+// update replaces the halt flag with what change makes of it, unless change returns nil. Other runs'
+// changes in the meantime are never overwritten: change is applied again to the newer flag instead.
+func (s *Switch) update(ctx context.Context, change func(Status) *Status) error {
+	err := statestore.Update(ctx, s.store, Key, func(data []byte) ([]byte, error) {
+		status, err := decode(data)
+		if err != nil {
+			return nil, err
+		}
+		changed := change(status)
+		if changed == nil {
+			return nil, nil
+		}
+		data, err = json.Marshal(changed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the halt flag: %w", err)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save the halt flag: %w", err)
+	}
+	return nil
+}
+
+func (s *Switch) save(ctx context.Context, status Status) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode the halt flag: %w", err)
+	}
+	if err := s.store.Put(ctx, Key, data); err != nil {
+		return fmt.Errorf("failed to save the halt flag: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package halt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/halt"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+const leap = "QQQ260320C00450000"
+
+var now = time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC)
+
+func newAccount(t *testing.T) *sim.Broker {
+	t.Helper()
+	b := sim.NewBroker(now)
+	b.SetBuyingPower(money.NewFromInt(20000))
+	if err := b.SetOption(leap, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}}); err != nil {
+		t.Fatal(err)
+	}
+	b.SetPosition(alpaca.Position{Symbol: leap, Qty: decimal.NewFromInt(5), CostBasis: decimal.NewFromInt(5000)})
+	return b
+}
+
+func buy(b *halt.Broker, clientOrderID string) error {
+	_, err := b.PlaceOptionBuyOrder(context.Background(), clientOrderID, leap, decimal.NewFromInt(1), money.NewFromInt(10))
+	return err
+}
+
+func status(t *testing.T, sw *halt.Switch) halt.Status {
+	t.Helper()
+	status, err := sw.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestHaltRefusesEveryOrder(t *testing.T) {
+	ctx := context.Background()
+	account := newAccount(t)
+	sw := halt.NewSwitch(statestore.NewMemoryStore(), 0)
+	b := halt.NewBroker(account, sw)
+
+	account.SetFillRule(sim.FillNever)
+	resting, err := b.PlaceOptionBuyOrder(ctx, "qqq-gap.20250304.resting", leap, decimal.NewFromInt(1), money.NewFromInt(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sw.Halt(ctx, halt.SourceManual, "fills look wrong", now); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := map[string]func() error{
+		"buy": func() error { return buy(b, "qqq-gap.20250304.gap-down") },
+		"sell": func() error {
+			_, err := b.PlaceOptionSellOrder(ctx, "qqq-gap.20250304.trim", leap, decimal.NewFromInt(1), money.Zero)
+			return err
+		},
+		"bracket": func() error {
+			_, _, err := b.PlaceOptionBracketOrder(ctx, "qqq-gap.20250304.bracket", money.NewFromInt(2000), leap,
+				&marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, pricing.PercentOfAsk(100), bracket.Exits{TakeProfitPercent: 50})
+			return err
+		},
+		"replace": func() error {
+			_, err := b.ReplaceOrder(ctx, resting.ID, "qqq-gap.20250304.resting-r1", money.Cents(950))
+			return err
+		},
+	}
+	for name, place := range orders {
+		if err := place(); !errors.Is(err, halt.ErrHalted) {
+			t.Errorf("%s while halted: error = %v, want ErrHalted", name, err)
+		}
+	}
+	if n := len(account.Orders()); n != 1 {
+		t.Errorf("%d orders reached the broker, want only the one placed before the halt", n)
+	}
+
+	// Cancellations still go through
+	if err := b.CancelOrder(ctx, resting.ID); err != nil {
+		t.Errorf("CancelOrder() while halted: %v", err)
+	}
+
+	// The first reason is kept until resumed
+	if _, err := sw.Halt(ctx, halt.SourceOrderFailures, "later", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(t, sw); got.Source != halt.SourceManual || got.Reason != "fills look wrong" {
+		t.Errorf("status = %s, want the manual halt kept", got)
+	}
+
+	if _, err := sw.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := buy(b, "qqq-gap.20250304.gap-down"); err != nil {
+		t.Errorf("buy after resuming: %v", err)
+	}
+}
+
+func TestHaltTripsAfterConsecutiveFailures(t *testing.T) {
+	account := newAccount(t)
+	sw := halt.NewSwitch(statestore.NewMemoryStore(), 3)
+	b := halt.NewBroker(account, sw)
+
+	account.FailOrders(errors.New("503 service unavailable"))
+	for i := range 2 {
+		if err := buy(b, "qqq-gap.20250304.a"); err == nil || errors.Is(err, halt.ErrHalted) {
+			t.Fatalf("failure %d: error = %v, want the broker's", i+1, err)
+		}
+	}
+	// A success resets the count
+	account.FailOrders(nil)
+	if err := buy(b, "qqq-gap.20250304.b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(t, sw); got.Failures != 0 || got.Halted {
+		t.Fatalf("status after a success = %s, want no failures", got)
+	}
+
+	account.FailOrders(errors.New("503 service unavailable"))
+	for range 3 {
+		_ = buy(b, "qqq-gap.20250304.c")
+	}
+	got := status(t, sw)
+	if !got.Halted || got.Source != halt.SourceOrderFailures || got.Failures != 3 {
+		t.Fatalf("status after 3 failures = %s (%d failures), want halted by order failures", got, got.Failures)
+	}
+	account.FailOrders(nil)
+	if err := buy(b, "qqq-gap.20250304.d"); !errors.Is(err, halt.ErrHalted) {
+		t.Errorf("buy after tripping: error = %v, want ErrHalted", err)
+	}
+}
+
+// overlappingStore runs another run's update the first time the flag is read for one, between that read
+// and its write
+type overlappingStore struct {
+	*statestore.MemoryStore
+	overlap func()
+}
+
+func (s *overlappingStore) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	value, version, err := s.MemoryStore.GetVersion(ctx, key)
+	if overlap := s.overlap; overlap != nil {
+		s.overlap = nil
+		overlap()
+	}
+	return value, version, err
+}
+
+func TestHaltCountsOverlappingFailures(t *testing.T) {
+	ctx := context.Background()
+	shared := statestore.NewMemoryStore()
+	store := &overlappingStore{MemoryStore: shared}
+	sw := halt.NewSwitch(store, 2)
+	other := halt.NewSwitch(shared, 2)
+	store.overlap = func() {
+		if err := other.OrderFailed(ctx, now, errors.New("503 service unavailable")); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := sw.OrderFailed(ctx, now, errors.New("503 service unavailable")); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(t, sw); !got.Halted || got.Source != halt.SourceOrderFailures || got.Failures != 2 {
+		t.Errorf("status after a failure in each run = %s (%d failures), want halted by order failures", got, got.Failures)
+	}
+}
+
+func TestHaltTripsOnDailyLoss(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits risk.Limits
+		halted bool
+	}{
+		{name: "daily loss", limits: risk.Limits{DailyLossLimit: money.NewFromInt(1000)}, halted: true},
+		{name: "another limit", limits: risk.Limits{MaxTradeNotional: money.NewFromInt(500)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := newAccount(t)
+			// Equity is $25,000 less than $27,000 at the previous close
+			account.SetLastEquity(money.NewFromInt(27000))
+			store := statestore.NewMemoryStore()
+			sw := halt.NewSwitch(store, 1)
+			b := halt.NewBroker(risk.NewBroker(account, tt.limits, store), sw)
+
+			var rejection *risk.Rejection
+			if err := buy(b, "qqq-gap.20250304.gap-down"); !errors.As(err, &rejection) {
+				t.Fatalf("error = %v, want a risk rejection", err)
+			}
+			got := status(t, sw)
+			if got.Halted != tt.halted {
+				t.Errorf("halted = %v, want %v", got.Halted, tt.halted)
+			}
+			if tt.halted && got.Source != halt.SourceDailyLoss {
+				t.Errorf("source = %s, want %s", got.Source, halt.SourceDailyLoss)
+			}
+			// A rejection isn't a failed submission
+			if got.Failures != 0 {
+				t.Errorf("failures = %d, want 0", got.Failures)
+			}
+		})
+	}
+}
+
+func TestUnbuildableOrderIsNotAFailure(t *testing.T) {
+	sw := halt.NewSwitch(statestore.NewMemoryStore(), 1)
+	b := halt.NewBroker(newAccount(t), sw)
+
+	policy := pricing.Policy{Method: pricing.MethodMid, MaxSpreadPercent: 0.5}
+	_, _, err := b.PlaceOptionBracketOrder(context.Background(), "qqq-gap.20250304.gap-down", money.NewFromInt(2000), leap,
+		&marketdata.OptionQuote{BidPrice: 9.00, AskPrice: 10.00}, policy, bracket.Exits{TakeProfitPercent: 50})
+	if !errors.Is(err, pricing.ErrSpreadTooWide) {
+		t.Fatalf("error = %v, want ErrSpreadTooWide", err)
+	}
+	if got := status(t, sw); got.Halted || got.Failures != 0 {
+		t.Errorf("status = %s, want a wide spread not counted", got)
+	}
+}
+
+// brokenStore fails every read
+type brokenStore struct {
+	*statestore.MemoryStore
+}
+
+func (brokenStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("access denied")
+}
+
+func TestUnreadableFlagCountsAsHalted(t *testing.T) {
+	account := newAccount(t)
+	b := halt.NewBroker(account, halt.NewSwitch(brokenStore{statestore.NewMemoryStore()}, 0))
+	if err := buy(b, "qqq-gap.20250304.gap-down"); !errors.Is(err, halt.ErrHalted) {
+		t.Errorf("error = %v, want ErrHalted", err)
+	}
+	if n := len(account.Orders()); n != 0 {
+		t.Errorf("%d orders reached the broker, want none", n)
+	}
+}
@@ -0,0 +1,338 @@
+package journal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+
+	// Registers the pure-Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// DefaultPath is the journal file, or state store key, used when the config doesn't name one
+const DefaultPath = "athenax.db"
+
+// uploadTimeout bounds writing a store-backed journal back when it is closed
+const uploadTimeout = time.Minute
+
+// timeFormat stores timestamps as fixed-width UTC text so they sort and compare as strings
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// Journal is a persistent record of every strategy run, signal, order, fill and exit, kept in an
+// embedded SQLite file
+type Journal struct {
+	db          *sql.DB
+	environment string
+
+	// store and key are set for a journal kept in a state store and worked on in the local file path.
+	// version is the stored journal's version when it was downloaded, empty if there was none.
+	store   statestore.VersionedStore
+	key     string
+	path    string
+	version string
+	// dirty records whether the journal changed since it was opened
+	dirty bool
+}
+
+// Run is one engine run across its strategy instances
+type Run struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	MarketOpen bool
+	// Equity and Cash are the account's equity and non-marginable buying power at the end of the run,
+	// or zero when they couldn't be read
+	Equity     money.Money
+	Cash       money.Money
+	Strategies []StrategyRun
+}
+
+// StrategyRun is the outcome of one strategy instance within a run
+type StrategyRun struct {
+	InstanceID  string
+	Strategy    string
+	Decision    string
+	Outcome     string
+	Message     string
+	Error       string
+	DryRun      bool
+	Duration    time.Duration
+	Signals     []Signal
+	Orders      []Order
+	Diagnostics map[string]any
+}
+
+// Signal is one condition a strategy evaluated
+type Signal struct {
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Triggered bool    `json:"triggered"`
+}
+
+// Order is an order submission. AlpacaOrderID is the ID the broker returned for it, or for its last
+// replacement when it was repriced.
+type Order struct {
+	AlpacaOrderID   string      `json:"alpaca_order_id"`
+	ClientOrderID   string      `json:"client_order_id"`
+	Symbol          string      `json:"symbol"`
+	Side            string      `json:"side"`
+	Qty             float64     `json:"qty"`
+	LimitPrice      money.Money `json:"limit_price"`
+	TakeProfitPrice money.Money `json:"take_profit_price,omitzero"`
+	// StopPrice is the option stop of a bracket leg or of a client-managed exit
+	StopPrice money.Money `json:"stop_price,omitzero"`
+	// UnderlyingStopPrice is the underlying stop of a client-managed exit
+	UnderlyingStopPrice money.Money  `json:"underlying_stop_price,omitzero"`
+	DryRun              bool         `json:"dry_run,omitempty"`
+	Events              []OrderEvent `json:"events,omitempty"`
+}
+
+// OrderEvent is a transition of an order tracked after submission: a fill, a repricing or a cancellation
+type OrderEvent struct {
+	At             time.Time   `json:"at"`
+	Type           string      `json:"type"`
+	OrderID        string      `json:"order_id"`
+	ClientOrderID  string      `json:"client_order_id"`
+	Status         string      `json:"status"`
+	LimitPrice     money.Money `json:"limit_price"`
+	FilledQty      float64     `json:"filled_qty"`
+	FilledAvgPrice money.Money `json:"filled_avg_price,omitzero"`
+	Message        string      `json:"message"`
+}
+
+// Open opens the journal at path, creating the file if needed and applying pending migrations
+func Open(path string) (*Journal, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{"PRAGMA foreign_keys = ON", "PRAGMA busy_timeout = 5000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to configure journal %s: %w", path, err)
+		}
+	}
+
+	applied, err := migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate journal %s: %w", path, err)
+	}
+	return &Journal{db: db, dirty: applied > 0}, nil
+}
+
+// OpenStore opens the journal kept under key in store. A local store's file is used in place;
+// otherwise the journal is downloaded to a temporary file and uploaded again by Close if it changed,
+// provided no other run uploaded it in the meantime.
+func OpenStore(ctx context.Context, store statestore.StateStore, key string) (*Journal, error) {
+	if local, ok := store.(*statestore.LocalStore); ok {
+		path, err := local.Path(key)
+		if err != nil {
+			return nil, err
+		}
+		return Open(path)
+	}
+	versioned, ok := store.(statestore.VersionedStore)
+	if !ok {
+		return nil, fmt.Errorf("state store %T can't hold the journal: it has no conditional writes", store)
+	}
+
+	data, version, err := versioned.GetVersion(ctx, key)
+	if err != nil && !errors.Is(err, statestore.ErrNotFound) {
+		return nil, fmt.Errorf("failed to download journal %s: %w", key, err)
+	}
+
+	f, err := os.CreateTemp("", "athenax-journal-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local journal copy: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to create local journal copy: %w", err)
+	}
+
+	j, err := Open(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	j.store, j.key, j.path, j.version = versioned, key, f.Name(), version
+	return j, nil
+}
+
+// Close closes the journal file, uploading a store-backed journal that changed. If another run uploaded
+// the journal since it was opened, nothing is overwritten: the error names the local copy, which is kept
+// so its records can be recovered.
+func (j *Journal) Close() error {
+	if err := j.db.Close(); err != nil {
+		return fmt.Errorf("failed to close journal: %w", err)
+	}
+	if j.store == nil {
+		return nil
+	}
+
+	if !j.dirty {
+		os.Remove(j.path)
+		return nil
+	}
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		os.Remove(j.path)
+		return fmt.Errorf("failed to read local journal copy: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	err = j.store.PutVersion(ctx, j.key, data, j.version)
+	if errors.Is(err, statestore.ErrConflict) {
+		return fmt.Errorf("journal %s was uploaded by another run since this one opened it; this run's records are kept in %s: %w",
+			j.key, j.path, err)
+	}
+	os.Remove(j.path)
+	if err != nil {
+		return fmt.Errorf("failed to upload journal %s: %w", j.key, err)
+	}
+	return nil
+}
+
+// SetEnvironment sets the trading environment recorded with every following run and order
+func (j *Journal) SetEnvironment(environment string) {
+	j.environment = environment
+}
+
+// RecordRun persists a run with the signals and order submissions of each of its strategies,
+// returning the run's ID
+func (j *Journal) RecordRun(ctx context.Context, run Run) (int64, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin journal transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO runs (started_at, finished_at, environment, market_open, equity, cash) VALUES (?, ?, ?, ?, ?, ?)`,
+		formatTime(run.StartedAt), formatTime(run.FinishedAt), j.environment, run.MarketOpen, run.Equity, run.Cash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+
+	for _, strategy := range run.Strategies {
+		if err := j.recordStrategyRun(ctx, tx, runID, run.FinishedAt, strategy); err != nil {
+			return 0, fmt.Errorf("failed to record strategy instance %s: %w", strategy.InstanceID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit run: %w", err)
+	}
+	j.dirty = true
+	return runID, nil
+}
+
+func (j *Journal) recordStrategyRun(ctx context.Context, tx *sql.Tx, runID int64, submittedAt time.Time, strategy StrategyRun) error {
+	diagnostics, err := json.Marshal(strategy.Diagnostics)
+	if err != nil {
+		return fmt.Errorf("failed to encode diagnostics: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO strategy_runs (run_id, instance_id, strategy, decision, outcome, message, error, dry_run, duration_ms, diagnostics)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID, strategy.InstanceID, strategy.Strategy, strategy.Decision, strategy.Outcome, strategy.Message,
+		strategy.Error, strategy.DryRun, strategy.Duration.Milliseconds(), string(diagnostics))
+	if err != nil {
+		return err
+	}
+	strategyRunID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, signal := range strategy.Signals {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO signals (strategy_run_id, name, value, threshold, triggered) VALUES (?, ?, ?, ?, ?)`,
+			strategyRunID, signal.Name, signal.Value, signal.Threshold, signal.Triggered); err != nil {
+			return err
+		}
+	}
+
+	for _, order := range strategy.Orders {
+		if err := j.recordOrder(ctx, tx, strategyRunID, strategy.InstanceID, strategy.Strategy, submittedAt, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *Journal) recordOrder(ctx context.Context, tx *sql.Tx, strategyRunID int64, instanceID, strategy string, submittedAt time.Time, order Order) error {
+	underlying := order.Symbol
+	if option, err := athenaxalpaca.ParseOptionTicker(order.Symbol); err == nil {
+		underlying = option.Underlying
+	}
+	status := statusSubmitted
+	if order.DryRun {
+		status = statusDryRun
+	}
+	// The last tracked event has the latest status; Sync still follows the order and its legs
+	if len(order.Events) > 0 {
+		status = order.Events[len(order.Events)-1].Status
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO orders (strategy_run_id, submitted_at, updated_at, environment, instance_id, strategy,
+			alpaca_order_id, client_order_id, symbol, underlying, side, qty, limit_price, take_profit_price, stop_price,
+			underlying_stop_price, dry_run, status, closed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		strategyRunID, formatTime(submittedAt), formatTime(submittedAt), j.environment, instanceID, strategy,
+		order.AlpacaOrderID, order.ClientOrderID, order.Symbol, underlying, order.Side, order.Qty, order.LimitPrice,
+		order.TakeProfitPrice, order.StopPrice, order.UnderlyingStopPrice, order.DryRun, status, order.DryRun)
+	if err != nil {
+		return err
+	}
+	orderID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, event := range order.Events {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO order_events (order_id, at, type, alpaca_order_id, client_order_id, status, limit_price,
+				filled_qty, filled_avg_price, message)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			orderID, formatTime(event.At), event.Type, event.OrderID, event.ClientOrderID, event.Status,
+			event.LimitPrice, event.FilledQty, event.FilledAvgPrice, event.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(timeFormat, s)
+	return t
+}
@@ -0,0 +1,62 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+func TestOpenStoreRefusesToOverwriteConcurrentUpload(t *testing.T) {
+	ctx := context.Background()
+	store := statestore.NewMemoryStore()
+
+	first, err := OpenStore(ctx, store, DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenStore(ctx, store, DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range []*Journal{first, second} {
+		if _, err := j.RecordRun(ctx, Run{StartedAt: time.Now(), FinishedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("first Close() = %v", err)
+	}
+	uploaded, err := store.Get(ctx, DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = second.Close()
+	if !errors.Is(err, statestore.ErrConflict) {
+		t.Fatalf("second Close() = %v, want ErrConflict", err)
+	}
+	defer os.Remove(second.path)
+	if _, err := os.Stat(second.path); err != nil {
+		t.Errorf("the conflicting copy wasn't kept: %v", err)
+	}
+	if current, _ := store.Get(ctx, DefaultPath); string(current) != string(uploaded) {
+		t.Error("the second run overwrote the first run's upload")
+	}
+
+	// A run opening the journal after the first upload sees its run and can upload again
+	third, err := OpenStore(ctx, store, DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := third.RecordRun(ctx, Run{StartedAt: time.Now(), FinishedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := third.Close(); err != nil {
+		t.Fatalf("third Close() = %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package journal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migrations are applied in order, each exactly once; the position of a migration is its version.
+// Never edit a released migration: append a new one instead.
+var migrations = []string{
+	// 1: runs, signals, orders, fills and exits
+	`CREATE TABLE runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at TEXT NOT NULL,
+		finished_at TEXT NOT NULL,
+		environment TEXT NOT NULL,
+		market_open INTEGER NOT NULL
+	);
+	CREATE INDEX runs_started_at ON runs (started_at);
+
+	CREATE TABLE strategy_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id INTEGER NOT NULL REFERENCES runs (id),
+		instance_id TEXT NOT NULL,
+		strategy TEXT NOT NULL,
+		decision TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		message TEXT NOT NULL,
+		error TEXT NOT NULL,
+		dry_run INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		diagnostics TEXT NOT NULL
+	);
+	CREATE INDEX strategy_runs_run_id ON strategy_runs (run_id);
+
+	CREATE TABLE signals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		strategy_run_id INTEGER NOT NULL REFERENCES strategy_runs (id),
+		name TEXT NOT NULL,
+		value REAL NOT NULL,
+		threshold REAL NOT NULL,
+		triggered INTEGER NOT NULL
+	);
+	CREATE INDEX signals_strategy_run_id ON signals (strategy_run_id);
+
+	CREATE TABLE orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		strategy_run_id INTEGER REFERENCES strategy_runs (id),
+		submitted_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		environment TEXT NOT NULL,
+		instance_id TEXT NOT NULL,
+		strategy TEXT NOT NULL,
+		alpaca_order_id TEXT NOT NULL,
+		client_order_id TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		underlying TEXT NOT NULL,
+		side TEXT NOT NULL,
+		qty REAL NOT NULL,
+		limit_price REAL NOT NULL,
+		take_profit_price REAL NOT NULL,
+		dry_run INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		closed INTEGER NOT NULL
+	);
+	CREATE INDEX orders_submitted_at ON orders (submitted_at);
+	CREATE INDEX orders_alpaca_order_id ON orders (alpaca_order_id);
+	CREATE INDEX orders_open ON orders (closed, dry_run);
+
+	CREATE TABLE fills (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER NOT NULL UNIQUE REFERENCES orders (id),
+		qty REAL NOT NULL,
+		price REAL NOT NULL,
+		filled_at TEXT NOT NULL
+	);
+
+	CREATE TABLE exits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER NOT NULL REFERENCES orders (id),
+		exit_order_id TEXT NOT NULL UNIQUE,
+		qty REAL NOT NULL,
+		price REAL NOT NULL,
+		exited_at TEXT NOT NULL,
+		reason TEXT NOT NULL
+	);
+	CREATE INDEX exits_order_id ON exits (order_id);`,
+
+	// 2: order lifecycle events reported while an order was tracked to a fill
+	`CREATE TABLE order_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER NOT NULL REFERENCES orders (id),
+		at TEXT NOT NULL,
+		type TEXT NOT NULL,
+		alpaca_order_id TEXT NOT NULL,
+		client_order_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		limit_price REAL NOT NULL,
+		filled_qty REAL NOT NULL,
+		filled_avg_price REAL NOT NULL,
+		message TEXT NOT NULL
+	);
+	CREATE INDEX order_events_order_id ON order_events (order_id);`,
+
+	// 3: store amounts as exact decimal text rather than REAL
+	textColumns("orders", "limit_price", "take_profit_price") +
+		textColumns("fills", "price") +
+		textColumns("exits", "price") +
+		textColumns("order_events", "limit_price", "filled_avg_price"),
+
+	// 4: stop prices of the exits attached to an order, so its bracket can be reconstructed
+	`ALTER TABLE orders ADD COLUMN stop_price TEXT NOT NULL DEFAULT '0';
+	ALTER TABLE orders ADD COLUMN underlying_stop_price TEXT NOT NULL DEFAULT '0';`,
+
+	// 5: hold and exit decisions of the position manager
+	`CREATE TABLE position_decisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		at TEXT NOT NULL,
+		environment TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		underlying TEXT NOT NULL,
+		qty REAL NOT NULL,
+		entry_price TEXT NOT NULL,
+		mark TEXT NOT NULL,
+		high_water TEXT NOT NULL,
+		delta REAL NOT NULL,
+		days_held INTEGER NOT NULL,
+		days_to_expiry INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		rule TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		exit_order_id TEXT NOT NULL,
+		dry_run INTEGER NOT NULL,
+		error TEXT NOT NULL
+	);
+	CREATE INDEX position_decisions_at ON position_decisions (at);`,
+
+	// 6: LEAP rolls, linking the closed position to the one opened in its place
+	`CREATE TABLE rolls (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		at TEXT NOT NULL,
+		environment TEXT NOT NULL,
+		instance_id TEXT NOT NULL,
+		underlying TEXT NOT NULL,
+		method TEXT NOT NULL,
+		status TEXT NOT NULL,
+		qty REAL NOT NULL,
+		close_symbol TEXT NOT NULL,
+		close_order_id TEXT NOT NULL,
+		closed_qty REAL NOT NULL,
+		close_price TEXT NOT NULL,
+		open_symbol TEXT NOT NULL,
+		open_order_id TEXT NOT NULL,
+		opened_qty REAL NOT NULL,
+		open_price TEXT NOT NULL,
+		rollback_order_id TEXT NOT NULL,
+		dry_run INTEGER NOT NULL,
+		error TEXT NOT NULL
+	);
+	CREATE INDEX rolls_at ON rolls (at);
+	CREATE INDEX rolls_open_symbol ON rolls (open_symbol);`,
+
+	// 7: orders the risk manager rejected
+	`CREATE TABLE risk_rejections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		at TEXT NOT NULL,
+		environment TEXT NOT NULL,
+		instance_id TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		underlying TEXT NOT NULL,
+		limit_name TEXT NOT NULL,
+		value REAL NOT NULL,
+		threshold REAL NOT NULL,
+		reason TEXT NOT NULL
+	);
+	CREATE INDEX risk_rejections_at ON risk_rejections (at);`,
+
+	// 8: account equity at the end of each run, for performance reports of live trading
+	`ALTER TABLE runs ADD COLUMN equity TEXT NOT NULL DEFAULT '0';
+	ALTER TABLE runs ADD COLUMN cash TEXT NOT NULL DEFAULT '0';`,
+}
+
+// textColumns returns the statements converting the REAL columns of table to TEXT, keeping their
+// values. SQLite can't change a column's type in place, so each is copied into a new column that
+// takes its name.
+func textColumns(table string, columns ...string) string {
+	var statements string
+	for _, column := range columns {
+		statements += fmt.Sprintf(`
+	ALTER TABLE %[1]s ADD COLUMN %[2]s_text TEXT NOT NULL DEFAULT '0';
+	UPDATE %[1]s SET %[2]s_text = CAST(%[2]s AS TEXT);
+	ALTER TABLE %[1]s DROP COLUMN %[2]s;
+	ALTER TABLE %[1]s RENAME COLUMN %[2]s_text TO %[2]s;`, table, column)
+	}
+	return statements
+}
+
+// migrate brings the schema up to date, recording each applied version in schema_migrations,
+// and returns the number of migrations applied
+func migrate(db *sql.DB) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current > len(migrations) {
+		return 0, fmt.Errorf("journal schema version %d is newer than this build supports (%d)", current, len(migrations))
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		if err := applyMigration(db, version, migrations[version-1]); err != nil {
+			return 0, fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return len(migrations) - current, nil
+}
+
+func applyMigration(db *sql.DB, version int, statements string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(statements); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+		version, formatTime(time.Now())); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
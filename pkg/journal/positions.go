@@ -0,0 +1,230 @@
+package journal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// PositionDecision is what the position manager decided for one open position in a run
+type PositionDecision struct {
+	At           time.Time   `json:"at"`
+	Symbol       string      `json:"symbol"`
+	Underlying   string      `json:"underlying"`
+	Qty          float64     `json:"qty"`
+	EntryPrice   money.Money `json:"entry_price"`
+	Mark         money.Money `json:"mark"`
+	HighWater    money.Money `json:"high_water"`
+	Delta        float64     `json:"delta"`
+	DaysHeld     int         `json:"days_held"`
+	DaysToExpiry int         `json:"days_to_expiry"`
+	// Action is "hold" or "exit"
+	Action string `json:"action"`
+	// Rule is the exit rule that fired, empty when holding
+	Rule        string `json:"rule,omitempty"`
+	Reason      string `json:"reason"`
+	ExitOrderID string `json:"exit_order_id,omitempty"`
+	DryRun      bool   `json:"dry_run,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// PositionDecisionRecord is a journaled position manager decision
+type PositionDecisionRecord struct {
+	PositionDecision
+	ID          int64  `json:"id"`
+	Environment string `json:"environment"`
+}
+
+// RecordPositionDecision persists a decision of the position manager
+func (j *Journal) RecordPositionDecision(ctx context.Context, decision PositionDecision) error {
+	if _, err := j.db.ExecContext(ctx,
+		`INSERT INTO position_decisions (at, environment, symbol, underlying, qty, entry_price, mark, high_water, delta,
+			days_held, days_to_expiry, action, rule, reason, exit_order_id, dry_run, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		formatTime(decision.At), j.environment, decision.Symbol, decision.Underlying, decision.Qty, decision.EntryPrice,
+		decision.Mark, decision.HighWater, decision.Delta, decision.DaysHeld, decision.DaysToExpiry, decision.Action,
+		decision.Rule, decision.Reason, decision.ExitOrderID, decision.DryRun, decision.Error); err != nil {
+		return fmt.Errorf("failed to record position decision for %s: %w", decision.Symbol, err)
+	}
+	j.dirty = true
+	return nil
+}
+
+// PositionDecisions returns the position manager decisions matching filter, oldest first. A strategy
+// filter doesn't apply: the position manager acts on positions of every strategy.
+func (j *Journal) PositionDecisions(ctx context.Context, filter Filter) ([]PositionDecisionRecord, error) {
+	where, args := Filter{From: filter.From, To: filter.To}.where("at", "")
+	if filter.Symbol != "" {
+		where = append(where, `(symbol = ? OR underlying = ?)`)
+		args = append(args, filter.Symbol, filter.Symbol)
+	}
+
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT id, at, environment, symbol, underlying, qty, entry_price, mark, high_water, delta, days_held,
+			days_to_expiry, action, rule, reason, exit_order_id, dry_run, error
+		FROM position_decisions`+whereClause(where)+`
+		ORDER BY at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query position decisions: %w", err)
+	}
+	defer rows.Close()
+
+	var records []PositionDecisionRecord
+	for rows.Next() {
+		var record PositionDecisionRecord
+		var at string
+		if err := rows.Scan(&record.ID, &at, &record.Environment, &record.Symbol, &record.Underlying, &record.Qty,
+			&record.EntryPrice, &record.Mark, &record.HighWater, &record.Delta, &record.DaysHeld, &record.DaysToExpiry,
+			&record.Action, &record.Rule, &record.Reason, &record.ExitOrderID, &record.DryRun, &record.Error); err != nil {
+			return nil, fmt.Errorf("failed to read position decision: %w", err)
+		}
+		record.At = parseTime(at)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query position decisions: %w", err)
+	}
+	return records, nil
+}
+
+// OpenedAt returns when the oldest journaled entry in symbol that still has contracts open filled,
+// or false when the journal has none
+func (j *Journal) OpenedAt(ctx context.Context, symbol string) (time.Time, bool, error) {
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT f.filled_at, f.qty, COALESCE((SELECT SUM(e.qty) FROM exits e WHERE e.order_id = o.id), 0)
+		FROM orders o JOIN fills f ON f.order_id = o.id
+		WHERE o.symbol = ? AND o.environment = ? AND o.dry_run = 0 AND o.side = 'buy'
+		ORDER BY f.filled_at, o.id`, symbol, j.environment)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to query fills of %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var filledAt string
+		var filled, exited sql.NullFloat64
+		if err := rows.Scan(&filledAt, &filled, &exited); err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to read fill of %s: %w", symbol, err)
+		}
+		if filled.Float64 > exited.Float64 {
+			return parseTime(filledAt), true, nil
+		}
+	}
+	return time.Time{}, false, rows.Err()
+}
+
+// Roll is a LEAP rolled into a later expiry: the position closed and the one opened in its place
+type Roll struct {
+	At time.Time `json:"at"`
+	// InstanceID is the strategy instance the rolled position is attributed to, if known
+	InstanceID string `json:"instance_id"`
+	Underlying string `json:"underlying"`
+	// Method is "multi-leg" or "sequenced"
+	Method string `json:"method"`
+	// Status is "rolled", "rolled-back" or "failed"
+	Status       string      `json:"status"`
+	Qty          float64     `json:"qty"`
+	CloseSymbol  string      `json:"close_symbol"`
+	CloseOrderID string      `json:"close_order_id"`
+	ClosedQty    float64     `json:"closed_qty"`
+	ClosePrice   money.Money `json:"close_price"`
+	OpenSymbol   string      `json:"open_symbol"`
+	OpenOrderID  string      `json:"open_order_id,omitempty"`
+	OpenedQty    float64     `json:"opened_qty"`
+	OpenPrice    money.Money `json:"open_price"`
+	// RollbackOrderID is the order buying back the closed contracts the open leg failed to replace
+	RollbackOrderID string `json:"rollback_order_id,omitempty"`
+	DryRun          bool   `json:"dry_run,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// RollRecord is a journaled roll
+type RollRecord struct {
+	Roll
+	ID          int64  `json:"id"`
+	Environment string `json:"environment"`
+}
+
+// RecordRoll persists a roll and returns its ID
+func (j *Journal) RecordRoll(ctx context.Context, roll Roll) (int64, error) {
+	result, err := j.db.ExecContext(ctx,
+		`INSERT INTO rolls (at, environment, instance_id, underlying, method, status, qty, close_symbol, close_order_id,
+			closed_qty, close_price, open_symbol, open_order_id, opened_qty, open_price, rollback_order_id, dry_run, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		formatTime(roll.At), j.environment, roll.InstanceID, roll.Underlying, roll.Method, roll.Status, roll.Qty,
+		roll.CloseSymbol, roll.CloseOrderID, roll.ClosedQty, roll.ClosePrice, roll.OpenSymbol, roll.OpenOrderID,
+		roll.OpenedQty, roll.OpenPrice, roll.RollbackOrderID, roll.DryRun, roll.Error)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record roll of %s: %w", roll.CloseSymbol, err)
+	}
+	j.dirty = true
+	return result.LastInsertId()
+}
+
+// Rolls returns the rolls matching filter, oldest first. The strategy filter matches the instance ID.
+func (j *Journal) Rolls(ctx context.Context, filter Filter) ([]RollRecord, error) {
+	where, args := Filter{From: filter.From, To: filter.To}.where("at", "")
+	if filter.Strategy != "" {
+		where = append(where, `instance_id = ?`)
+		args = append(args, filter.Strategy)
+	}
+	if filter.Symbol != "" {
+		where = append(where, `(close_symbol = ? OR open_symbol = ? OR underlying = ?)`)
+		args = append(args, filter.Symbol, filter.Symbol, filter.Symbol)
+	}
+
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT id, at, environment, instance_id, underlying, method, status, qty, close_symbol, close_order_id,
+			closed_qty, close_price, open_symbol, open_order_id, opened_qty, open_price, rollback_order_id, dry_run, error
+		FROM rolls`+whereClause(where)+`
+		ORDER BY at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rolls: %w", err)
+	}
+	defer rows.Close()
+
+	var records []RollRecord
+	for rows.Next() {
+		var record RollRecord
+		var at string
+		if err := rows.Scan(&record.ID, &at, &record.Environment, &record.InstanceID, &record.Underlying, &record.Method,
+			&record.Status, &record.Qty, &record.CloseSymbol, &record.CloseOrderID, &record.ClosedQty, &record.ClosePrice,
+			&record.OpenSymbol, &record.OpenOrderID, &record.OpenedQty, &record.OpenPrice, &record.RollbackOrderID,
+			&record.DryRun, &record.Error); err != nil {
+			return nil, fmt.Errorf("failed to read roll: %w", err)
+		}
+		record.At = parseTime(at)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query rolls: %w", err)
+	}
+	return records, nil
+}
+
+// InstanceOf returns the strategy instance whose latest entry into symbol, by a journaled order or a
+// roll, is on record, or false when the journal has none
+func (j *Journal) InstanceOf(ctx context.Context, symbol string) (string, bool, error) {
+	var instanceID string
+	err := j.db.QueryRowContext(ctx,
+		`SELECT instance_id FROM (
+			SELECT instance_id, submitted_at AS at FROM orders
+			WHERE symbol = ? AND environment = ? AND side = 'buy' AND dry_run = 0 AND instance_id != ''
+			UNION ALL
+			SELECT instance_id, at FROM rolls
+			WHERE open_symbol = ? AND environment = ? AND dry_run = 0 AND opened_qty > 0 AND instance_id != ''
+		)
+		ORDER BY at DESC
+		LIMIT 1`, symbol, j.environment, symbol, j.environment).Scan(&instanceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up the instance holding %s: %w", symbol, err)
+	}
+	return instanceID, true, nil
+}
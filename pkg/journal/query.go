@@ -0,0 +1,296 @@
+package journal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// Filter narrows journal queries. Zero values match everything.
+type Filter struct {
+	// From and To bound the run or submission time; To is exclusive
+	From time.Time
+	To   time.Time
+	// Strategy matches a strategy instance ID or a strategy name
+	Strategy string
+	// Symbol matches an order's symbol or its underlying
+	Symbol string
+}
+
+// RunRecord is a journaled strategy run
+type RunRecord struct {
+	RunID       int64          `json:"run_id"`
+	StartedAt   time.Time      `json:"started_at"`
+	Environment string         `json:"environment"`
+	MarketOpen  bool           `json:"market_open"`
+	InstanceID  string         `json:"instance_id"`
+	Strategy    string         `json:"strategy"`
+	Decision    string         `json:"decision"`
+	Outcome     string         `json:"outcome"`
+	Message     string         `json:"message"`
+	Error       string         `json:"error,omitempty"`
+	DryRun      bool           `json:"dry_run,omitempty"`
+	Duration    time.Duration  `json:"duration_ns"`
+	Signals     []Signal       `json:"signals,omitempty"`
+	Diagnostics map[string]any `json:"diagnostics,omitempty"`
+}
+
+// Trade is a journaled order submission with its fill and exits
+type Trade struct {
+	Order
+	ID          int64     `json:"id"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	Environment string    `json:"environment"`
+	InstanceID  string    `json:"instance_id"`
+	Strategy    string    `json:"strategy"`
+	Underlying  string    `json:"underlying"`
+	Status      string    `json:"status"`
+	Fill        *Fill     `json:"fill,omitempty"`
+	Exits       []Exit    `json:"exits,omitempty"`
+}
+
+// ExitedQty returns the number of contracts closed so far
+func (t Trade) ExitedQty() float64 {
+	qty := 0.0
+	for _, exit := range t.Exits {
+		qty += exit.Qty
+	}
+	return qty
+}
+
+// RealizedPnL returns the dollar profit or loss of the exits recorded so far
+func (t Trade) RealizedPnL() money.Money {
+	if t.Fill == nil {
+		return money.Zero
+	}
+	pnl := money.Zero
+	for _, exit := range t.Exits {
+		contracts := decimal.NewFromFloat(exit.Qty).Mul(decimal.NewFromInt(money.ContractMultiplier))
+		pnl = pnl.Add(exit.Price.Sub(t.Fill.Price).Mul(contracts))
+	}
+	return pnl
+}
+
+// Runs returns the strategy runs matching filter, oldest first. A symbol filter matches runs that
+// submitted an order in that symbol.
+func (j *Journal) Runs(ctx context.Context, filter Filter) ([]RunRecord, error) {
+	where, args := filter.where("r.started_at", "s")
+	if filter.Symbol != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM orders o WHERE o.strategy_run_id = s.id AND (o.symbol = ? OR o.underlying = ?))`)
+		args = append(args, filter.Symbol, filter.Symbol)
+	}
+
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT s.id, r.id, r.started_at, r.environment, r.market_open, s.instance_id, s.strategy, s.decision, s.outcome,
+			s.message, s.error, s.dry_run, s.duration_ms, s.diagnostics
+		FROM strategy_runs s JOIN runs r ON r.id = s.run_id`+whereClause(where)+`
+		ORDER BY r.started_at, s.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	defer rows.Close()
+
+	var records []RunRecord
+	var strategyRunIDs []int64
+	for rows.Next() {
+		var record RunRecord
+		var strategyRunID, durationMS int64
+		var startedAt, diagnostics string
+		if err := rows.Scan(&strategyRunID, &record.RunID, &startedAt, &record.Environment, &record.MarketOpen,
+			&record.InstanceID, &record.Strategy, &record.Decision, &record.Outcome, &record.Message, &record.Error,
+			&record.DryRun, &durationMS, &diagnostics); err != nil {
+			return nil, fmt.Errorf("failed to read run: %w", err)
+		}
+		record.StartedAt = parseTime(startedAt)
+		record.Duration = time.Duration(durationMS) * time.Millisecond
+		if err := json.Unmarshal([]byte(diagnostics), &record.Diagnostics); err != nil {
+			return nil, fmt.Errorf("failed to decode diagnostics of run %d: %w", record.RunID, err)
+		}
+		records = append(records, record)
+		strategyRunIDs = append(strategyRunIDs, strategyRunID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+
+	for i := range records {
+		signals, err := j.signals(ctx, strategyRunIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		records[i].Signals = signals
+	}
+	return records, nil
+}
+
+// Trades returns the order submissions matching filter with their fills and exits, oldest first
+func (j *Journal) Trades(ctx context.Context, filter Filter) ([]Trade, error) {
+	where, args := filter.where("o.submitted_at", "o")
+	if filter.Symbol != "" {
+		where = append(where, `(o.symbol = ? OR o.underlying = ?)`)
+		args = append(args, filter.Symbol, filter.Symbol)
+	}
+
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT o.id, o.submitted_at, o.environment, o.instance_id, o.strategy, o.alpaca_order_id, o.client_order_id,
+			o.symbol, o.underlying, o.side, o.qty, o.limit_price, o.take_profit_price, o.stop_price,
+			o.underlying_stop_price, o.dry_run, o.status,
+			f.qty, f.price, f.filled_at
+		FROM orders o LEFT JOIN fills f ON f.order_id = o.id`+whereClause(where)+`
+		ORDER BY o.submitted_at, o.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trades: %w", err)
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var trade Trade
+		var submittedAt string
+		var fillQty sql.NullFloat64
+		var fillPrice money.Money
+		var fillAt sql.NullString
+		if err := rows.Scan(&trade.ID, &submittedAt, &trade.Environment, &trade.InstanceID, &trade.Strategy,
+			&trade.AlpacaOrderID, &trade.ClientOrderID, &trade.Symbol, &trade.Underlying, &trade.Side, &trade.Qty,
+			&trade.LimitPrice, &trade.TakeProfitPrice, &trade.StopPrice, &trade.UnderlyingStopPrice, &trade.DryRun, &trade.Status,
+			&fillQty, &fillPrice, &fillAt); err != nil {
+			return nil, fmt.Errorf("failed to read trade: %w", err)
+		}
+		trade.SubmittedAt = parseTime(submittedAt)
+		if fillQty.Valid {
+			trade.Fill = &Fill{Qty: fillQty.Float64, Price: fillPrice, FilledAt: parseTime(fillAt.String)}
+		}
+		trades = append(trades, trade)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query trades: %w", err)
+	}
+
+	for i := range trades {
+		exits, err := j.exits(ctx, trades[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		for k := range exits {
+			exits[k].AlpacaOrderID = trades[i].AlpacaOrderID
+		}
+		trades[i].Exits = exits
+
+		events, err := j.orderEvents(ctx, trades[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		trades[i].Events = events
+	}
+	return trades, nil
+}
+
+// OrderedOn reports whether a strategy instance submitted an order in this environment on the day
+// containing day, in day's location. Dry-run orders don't count.
+func (j *Journal) OrderedOn(ctx context.Context, instanceID string, day time.Time) (bool, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	var count int
+	if err := j.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM orders
+		WHERE instance_id = ? AND environment = ? AND dry_run = 0 AND submitted_at >= ? AND submitted_at < ?`,
+		instanceID, j.environment, formatTime(start), formatTime(start.AddDate(0, 0, 1))).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to query orders of %s: %w", instanceID, err)
+	}
+	return count > 0, nil
+}
+
+func (j *Journal) signals(ctx context.Context, strategyRunID int64) ([]Signal, error) {
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT name, value, threshold, triggered FROM signals WHERE strategy_run_id = ? ORDER BY id`, strategyRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signals: %w", err)
+	}
+	defer rows.Close()
+
+	var signals []Signal
+	for rows.Next() {
+		var signal Signal
+		if err := rows.Scan(&signal.Name, &signal.Value, &signal.Threshold, &signal.Triggered); err != nil {
+			return nil, fmt.Errorf("failed to read signal: %w", err)
+		}
+		signals = append(signals, signal)
+	}
+	return signals, rows.Err()
+}
+
+func (j *Journal) exits(ctx context.Context, orderID int64) ([]Exit, error) {
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT exit_order_id, qty, price, exited_at, reason FROM exits WHERE order_id = ? ORDER BY exited_at, id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exits: %w", err)
+	}
+	defer rows.Close()
+
+	var exits []Exit
+	for rows.Next() {
+		var exit Exit
+		var exitedAt string
+		if err := rows.Scan(&exit.ExitOrderID, &exit.Qty, &exit.Price, &exitedAt, &exit.Reason); err != nil {
+			return nil, fmt.Errorf("failed to read exit: %w", err)
+		}
+		exit.ExitedAt = parseTime(exitedAt)
+		exits = append(exits, exit)
+	}
+	return exits, rows.Err()
+}
+
+func (j *Journal) orderEvents(ctx context.Context, orderID int64) ([]OrderEvent, error) {
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT at, type, alpaca_order_id, client_order_id, status, limit_price, filled_qty, filled_avg_price, message
+		FROM order_events WHERE order_id = ? ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OrderEvent
+	for rows.Next() {
+		var event OrderEvent
+		var at string
+		if err := rows.Scan(&at, &event.Type, &event.OrderID, &event.ClientOrderID, &event.Status, &event.LimitPrice,
+			&event.FilledQty, &event.FilledAvgPrice, &event.Message); err != nil {
+			return nil, fmt.Errorf("failed to read order event: %w", err)
+		}
+		event.At = parseTime(at)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// where returns the conditions shared by every query: the time range on timeColumn and the strategy
+// of the table aliased as strategyTable
+func (f Filter) where(timeColumn, strategyTable string) ([]string, []any) {
+	var where []string
+	var args []any
+	if !f.From.IsZero() {
+		where = append(where, timeColumn+" >= ?")
+		args = append(args, formatTime(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, timeColumn+" < ?")
+		args = append(args, formatTime(f.To))
+	}
+	if f.Strategy != "" {
+		where = append(where, fmt.Sprintf("(%[1]s.instance_id = ? OR %[1]s.strategy = ?)", strategyTable))
+		args = append(args, f.Strategy, f.Strategy)
+	}
+	return where, args
+}
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return "\nWHERE " + strings.Join(where, " AND ")
+}
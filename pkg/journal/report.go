@@ -0,0 +1,111 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/report"
+)
+
+// Report computes the performance report of journaled trading in one environment, the same report a
+// backtest produces. The equity curve takes the account equity recorded by the last run of each
+// exchange day, in loc. Only closed lots count as trades: each exit of a filled buy order is one.
+// Strategy and symbol filters narrow the trades; the equity curve is always the whole account's.
+func (j *Journal) Report(ctx context.Context, filter Filter, environment string, loc *time.Location) (*report.Report, error) {
+	curve, err := j.EquityCurve(ctx, filter, environment, loc)
+	if err != nil {
+		return nil, err
+	}
+	trades, err := j.Trades(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return report.Compute(curve, ReportTrades(trades, environment, loc)), nil
+}
+
+// EquityCurve returns the account equity recorded by the last run of each exchange day in loc, in the
+// environment, oldest first. Runs that couldn't read the equity are left out.
+func (j *Journal) EquityCurve(ctx context.Context, filter Filter, environment string, loc *time.Location) ([]report.EquityPoint, error) {
+	where, args := Filter{From: filter.From, To: filter.To}.where("started_at", "")
+	where = append(where, "environment = ?", "equity <> '0'")
+	args = append(args, environment)
+
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT started_at, equity, cash FROM runs`+whereClause(where)+`
+		ORDER BY started_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query equity: %w", err)
+	}
+	defer rows.Close()
+
+	var curve []report.EquityPoint
+	for rows.Next() {
+		var startedAt string
+		var equity, cash money.Money
+		if err := rows.Scan(&startedAt, &equity, &cash); err != nil {
+			return nil, fmt.Errorf("failed to read equity: %w", err)
+		}
+		point := report.EquityPoint{Date: exchangeDate(parseTime(startedAt), loc), Cash: cash, Equity: equity}
+		// A later run of the same day replaces the earlier one
+		if n := len(curve); n > 0 && curve[n-1].Date.Equal(point.Date) {
+			curve[n-1] = point
+			continue
+		}
+		curve = append(curve, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query equity: %w", err)
+	}
+	return curve, nil
+}
+
+// Environments returns the trading environments with journaled runs, in alphabetical order
+func (j *Journal) Environments(ctx context.Context) ([]string, error) {
+	rows, err := j.db.QueryContext(ctx, `SELECT DISTINCT environment FROM runs ORDER BY environment`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query environments: %w", err)
+	}
+	defer rows.Close()
+
+	var environments []string
+	for rows.Next() {
+		var environment string
+		if err := rows.Scan(&environment); err != nil {
+			return nil, fmt.Errorf("failed to read environment: %w", err)
+		}
+		environments = append(environments, environment)
+	}
+	return environments, rows.Err()
+}
+
+// ReportTrades converts the closed lots of journaled trades in the environment into report trades,
+// dated by exchange day in loc like a backtest's. Dry runs, sells and unfilled orders have no lot.
+func ReportTrades(trades []Trade, environment string, loc *time.Location) []report.Trade {
+	var lots []report.Trade
+	for _, trade := range trades {
+		if trade.Environment != environment || trade.DryRun || trade.Side != "buy" || trade.Fill == nil {
+			continue
+		}
+		for _, exit := range trade.Exits {
+			lots = append(lots, report.Trade{
+				Symbol:     trade.Symbol,
+				OrderID:    trade.AlpacaOrderID,
+				Quantity:   int64(exit.Qty),
+				EntryDate:  exchangeDate(trade.Fill.FilledAt, loc),
+				EntryPrice: trade.Fill.Price,
+				ExitDate:   exchangeDate(exit.ExitedAt, loc),
+				ExitPrice:  exit.Price,
+				ExitReason: exit.Reason,
+			})
+		}
+	}
+	return lots
+}
+
+// exchangeDate returns midnight of the exchange day containing t
+func exchangeDate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,88 @@
+package journal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+func TestReport(t *testing.T) {
+	ctx := context.Background()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	j, err := Open(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, loc) }
+	record := func(environment string, startedAt time.Time, equity int64, orders ...Order) {
+		t.Helper()
+		j.SetEnvironment(environment)
+		run := Run{StartedAt: startedAt, FinishedAt: startedAt, MarketOpen: true,
+			Equity: money.NewFromInt(equity), Cash: money.NewFromInt(equity / 2)}
+		if len(orders) > 0 {
+			run.Strategies = []StrategyRun{{InstanceID: "qqq-gap", Strategy: "two-percent-down", Orders: orders}}
+		}
+		if _, err := j.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record("paper", at(3, 10), 10000, Order{AlpacaOrderID: "entry", ClientOrderID: "qqq-gap.20250303.gap-down",
+		Symbol: "QQQ260116C00400000", Side: "buy", Qty: 2, LimitPrice: money.Cents(1000)})
+	// A later run of the same day replaces the day's equity
+	record("paper", at(3, 15), 10100)
+	record("paper", at(4, 10), 9800, Order{AlpacaOrderID: "dry", ClientOrderID: "qqq-gap.20250304.gap-down",
+		Symbol: "QQQ260116C00410000", Side: "buy", Qty: 1, LimitPrice: money.Cents(900), DryRun: true})
+	record("paper", at(6, 10), 10400)
+	record("live", at(6, 10), 50000)
+
+	j.SetEnvironment("paper")
+	filledAt := at(3, 10).Add(time.Minute)
+	price := decimal.RequireFromString("10.00")
+	if err := j.RecordOrderUpdate(ctx, &alpaca.Order{ID: "entry", Status: "filled", FilledQty: decimal.NewFromInt(2),
+		FilledAvgPrice: &price, FilledAt: &filledAt, UpdatedAt: filledAt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.RecordExit(ctx, Exit{AlpacaOrderID: "entry", ExitOrderID: "exit", Qty: 2,
+		Price: money.Cents(1500), ExitedAt: at(6, 11), Reason: ExitTakeProfit}); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := j.Report(ctx, Filter{}, "paper", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.TradingDays != 3 {
+		t.Errorf("TradingDays = %d, want 3", rep.TradingDays)
+	}
+	if !rep.StartEquity.Equal(money.NewFromInt(10100)) || !rep.EndEquity.Equal(money.NewFromInt(10400)) {
+		t.Errorf("equity = %s to %s, want 10100.00 to 10400.00", rep.StartEquity, rep.EndEquity)
+	}
+	if rep.Trades != 1 {
+		t.Fatalf("Trades = %d, want 1", rep.Trades)
+	}
+	if want := money.NewFromInt(1000); !rep.TotalPnL.Equal(want) {
+		t.Errorf("TotalPnL = %s, want %s", rep.TotalPnL, want)
+	}
+	if rep.AvgHoldingDays != 3 {
+		t.Errorf("AvgHoldingDays = %v, want 3", rep.AvgHoldingDays)
+	}
+
+	environments, err := j.Environments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(environments) != 2 || environments[0] != "live" || environments[1] != "paper" {
+		t.Errorf("Environments = %v, want [live paper]", environments)
+	}
+}
@@ -0,0 +1,80 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RiskRejection is an order the risk manager refused to send, with the limit it would have breached
+type RiskRejection struct {
+	At         time.Time `json:"at"`
+	InstanceID string    `json:"instance_id"`
+	Symbol     string    `json:"symbol"`
+	Underlying string    `json:"underlying"`
+	// Limit names the limit the order would have breached
+	Limit string `json:"limit"`
+	// Value is what the limit would have measured with the order placed, and Threshold the limit itself
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Reason    string  `json:"reason"`
+}
+
+// RiskRejectionRecord is a journaled risk rejection
+type RiskRejectionRecord struct {
+	RiskRejection
+	ID          int64  `json:"id"`
+	Environment string `json:"environment"`
+}
+
+// RecordRiskRejection persists an order rejected by the risk manager
+func (j *Journal) RecordRiskRejection(ctx context.Context, rejection RiskRejection) error {
+	if _, err := j.db.ExecContext(ctx,
+		`INSERT INTO risk_rejections (at, environment, instance_id, symbol, underlying, limit_name, value, threshold, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		formatTime(rejection.At), j.environment, rejection.InstanceID, rejection.Symbol, rejection.Underlying,
+		rejection.Limit, rejection.Value, rejection.Threshold, rejection.Reason); err != nil {
+		return fmt.Errorf("failed to record risk rejection for %s: %w", rejection.Symbol, err)
+	}
+	j.dirty = true
+	return nil
+}
+
+// RiskRejections returns the risk rejections matching filter, oldest first. The strategy filter matches
+// the instance ID.
+func (j *Journal) RiskRejections(ctx context.Context, filter Filter) ([]RiskRejectionRecord, error) {
+	where, args := Filter{From: filter.From, To: filter.To}.where("at", "")
+	if filter.Strategy != "" {
+		where = append(where, `instance_id = ?`)
+		args = append(args, filter.Strategy)
+	}
+	if filter.Symbol != "" {
+		where = append(where, `(symbol = ? OR underlying = ?)`)
+		args = append(args, filter.Symbol, filter.Symbol)
+	}
+
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT id, at, environment, instance_id, symbol, underlying, limit_name, value, threshold, reason
+		FROM risk_rejections`+whereClause(where)+`
+		ORDER BY at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query risk rejections: %w", err)
+	}
+	defer rows.Close()
+
+	var records []RiskRejectionRecord
+	for rows.Next() {
+		var record RiskRejectionRecord
+		var at string
+		if err := rows.Scan(&record.ID, &at, &record.Environment, &record.InstanceID, &record.Symbol, &record.Underlying,
+			&record.Limit, &record.Value, &record.Threshold, &record.Reason); err != nil {
+			return nil, fmt.Errorf("failed to read risk rejection: %w", err)
+		}
+		record.At = parseTime(at)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query risk rejections: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,205 @@
+package journal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// Order statuses recorded before the broker reports one
+const (
+	statusSubmitted = "submitted"
+	statusDryRun    = "dry_run"
+)
+
+// Exit reasons recorded for bracket legs
+const (
+	ExitTakeProfit = "take-profit"
+	ExitStopLoss   = "stop-loss"
+)
+
+// OrderGetter looks up an order by its broker ID
+type OrderGetter interface {
+	GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error)
+}
+
+// Fill is the executed part of an entry order
+type Fill struct {
+	Qty      float64     `json:"qty"`
+	Price    money.Money `json:"price"`
+	FilledAt time.Time   `json:"filled_at"`
+}
+
+// Exit closes all or part of the position opened by an entry order
+type Exit struct {
+	// AlpacaOrderID is the broker ID of the entry order whose position was closed
+	AlpacaOrderID string `json:"-"`
+	// ExitOrderID is the broker ID of the closing order
+	ExitOrderID string      `json:"exit_order_id"`
+	Qty         float64     `json:"qty"`
+	Price       money.Money `json:"price"`
+	ExitedAt    time.Time   `json:"exited_at"`
+	Reason      string      `json:"reason"`
+}
+
+// Sync fetches every submitted order the journal still tracks from the broker and records its
+// status, fill and the fills of its bracket legs as exits. An order stops being tracked once it
+// and its legs reach a final state.
+func (j *Journal) Sync(ctx context.Context, orders OrderGetter) error {
+	rows, err := j.db.QueryContext(ctx,
+		`SELECT alpaca_order_id FROM orders WHERE closed = 0 AND dry_run = 0 AND environment = ? AND alpaca_order_id != ''`,
+		j.environment)
+	if err != nil {
+		return fmt.Errorf("failed to query open orders: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to query open orders: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query open orders: %w", err)
+	}
+
+	for _, id := range ids {
+		order, err := orders.GetOrder(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get order %s: %w", id, err)
+		}
+		if err := j.RecordOrderUpdate(ctx, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordOrderUpdate records the broker's current view of an entry order: its status, its fill and
+// any filled bracket legs as exits
+func (j *Journal) RecordOrderUpdate(ctx context.Context, order *alpaca.Order) error {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin journal transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orderID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM orders WHERE alpaca_order_id = ?`, order.ID).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order %s is not in the journal", order.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up order %s: %w", order.ID, err)
+	}
+
+	closed := isFinal(order.Status)
+	if order.FilledQty.IsPositive() && order.FilledAvgPrice != nil {
+		if err := recordFill(ctx, tx, orderID, Fill{
+			Qty:      order.FilledQty.InexactFloat64(),
+			Price:    money.New(*order.FilledAvgPrice),
+			FilledAt: filledAt(order),
+		}); err != nil {
+			return fmt.Errorf("failed to record fill of order %s: %w", order.ID, err)
+		}
+	}
+	for _, leg := range order.Legs {
+		if !isFinal(leg.Status) {
+			closed = false
+		}
+		if !leg.FilledQty.IsPositive() || leg.FilledAvgPrice == nil {
+			continue
+		}
+		reason := ExitTakeProfit
+		if leg.Type == alpaca.Stop || leg.Type == alpaca.StopLimit {
+			reason = ExitStopLoss
+		}
+		if err := recordExit(ctx, tx, orderID, Exit{
+			ExitOrderID: leg.ID,
+			Qty:         leg.FilledQty.InexactFloat64(),
+			Price:       money.New(*leg.FilledAvgPrice),
+			ExitedAt:    filledAt(&leg),
+			Reason:      reason,
+		}); err != nil {
+			return fmt.Errorf("failed to record exit of order %s: %w", order.ID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = ?, closed = ?, updated_at = ? WHERE id = ?`,
+		order.Status, closed, formatTime(order.UpdatedAt), orderID); err != nil {
+		return fmt.Errorf("failed to update order %s: %w", order.ID, err)
+	}
+	return j.commit(tx)
+}
+
+// RecordExit records a position closed outside a bracket, e.g. by an exit rule or at expiry
+func (j *Journal) RecordExit(ctx context.Context, exit Exit) error {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin journal transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orderID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM orders WHERE alpaca_order_id = ?`, exit.AlpacaOrderID).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order %s is not in the journal", exit.AlpacaOrderID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up order %s: %w", exit.AlpacaOrderID, err)
+	}
+	if err := recordExit(ctx, tx, orderID, exit); err != nil {
+		return fmt.Errorf("failed to record exit of order %s: %w", exit.AlpacaOrderID, err)
+	}
+	return j.commit(tx)
+}
+
+// commit commits tx, marking the journal changed
+func (j *Journal) commit(tx *sql.Tx) error {
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit journal transaction: %w", err)
+	}
+	j.dirty = true
+	return nil
+}
+
+// recordFill stores an order's cumulative fill, replacing an earlier partial fill
+func recordFill(ctx context.Context, tx *sql.Tx, orderID int64, fill Fill) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO fills (order_id, qty, price, filled_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (order_id) DO UPDATE SET qty = excluded.qty, price = excluded.price, filled_at = excluded.filled_at`,
+		orderID, fill.Qty, fill.Price, formatTime(fill.FilledAt))
+	return err
+}
+
+// recordExit stores an exit, replacing an earlier partial fill of the same closing order
+func recordExit(ctx context.Context, tx *sql.Tx, orderID int64, exit Exit) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO exits (order_id, exit_order_id, qty, price, exited_at, reason) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (exit_order_id) DO UPDATE SET qty = excluded.qty, price = excluded.price, exited_at = excluded.exited_at`,
+		orderID, exit.ExitOrderID, exit.Qty, exit.Price, formatTime(exit.ExitedAt), exit.Reason)
+	return err
+}
+
+// isFinal reports whether the broker will no longer change an order
+func isFinal(status string) bool {
+	switch status {
+	case "filled", "canceled", "expired", "rejected", "replaced":
+		return true
+	}
+	return false
+}
+
+func filledAt(order *alpaca.Order) time.Time {
+	if order.FilledAt != nil {
+		return *order.FilledAt
+	}
+	return order.UpdatedAt
+}
@@ -0,0 +1,243 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// ContractMultiplier is the number of shares of the underlying an equity option contract covers
+const ContractMultiplier = 100
+
+// Money is an exact dollar amount: a quote, a limit price, a balance or a P&L.
+// The zero value is $0.
+type Money struct {
+	amount decimal.Decimal
+}
+
+// Zero is $0
+var Zero = Money{}
+
+// New returns the amount d
+func New(d decimal.Decimal) Money {
+	return Money{amount: d}
+}
+
+// NewFromFloat returns the amount f, using the shortest decimal that represents the float.
+// Use it only at boundaries where an API or data file hands over a float, such as quotes.
+func NewFromFloat(f float64) Money {
+	return Money{amount: decimal.NewFromFloat(f)}
+}
+
+// NewFromInt returns a whole dollar amount
+func NewFromInt(dollars int64) Money {
+	return Money{amount: decimal.NewFromInt(dollars)}
+}
+
+// Cents returns an amount given in cents
+func Cents(cents int64) Money {
+	return Money{amount: decimal.New(cents, -2)}
+}
+
+// FromPtr returns the amount d points to, or $0 when it is nil, as for an order's optional prices
+func FromPtr(d *decimal.Decimal) Money {
+	if d == nil {
+		return Zero
+	}
+	return Money{amount: *d}
+}
+
+// Parse parses a decimal amount such as "1234.56"
+func Parse(s string) (Money, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Zero, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	return Money{amount: d}, nil
+}
+
+// Decimal returns the amount as a decimal
+func (m Money) Decimal() decimal.Decimal {
+	return m.amount
+}
+
+// Ptr returns a pointer to a copy of the amount, for order requests
+func (m Money) Ptr() *decimal.Decimal {
+	d := m.amount
+	return &d
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) Money {
+	return Money{amount: m.amount.Add(o.amount)}
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return Money{amount: m.amount.Sub(o.amount)}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{amount: m.amount.Neg()}
+}
+
+// Mul returns m scaled by d, e.g. a price times a quantity
+func (m Money) Mul(d decimal.Decimal) Money {
+	return Money{amount: m.amount.Mul(d)}
+}
+
+// MulInt returns m scaled by n
+func (m Money) MulInt(n int64) Money {
+	return Money{amount: m.amount.Mul(decimal.NewFromInt(n))}
+}
+
+// DivInt returns m divided by n
+func (m Money) DivInt(n int64) Money {
+	return Money{amount: m.amount.Div(decimal.NewFromInt(n))}
+}
+
+// Percent returns percent percent of m, e.g. Percent(99) of $3.10 is $3.069
+func (m Money) Percent(percent float64) Money {
+	return Money{amount: m.amount.Mul(decimal.NewFromFloat(percent)).Div(decimal.NewFromInt(100))}
+}
+
+// Ratio returns m / o, e.g. a budget over a contract's cost; it panics when o is zero
+func (m Money) Ratio(o Money) decimal.Decimal {
+	return m.amount.Div(o.amount)
+}
+
+// PercentChange returns the change from m to to as a percentage of m, or 0 when m is zero
+func (m Money) PercentChange(to Money) float64 {
+	if m.IsZero() {
+		return 0
+	}
+	return to.Sub(m).Ratio(m).InexactFloat64() * 100
+}
+
+// Cmp compares m and o, returning -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	return m.amount.Cmp(o.amount)
+}
+
+// Equal reports whether m == o
+func (m Money) Equal(o Money) bool {
+	return m.amount.Equal(o.amount)
+}
+
+// LessThan reports whether m < o
+func (m Money) LessThan(o Money) bool {
+	return m.amount.LessThan(o.amount)
+}
+
+// LessThanOrEqual reports whether m <= o
+func (m Money) LessThanOrEqual(o Money) bool {
+	return m.amount.LessThanOrEqual(o.amount)
+}
+
+// GreaterThan reports whether m > o
+func (m Money) GreaterThan(o Money) bool {
+	return m.amount.GreaterThan(o.amount)
+}
+
+// GreaterThanOrEqual reports whether m >= o
+func (m Money) GreaterThanOrEqual(o Money) bool {
+	return m.amount.GreaterThanOrEqual(o.amount)
+}
+
+// IsZero reports whether m is $0
+func (m Money) IsZero() bool {
+	return m.amount.IsZero()
+}
+
+// IsPositive reports whether m > $0
+func (m Money) IsPositive() bool {
+	return m.amount.IsPositive()
+}
+
+// IsNegative reports whether m < $0
+func (m Money) IsNegative() bool {
+	return m.amount.IsNegative()
+}
+
+// Min returns the smaller of a and b
+func Min(a, b Money) Money {
+	if b.LessThan(a) {
+		return b
+	}
+	return a
+}
+
+// Max returns the larger of a and b
+func Max(a, b Money) Money {
+	if b.GreaterThan(a) {
+		return b
+	}
+	return a
+}
+
+// Float64 returns the nearest float, for statistics and ratios that don't need exact cents
+func (m Money) Float64() float64 {
+	return m.amount.InexactFloat64()
+}
+
+// String formats the amount rounded to cents, e.g. "1234.50"
+func (m Money) String() string {
+	return m.amount.StringFixed(2)
+}
+
+// Signed formats the amount rounded to cents with an explicit sign, e.g. "+12.30", for P&L
+func (m Money) Signed() string {
+	if m.amount.IsNegative() {
+		return m.String()
+	}
+	return "+" + m.String()
+}
+
+// MarshalJSON encodes the exact amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.amount.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or numeric string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Zero
+		return nil
+	}
+	return m.amount.UnmarshalJSON(data)
+}
+
+// Value stores the exact amount as decimal text, for a TEXT column
+func (m Money) Value() (driver.Value, error) {
+	return m.amount.String(), nil
+}
+
+// Scan reads the amount from a TEXT column, or from a REAL or INTEGER value such as one journaled
+// before amounts were stored as text; NULL is $0
+func (m *Money) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Zero
+	case float64:
+		*m = NewFromFloat(v)
+	case int64:
+		*m = NewFromInt(v)
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into an amount", value)
+	}
+	return nil
+}
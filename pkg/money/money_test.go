@@ -0,0 +1,133 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestArithmeticIsExact(t *testing.T) {
+	// 0.1 + 0.2 is 0.30000000000000004 in floats
+	if sum := NewFromFloat(0.1).Add(NewFromFloat(0.2)); !sum.Equal(Cents(30)) {
+		t.Errorf("0.1 + 0.2 = %s, want 0.30", sum.amount)
+	}
+
+	total := Zero
+	for range 1000 {
+		total = total.Add(Cents(1))
+	}
+	if !total.Equal(NewFromInt(10)) {
+		t.Errorf("1000 × 0.01 = %s, want 10", total.amount)
+	}
+
+	if cost := Cents(1235).MulInt(ContractMultiplier).MulInt(3); !cost.Equal(NewFromInt(3705)) {
+		t.Errorf("3 contracts at 12.35 = %s, want 3705", cost)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		amount  Money
+		percent float64
+		want    Money
+	}{
+		{NewFromInt(50000), 2, NewFromInt(1000)},
+		{Cents(1000), 150, Cents(1500)},
+		{Cents(333), 50, NewFromFloat(1.665)},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Percent(tt.percent); !got.Equal(tt.want) {
+			t.Errorf("%s.Percent(%v) = %s, want %s", tt.amount, tt.percent, got.amount, tt.want.amount)
+		}
+	}
+
+	if change := NewFromInt(500).PercentChange(NewFromInt(490)); change != -2 {
+		t.Errorf("PercentChange(500 to 490) = %v, want -2", change)
+	}
+	if change := Zero.PercentChange(NewFromInt(490)); change != 0 {
+		t.Errorf("PercentChange from 0 = %v, want 0", change)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	tests := []struct {
+		amount Money
+		str    string
+		signed string
+	}{
+		{Zero, "0.00", "+0.00"},
+		{Cents(123450), "1234.50", "+1234.50"},
+		{Cents(-1230), "-12.30", "-12.30"},
+		{NewFromFloat(1.005), "1.01", "+1.01"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.str {
+			t.Errorf("String() = %q, want %q", got, tt.str)
+		}
+		if got := tt.amount.Signed(); got != tt.signed {
+			t.Errorf("Signed() = %q, want %q", got, tt.signed)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{NewFromFloat(12.345)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":12.345}` {
+		t.Errorf("Marshal = %s, want the exact amount", data)
+	}
+
+	for _, input := range []string{`{"price":12.345}`, `{"price":"12.345"}`} {
+		var decoded struct {
+			Price Money `json:"price"`
+		}
+		if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", input, err)
+		}
+		if !decoded.Price.Equal(NewFromFloat(12.345)) {
+			t.Errorf("Unmarshal(%s) = %s, want 12.345", input, decoded.Price.amount)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	value, err := NewFromFloat(0.1).Add(NewFromFloat(0.2)).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "0.3" {
+		t.Errorf("Value() = %#v, want the text \"0.3\"", value)
+	}
+
+	tests := []struct {
+		value any
+		want  Money
+	}{
+		{nil, Zero},
+		{"12.34", Cents(1234)},
+		{[]byte("0.3"), Cents(30)},
+		{12.34, Cents(1234)},
+		{int64(7), NewFromInt(7)},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.value); err != nil {
+			t.Errorf("Scan(%#v) error = %v", tt.value, err)
+			continue
+		}
+		if !m.Equal(tt.want) {
+			t.Errorf("Scan(%#v) = %s, want %s", tt.value, m.amount, tt.want.amount)
+		}
+	}
+
+	var m Money
+	if err := m.Scan("twelve"); err == nil {
+		t.Error("Scan of a non-numeric string succeeded")
+	}
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan of a bool succeeded")
+	}
+}
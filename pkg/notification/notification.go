@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 )
 
@@ -15,17 +15,24 @@ type Client struct {
 	noisyWebhookURL  string
 	normalWebhookURL string
 	method           string // "generic" or "discord"
+	environment      string // trading environment shown on every notification
 }
 
-// NewClient creates a new notification client using the NOTIFY_WEBHOOK_URL and NOTIFY_METHOD environment variables
-func NewClient() (*Client, error) {
-	noisyWebhookURL := os.Getenv("NOTIFY_NOISY_WEBHOOK_URL")
-	normalWebhookURL := os.Getenv("NOTIFY_NORMAL_WEBHOOK_URL")
-	method := os.Getenv("NOTIFY_METHOD")
+// NewClient creates a new notification client. An empty webhook URL disables that channel.
+// Every message is tagged with environment, e.g. "[PAPER]".
+func NewClient(method, noisyWebhookURL, normalWebhookURL, environment string) (*Client, error) {
 	if method == "" {
 		method = "generic"
 	}
-	return &Client{noisyWebhookURL: noisyWebhookURL, normalWebhookURL: normalWebhookURL, method: method}, nil
+	if method != "generic" && method != "discord" {
+		return nil, fmt.Errorf("unsupported notification method: %s", method)
+	}
+	return &Client{noisyWebhookURL: noisyWebhookURL, normalWebhookURL: normalWebhookURL, method: method, environment: environment}, nil
+}
+
+// NewNoopClient creates a notification client that never sends anything, for backtests and simulations
+func NewNoopClient() *Client {
+	return &Client{method: "generic"}
 }
 
 type payload struct {
@@ -39,6 +46,10 @@ func (c *Client) sendNotification(webhookURL, notificationType, message string)
 		return nil
 	}
 
+	if c.environment != "" {
+		message = "[" + strings.ToUpper(c.environment) + "] " + message
+	}
+
 	var b []byte
 	var err error
 	var contentType string
@@ -83,6 +94,31 @@ func (c *Client) OrderPlaced(message string) error {
 	return nil
 }
 
+func (c *Client) OrderFilled(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "💰 Order filled", message)
+	return nil
+}
+
+func (c *Client) OrderRepriced(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🔼 Order repriced", message)
+	return nil
+}
+
+func (c *Client) OrderNotFilled(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🛑 Order not filled", message)
+	return nil
+}
+
+func (c *Client) PositionExit(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🚪 Position exit", message)
+	return nil
+}
+
+func (c *Client) DryRunOrder(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🧪 Dry run order (not sent)", message)
+	return nil
+}
+
 func (c *Client) Failure(message string) error {
 	_ = c.sendNotification(c.normalWebhookURL, "❌ Error occurred", message)
 	return fmt.Errorf("%s", message)
@@ -98,8 +134,38 @@ func (c *Client) MaxActiveOptions(message string) error {
 	return nil
 }
 
-func (c *Client) NoGapDown(message string) error {
-	_ = c.sendNotification(c.noisyWebhookURL, "🚫 No gap down", message)
+func (c *Client) NoSize(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "📏 No position size", message)
+	return nil
+}
+
+func (c *Client) WideSpread(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "↔️ Spread too wide", message)
+	return nil
+}
+
+func (c *Client) RiskRejected(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🛡️ Risk limit", message)
+	return nil
+}
+
+func (c *Client) Halted(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🚨 TRADING HALTED", message)
+	return nil
+}
+
+func (c *Client) Resumed(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "▶️ Trading resumed", message)
+	return nil
+}
+
+func (c *Client) AlreadyActed(message string) error {
+	_ = c.sendNotification(c.normalWebhookURL, "🔁 Already acted today", message)
+	return nil
+}
+
+func (c *Client) NoSignal(message string) error {
+	_ = c.sendNotification(c.noisyWebhookURL, "🚫 No signal", message)
 	return nil
 }
 
@@ -0,0 +1,268 @@
+package ordertracker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+// Action is what the tracker does with an order still open when its timeout passes
+type Action string
+
+const (
+	// ActionCancel cancels the order
+	ActionCancel Action = "cancel"
+	// ActionWalk cancels and replaces the order at a limit walked toward the ask, up to MaxSteps times,
+	// and cancels it once the last step times out. Sell orders are cancelled without walking.
+	ActionWalk Action = "walk"
+)
+
+// Defaults used for unset configuration
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxSteps     = 3
+)
+
+// Event types reported as an order moves through its lifecycle
+const (
+	// EventFilled means the order filled completely
+	EventFilled = "filled"
+	// EventRepriced means the order was replaced at a higher limit after timing out
+	EventRepriced = "repriced"
+	// EventCanceled means the tracker cancelled the order after it timed out
+	EventCanceled = "canceled"
+	// EventClosed means the broker ended the order without a complete fill, e.g. it expired or was rejected
+	EventClosed = "closed"
+)
+
+// Config configures the tracker
+type Config struct {
+	// PollInterval is how often the order's status is checked
+	PollInterval time.Duration
+	// Timeout is how long the order may rest at each limit price before OnTimeout applies
+	Timeout time.Duration
+	// OnTimeout is what happens to an order still open after Timeout
+	OnTimeout Action
+	// MaxSteps bounds the number of replacements when walking toward the ask
+	MaxSteps int
+}
+
+// Event is a transition of a tracked order
+type Event struct {
+	At             time.Time   `json:"at"`
+	Type           string      `json:"type"`
+	OrderID        string      `json:"order_id"`
+	ClientOrderID  string      `json:"client_order_id"`
+	Status         string      `json:"status"`
+	LimitPrice     money.Money `json:"limit_price"`
+	FilledQty      float64     `json:"filled_qty"`
+	FilledAvgPrice money.Money `json:"filled_avg_price,omitzero"`
+	Message        string      `json:"message"`
+}
+
+// Tracker follows submitted orders until they reach a terminal state, cancelling or repricing
+// the ones that don't fill in time
+type Tracker struct {
+	broker broker.Broker
+	config Config
+}
+
+// New creates a tracker using b, filling in defaults for unset configuration
+func New(b broker.Broker, config Config) *Tracker {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.OnTimeout == "" {
+		config.OnTimeout = ActionCancel
+	}
+	if config.MaxSteps <= 0 {
+		config.MaxSteps = DefaultMaxSteps
+	}
+	return &Tracker{broker: b, config: config}
+}
+
+// Track follows the order until it fills or is closed, calling report with each event as it happens.
+// It returns the final order, which is the last replacement when the order was repriced, and every
+// event reported. An error leaves the last order in whatever state the broker has it.
+func (t *Tracker) Track(ctx context.Context, orderID string, report func(Event)) (*alpaca.Order, []Event, error) {
+	var events []Event
+	emit := func(event Event) {
+		log.Printf("Order %s %s: %s", event.OrderID, event.Type, event.Message)
+		events = append(events, event)
+		if report != nil {
+			report(event)
+		}
+	}
+
+	order, err := t.broker.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.LimitPrice == nil {
+		return order, nil, fmt.Errorf("order %s has no limit price to track", orderID)
+	}
+	// Replacements are numbered from the original's client order ID, and walk from its price
+	clientOrderID := order.ClientOrderID
+	startPrice := money.FromPtr(order.LimitPrice)
+
+	for step := 1; ; step++ {
+		order, err = t.await(ctx, order)
+		if err != nil {
+			return order, events, err
+		}
+		if isTerminal(order.Status) {
+			emit(closedEvent(order))
+			return order, events, nil
+		}
+
+		// Walking toward the ask only helps a buy fill, so a sell left open is cancelled instead
+		if t.config.OnTimeout != ActionWalk || order.Side != alpaca.Buy || step > t.config.MaxSteps {
+			order, err = t.cancel(ctx, order)
+			if err != nil {
+				return order, events, err
+			}
+			if order.Status == "filled" {
+				emit(closedEvent(order))
+			} else {
+				emit(newEvent(EventCanceled, order, fmt.Sprintf("%s cancelled: not filled within %s at %s%s",
+					order.Symbol, t.config.Timeout, limitPrice(order), partialFill(order))))
+			}
+			return order, events, nil
+		}
+
+		price, err := t.walkPrice(ctx, order, startPrice, step)
+		if err != nil {
+			return order, events, err
+		}
+		if price.LessThanOrEqual(limitPrice(order)) {
+			log.Printf("Order %s: ask is at or below the limit %s, waiting at the same price", order.ID, limitPrice(order))
+			continue
+		}
+
+		previous := limitPrice(order)
+		replacement, err := t.broker.ReplaceOrder(ctx, order.ID, athenaxalpaca.ReplacementClientOrderID(clientOrderID, step), price)
+		if err != nil {
+			// The order may have filled while the replacement was in flight
+			if latest, getErr := t.broker.GetOrder(ctx, order.ID); getErr == nil && isTerminal(latest.Status) {
+				emit(closedEvent(latest))
+				return latest, events, nil
+			}
+			return order, events, err
+		}
+		emit(newEvent(EventRepriced, replacement, fmt.Sprintf("%s repriced from %s to %s (step %d/%d), replacing order %s",
+			replacement.Symbol, previous, price, step, t.config.MaxSteps, order.ID)))
+		order = replacement
+	}
+}
+
+// await polls the order until it reaches a terminal state or the timeout passes, returning its latest state
+func (t *Tracker) await(ctx context.Context, order *alpaca.Order) (*alpaca.Order, error) {
+	deadline := time.Now().Add(t.config.Timeout)
+	for {
+		latest, err := t.broker.GetOrder(ctx, order.ID)
+		if err != nil {
+			return order, err
+		}
+		order = latest
+		remaining := time.Until(deadline)
+		if isTerminal(order.Status) || remaining <= 0 {
+			return order, nil
+		}
+
+		timer := time.NewTimer(min(t.config.PollInterval, remaining))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return order, fmt.Errorf("stopped tracking order %s: %w", order.ID, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// cancel cancels the order and returns its latest state, which is filled if the fill won the race
+func (t *Tracker) cancel(ctx context.Context, order *alpaca.Order) (*alpaca.Order, error) {
+	cancelErr := t.broker.CancelOrder(ctx, order.ID)
+	latest, err := t.broker.GetOrder(ctx, order.ID)
+	if err != nil {
+		if cancelErr != nil {
+			return order, cancelErr
+		}
+		return order, err
+	}
+	if cancelErr != nil && latest.Status != "filled" {
+		return latest, cancelErr
+	}
+	return latest, nil
+}
+
+// walkPrice returns the limit of the step-th replacement: the fraction step/MaxSteps of the way from the
+// original limit to the current ask, rounded up to a valid tick and never above the ask
+func (t *Tracker) walkPrice(ctx context.Context, order *alpaca.Order, startPrice money.Money, step int) (money.Money, error) {
+	snapshot, err := t.broker.GetOptionSnapshot(ctx, order.Symbol)
+	if err != nil {
+		return money.Zero, fmt.Errorf("failed to get the ask to reprice order %s: %w", order.ID, err)
+	}
+	if snapshot.LatestQuote == nil || snapshot.LatestQuote.AskPrice <= 0 {
+		return money.Zero, fmt.Errorf("failed to reprice order %s: no ask for %s", order.ID, order.Symbol)
+	}
+	ask := money.NewFromFloat(snapshot.LatestQuote.AskPrice)
+
+	// Standard ticks are valid for penny classes too, and the ask itself is always a quoted price
+	price := startPrice.Add(ask.Sub(startPrice).MulInt(int64(step)).DivInt(int64(t.config.MaxSteps)))
+	return money.Min(pricing.RoundUp(price, pricing.TickStandard), ask), nil
+}
+
+// closedEvent reports an order the broker ended: filled, or closed without a complete fill
+func closedEvent(order *alpaca.Order) Event {
+	if order.Status == "filled" {
+		return newEvent(EventFilled, order, fmt.Sprintf("%s filled %s @ %s",
+			order.Symbol, order.FilledQty, filledAvgPrice(order)))
+	}
+	return newEvent(EventClosed, order, fmt.Sprintf("%s order ended %s at %s%s",
+		order.Symbol, order.Status, limitPrice(order), partialFill(order)))
+}
+
+func newEvent(eventType string, order *alpaca.Order, message string) Event {
+	return Event{
+		At:             time.Now(),
+		Type:           eventType,
+		OrderID:        order.ID,
+		ClientOrderID:  order.ClientOrderID,
+		Status:         order.Status,
+		LimitPrice:     limitPrice(order),
+		FilledQty:      order.FilledQty.InexactFloat64(),
+		FilledAvgPrice: filledAvgPrice(order),
+		Message:        message,
+	}
+}
+
+// isTerminal reports whether the broker will no longer change an order of this status
+func isTerminal(status string) bool {
+	switch status {
+	case "filled", "canceled", "expired", "rejected", "replaced", "done_for_day":
+		return true
+	}
+	return false
+}
+
+func partialFill(order *alpaca.Order) string {
+	if order.FilledQty.IsPositive() {
+		return fmt.Sprintf(" (%s partially filled @ %s)", order.FilledQty, filledAvgPrice(order))
+	}
+	return ""
+}
+
+func limitPrice(order *alpaca.Order) money.Money {
+	return money.FromPtr(order.LimitPrice)
+}
+
+func filledAvgPrice(order *alpaca.Order) money.Money {
+	return money.FromPtr(order.FilledAvgPrice)
+}
@@ -0,0 +1,226 @@
+package ordertracker_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
+)
+
+const (
+	// testOption is quoted at 9.90 bid and 10.00 ask
+	testOption = "QQQ260320C00450000"
+	// testLimit is the limit the tracked order rests at, 60 cents below the ask
+	testLimit = 9.40
+)
+
+var (
+	testDay           = time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC)
+	testClientOrderID = athenaxalpaca.NewClientOrderID("qqq-gap", testDay, "entry")
+)
+
+// polledBroker runs onPoll before answering the n-th GetOrder, letting a test change the market while
+// the tracker waits on an order
+type polledBroker struct {
+	*sim.Broker
+	polls  int
+	onPoll map[int]func()
+}
+
+func (b *polledBroker) GetOrder(ctx context.Context, orderID string) (*alpaca.Order, error) {
+	b.polls++
+	if do, ok := b.onPoll[b.polls]; ok {
+		do()
+	}
+	return b.Broker.GetOrder(ctx, orderID)
+}
+
+// newBroker returns a simulated broker quoting testOption under the fill rule, holding 2 contracts of it
+func newBroker(t *testing.T, rule sim.FillRule) *sim.Broker {
+	t.Helper()
+	b := sim.NewBroker(testDay)
+	b.SetCalendar(sim.RegularSession(testDay, time.UTC))
+	if err := b.SetOption(testOption, marketdata.OptionSnapshot{
+		LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b.SetBuyingPower(money.NewFromInt(10000))
+	b.SetPosition(alpaca.Position{Symbol: testOption, Qty: decimal.NewFromInt(2), AvgEntryPrice: decimal.NewFromInt(8)})
+	b.SetFillRule(rule)
+	return b
+}
+
+func TestTrack(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   sim.FillRule
+		side   alpaca.Side
+		config ordertracker.Config
+		onPoll map[int]func(b *sim.Broker)
+		// events are the types of the events reported, each with the limit of the order it reports
+		events []string
+		// status is the final order's status
+		status string
+	}{
+		{
+			name:   "fills while polled",
+			rule:   sim.FillWhenMarketable,
+			config: ordertracker.Config{Timeout: time.Second},
+			onPoll: map[int]func(b *sim.Broker){3: func(b *sim.Broker) {
+				if err := b.SetOptionQuote(testOption, 9.30, 9.40); err != nil {
+					t.Fatal(err)
+				}
+				b.MatchOrders()
+			}},
+			events: []string{"filled @ 9.40"},
+			status: "filled",
+		},
+		{
+			name:   "closed by the broker",
+			rule:   sim.FillNever,
+			config: ordertracker.Config{Timeout: time.Second},
+			onPoll: map[int]func(b *sim.Broker){2: func(b *sim.Broker) { b.CancelOpenOrders() }},
+			events: []string{"closed @ 9.40"},
+			status: "canceled",
+		},
+		{
+			name:   "timeout cancels",
+			rule:   sim.FillNever,
+			config: ordertracker.Config{Timeout: 20 * time.Millisecond},
+			events: []string{"canceled @ 9.40"},
+			status: "canceled",
+		},
+		{
+			// A third of the way from 9.40 to the 10.00 ask each step, filling once it reaches the ask
+			name:   "walk toward the ask",
+			rule:   sim.FillWhenMarketable,
+			config: ordertracker.Config{Timeout: 10 * time.Millisecond, OnTimeout: ordertracker.ActionWalk},
+			events: []string{"repriced @ 9.60", "repriced @ 9.80", "repriced @ 10.00", "filled @ 10.00"},
+			status: "filled",
+		},
+		{
+			name:   "walk up to max steps",
+			rule:   sim.FillNever,
+			config: ordertracker.Config{Timeout: 10 * time.Millisecond, OnTimeout: ordertracker.ActionWalk, MaxSteps: 2},
+			events: []string{"repriced @ 9.70", "repriced @ 10.00", "canceled @ 10.00"},
+			status: "canceled",
+		},
+		{
+			// With the ask down at the limit there is nothing to walk toward, so every step waits at the
+			// limit until the order is cancelled
+			name:   "walk waits while the ask is at the limit",
+			rule:   sim.FillNever,
+			config: ordertracker.Config{Timeout: 10 * time.Millisecond, OnTimeout: ordertracker.ActionWalk, MaxSteps: 2},
+			onPoll: map[int]func(b *sim.Broker){1: func(b *sim.Broker) {
+				if err := b.SetOptionQuote(testOption, 9.30, 9.40); err != nil {
+					t.Fatal(err)
+				}
+			}},
+			events: []string{"canceled @ 9.40"},
+			status: "canceled",
+		},
+		{
+			name:   "sell cancelled without walking",
+			rule:   sim.FillNever,
+			side:   alpaca.Sell,
+			config: ordertracker.Config{Timeout: 10 * time.Millisecond, OnTimeout: ordertracker.ActionWalk},
+			events: []string{"canceled @ 9.40"},
+			status: "canceled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newBroker(t, tt.rule)
+			place := b.PlaceOptionBuyOrder
+			if tt.side == alpaca.Sell {
+				place = b.PlaceOptionSellOrder
+			}
+			order, err := place(ctx, testClientOrderID, testOption, decimal.NewFromInt(2), money.NewFromFloat(testLimit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			polled := &polledBroker{Broker: b, onPoll: make(map[int]func())}
+			for n, do := range tt.onPoll {
+				polled.onPoll[n] = func() { do(b) }
+			}
+
+			tt.config.PollInterval = 2 * time.Millisecond
+			var reported []ordertracker.Event
+			final, events, err := ordertracker.New(polled, tt.config).Track(ctx, order.ID, func(event ordertracker.Event) {
+				reported = append(reported, event)
+			})
+			if err != nil {
+				t.Fatalf("Track() error = %v", err)
+			}
+
+			var got []string
+			for _, event := range events {
+				got = append(got, fmt.Sprintf("%s @ %s", event.Type, event.LimitPrice))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.events, ", ") {
+				t.Errorf("events = %q, want %q", got, tt.events)
+			}
+			if len(reported) != len(events) {
+				t.Errorf("reported %d events, returned %d", len(reported), len(events))
+			}
+			if final.Status != tt.status {
+				t.Errorf("final order is %s, want %s", final.Status, tt.status)
+			}
+			if last := events[len(events)-1]; last.OrderID != final.ID || last.Status != final.Status {
+				t.Errorf("last event is order %s %s, want the final order %s %s", last.OrderID, last.Status, final.ID, final.Status)
+			}
+
+			// Each replacement numbers the original's client order ID and replaces the order before it
+			orders := b.Orders()
+			if steps := strings.Count(strings.Join(got, ","), ordertracker.EventRepriced); len(orders) != steps+1 {
+				t.Fatalf("%d orders, want the original and %d replacements", len(orders), steps)
+			}
+			for step, replacement := range orders[1:] {
+				previous := orders[step]
+				if want := athenaxalpaca.ReplacementClientOrderID(testClientOrderID, step+1); replacement.ClientOrderID != want {
+					t.Errorf("replacement %d client order ID = %s, want %s", step+1, replacement.ClientOrderID, want)
+				}
+				if previous.Status != "replaced" || replacement.Replaces == nil || *replacement.Replaces != previous.ID {
+					t.Errorf("replacement %d doesn't replace order %s (%s)", step+1, previous.ID, previous.Status)
+				}
+				if event := events[step]; event.OrderID != replacement.ID || !strings.Contains(event.Message, fmt.Sprintf("replacing order %s", previous.ID)) {
+					t.Errorf("repriced event = %s %q, want order %s replacing %s", event.OrderID, event.Message, replacement.ID, previous.ID)
+				}
+			}
+			if final.ID != orders[len(orders)-1].ID {
+				t.Errorf("final order = %s, want the last replacement %s", final.ID, orders[len(orders)-1].ID)
+			}
+		})
+	}
+}
+
+func TestTrackStopsWithContext(t *testing.T) {
+	b := newBroker(t, sim.FillNever)
+	order, err := b.PlaceOptionBuyOrder(context.Background(), testClientOrderID, testOption, decimal.NewFromInt(2), money.NewFromFloat(testLimit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	tracker := ordertracker.New(b, ordertracker.Config{PollInterval: 2 * time.Millisecond, Timeout: time.Minute})
+	final, events, err := tracker.Track(ctx, order.ID, nil)
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("Track() error = %v, want the context's", err)
+	}
+	if len(events) != 0 || final.Status != "new" {
+		t.Errorf("%d events, final order %s; want the order left open", len(events), final.Status)
+	}
+}
@@ -0,0 +1,482 @@
+package positions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/ordertracker"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// InstanceID tags the client order IDs of the orders the position manager places
+const InstanceID = "position-manager"
+
+// stateKey is the state store key holding what the manager tracks about open positions
+const stateKey = "positions/manager.json"
+
+// maxOrderAttempts bounds the orders submitted for one purpose in one symbol on one trading day
+const maxOrderAttempts = 20
+
+// Actions the manager takes on a position
+const (
+	ActionHold = "hold"
+	ActionExit = "exit"
+	ActionRoll = "roll"
+)
+
+// RuleRoll is recorded with the decisions to roll a position into a later expiry
+const RuleRoll = "roll"
+
+// tracked is what the manager remembers about an open position between runs
+type tracked struct {
+	// FirstSeen is when the manager first saw the position, its age when the journal has no entry for it
+	FirstSeen time.Time   `json:"first_seen"`
+	HighWater money.Money `json:"high_water"`
+}
+
+// Decision is what the manager decided for one open position
+type Decision journal.PositionDecision
+
+// Result is the outcome of a position manager run
+type Result struct {
+	MarketOpen bool       `json:"market_open"`
+	Decisions  []Decision `json:"decisions"`
+}
+
+// Manager enforces exit rules on the open option positions of the account, whichever strategy opened them
+type Manager struct {
+	broker      broker.Broker
+	store       statestore.StateStore
+	rules       Rules
+	notifier    *notification.Client
+	journal     *journal.Journal
+	exitMonitor *bracket.Monitor
+	roll        RollConfig
+	tracker     *ordertracker.Tracker
+}
+
+// NewManager creates a manager applying rules to positions held with b, tracking high-water marks in store
+func NewManager(b broker.Broker, store statestore.StateStore, rules Rules, notifier *notification.Client) *Manager {
+	return &Manager{broker: b, store: store, rules: rules, notifier: notifier}
+}
+
+// SetJournal records every decision in j and dates positions by their journaled entry fills. Without a
+// journal a position's age counts from the first run that saw it.
+func (m *Manager) SetJournal(j *journal.Journal) {
+	m.journal = j
+}
+
+// SetExitMonitor stops monitor from enforcing client-managed exits on positions the manager closes
+func (m *Manager) SetExitMonitor(monitor *bracket.Monitor) {
+	m.exitMonitor = monitor
+}
+
+// SetRoll rolls calls into a later expiry once they come within config.DaysToExpiry of expiring,
+// ahead of the exit rules. Each order of a roll is followed until it fills or config.FillTimeout passes.
+func (m *Manager) SetRoll(config RollConfig) {
+	m.roll = config
+	m.tracker = ordertracker.New(m.broker, ordertracker.Config{Timeout: config.FillTimeout, OnTimeout: ordertracker.ActionCancel})
+}
+
+// Run evaluates every long option position on the underlyings, rolls those due to roll and sells those
+// an exit rule calls to close. A position that fails to be evaluated, rolled or sold is reported in its decision and doesn't stop
+// the others; the returned error only reports failures of the run itself.
+func (m *Manager) Run(ctx context.Context, underlyings []string) (*Result, error) {
+	result := &Result{}
+	isOpen, err := m.broker.IsMarketOpen(ctx)
+	if err != nil {
+		return nil, m.notifier.Failure(fmt.Sprintf("failed to check if market is open: %v", err))
+	}
+	if !isOpen {
+		log.Println("Market is closed, exiting...")
+		return result, m.notifier.MarketClosed()
+	}
+	result.MarketOpen = true
+
+	state, err := m.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := m.broker.Now()
+	seen := make(map[string]bool)
+
+	for _, underlying := range underlyings {
+		held, err := m.broker.GetOptionsPositions(ctx, underlying)
+		if err != nil {
+			return nil, m.notifier.Failure(fmt.Sprintf("failed to get option positions on %s: %v", underlying, err))
+		}
+		for _, position := range held {
+			if !position.Qty.IsPositive() {
+				continue
+			}
+			seen[position.Symbol] = true
+			decision := m.manage(ctx, underlying, position, state, now)
+			m.record(ctx, decision)
+			result.Decisions = append(result.Decisions, decision)
+		}
+	}
+
+	// Forget positions that were closed since the last run
+	for symbol := range state {
+		if !seen[symbol] {
+			delete(state, symbol)
+		}
+	}
+	if err := m.save(ctx, state); err != nil {
+		log.Printf("Failed to save position manager state: %v", err)
+		_ = m.notifier.ActionNeeded(fmt.Sprintf("Failed to save position manager state, high-water marks may be lost: %v", err), err)
+	}
+	return result, nil
+}
+
+// manage rolls one position if it is due, or else evaluates the exit rules on it and sells it if one fires
+func (m *Manager) manage(ctx context.Context, underlying string, position alpaca.Position, state map[string]*tracked, now time.Time) Decision {
+	decision := Decision{
+		At:         now,
+		Symbol:     position.Symbol,
+		Underlying: underlying,
+		Qty:        position.Qty.InexactFloat64(),
+		EntryPrice: money.New(position.AvgEntryPrice),
+		Action:     ActionHold,
+	}
+
+	p, bid, err := m.position(ctx, position, state, now)
+	if err != nil {
+		decision.Error = err.Error()
+		decision.Reason = "failed to evaluate the position"
+		return decision
+	}
+	decision.Mark = p.Mark
+	decision.HighWater = p.HighWater
+	decision.Delta = p.Delta
+	decision.DaysHeld = p.DaysHeld(now)
+	decision.DaysToExpiry = p.DaysToExpiry(now)
+
+	var rollSkipped string
+	if m.roll.Due(p, now) {
+		roll, err := m.rollPosition(ctx, underlying, position, bid, now)
+		if err == nil {
+			m.recordRoll(ctx, roll)
+			decision.Action = ActionRoll
+			decision.Rule = RuleRoll
+			decision.Reason = fmt.Sprintf("%d days to expiry, at or below the roll threshold of %d: into %s (%s)",
+				decision.DaysToExpiry, m.roll.DaysToExpiry, roll.OpenSymbol, roll.Status)
+			decision.ExitOrderID = roll.CloseOrderID
+			decision.DryRun = roll.DryRun
+			decision.Error = roll.Error
+			return decision
+		}
+		log.Printf("Not rolling %s: %v", position.Symbol, err)
+		rollSkipped = fmt.Sprintf("roll skipped: %v; ", err)
+	}
+
+	rule, reason := m.rules.Evaluate(p, now)
+	if rule == "" {
+		decision.Reason = rollSkipped + "no exit rule triggered"
+		return decision
+	}
+	decision.Action = ActionExit
+	decision.Rule = rule
+	decision.Reason = rollSkipped + reason
+
+	order, err := m.exit(ctx, position, bid, now)
+	if err != nil {
+		decision.Error = err.Error()
+		return decision
+	}
+	decision.ExitOrderID = order.ID
+	decision.DryRun = order.Status == dryrun.Status
+	return decision
+}
+
+// position builds the rules' view of a broker position from its latest snapshot, updating the tracked
+// high-water mark, and returns the bid a closing order is priced at
+func (m *Manager) position(ctx context.Context, position alpaca.Position, state map[string]*tracked, now time.Time) (Position, money.Money, error) {
+	option, err := athenaxalpaca.ParseOptionTicker(position.Symbol)
+	if err != nil {
+		return Position{}, money.Zero, err
+	}
+	snapshot, err := m.broker.GetOptionSnapshot(ctx, position.Symbol)
+	if err != nil {
+		return Position{}, money.Zero, fmt.Errorf("failed to get snapshot of %s: %w", position.Symbol, err)
+	}
+
+	p := Position{
+		Symbol:     position.Symbol,
+		Qty:        position.Qty,
+		EntryPrice: money.New(position.AvgEntryPrice),
+		Expiry:     option.Expiry,
+	}
+	var bid money.Money
+	if quote := snapshot.LatestQuote; quote != nil {
+		bid = money.NewFromFloat(quote.BidPrice)
+		ask := money.NewFromFloat(quote.AskPrice)
+		if bid.IsPositive() && ask.IsPositive() {
+			p.Mark = bid.Add(ask).DivInt(2)
+		}
+	}
+	if p.Mark.IsZero() && position.CurrentPrice != nil {
+		p.Mark = money.FromPtr(position.CurrentPrice)
+	}
+	if p.Mark.IsZero() {
+		return Position{}, money.Zero, fmt.Errorf("no price available for %s", position.Symbol)
+	}
+	if snapshot.Greeks != nil {
+		p.Delta = snapshot.Greeks.Delta
+		p.HasDelta = true
+	}
+
+	t, ok := state[position.Symbol]
+	if !ok {
+		t = &tracked{FirstSeen: now}
+		state[position.Symbol] = t
+	}
+	t.HighWater = money.Max(t.HighWater, money.Max(p.EntryPrice, p.Mark))
+	p.HighWater = t.HighWater
+
+	p.OpenedAt = t.FirstSeen
+	if m.journal != nil {
+		openedAt, found, err := m.journal.OpenedAt(ctx, position.Symbol)
+		if err != nil {
+			log.Printf("Failed to look up when %s was opened, using when it was first seen: %v", position.Symbol, err)
+		} else if found {
+			p.OpenedAt = openedAt
+		}
+	}
+	return p, bid, nil
+}
+
+// exit sells the whole position at the bid, or at market when there is none. Open sell orders in the
+// symbol, such as bracket legs, are cancelled first so they don't hold the contracts; a closing order
+// the manager already has working is left to fill instead of being submitted again.
+func (m *Manager) exit(ctx context.Context, position alpaca.Position, bid money.Money, now time.Time) (*alpaca.Order, error) {
+	working, err := m.cancelOpenSells(ctx, position.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if working != nil {
+		log.Printf("Closing order %s for %s is already working", working.ID, position.Symbol)
+		return working, nil
+	}
+
+	clientOrderID, err := m.newClientOrderID(ctx, InstanceID, now, "exit-"+position.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	order, err := m.broker.PlaceOptionSellOrder(ctx, clientOrderID, position.Symbol, position.Qty, bid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place closing order for %s: %w", position.Symbol, err)
+	}
+	if order.Status != dryrun.Status {
+		m.dropExits(ctx, position.Symbol)
+	}
+	return order, nil
+}
+
+// cancelOpenSells cancels the open sell orders in symbol, such as bracket legs, so they don't hold its
+// contracts, and returns the manager's own closing order instead if one is working
+func (m *Manager) cancelOpenSells(ctx context.Context, symbol string) (*alpaca.Order, error) {
+	open, err := m.broker.GetOpenOrders(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders in %s: %w", symbol, err)
+	}
+	for _, order := range open {
+		if order.Side != alpaca.Sell {
+			continue
+		}
+		if instance, ok := athenaxalpaca.InstanceOfClientOrderID(order.ClientOrderID); ok && instance == InstanceID {
+			return &order, nil
+		}
+	}
+	for _, order := range open {
+		if order.Side != alpaca.Sell {
+			continue
+		}
+		if err := m.broker.CancelOrder(ctx, order.ID); err != nil {
+			return nil, fmt.Errorf("failed to cancel open sell order %s in %s: %w", order.ID, symbol, err)
+		}
+	}
+	return nil, nil
+}
+
+// dropExits stops the exit monitor from enforcing client-managed exits on a position being closed, so
+// they don't sell it a second time
+func (m *Manager) dropExits(ctx context.Context, symbol string) {
+	if m.exitMonitor == nil {
+		return
+	}
+	if err := m.exitMonitor.Drop(ctx, symbol); err != nil {
+		log.Printf("Failed to drop client-managed exits of %s: %v", symbol, err)
+	}
+}
+
+// newClientOrderID returns a client order ID of instanceID for signal that the broker hasn't seen yet.
+// IDs are deterministic per trading day, numbered after the first so that an order that expired or was
+// cancelled earlier in the day can be resubmitted.
+func (m *Manager) newClientOrderID(ctx context.Context, instanceID string, now time.Time, signal string) (string, error) {
+	base := athenaxalpaca.NewClientOrderID(instanceID, now, signal)
+	for attempt := 1; attempt <= maxOrderAttempts; attempt++ {
+		clientOrderID := base
+		if attempt > 1 {
+			clientOrderID = fmt.Sprintf("%s-%d", base, attempt)
+		}
+		existing, err := m.broker.GetOrderByClientOrderID(ctx, clientOrderID)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up order %s: %w", clientOrderID, err)
+		}
+		if existing == nil {
+			return clientOrderID, nil
+		}
+	}
+	return "", fmt.Errorf("%d orders for %s were already submitted today", maxOrderAttempts, base)
+}
+
+// record journals and notifies a decision. A journal failure is logged; the decision was already acted on.
+func (m *Manager) record(ctx context.Context, decision Decision) {
+	if m.journal != nil {
+		if err := m.journal.RecordPositionDecision(ctx, journal.PositionDecision(decision)); err != nil {
+			log.Printf("Failed to journal decision on %s: %v", decision.Symbol, err)
+		}
+	}
+
+	switch {
+	case decision.Action == ActionRoll && decision.Error != "":
+		log.Printf("Failed to roll %s: %s", decision.Symbol, decision.Error)
+		_ = m.notifier.ActionNeeded(fmt.Sprintf("[%s] Failed to roll %s (%s), check the positions with the broker: %s",
+			InstanceID, decision.Symbol, decision.Reason, decision.Error), errors.New(decision.Error))
+	case decision.Action == ActionRoll && decision.DryRun:
+		log.Printf("DRY RUN: would roll %s: %s", decision.Symbol, decision.Reason)
+		_ = m.notifier.DryRunOrder(fmt.Sprintf("[%s] Would roll %g x %s: %s", InstanceID, decision.Qty, decision.Symbol, decision.Reason))
+	case decision.Action == ActionRoll:
+		log.Printf("Rolled %s: %s", decision.Symbol, decision.Reason)
+		_ = m.notifier.PositionExit(fmt.Sprintf("[%s] Rolled %g x %s: %s", InstanceID, decision.Qty, decision.Symbol, decision.Reason))
+	case decision.Error != "" && decision.Action == ActionExit:
+		log.Printf("Failed to exit %s (%s): %s", decision.Symbol, decision.Rule, decision.Error)
+		_ = m.notifier.ActionNeeded(fmt.Sprintf("[%s] Failed to exit %s on %s (%s), check the position with the broker: %s",
+			InstanceID, decision.Symbol, decision.Rule, decision.Reason, decision.Error), errors.New(decision.Error))
+	case decision.Error != "":
+		log.Printf("Failed to evaluate %s: %s", decision.Symbol, decision.Error)
+		_ = m.notifier.Failure(fmt.Sprintf("[%s] Failed to evaluate %s: %s", InstanceID, decision.Symbol, decision.Error))
+	case decision.Action == ActionExit && decision.DryRun:
+		log.Printf("DRY RUN: would exit %s on %s: %s", decision.Symbol, decision.Rule, decision.Reason)
+		_ = m.notifier.DryRunOrder(fmt.Sprintf("[%s] Would sell %g x %s on %s: %s",
+			InstanceID, decision.Qty, decision.Symbol, decision.Rule, decision.Reason))
+	case decision.Action == ActionExit:
+		log.Printf("Exiting %s on %s: %s", decision.Symbol, decision.Rule, decision.Reason)
+		_ = m.notifier.PositionExit(fmt.Sprintf("[%s] Selling %g x %s on %s: %s (order %s)",
+			InstanceID, decision.Qty, decision.Symbol, decision.Rule, decision.Reason, decision.ExitOrderID))
+	default:
+		log.Printf("Holding %s: mark %s, high-water %s, %d days held, %d days to expiry",
+			decision.Symbol, decision.Mark, decision.HighWater, decision.DaysHeld, decision.DaysToExpiry)
+	}
+}
+
+// recordRoll journals a roll. A failure is logged; the roll already traded.
+func (m *Manager) recordRoll(ctx context.Context, roll *journal.Roll) {
+	if m.journal == nil {
+		return
+	}
+	if _, err := m.journal.RecordRoll(ctx, *roll); err != nil {
+		log.Printf("Failed to journal roll of %s into %s: %v", roll.CloseSymbol, roll.OpenSymbol, err)
+	}
+}
+
+func (m *Manager) load(ctx context.Context) (map[string]*tracked, error) {
+	state := make(map[string]*tracked)
+	data, err := m.store.Get(ctx, stateKey)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load position manager state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode position manager state: %w", err)
+	}
+	return state, nil
+}
+
+func (m *Manager) save(ctx context.Context, state map[string]*tracked) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode position manager state: %w", err)
+	}
+	if err := m.store.Put(ctx, stateKey, data); err != nil {
+		return fmt.Errorf("failed to save position manager state: %w", err)
+	}
+	return nil
+}
+
+// Exits returns the number of positions the run decided to sell
+func (r *Result) Exits() int {
+	return r.count(ActionExit)
+}
+
+// Rolls returns the number of positions the run rolled or tried to roll
+func (r *Result) Rolls() int {
+	return r.count(ActionRoll)
+}
+
+func (r *Result) count(action string) int {
+	count := 0
+	for _, decision := range r.Decisions {
+		if decision.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of positions that failed to be evaluated or sold
+func (r *Result) Failed() int {
+	count := 0
+	for _, decision := range r.Decisions {
+		if decision.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary describes the run in one line, e.g. "3 positions: 1 held, 1 exited, 1 rolled, 0 failed"
+func (r *Result) Summary() string {
+	summary := fmt.Sprintf("%d positions: %d held, %d exited, %d rolled, %d failed",
+		len(r.Decisions), r.count(ActionHold), r.Exits(), r.Rolls(), r.Failed())
+	if !r.MarketOpen {
+		summary += " (market closed)"
+	}
+	return summary
+}
+
+// WriteTable writes each decision on its own aligned row
+func (r *Result) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SYMBOL\tQTY\tENTRY\tMARK\tHIGH\tDELTA\tHELD\tDTE\tACTION\tREASON")
+	for _, d := range r.Decisions {
+		reason := d.Reason
+		if d.Error != "" {
+			reason += ": " + d.Error
+		}
+		action := d.Action
+		if d.DryRun {
+			action += " (dry run)"
+		}
+		fmt.Fprintf(tw, "%s\t%g\t%s\t%s\t%s\t%.2f\t%d\t%d\t%s\t%s\n",
+			d.Symbol, d.Qty, d.EntryPrice, d.Mark, d.HighWater, d.Delta, d.DaysHeld, d.DaysToExpiry, action, reason)
+	}
+	return tw.Flush()
+}
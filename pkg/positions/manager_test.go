@@ -0,0 +1,363 @@
+package positions_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/positions"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+const (
+	testInstance = "qqq-leaps"
+	// expiring is a QQQ call 17 days from expiry on testDay, bid at $5.00
+	expiring = "QQQ250321C00450000"
+	// leap is a QQQ LEAP with a 0.70 delta at $9.90/$10.00, the replacement a roll selects
+	leap = "QQQ260320C00450000"
+)
+
+var testDay = time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC)
+
+// newAccount returns a simulated broker during the session of testDay with expiring and leap on the
+// chain and $20,000 of buying power
+func newAccount(t *testing.T) *sim.Broker {
+	t.Helper()
+	b := sim.NewBroker(testDay)
+	b.SetCalendar(sim.RegularSession(testDay, time.UTC))
+	b.SetBuyingPower(money.NewFromInt(20000))
+	for symbol, snapshot := range map[string]marketdata.OptionSnapshot{
+		expiring: {LatestQuote: &marketdata.OptionQuote{BidPrice: 5.00, AskPrice: 5.10}, Greeks: &marketdata.OptionGreeks{Delta: 0.80}},
+		leap:     {LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, Greeks: &marketdata.OptionGreeks{Delta: 0.70}},
+	} {
+		if err := b.SetOption(symbol, snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b
+}
+
+// hold gives the account 2 contracts of symbol bought at entry
+func hold(b *sim.Broker, symbol string, entry money.Money) {
+	b.SetPosition(alpaca.Position{
+		Symbol:        symbol,
+		AssetClass:    "us_option",
+		Side:          "long",
+		Qty:           decimal.NewFromInt(2),
+		QtyAvailable:  decimal.NewFromInt(2),
+		AvgEntryPrice: entry.Decimal(),
+		CostBasis:     entry.MulInt(2 * money.ContractMultiplier).Decimal(),
+	})
+}
+
+// newJournal returns a journal recording that testInstance bought the 2 contracts of symbol held,
+// filled at openedAt
+func newJournal(t *testing.T, symbol string, openedAt time.Time) *journal.Journal {
+	t.Helper()
+	ctx := context.Background()
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = j.Close() })
+
+	if _, err := j.RecordRun(ctx, journal.Run{
+		StartedAt:  openedAt,
+		FinishedAt: openedAt,
+		MarketOpen: true,
+		Strategies: []journal.StrategyRun{{
+			InstanceID: testInstance,
+			Strategy:   "test",
+			Decision:   "ordered",
+			Outcome:    "ran",
+			Orders: []journal.Order{{
+				AlpacaOrderID: "opening",
+				ClientOrderID: athenaxalpaca.NewClientOrderID(testInstance, openedAt, "entry"),
+				Symbol:        symbol,
+				Side:          "buy",
+				Qty:           2,
+				LimitPrice:    money.NewFromInt(4),
+			}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	qty := decimal.NewFromInt(2)
+	if err := j.RecordOrderUpdate(ctx, &alpaca.Order{
+		ID:             "opening",
+		Symbol:         symbol,
+		Status:         "filled",
+		Qty:            &qty,
+		FilledQty:      qty,
+		FilledAvgPrice: money.NewFromInt(4).Ptr(),
+		FilledAt:       &openedAt,
+		UpdatedAt:      openedAt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+// decisions returns the journaled decisions of the position manager
+func decisions(t *testing.T, j *journal.Journal) []journal.PositionDecisionRecord {
+	t.Helper()
+	records, err := j.PositionDecisions(context.Background(), journal.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// exitClientOrderID is the client order ID of the manager's closing order for leap on testDay
+var exitClientOrderID = athenaxalpaca.NewClientOrderID(positions.InstanceID, testDay, "exit-"+leap)
+
+func TestExitRules(t *testing.T) {
+	// The position in leap was bought at $8.00 and filled 304 days before testDay, 381 days before it
+	// expires. It is marked at $9.95, a 24.375% gain, with a 0.70 delta.
+	tests := []struct {
+		name  string
+		rules positions.Rules
+		// peak is the bid and ask of an earlier run, setting the high-water mark
+		peak []float64
+		// rule is the rule expected to fire, empty to hold
+		rule string
+	}{
+		{
+			name: "every rule short of firing",
+			rules: positions.Rules{
+				MaxHoldDays:         305,
+				MinDaysToExpiry:     380,
+				TrailingStopPercent: 20,
+				MinDelta:            0.70,
+				ProfitLocks:         []positions.ProfitLock{{GainPercent: 50, LockPercent: 25}},
+			},
+		},
+		{name: "max hold days", rules: positions.Rules{MaxHoldDays: 304}, rule: positions.RuleMaxHold},
+		{name: "expiry floor", rules: positions.Rules{MinDaysToExpiry: 381}, rule: positions.RuleExpiryFloor},
+		{
+			name:  "expiry floor ahead of the other rules",
+			rules: positions.Rules{MinDaysToExpiry: 381, MaxHoldDays: 1, MinDelta: 0.75},
+			rule:  positions.RuleExpiryFloor,
+		},
+		{name: "delta decay", rules: positions.Rules{MinDelta: 0.75}, rule: positions.RuleDeltaDecay},
+		{
+			// 20% below the $12.45 high-water mark is $9.96
+			name:  "trailing stop from the high-water mark",
+			rules: positions.Rules{TrailingStopPercent: 20},
+			peak:  []float64{12.40, 12.50},
+			rule:  positions.RuleTrailingStop,
+		},
+		{
+			// 21% below the $12.45 high-water mark is $9.84
+			name:  "trailing stop above the mark",
+			rules: positions.Rules{TrailingStopPercent: 21},
+			peak:  []float64{12.40, 12.50},
+		},
+		{
+			// The $12.45 high-water mark is a 55.6% gain, locking in 25%
+			name:  "profit lock ladder",
+			rules: positions.Rules{ProfitLocks: []positions.ProfitLock{{GainPercent: 25, LockPercent: 10}, {GainPercent: 50, LockPercent: 25}}},
+			peak:  []float64{12.40, 12.50},
+			rule:  positions.RuleProfitLock,
+		},
+		{
+			// The $10.45 high-water mark is a 30.6% gain, locking in only 10%
+			name:  "profit lock ladder's lower rung",
+			rules: positions.Rules{ProfitLocks: []positions.ProfitLock{{GainPercent: 25, LockPercent: 10}, {GainPercent: 50, LockPercent: 25}}},
+			peak:  []float64{10.40, 10.50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			account := newAccount(t)
+			hold(account, leap, money.NewFromInt(8))
+			j := newJournal(t, leap, testDay.AddDate(0, -10, 0))
+			m := positions.NewManager(account, statestore.NewMemoryStore(), tt.rules, notification.NewNoopClient())
+			m.SetJournal(j)
+
+			if tt.peak != nil {
+				if err := account.SetOptionQuote(leap, tt.peak[0], tt.peak[1]); err != nil {
+					t.Fatal(err)
+				}
+				result, err := m.Run(ctx, []string{"QQQ"})
+				if err != nil {
+					t.Fatalf("Run() at the peak error = %v", err)
+				}
+				if decision := result.Decisions[0]; decision.Action != positions.ActionHold {
+					t.Fatalf("at the peak: %s (%s), want held", decision.Action, decision.Reason)
+				}
+				if err := account.SetOptionQuote(leap, 9.90, 10.00); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := m.Run(ctx, []string{"QQQ"})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if len(result.Decisions) != 1 {
+				t.Fatalf("%d decisions, want 1", len(result.Decisions))
+			}
+			decision := result.Decisions[0]
+			if decision.Rule != tt.rule {
+				t.Fatalf("Rule = %q (%s), want %q", decision.Rule, decision.Reason, tt.rule)
+			}
+			if decision.DaysHeld != 304 || decision.DaysToExpiry != 381 {
+				t.Errorf("held %d days with %d to expiry, want 304 and 381", decision.DaysHeld, decision.DaysToExpiry)
+			}
+
+			journaled := decisions(t, j)
+			last := journaled[len(journaled)-1]
+			if last.Action != decision.Action || last.Rule != tt.rule || last.ExitOrderID != decision.ExitOrderID {
+				t.Errorf("journaled %s %q with order %q, want %s %q with order %q",
+					last.Action, last.Rule, last.ExitOrderID, decision.Action, tt.rule, decision.ExitOrderID)
+			}
+
+			orders := account.Orders()
+			if tt.rule == "" {
+				if decision.Action != positions.ActionHold || len(orders) != 0 {
+					t.Errorf("%s with %d orders, want held without orders", decision.Action, len(orders))
+				}
+				return
+			}
+			if decision.Action != positions.ActionExit || len(orders) != 1 {
+				t.Fatalf("%s with %d orders, want one exit", decision.Action, len(orders))
+			}
+			exit := orders[0]
+			if exit.ID != decision.ExitOrderID || exit.Side != alpaca.Sell || exit.Symbol != leap || exit.ClientOrderID != exitClientOrderID {
+				t.Errorf("exit order = %s %s %s (%s), want %s selling %s as %s",
+					exit.ID, exit.Side, exit.Symbol, exit.ClientOrderID, decision.ExitOrderID, leap, exitClientOrderID)
+			}
+			if !exit.Qty.Equal(decimal.NewFromInt(2)) || !money.FromPtr(exit.LimitPrice).Equal(money.Cents(990)) {
+				t.Errorf("exit order for %s @ %s, want 2 @ the 9.90 bid", exit.Qty, money.FromPtr(exit.LimitPrice))
+			}
+			if n := len(account.Positions()); n != 0 {
+				t.Errorf("%d positions left, want the position sold", n)
+			}
+		})
+	}
+}
+
+func TestExitCancelsBeforeSelling(t *testing.T) {
+	entryClientOrderID := athenaxalpaca.NewClientOrderID(testInstance, testDay, "entry")
+	quote := &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}
+
+	tests := []struct {
+		name string
+		// setup opens the position and its exits, returning the monitor enforcing client-managed exits
+		setup func(t *testing.T, b *sim.Broker) *bracket.Monitor
+		// sold reports whether the manager places a new closing order
+		sold bool
+	}{
+		{
+			name: "bracket legs",
+			setup: func(t *testing.T, b *sim.Broker) *bracket.Monitor {
+				exits := bracket.Exits{TakeProfitPercent: 50, StopLoss: bracket.StopLoss{Kind: bracket.StopPercent, Percent: 50}}
+				if _, _, err := b.PlaceOptionBracketOrder(context.Background(), entryClientOrderID, money.NewFromInt(2000), leap,
+					quote, pricing.PercentOfAsk(100), exits); err != nil {
+					t.Fatal(err)
+				}
+				return nil
+			},
+			sold: true,
+		},
+		{
+			name: "client-managed exits",
+			setup: func(t *testing.T, b *sim.Broker) *bracket.Monitor {
+				ctx := context.Background()
+				b.RejectBracketOrders(true)
+				exits := bracket.Exits{TakeProfitPercent: 50, StopLoss: bracket.StopLoss{Kind: bracket.StopUnderlying, UnderlyingPrice: money.NewFromInt(400)}}
+				_, oco, err := b.PlaceOptionBracketOrder(ctx, entryClientOrderID, money.NewFromInt(2000), leap, quote, pricing.PercentOfAsk(100), exits)
+				if err != nil {
+					t.Fatal(err)
+				}
+				monitor := bracket.NewMonitor(b, statestore.NewMemoryStore())
+				if err := monitor.Add(ctx, *oco); err != nil {
+					t.Fatal(err)
+				}
+				return monitor
+			},
+			sold: true,
+		},
+		{
+			name: "closing order already working",
+			setup: func(t *testing.T, b *sim.Broker) *bracket.Monitor {
+				hold(b, leap, money.NewFromInt(8))
+				if _, err := b.PlaceOptionSellOrder(context.Background(), exitClientOrderID, leap, decimal.NewFromInt(2), money.NewFromInt(12)); err != nil {
+					t.Fatal(err)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			account := newAccount(t)
+			monitor := tt.setup(t, account)
+			before := account.Orders()
+
+			m := positions.NewManager(account, statestore.NewMemoryStore(), positions.Rules{MinDaysToExpiry: 381}, notification.NewNoopClient())
+			if monitor != nil {
+				m.SetExitMonitor(monitor)
+			}
+			result, err := m.Run(ctx, []string{"QQQ"})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			decision := result.Decisions[0]
+			if decision.Action != positions.ActionExit || decision.Error != "" {
+				t.Fatalf("%s (%s), want an exit", decision.Action, decision.Error)
+			}
+
+			orders := account.Orders()
+			if !tt.sold {
+				if len(orders) != len(before) || decision.ExitOrderID != before[len(before)-1].ID {
+					t.Errorf("exit order %s with %d new orders, want the working order %s kept",
+						decision.ExitOrderID, len(orders)-len(before), before[len(before)-1].ID)
+				}
+				return
+			}
+
+			if len(orders) != len(before)+1 {
+				t.Fatalf("%d new orders, want one closing order", len(orders)-len(before))
+			}
+			exit := orders[len(orders)-1]
+			if exit.ID != decision.ExitOrderID || exit.ClientOrderID != exitClientOrderID || exit.Status != "filled" {
+				t.Errorf("closing order = %s (%s) %s, want %s filled", exit.ID, exit.ClientOrderID, exit.Status, exitClientOrderID)
+			}
+			// The exits resting on the position were cancelled before it was sold
+			for _, leg := range orders[0].Legs {
+				if leg.Status != "canceled" {
+					t.Errorf("%s leg %s is %s, want canceled", leg.Type, leg.ID, leg.Status)
+				}
+			}
+			if monitor != nil {
+				brackets, err := monitor.Brackets(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(brackets) != 0 {
+					t.Errorf("monitor still enforces %d brackets, want them dropped", len(brackets))
+				}
+			}
+			if n := len(account.Positions()); n != 0 {
+				t.Errorf("%d positions left, want the position sold", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package positions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+)
+
+// Roll methods
+const (
+	// RollMultiLeg closes and opens in one multi-leg order that fills completely or not at all
+	RollMultiLeg = "multi-leg"
+	// RollSequenced closes first and opens with the proceeds, buying the closed contracts back if the open fails
+	RollSequenced = "sequenced"
+)
+
+// Roll statuses
+const (
+	RollRolled = "rolled"
+	// RollRolledBack means the open failed and the closed contracts were bought back
+	RollRolledBack = "rolled-back"
+	// RollFailed means the roll stopped part way and the positions need checking with the broker
+	RollFailed = "failed"
+)
+
+// Defaults of the roll settings left unset
+const (
+	DefaultRollMinDelta        = 0.60
+	DefaultRollMinExpiryMonths = 11
+	DefaultRollFillTimeout     = 2 * time.Minute
+)
+
+// RollConfig configures rolling calls into a later expiry before they age into short-dated options
+type RollConfig struct {
+	// DaysToExpiry rolls calls this many calendar days or fewer before expiry; 0 disables rolling
+	DaysToExpiry int
+	// MinDelta and MinExpiryMonths select the replacement like GetCallLeapsByDelta
+	MinDelta        float64
+	MinExpiryMonths int
+	// FillTimeout is how long each order of a roll may rest before it is cancelled
+	FillTimeout time.Duration
+}
+
+// Enabled reports whether calls are rolled
+func (c RollConfig) Enabled() bool {
+	return c.DaysToExpiry > 0
+}
+
+// Validate checks that the roll settings are usable
+func (c RollConfig) Validate() error {
+	if c.DaysToExpiry < 0 {
+		return fmt.Errorf("roll days_to_expiry must not be negative, got %d", c.DaysToExpiry)
+	}
+	if !c.Enabled() {
+		return nil
+	}
+	if c.MinDelta <= 0 || c.MinDelta > 1 {
+		return fmt.Errorf("roll min_delta must be in (0, 1], got %.2f", c.MinDelta)
+	}
+	if c.MinExpiryMonths <= 0 {
+		return fmt.Errorf("roll min_expiry_months must be greater than 0, got %d", c.MinExpiryMonths)
+	}
+	// A replacement inside the threshold would be rolled again on the next run
+	if c.MinExpiryMonths*31 <= c.DaysToExpiry {
+		return fmt.Errorf("roll min_expiry_months (%d) must reach past days_to_expiry (%d)", c.MinExpiryMonths, c.DaysToExpiry)
+	}
+	if c.FillTimeout <= 0 {
+		return fmt.Errorf("roll fill_timeout must be greater than 0, got %s", c.FillTimeout)
+	}
+	return nil
+}
+
+// Due reports whether the position is close enough to expiry to roll at now
+func (c RollConfig) Due(p Position, now time.Time) bool {
+	return c.Enabled() && p.DaysToExpiry(now) <= c.DaysToExpiry
+}
+
+// rollPosition rolls a call into the LEAP selected for its underlying, as one multi-leg order when the
+// broker supports it and as a close followed by an open otherwise. An error means nothing was traded
+// and the position is as it was; once an order trades, the outcome is in the returned roll's status.
+func (m *Manager) rollPosition(ctx context.Context, underlying string, position alpaca.Position, bid money.Money, now time.Time) (*journal.Roll, error) {
+	current, err := athenaxalpaca.ParseOptionTicker(position.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if current.Type != "C" {
+		return nil, fmt.Errorf("%s is not a call", position.Symbol)
+	}
+	if !bid.IsPositive() {
+		return nil, fmt.Errorf("no bid for %s", position.Symbol)
+	}
+
+	symbol, snapshot, err := m.broker.GetCallLeapsByDelta(ctx, underlying, m.roll.MinDelta, m.roll.MinExpiryMonths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a replacement: %w", err)
+	}
+	replacement, err := athenaxalpaca.ParseOptionTicker(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if !replacement.Expiry.After(current.Expiry) {
+		return nil, fmt.Errorf("replacement %s doesn't expire after %s", symbol, position.Symbol)
+	}
+	if snapshot.LatestQuote == nil || snapshot.LatestQuote.AskPrice <= 0 {
+		return nil, fmt.Errorf("no ask for replacement %s", symbol)
+	}
+	ask := money.NewFromFloat(snapshot.LatestQuote.AskPrice)
+
+	// The close funds the open, so only the net debit needs to be available
+	buyingPower, err := m.broker.GetNonMarginableBuyingPower(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get buying power: %w", err)
+	}
+	netDebit := ask.Sub(bid)
+	if cost := netDebit.Mul(position.Qty).MulInt(money.ContractMultiplier); cost.GreaterThan(buyingPower) {
+		return nil, fmt.Errorf("rolling into %s costs %s net, more than the buying power of %s", symbol, cost, buyingPower)
+	}
+
+	working, err := m.cancelOpenSells(ctx, position.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if working != nil {
+		return nil, fmt.Errorf("closing order %s is already working", working.ID)
+	}
+
+	roll := &journal.Roll{
+		At:          now,
+		InstanceID:  m.instanceOf(ctx, position.Symbol),
+		Underlying:  underlying,
+		Qty:         position.Qty.InexactFloat64(),
+		CloseSymbol: position.Symbol,
+		ClosePrice:  bid,
+		OpenSymbol:  symbol,
+		OpenPrice:   ask,
+	}
+	if multiLeg, err := broker.MultiLeg(m.broker); err == nil {
+		rolled, err := m.rollMultiLeg(ctx, multiLeg, roll, position.Qty, netDebit, now)
+		// A broker beneath a wrapper may still turn out not to support the order, which then never traded
+		if !errors.Is(err, broker.ErrMultiLegUnsupported) {
+			return rolled, err
+		}
+		log.Printf("Rolling %s with a close and an open: %v", position.Symbol, err)
+	}
+	return m.rollSequenced(ctx, roll, position.Qty, bid, ask, now)
+}
+
+// rollMultiLeg rolls in one order, which trades both legs or neither
+func (m *Manager) rollMultiLeg(ctx context.Context, multiLeg broker.MultiLegBroker, roll *journal.Roll, qty decimal.Decimal, netDebit money.Money, now time.Time) (*journal.Roll, error) {
+	roll.Method = RollMultiLeg
+	clientOrderID, err := m.newClientOrderID(ctx, roll.InstanceID, now, "roll-"+roll.CloseSymbol)
+	if err != nil {
+		return nil, err
+	}
+	order, err := multiLeg.PlaceOptionRollOrder(ctx, clientOrderID, roll.CloseSymbol, roll.OpenSymbol, qty, netDebit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place roll order: %w", err)
+	}
+	roll.CloseOrderID = order.ID
+	roll.OpenOrderID = order.ID
+	roll.DryRun = order.Status == dryrun.Status
+
+	order, err = m.await(ctx, order)
+	if err != nil {
+		roll.Status = RollFailed
+		roll.Error = fmt.Sprintf("failed to follow roll order %s: %v", order.ID, err)
+		return roll, nil
+	}
+	if !isFilled(order) {
+		return nil, fmt.Errorf("roll order %s ended %s without filling", order.ID, order.Status)
+	}
+
+	roll.Status = RollRolled
+	roll.ClosedQty = qty.InexactFloat64()
+	roll.OpenedQty = qty.InexactFloat64()
+	for _, leg := range order.Legs {
+		switch leg.Symbol {
+		case roll.CloseSymbol:
+			roll.ClosePrice = fillPrice(&leg, roll.ClosePrice)
+		case roll.OpenSymbol:
+			roll.OpenPrice = fillPrice(&leg, roll.OpenPrice)
+		}
+	}
+	m.dropExits(ctx, roll.CloseSymbol)
+	return roll, nil
+}
+
+// rollSequenced sells the position at the bid, then buys as many replacement contracts as were sold at
+// the ask. Contracts the open fails to replace are bought back at the ask.
+func (m *Manager) rollSequenced(ctx context.Context, roll *journal.Roll, qty decimal.Decimal, bid, ask money.Money, now time.Time) (*journal.Roll, error) {
+	roll.Method = RollSequenced
+	clientOrderID, err := m.newClientOrderID(ctx, roll.InstanceID, now, "roll-close-"+roll.CloseSymbol)
+	if err != nil {
+		return nil, err
+	}
+	closeOrder, err := m.broker.PlaceOptionSellOrder(ctx, clientOrderID, roll.CloseSymbol, qty, bid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place closing order: %w", err)
+	}
+	roll.CloseOrderID = closeOrder.ID
+	roll.DryRun = closeOrder.Status == dryrun.Status
+
+	closeOrder, err = m.await(ctx, closeOrder)
+	if err != nil {
+		roll.Status = RollFailed
+		roll.Error = fmt.Sprintf("failed to follow closing order %s: %v", closeOrder.ID, err)
+		return roll, nil
+	}
+	closed := filledQty(closeOrder)
+	if !closed.IsPositive() {
+		return nil, fmt.Errorf("closing order %s ended %s without a fill", closeOrder.ID, closeOrder.Status)
+	}
+	roll.ClosedQty = closed.InexactFloat64()
+	roll.ClosePrice = fillPrice(closeOrder, bid)
+	m.dropExits(ctx, roll.CloseSymbol)
+
+	opened := decimal.Zero
+	clientOrderID, err = m.newClientOrderID(ctx, roll.InstanceID, now, "roll-open-"+roll.OpenSymbol)
+	var openOrder *alpaca.Order
+	if err == nil {
+		openOrder, err = m.broker.PlaceOptionBuyOrder(ctx, clientOrderID, roll.OpenSymbol, closed, ask)
+	}
+	if err == nil {
+		roll.OpenOrderID = openOrder.ID
+		openOrder, err = m.await(ctx, openOrder)
+		opened = filledQty(openOrder)
+		roll.OpenedQty = opened.InexactFloat64()
+		roll.OpenPrice = fillPrice(openOrder, ask)
+	}
+	if err == nil && opened.Equal(closed) {
+		roll.Status = RollRolled
+		return roll, nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("opening order %s ended %s with %s of %s contracts filled", openOrder.ID, openOrder.Status, opened, closed)
+	}
+	roll.Error = fmt.Sprintf("failed to open %s: %v", roll.OpenSymbol, err)
+	rollbackOrderID, err := m.rollBack(ctx, roll, closed.Sub(opened), now)
+	roll.RollbackOrderID = rollbackOrderID
+	if err != nil {
+		roll.Status = RollFailed
+		roll.Error += fmt.Sprintf("; failed to buy back %s: %v", roll.CloseSymbol, err)
+		return roll, nil
+	}
+	roll.Status = RollRolledBack
+	return roll, nil
+}
+
+// rollBack buys back qty contracts of the closed position at the ask, returning the order's ID
+func (m *Manager) rollBack(ctx context.Context, roll *journal.Roll, qty decimal.Decimal, now time.Time) (string, error) {
+	snapshot, err := m.broker.GetOptionSnapshot(ctx, roll.CloseSymbol)
+	if err != nil {
+		return "", fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if snapshot.LatestQuote == nil || snapshot.LatestQuote.AskPrice <= 0 {
+		return "", fmt.Errorf("no ask")
+	}
+	clientOrderID, err := m.newClientOrderID(ctx, roll.InstanceID, now, "roll-undo-"+roll.CloseSymbol)
+	if err != nil {
+		return "", err
+	}
+	// The buy back restores the position, so the risk limits that may have refused the open don't apply to it
+	order, err := m.broker.PlaceOptionBuyOrder(risk.Exempt(ctx), clientOrderID, roll.CloseSymbol, qty, money.NewFromFloat(snapshot.LatestQuote.AskPrice))
+	if err != nil {
+		return "", err
+	}
+	order, err = m.await(ctx, order)
+	if err != nil {
+		return order.ID, err
+	}
+	if bought := filledQty(order); !bought.Equal(qty) {
+		return order.ID, fmt.Errorf("order %s ended %s with %s of %s contracts filled", order.ID, order.Status, bought, qty)
+	}
+	log.Printf("Bought back %s x %s after the roll into %s failed", qty, roll.CloseSymbol, roll.OpenSymbol)
+	return order.ID, nil
+}
+
+// instanceOf returns the strategy instance the journal attributes symbol to, so that the orders rolling
+// it stay attributed to that instance, or the position manager's own ID when it has none
+func (m *Manager) instanceOf(ctx context.Context, symbol string) string {
+	if m.journal == nil {
+		return InstanceID
+	}
+	instanceID, ok, err := m.journal.InstanceOf(ctx, symbol)
+	if err != nil {
+		log.Printf("Failed to look up the instance holding %s: %v", symbol, err)
+	}
+	if !ok || athenaxalpaca.ValidateInstanceID(instanceID) != nil {
+		return InstanceID
+	}
+	return instanceID
+}
+
+// await follows an order until it fills or is cancelled on timeout; dry-run orders are returned as is
+func (m *Manager) await(ctx context.Context, order *alpaca.Order) (*alpaca.Order, error) {
+	if order.Status == dryrun.Status {
+		return order, nil
+	}
+	final, _, err := m.tracker.Track(ctx, order.ID, nil)
+	if final == nil {
+		final = order
+	}
+	return final, err
+}
+
+// filledQty returns the contracts an order traded, taking a dry-run order as completely filled
+func filledQty(order *alpaca.Order) decimal.Decimal {
+	if order.Status == dryrun.Status && order.Qty != nil {
+		return *order.Qty
+	}
+	return order.FilledQty
+}
+
+// fillPrice returns an order's average fill price, or fallback before it has one
+func fillPrice(order *alpaca.Order, fallback money.Money) money.Money {
+	if order.FilledAvgPrice == nil {
+		return fallback
+	}
+	return money.FromPtr(order.FilledAvgPrice)
+}
+
+func isFilled(order *alpaca.Order) bool {
+	return order.Status == "filled" || order.Status == dryrun.Status
+}
@@ -0,0 +1,206 @@
+package positions_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/halt"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/positions"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// noMultiLeg hides the multi-leg support of the broker it wraps
+type noMultiLeg struct {
+	broker.Broker
+}
+
+// held returns the contracts of each position in the account
+func held(b *sim.Broker) map[string]int64 {
+	qty := make(map[string]int64)
+	for _, position := range b.Positions() {
+		qty[position.Symbol] = position.Qty.IntPart()
+	}
+	return qty
+}
+
+func TestRoll(t *testing.T) {
+	rollConfig := positions.RollConfig{DaysToExpiry: 30, MinDelta: 0.60, MinExpiryMonths: 11, FillTimeout: time.Second}
+
+	tests := []struct {
+		name string
+		// wrap builds the broker the manager trades through
+		wrap   func(b *sim.Broker) broker.Broker
+		method string
+		// status is the journaled roll's status, empty when the position isn't rolled
+		status string
+		// orders describes the orders placed, as side and symbol, with "roll" for a multi-leg order
+		orders []string
+		held   map[string]int64
+		dryRun bool
+	}{
+		{
+			name:   "multi-leg",
+			wrap:   func(b *sim.Broker) broker.Broker { return b },
+			method: positions.RollMultiLeg,
+			status: positions.RollRolled,
+			orders: []string{"roll"},
+			held:   map[string]int64{leap: 2},
+		},
+		{
+			name: "multi-leg through the risk manager and halt switch",
+			wrap: func(b *sim.Broker) broker.Broker {
+				store := statestore.NewMemoryStore()
+				limits := risk.Limits{MaxTradeNotional: money.NewFromInt(2000)}
+				return halt.NewBroker(risk.NewBroker(b, limits, store), halt.NewSwitch(store, 3))
+			},
+			method: positions.RollMultiLeg,
+			status: positions.RollRolled,
+			orders: []string{"roll"},
+			held:   map[string]int64{leap: 2},
+		},
+		{
+			name: "multi-leg refused by the risk manager",
+			wrap: func(b *sim.Broker) broker.Broker {
+				return risk.NewBroker(b, risk.Limits{MaxTradeNotional: money.NewFromInt(1000)}, statestore.NewMemoryStore())
+			},
+			held: map[string]int64{expiring: 2},
+		},
+		{
+			name:   "dry run",
+			wrap:   func(b *sim.Broker) broker.Broker { return dryrun.NewBroker(b) },
+			method: positions.RollMultiLeg,
+			status: positions.RollRolled,
+			held:   map[string]int64{expiring: 2},
+			dryRun: true,
+		},
+		{
+			name: "sequenced without multi-leg support",
+			wrap: func(b *sim.Broker) broker.Broker {
+				return halt.NewBroker(noMultiLeg{b}, halt.NewSwitch(statestore.NewMemoryStore(), 3))
+			},
+			method: positions.RollSequenced,
+			status: positions.RollRolled,
+			orders: []string{"sell " + expiring, "buy " + leap},
+			held:   map[string]int64{leap: 2},
+		},
+		{
+			name: "sequenced open refused and rolled back",
+			wrap: func(b *sim.Broker) broker.Broker {
+				return risk.NewBroker(noMultiLeg{b}, risk.Limits{MaxTradeNotional: money.NewFromInt(1500)}, statestore.NewMemoryStore())
+			},
+			method: positions.RollSequenced,
+			status: positions.RollRolledBack,
+			orders: []string{"sell " + expiring, "buy " + expiring},
+			held:   map[string]int64{expiring: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			account := newAccount(t)
+			hold(account, expiring, money.NewFromInt(4))
+			j := newJournal(t, expiring, testDay.AddDate(0, -10, 0))
+			m := positions.NewManager(tt.wrap(account), statestore.NewMemoryStore(), positions.Rules{}, notification.NewNoopClient())
+			m.SetJournal(j)
+			m.SetRoll(rollConfig)
+
+			result, err := m.Run(ctx, []string{"QQQ"})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if len(result.Decisions) != 1 {
+				t.Fatalf("%d decisions, want 1", len(result.Decisions))
+			}
+			decision := result.Decisions[0]
+
+			placed := account.Orders()
+			var orders []string
+			byID := make(map[string]alpaca.Order)
+			for _, order := range placed {
+				byID[order.ID] = order
+				if order.OrderClass == broker.OrderClassMultiLeg {
+					orders = append(orders, "roll")
+				} else {
+					orders = append(orders, string(order.Side)+" "+order.Symbol)
+				}
+				if instance, _ := athenaxalpaca.InstanceOfClientOrderID(order.ClientOrderID); instance != testInstance {
+					t.Errorf("order %s placed as %q, want attributed to %s", order.ClientOrderID, instance, testInstance)
+				}
+			}
+			if strings.Join(orders, ", ") != strings.Join(tt.orders, ", ") {
+				t.Errorf("orders = %v, want %v", orders, tt.orders)
+			}
+			if got := held(account); len(got) != len(tt.held) || got[expiring] != tt.held[expiring] || got[leap] != tt.held[leap] {
+				t.Errorf("positions = %v, want %v", got, tt.held)
+			}
+
+			rolls, err := j.Rolls(ctx, journal.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.status == "" {
+				if decision.Action != positions.ActionHold || !strings.Contains(decision.Reason, "roll skipped") || len(rolls) != 0 {
+					t.Errorf("decision = %s (%s) with %d rolls journaled, want held with the roll skipped", decision.Action, decision.Reason, len(rolls))
+				}
+				return
+			}
+
+			if decision.Action != positions.ActionRoll || decision.DryRun != tt.dryRun {
+				t.Errorf("decision = %s (dry run %v), want %s (dry run %v)", decision.Action, decision.DryRun, positions.ActionRoll, tt.dryRun)
+			}
+			if len(rolls) != 1 {
+				t.Fatalf("%d rolls journaled, want 1", len(rolls))
+			}
+			roll := rolls[0]
+			if roll.Method != tt.method || roll.Status != tt.status || roll.DryRun != tt.dryRun {
+				t.Errorf("roll = %s %s (dry run %v), want %s %s (dry run %v)", roll.Method, roll.Status, roll.DryRun, tt.method, tt.status, tt.dryRun)
+			}
+			if roll.InstanceID != testInstance || roll.CloseSymbol != expiring || roll.OpenSymbol != leap {
+				t.Errorf("roll of %s: %s into %s, want %s: %s into %s", roll.InstanceID, roll.CloseSymbol, roll.OpenSymbol, testInstance, expiring, leap)
+			}
+			journaled := decisions(t, j)
+			if len(journaled) != 1 || journaled[0].Action != positions.ActionRoll || journaled[0].ExitOrderID != roll.CloseOrderID {
+				t.Errorf("journaled decisions = %+v, want the roll of order %s", journaled, roll.CloseOrderID)
+			}
+			if tt.dryRun {
+				return
+			}
+
+			// The journaled order IDs link the roll to the orders placed
+			if closeOrder := byID[roll.CloseOrderID]; closeOrder.Side != alpaca.Sell && closeOrder.OrderClass != broker.OrderClassMultiLeg {
+				t.Errorf("close order %s = %+v, want the order selling %s", roll.CloseOrderID, closeOrder, expiring)
+			}
+			switch tt.status {
+			case positions.RollRolled:
+				open := byID[roll.OpenOrderID]
+				if open.OrderClass != broker.OrderClassMultiLeg && (open.Side != alpaca.Buy || open.Symbol != leap) {
+					t.Errorf("open order %s = %+v, want the order buying %s", roll.OpenOrderID, open, leap)
+				}
+				if roll.ClosedQty != 2 || roll.OpenedQty != 2 || !roll.ClosePrice.Equal(money.NewFromInt(5)) || !roll.OpenPrice.Equal(money.NewFromInt(10)) {
+					t.Errorf("roll closed %g @ %s and opened %g @ %s, want 2 @ 5.00 and 2 @ 10.00",
+						roll.ClosedQty, roll.ClosePrice, roll.OpenedQty, roll.OpenPrice)
+				}
+			case positions.RollRolledBack:
+				if roll.OpenOrderID != "" || roll.OpenedQty != 0 || roll.Error == "" {
+					t.Errorf("roll opened %g with order %q (%s), want nothing opened and the error kept", roll.OpenedQty, roll.OpenOrderID, roll.Error)
+				}
+				if undo := byID[roll.RollbackOrderID]; undo.Side != alpaca.Buy || undo.Symbol != expiring || !undo.FilledQty.Equal(decimal.NewFromInt(2)) {
+					t.Errorf("rollback order %s = %+v, want 2 contracts of %s bought back", roll.RollbackOrderID, undo, expiring)
+				}
+			}
+		})
+	}
+}
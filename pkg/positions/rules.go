@@ -0,0 +1,161 @@
+package positions
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// Exit rules, recorded with the decisions they trigger
+const (
+	// RuleExpiryFloor sells a position once it is within MinDaysToExpiry of expiring
+	RuleExpiryFloor = "expiry-floor"
+	// RuleMaxHold sells a position held for MaxHoldDays
+	RuleMaxHold = "max-hold"
+	// RuleDeltaDecay sells a position whose delta fell below MinDelta
+	RuleDeltaDecay = "delta-decay"
+	// RuleProfitLock sells a position whose gain fell back to the floor locked by its ladder
+	RuleProfitLock = "profit-lock"
+	// RuleTrailingStop sells a position that fell TrailingStopPercent below its high-water mark
+	RuleTrailingStop = "trailing-stop"
+)
+
+// ProfitLock is a rung of a profit-lock ladder: once a position's gain has reached GainPercent, it is
+// sold if the gain falls back to LockPercent
+type ProfitLock struct {
+	GainPercent float64 `yaml:"gain_percent" json:"gain_percent"`
+	LockPercent float64 `yaml:"lock_percent" json:"lock_percent"`
+}
+
+// Rules are the exit rules applied to open positions; the zero value of each disables it
+type Rules struct {
+	// MaxHoldDays is the number of calendar days after which a position is sold
+	MaxHoldDays int
+	// MinDaysToExpiry is the number of calendar days before expiry at which a position is sold,
+	// e.g. 90 to sell three months out
+	MinDaysToExpiry int
+	// TrailingStopPercent is the decline from the high-water mark at which a position is sold
+	TrailingStopPercent float64
+	// MinDelta is the delta below which a position is sold
+	MinDelta float64
+	// ProfitLocks is the profit-lock ladder; the highest rung reached applies
+	ProfitLocks []ProfitLock
+}
+
+// Validate checks that the rules are usable
+func (r Rules) Validate() error {
+	if r.MaxHoldDays < 0 {
+		return fmt.Errorf("max_hold_days must not be negative, got %d", r.MaxHoldDays)
+	}
+	if r.MinDaysToExpiry < 0 {
+		return fmt.Errorf("min_days_to_expiry must not be negative, got %d", r.MinDaysToExpiry)
+	}
+	if r.TrailingStopPercent < 0 || r.TrailingStopPercent >= 100 {
+		return fmt.Errorf("trailing_stop_percent must be in [0, 100), got %.2f", r.TrailingStopPercent)
+	}
+	if r.MinDelta < 0 || r.MinDelta > 1 {
+		return fmt.Errorf("min_delta must be in [0, 1], got %.2f", r.MinDelta)
+	}
+	for i, lock := range r.ProfitLocks {
+		if lock.GainPercent <= 0 {
+			return fmt.Errorf("profit_locks[%d].gain_percent must be greater than 0, got %.2f", i, lock.GainPercent)
+		}
+		if lock.LockPercent >= lock.GainPercent {
+			return fmt.Errorf("profit_locks[%d].lock_percent must be below its gain_percent %.2f, got %.2f", i, lock.GainPercent, lock.LockPercent)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether any rule is set
+func (r Rules) Enabled() bool {
+	return r.MaxHoldDays > 0 || r.MinDaysToExpiry > 0 || r.TrailingStopPercent > 0 || r.MinDelta > 0 || len(r.ProfitLocks) > 0
+}
+
+// Position is what the exit rules see of an open option position
+type Position struct {
+	Symbol     string
+	Qty        decimal.Decimal
+	EntryPrice money.Money
+	// Mark is the current value of one contract's option price
+	Mark money.Money
+	// HighWater is the highest mark seen since the position was opened
+	HighWater money.Money
+	// Delta is the option's delta, when HasDelta
+	Delta    float64
+	HasDelta bool
+	Expiry   time.Time
+	OpenedAt time.Time
+}
+
+// GainPercent returns the position's gain on its entry price at its mark
+func (p Position) GainPercent() float64 {
+	return p.EntryPrice.PercentChange(p.Mark)
+}
+
+// DaysHeld returns the number of calendar days the position has been open at now
+func (p Position) DaysHeld(now time.Time) int {
+	return calendarDays(p.OpenedAt, now)
+}
+
+// DaysToExpiry returns the number of calendar days until the option expires, from now
+func (p Position) DaysToExpiry(now time.Time) int {
+	return calendarDays(now, p.Expiry)
+}
+
+// Evaluate returns the first rule calling for the position to be sold at now and why, or an empty
+// rule to keep holding it
+func (r Rules) Evaluate(p Position, now time.Time) (string, string) {
+	if r.MinDaysToExpiry > 0 {
+		if days := p.DaysToExpiry(now); days <= r.MinDaysToExpiry {
+			return RuleExpiryFloor, fmt.Sprintf("%d days to expiry, at or below the floor of %d", days, r.MinDaysToExpiry)
+		}
+	}
+	if r.MaxHoldDays > 0 && !p.OpenedAt.IsZero() {
+		if days := p.DaysHeld(now); days >= r.MaxHoldDays {
+			return RuleMaxHold, fmt.Sprintf("held %d days, at or past the limit of %d", days, r.MaxHoldDays)
+		}
+	}
+	if r.MinDelta > 0 && p.HasDelta && p.Delta < r.MinDelta {
+		return RuleDeltaDecay, fmt.Sprintf("delta %.2f decayed below %.2f", p.Delta, r.MinDelta)
+	}
+	if lock, ok := r.lockedIn(p); ok {
+		if gain := p.GainPercent(); gain <= lock.LockPercent {
+			return RuleProfitLock, fmt.Sprintf("gain %.2f%% fell back to the %.2f%% locked in after reaching %.2f%%",
+				gain, lock.LockPercent, lock.GainPercent)
+		}
+	}
+	if r.TrailingStopPercent > 0 && p.HighWater.IsPositive() {
+		stop := p.HighWater.Sub(p.HighWater.Percent(r.TrailingStopPercent))
+		if p.Mark.LessThanOrEqual(stop) {
+			return RuleTrailingStop, fmt.Sprintf("mark %s fell %.2f%% or more below the high-water mark %s",
+				p.Mark, r.TrailingStopPercent, p.HighWater)
+		}
+	}
+	return "", ""
+}
+
+// lockedIn returns the highest rung of the profit-lock ladder the position's high-water mark reached
+func (r Rules) lockedIn(p Position) (ProfitLock, bool) {
+	peak := p.EntryPrice.PercentChange(p.HighWater)
+	ladder := append([]ProfitLock(nil), r.ProfitLocks...)
+	sort.Slice(ladder, func(i, j int) bool { return ladder[i].GainPercent > ladder[j].GainPercent })
+	for _, lock := range ladder {
+		if peak >= lock.GainPercent {
+			return lock, true
+		}
+	}
+	return ProfitLock{}, false
+}
+
+// calendarDays returns the number of calendar days from from to to, ignoring the time of day
+func calendarDays(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	// Rounded so daylight saving transitions don't produce fractional days
+	return int(math.Round(toDate.Sub(fromDate).Hours() / 24))
+}
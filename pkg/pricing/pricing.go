@@ -0,0 +1,193 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// ErrSpreadTooWide is returned when the quote's spread exceeds the policy's MaxSpreadPercent
+var ErrSpreadTooWide = errors.New("bid/ask spread too wide")
+
+// Method is how a policy derives a buy limit price from the bid and ask
+type Method string
+
+const (
+	// MethodPercentOfAsk prices at PercentOfAsk percent of the ask
+	MethodPercentOfAsk Method = "percent-of-ask"
+	// MethodMid prices at the midpoint of the bid and ask
+	MethodMid Method = "mid"
+	// MethodMidPlusSpread prices at the mid plus SpreadPercent percent of the spread; 0 is the mid and 50 the ask
+	MethodMidPlusSpread Method = "mid-plus-spread"
+	// MethodAskMinusTicks prices Ticks tick increments below the ask
+	MethodAskMinusTicks Method = "ask-minus-ticks"
+)
+
+// Methods lists every pricing method
+var Methods = []Method{MethodPercentOfAsk, MethodMid, MethodMidPlusSpread, MethodAskMinusTicks}
+
+// TickRule selects the minimum price increments an option is quoted in
+type TickRule string
+
+const (
+	// TickStandard quotes in $0.01 below $3.00 and $0.05 at or above
+	TickStandard TickRule = "standard"
+	// TickPenny quotes in $0.01 at every price, as for QQQ, SPY and IWM options
+	TickPenny TickRule = "penny"
+)
+
+// Tick sizes and the price at and above which standard options move in nickels
+var (
+	penny          = money.Cents(1)
+	nickel         = money.Cents(5)
+	pennyThreshold = money.NewFromInt(3)
+)
+
+// Policy prices buy limit orders from an option's bid and ask
+type Policy struct {
+	Method Method
+	// PercentOfAsk is the limit as a percentage of the ask for MethodPercentOfAsk, e.g. 99
+	PercentOfAsk float64
+	// SpreadPercent is the percentage of the spread added to the mid for MethodMidPlusSpread
+	SpreadPercent float64
+	// Ticks is the number of tick increments below the ask for MethodAskMinusTicks
+	Ticks int
+	// MaxSpreadPercent refuses to price a quote whose spread is wider than this percentage of the mid; 0 disables the guard
+	MaxSpreadPercent float64
+	// TickRule is the option's tick size rule; empty is TickStandard
+	TickRule TickRule
+}
+
+// PercentOfAsk returns the policy pricing at percent of the ask with standard ticks
+func PercentOfAsk(percent float64) Policy {
+	return Policy{Method: MethodPercentOfAsk, PercentOfAsk: percent}
+}
+
+// Validate checks that the policy's settings are usable by its method
+func (p Policy) Validate() error {
+	switch p.Method {
+	case MethodPercentOfAsk:
+		if p.PercentOfAsk <= 0 || p.PercentOfAsk > 100 {
+			return fmt.Errorf("limit percent of ask must be in (0, 100], got %.2f", p.PercentOfAsk)
+		}
+	case MethodMid:
+	case MethodMidPlusSpread:
+		if p.SpreadPercent < 0 || p.SpreadPercent > 50 {
+			return fmt.Errorf("spread percent must be in [0, 50], got %.2f", p.SpreadPercent)
+		}
+	case MethodAskMinusTicks:
+		if p.Ticks < 0 {
+			return fmt.Errorf("ticks below the ask must not be negative, got %d", p.Ticks)
+		}
+	default:
+		return fmt.Errorf("unknown pricing method %q", p.Method)
+	}
+
+	if p.MaxSpreadPercent < 0 {
+		return fmt.Errorf("max spread percent must not be negative, got %.2f", p.MaxSpreadPercent)
+	}
+	switch p.TickRule {
+	case "", TickStandard, TickPenny:
+	default:
+		return fmt.Errorf("unknown tick rule %q", p.TickRule)
+	}
+	return nil
+}
+
+// LimitPrice returns the buy limit price for a quote, rounded down to the tick grid.
+// It returns an error wrapping ErrSpreadTooWide when the spread guard refuses the quote.
+func (p Policy) LimitPrice(bid, ask money.Money) (money.Money, error) {
+	if err := p.Validate(); err != nil {
+		return money.Zero, err
+	}
+	if !bid.IsPositive() || !ask.IsPositive() || bid.GreaterThan(ask) {
+		return money.Zero, fmt.Errorf("invalid bid/ask prices: bid=%s, ask=%s", bid, ask)
+	}
+
+	if spread := SpreadPercent(bid, ask); p.MaxSpreadPercent > 0 && spread > p.MaxSpreadPercent {
+		return money.Zero, fmt.Errorf("%w: %s-%s is %.1f%% of the mid, above the %.1f%% limit",
+			ErrSpreadTooWide, bid, ask, spread, p.MaxSpreadPercent)
+	}
+
+	mid := bid.Add(ask).DivInt(2)
+	var price money.Money
+	switch p.Method {
+	case MethodPercentOfAsk:
+		price = ask.Percent(p.PercentOfAsk)
+	case MethodMid:
+		price = mid
+	case MethodMidPlusSpread:
+		price = mid.Add(ask.Sub(bid).Percent(p.SpreadPercent))
+	case MethodAskMinusTicks:
+		price = RoundDown(ask, p.TickRule)
+		for i := 0; i < p.Ticks; i++ {
+			// The tick below a price is the size of the step just under it, e.g. 3.00 steps down to 2.99
+			price = price.Sub(p.TickRule.Size(price.Sub(penny)))
+		}
+	}
+
+	price = RoundDown(price, p.TickRule)
+	if !price.IsPositive() {
+		return money.Zero, fmt.Errorf("limit price for bid=%s, ask=%s rounds to 0 with %s", bid, ask, p)
+	}
+	return price, nil
+}
+
+// String describes the policy for logs
+func (p Policy) String() string {
+	var s string
+	switch p.Method {
+	case MethodPercentOfAsk:
+		s = fmt.Sprintf("%g%% of ask", p.PercentOfAsk)
+	case MethodMidPlusSpread:
+		s = fmt.Sprintf("mid + %g%% of spread", p.SpreadPercent)
+	case MethodAskMinusTicks:
+		s = fmt.Sprintf("ask - %d ticks", p.Ticks)
+	default:
+		s = string(p.Method)
+	}
+	if p.MaxSpreadPercent > 0 {
+		s += fmt.Sprintf(", max spread %g%%", p.MaxSpreadPercent)
+	}
+	return s
+}
+
+// SpreadPercent returns the bid/ask spread as a percentage of the mid
+func SpreadPercent(bid, ask money.Money) float64 {
+	mid := bid.Add(ask).DivInt(2)
+	if !mid.IsPositive() {
+		return 0
+	}
+	return ask.Sub(bid).Ratio(mid).InexactFloat64() * 100
+}
+
+// Size returns the tick size for an option quoted at price
+func (r TickRule) Size(price money.Money) money.Money {
+	if r != TickPenny && price.GreaterThanOrEqual(pennyThreshold) {
+		return nickel
+	}
+	return penny
+}
+
+// RoundDown rounds price down to the nearest valid tick
+func RoundDown(price money.Money, rule TickRule) money.Money {
+	return round(price, rule, decimal.Decimal.Floor)
+}
+
+// RoundUp rounds price up to the nearest valid tick
+func RoundUp(price money.Money, rule TickRule) money.Money {
+	return round(price, rule, decimal.Decimal.Ceil)
+}
+
+// RoundNearest rounds price to the closest valid tick, halves rounding up
+func RoundNearest(price money.Money, rule TickRule) money.Money {
+	return round(price, rule, func(d decimal.Decimal) decimal.Decimal { return d.Round(0) })
+}
+
+// round aligns price to a whole number of ticks, choosing the tick size at the price itself
+func round(price money.Money, rule TickRule, direction func(decimal.Decimal) decimal.Decimal) money.Money {
+	tick := rule.Size(price)
+	return tick.Mul(direction(price.Ratio(tick)))
+}
@@ -0,0 +1,100 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+func price(s string) money.Money {
+	m, err := money.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		price             string
+		rule              TickRule
+		down, up, nearest string
+	}{
+		{"2.994", TickStandard, "2.99", "3.00", "2.99"},
+		{"3.00", TickStandard, "3.00", "3.00", "3.00"},
+		{"3.01", TickStandard, "3.00", "3.05", "3.00"},
+		{"3.025", TickStandard, "3.00", "3.05", "3.05"},
+		{"12.37", TickStandard, "12.35", "12.40", "12.35"},
+		{"12.37", TickPenny, "12.37", "12.37", "12.37"},
+		{"12.375", TickPenny, "12.37", "12.38", "12.38"},
+		{"12.37", "", "12.35", "12.40", "12.35"},
+	}
+	for _, tt := range tests {
+		p := price(tt.price)
+		if got := RoundDown(p, tt.rule); !got.Equal(price(tt.down)) {
+			t.Errorf("RoundDown(%s, %q) = %s, want %s", tt.price, tt.rule, got, tt.down)
+		}
+		if got := RoundUp(p, tt.rule); !got.Equal(price(tt.up)) {
+			t.Errorf("RoundUp(%s, %q) = %s, want %s", tt.price, tt.rule, got, tt.up)
+		}
+		if got := RoundNearest(p, tt.rule); !got.Equal(price(tt.nearest)) {
+			t.Errorf("RoundNearest(%s, %q) = %s, want %s", tt.price, tt.rule, got, tt.nearest)
+		}
+	}
+}
+
+func TestLimitPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		bid, ask string
+		want     string
+		wantErr  error
+	}{
+		{name: "percent of ask", policy: PercentOfAsk(99), bid: "9.90", ask: "10.00", want: "9.90"},
+		{name: "percent of ask rounded to a nickel", policy: PercentOfAsk(99), bid: "12.00", ask: "12.50", want: "12.35"},
+		{name: "percent of ask in pennies", policy: Policy{Method: MethodPercentOfAsk, PercentOfAsk: 99, TickRule: TickPenny}, bid: "12.00", ask: "12.50", want: "12.37"},
+		{name: "mid below $3", policy: Policy{Method: MethodMid}, bid: "2.00", ask: "2.05", want: "2.02"},
+		{name: "mid plus spread", policy: Policy{Method: MethodMidPlusSpread, SpreadPercent: 25}, bid: "10.00", ask: "11.00", want: "10.75"},
+		{name: "mid plus half the spread is the ask", policy: Policy{Method: MethodMidPlusSpread, SpreadPercent: 50}, bid: "10.00", ask: "11.00", want: "11.00"},
+		{name: "ask minus ticks", policy: Policy{Method: MethodAskMinusTicks, Ticks: 2}, bid: "3.00", ask: "3.10", want: "3.00"},
+		{name: "ask minus ticks across $3", policy: Policy{Method: MethodAskMinusTicks, Ticks: 2}, bid: "2.90", ask: "3.05", want: "2.99"},
+		{name: "ask minus no ticks", policy: Policy{Method: MethodAskMinusTicks}, bid: "3.00", ask: "3.10", want: "3.10"},
+		{name: "spread within the guard", policy: Policy{Method: MethodMid, MaxSpreadPercent: 5}, bid: "9.80", ask: "10.00", want: "9.90"},
+		{name: "spread too wide", policy: Policy{Method: MethodMid, MaxSpreadPercent: 10}, bid: "9.00", ask: "11.00", wantErr: ErrSpreadTooWide},
+		{name: "bid above ask", policy: PercentOfAsk(99), bid: "10.10", ask: "10.00"},
+		{name: "no bid", policy: PercentOfAsk(99), bid: "0", ask: "10.00"},
+		{name: "rounds to zero", policy: PercentOfAsk(1), bid: "0.40", ask: "0.50"},
+		{name: "invalid policy", policy: PercentOfAsk(120), bid: "9.90", ask: "10.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.LimitPrice(price(tt.bid), price(tt.ask))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("LimitPrice() = %s, want an error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("LimitPrice() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LimitPrice() error = %v", err)
+			}
+			if !got.Equal(price(tt.want)) {
+				t.Errorf("LimitPrice() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpreadPercent(t *testing.T) {
+	if got := SpreadPercent(price("9.00"), price("11.00")); got != 20 {
+		t.Errorf("SpreadPercent(9, 11) = %v, want 20", got)
+	}
+	if got := SpreadPercent(money.Zero, money.Zero); got != 0 {
+		t.Errorf("SpreadPercent(0, 0) = %v, want 0", got)
+	}
+}
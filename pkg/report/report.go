@@ -0,0 +1,274 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// tradingDaysPerYear annualizes daily return statistics
+const tradingDaysPerYear = 252
+
+// EquityPoint is the account value at the close of one trading day
+type EquityPoint struct {
+	Date   time.Time   `json:"date"`
+	Cash   money.Money `json:"cash"`
+	Equity money.Money `json:"equity"`
+}
+
+// Trade is one position lot from entry fill to exit
+type Trade struct {
+	Symbol     string      `json:"symbol"`
+	OrderID    string      `json:"order_id"`
+	Quantity   int64       `json:"quantity"`
+	EntryDate  time.Time   `json:"entry_date"`
+	EntryPrice money.Money `json:"entry_price"`
+	ExitDate   time.Time   `json:"exit_date"`
+	ExitPrice  money.Money `json:"exit_price"`
+	ExitReason string      `json:"exit_reason"`
+}
+
+// PnL returns the dollar profit or loss of an option trade
+func (t Trade) PnL() money.Money {
+	return t.ExitPrice.Sub(t.EntryPrice).MulInt(t.Quantity * money.ContractMultiplier)
+}
+
+// ReturnPercent returns the trade's return on premium paid, in percent
+func (t Trade) ReturnPercent() float64 {
+	return t.EntryPrice.PercentChange(t.ExitPrice)
+}
+
+// HoldingDays returns the number of calendar days between entry and exit
+func (t Trade) HoldingDays() float64 {
+	// Rounded so daylight saving transitions don't produce fractional days
+	return math.Round(t.ExitDate.Sub(t.EntryDate).Hours() / 24)
+}
+
+// Report holds the standard performance and risk metrics for a run.
+// Percentages are expressed in percent (e.g. 12.5 means 12.5%).
+type Report struct {
+	Start              time.Time   `json:"start"`
+	End                time.Time   `json:"end"`
+	TradingDays        int         `json:"trading_days"`
+	StartEquity        money.Money `json:"start_equity"`
+	EndEquity          money.Money `json:"end_equity"`
+	TotalReturnPercent float64     `json:"total_return_percent"`
+	CAGRPercent        float64     `json:"cagr_percent"`
+	MaxDrawdownPercent float64     `json:"max_drawdown_percent"`
+	Sharpe             float64     `json:"sharpe"`
+	Sortino            float64     `json:"sortino"`
+	Trades             int         `json:"trades"`
+	WinRatePercent     float64     `json:"win_rate_percent"`
+	AvgHoldingDays     float64     `json:"avg_holding_days"`
+	AvgReturnPercent   float64     `json:"avg_return_percent"`
+	TotalPnL           money.Money `json:"total_pnl"`
+	ExposurePercent    float64     `json:"exposure_percent"`
+}
+
+// Compute builds a report from a daily equity curve and the closed trades of the same period.
+// Ratios are annualized from daily returns with a zero risk-free rate.
+func Compute(curve []EquityPoint, trades []Trade) *Report {
+	r := &Report{Trades: len(trades)}
+	if len(curve) > 0 {
+		r.Start = curve[0].Date
+		r.End = curve[len(curve)-1].Date
+		r.TradingDays = len(curve)
+		r.StartEquity = curve[0].Equity
+		r.EndEquity = curve[len(curve)-1].Equity
+	}
+
+	if r.StartEquity.IsPositive() {
+		r.TotalReturnPercent = r.StartEquity.PercentChange(r.EndEquity)
+		years := r.End.Sub(r.Start).Hours() / 24 / 365.25
+		if years > 0 && r.EndEquity.IsPositive() {
+			r.CAGRPercent = (math.Pow(r.EndEquity.Ratio(r.StartEquity).InexactFloat64(), 1/years) - 1) * 100
+		}
+	}
+
+	r.MaxDrawdownPercent = maxDrawdown(curve) * 100
+	r.Sharpe, r.Sortino = riskAdjustedRatios(dailyReturns(curve))
+
+	if len(trades) > 0 {
+		wins := 0
+		var holding, returns float64
+		for _, trade := range trades {
+			if trade.PnL().IsPositive() {
+				wins++
+			}
+			holding += trade.HoldingDays()
+			returns += trade.ReturnPercent()
+			r.TotalPnL = r.TotalPnL.Add(trade.PnL())
+		}
+		r.WinRatePercent = float64(wins) / float64(len(trades)) * 100
+		r.AvgHoldingDays = holding / float64(len(trades))
+		r.AvgReturnPercent = returns / float64(len(trades))
+	}
+
+	r.ExposurePercent = exposure(curve, trades) * 100
+	return r
+}
+
+// Metrics lists the names accepted by Metric, in report order
+var Metrics = []string{
+	"total_return_percent",
+	"cagr_percent",
+	"max_drawdown_percent",
+	"sharpe",
+	"sortino",
+	"trades",
+	"win_rate_percent",
+	"avg_holding_days",
+	"avg_return_percent",
+	"total_pnl",
+	"exposure_percent",
+}
+
+// Metric returns a metric by its JSON name
+func (r *Report) Metric(name string) (float64, error) {
+	switch name {
+	case "total_return_percent":
+		return r.TotalReturnPercent, nil
+	case "cagr_percent":
+		return r.CAGRPercent, nil
+	case "max_drawdown_percent":
+		return r.MaxDrawdownPercent, nil
+	case "sharpe":
+		return r.Sharpe, nil
+	case "sortino":
+		return r.Sortino, nil
+	case "trades":
+		return float64(r.Trades), nil
+	case "win_rate_percent":
+		return r.WinRatePercent, nil
+	case "avg_holding_days":
+		return r.AvgHoldingDays, nil
+	case "avg_return_percent":
+		return r.AvgReturnPercent, nil
+	case "total_pnl":
+		return r.TotalPnL.Float64(), nil
+	case "exposure_percent":
+		return r.ExposurePercent, nil
+	default:
+		return 0, fmt.Errorf("unknown metric: %s", name)
+	}
+}
+
+// LowerIsBetter reports whether smaller values of the metric rank higher
+func LowerIsBetter(name string) bool {
+	return name == "max_drawdown_percent"
+}
+
+// WriteTable writes the report as an aligned, human-readable table
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rows := []struct {
+		name  string
+		value string
+	}{
+		{"Period", fmt.Sprintf("%s to %s (%d trading days)", r.Start.Format("2006-01-02"), r.End.Format("2006-01-02"), r.TradingDays)},
+		{"Start equity", fmt.Sprintf("$%s", r.StartEquity)},
+		{"End equity", fmt.Sprintf("$%s", r.EndEquity)},
+		{"Total return", fmt.Sprintf("%+.2f%%", r.TotalReturnPercent)},
+		{"CAGR", fmt.Sprintf("%+.2f%%", r.CAGRPercent)},
+		{"Max drawdown", fmt.Sprintf("%.2f%%", r.MaxDrawdownPercent)},
+		{"Sharpe", fmt.Sprintf("%.2f", r.Sharpe)},
+		{"Sortino", fmt.Sprintf("%.2f", r.Sortino)},
+		{"Trades", fmt.Sprintf("%d", r.Trades)},
+		{"Win rate", fmt.Sprintf("%.2f%%", r.WinRatePercent)},
+		{"Avg holding period", fmt.Sprintf("%.1f days", r.AvgHoldingDays)},
+		{"Avg return per trade", fmt.Sprintf("%+.2f%%", r.AvgReturnPercent)},
+		{"Total P&L", fmt.Sprintf("$%s", r.TotalPnL)},
+		{"Exposure time", fmt.Sprintf("%.2f%%", r.ExposurePercent)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row.name, row.value)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func dailyReturns(curve []EquityPoint) []float64 {
+	var returns []float64
+	for i := 1; i < len(curve); i++ {
+		if !curve[i-1].Equity.IsPositive() {
+			continue
+		}
+		returns = append(returns, curve[i-1].Equity.PercentChange(curve[i].Equity)/100)
+	}
+	return returns
+}
+
+// maxDrawdown returns the largest peak-to-trough decline as a fraction of the peak
+func maxDrawdown(curve []EquityPoint) float64 {
+	peak := money.Zero
+	var drawdown float64
+	for _, point := range curve {
+		peak = money.Max(peak, point.Equity)
+		if peak.IsPositive() {
+			drawdown = math.Max(drawdown, peak.Sub(point.Equity).Ratio(peak).InexactFloat64())
+		}
+	}
+	return drawdown
+}
+
+// riskAdjustedRatios returns the annualized Sharpe and Sortino ratios of daily returns
+func riskAdjustedRatios(returns []float64) (float64, float64) {
+	if len(returns) < 2 {
+		return 0, 0
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDev := math.Sqrt(downside / float64(len(returns)))
+
+	annualize := math.Sqrt(tradingDaysPerYear)
+	var sharpe, sortino float64
+	if stdDev > 0 {
+		sharpe = mean / stdDev * annualize
+	}
+	if downsideDev > 0 {
+		sortino = mean / downsideDev * annualize
+	}
+	return sharpe, sortino
+}
+
+// exposure returns the fraction of days in the curve on which at least one trade was open
+func exposure(curve []EquityPoint, trades []Trade) float64 {
+	if len(curve) == 0 {
+		return 0
+	}
+
+	exposed := 0
+	for _, point := range curve {
+		for _, trade := range trades {
+			if !point.Date.Before(trade.EntryDate) && point.Date.Before(trade.ExitDate) {
+				exposed++
+				break
+			}
+		}
+	}
+	return float64(exposed) / float64(len(curve))
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// approx reports whether got is within a millionth of want
+func approx(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-6*math.Max(1, math.Abs(want))
+}
+
+// testRun returns four closes either side of the March 9th, 2025 daylight saving change, rising 10%,
+// falling 10% and rising 10% again, and two trades: a 50% winner held over the change and a 10% loser
+func testRun(t *testing.T) ([]EquityPoint, []Trade) {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 16, 0, 0, 0, loc) }
+
+	curve := []EquityPoint{
+		{Date: day(7), Cash: money.NewFromInt(9000), Equity: money.NewFromInt(10000)},
+		{Date: day(10), Cash: money.NewFromInt(9000), Equity: money.NewFromInt(11000)},
+		{Date: day(11), Cash: money.NewFromInt(6000), Equity: money.NewFromInt(9900)},
+		{Date: day(12), Cash: money.NewFromInt(10890), Equity: money.NewFromInt(10890)},
+	}
+	trades := []Trade{
+		{Symbol: "QQQ260116C00400000", Quantity: 1, EntryDate: day(7), EntryPrice: money.NewFromInt(10),
+			ExitDate: day(11), ExitPrice: money.NewFromInt(15), ExitReason: "take-profit"},
+		{Symbol: "QQQ260116C00410000", Quantity: 2, EntryDate: day(11), EntryPrice: money.NewFromInt(20),
+			ExitDate: day(12), ExitPrice: money.NewFromInt(18), ExitReason: "stop-loss"},
+	}
+	return curve, trades
+}
+
+func TestTrade(t *testing.T) {
+	_, trades := testRun(t)
+	tests := []struct {
+		pnl         money.Money
+		returnPct   float64
+		holdingDays float64
+	}{
+		// Four calendar days, though the clocks went forward in between
+		{pnl: money.NewFromInt(500), returnPct: 50, holdingDays: 4},
+		{pnl: money.NewFromInt(-400), returnPct: -10, holdingDays: 1},
+	}
+	for i, tt := range tests {
+		trade := trades[i]
+		if !trade.PnL().Equal(tt.pnl) {
+			t.Errorf("%s: PnL() = %s, want %s", trade.Symbol, trade.PnL(), tt.pnl)
+		}
+		if !approx(trade.ReturnPercent(), tt.returnPct) {
+			t.Errorf("%s: ReturnPercent() = %v, want %v", trade.Symbol, trade.ReturnPercent(), tt.returnPct)
+		}
+		if trade.HoldingDays() != tt.holdingDays {
+			t.Errorf("%s: HoldingDays() = %v, want %v", trade.Symbol, trade.HoldingDays(), tt.holdingDays)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	curve, trades := testRun(t)
+	r := Compute(curve, trades)
+
+	if !r.Start.Equal(curve[0].Date) || !r.End.Equal(curve[3].Date) || r.TradingDays != 4 {
+		t.Errorf("period = %s to %s (%d days), want the curve's 4 days", r.Start, r.End, r.TradingDays)
+	}
+	if !r.StartEquity.Equal(money.NewFromInt(10000)) || !r.EndEquity.Equal(money.NewFromInt(10890)) {
+		t.Errorf("equity = %s to %s, want 10000 to 10890", r.StartEquity, r.EndEquity)
+	}
+
+	// Daily returns of +10%, -10% and +10%: a mean of 1/30, a sample deviation of 0.1155 and a downside
+	// deviation of 0.0577
+	years := curve[3].Date.Sub(curve[0].Date).Hours() / 24 / 365.25
+	mean := 0.1 / 3
+	annualize := math.Sqrt(tradingDaysPerYear)
+	tests := []struct {
+		metric string
+		want   float64
+	}{
+		{"total_return_percent", 8.9},
+		{"cagr_percent", (math.Pow(1.089, 1/years) - 1) * 100},
+		// From the 11,000 peak to 9,900
+		{"max_drawdown_percent", 10},
+		{"sharpe", mean / math.Sqrt(0.04/3) * annualize},
+		{"sortino", mean / math.Sqrt(0.01/3) * annualize},
+		{"trades", 2},
+		{"win_rate_percent", 50},
+		{"avg_holding_days", 2.5},
+		{"avg_return_percent", 20},
+		{"total_pnl", 100},
+		// Holding on the 7th, 10th and 11th, but not the 12th when the only trade open exits
+		{"exposure_percent", 75},
+	}
+	if len(tests) != len(Metrics) {
+		t.Fatalf("testing %d metrics, Metrics lists %d", len(tests), len(Metrics))
+	}
+	for i, tt := range tests {
+		if Metrics[i] != tt.metric {
+			t.Errorf("Metrics[%d] = %s, want %s", i, Metrics[i], tt.metric)
+		}
+		got, err := r.Metric(tt.metric)
+		if err != nil {
+			t.Errorf("Metric(%s) error = %v", tt.metric, err)
+			continue
+		}
+		if !approx(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.metric, got, tt.want)
+		}
+	}
+
+	if _, err := r.Metric("luck"); err == nil {
+		t.Error("Metric(luck) succeeded, want an error")
+	}
+	for _, metric := range Metrics {
+		if want := metric == "max_drawdown_percent"; LowerIsBetter(metric) != want {
+			t.Errorf("LowerIsBetter(%s) = %v, want %v", metric, !want, want)
+		}
+	}
+}
+
+func TestComputeWithoutData(t *testing.T) {
+	r := Compute(nil, nil)
+	for _, metric := range Metrics {
+		if got, err := r.Metric(metric); err != nil || got != 0 {
+			t.Errorf("%s = %v (%v), want 0", metric, got, err)
+		}
+	}
+
+	// A flat curve has no deviation to divide by
+	curve, _ := testRun(t)
+	for i := range curve {
+		curve[i].Equity = money.NewFromInt(10000)
+	}
+	if r := Compute(curve, nil); r.Sharpe != 0 || r.Sortino != 0 || r.MaxDrawdownPercent != 0 || r.ExposurePercent != 0 {
+		t.Errorf("flat curve: Sharpe %v, Sortino %v, drawdown %v, exposure %v; want all 0",
+			r.Sharpe, r.Sortino, r.MaxDrawdownPercent, r.ExposurePercent)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := Compute(testRun(t))
+
+	var table bytes.Buffer
+	if err := r.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		"Period                2025-03-07 to 2025-03-12 (4 trading days)\n",
+		"Total return          +8.90%\n",
+		"Max drawdown          10.00%\n",
+		"Avg holding period    2.5 days\n",
+		"Total P&L             $100.00\n",
+	} {
+		if !strings.Contains(table.String(), row) {
+			t.Errorf("table is missing %q:\n%s", row, table.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	// Every metric is written under the name Metric accepts
+	for _, metric := range Metrics {
+		if _, ok := decoded[metric]; !ok {
+			t.Errorf("JSON is missing %s:\n%s", metric, buf.String())
+		}
+	}
+}
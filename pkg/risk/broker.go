@@ -0,0 +1,266 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+
+	// Embeds the time zone database so exchange dates work where the system has none, e.g. on Lambda
+	_ "time/tzdata"
+)
+
+// ordersKey is the state store key counting the opening orders submitted on the current trading day
+const ordersKey = "risk/orders.json"
+
+// exchangeLocation is the exchange's time zone, in which trading days are dated
+var exchangeLocation = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic("risk: " + err.Error())
+	}
+	return loc
+}
+
+// Broker wraps a broker so that every opening order is checked against the limits before it is sent.
+// Closing orders always go through, since they only reduce risk. An order breaching a limit isn't sent;
+// its error is a *Rejection, which is also recorded in the journal. Wrap the broker that submits orders:
+// a dry-run broker wrapped around it computes its orders without consulting the limits.
+type Broker struct {
+	broker.Broker
+	limits  Limits
+	store   statestore.StateStore
+	journal *journal.Journal
+}
+
+// Ensure Broker satisfies the broker interfaces
+var (
+	_ broker.Broker         = (*Broker)(nil)
+	_ broker.MultiLegBroker = (*Broker)(nil)
+)
+
+// NewBroker creates a risk manager around b enforcing limits, counting the day's orders in store
+func NewBroker(b broker.Broker, limits Limits, store statestore.StateStore) *Broker {
+	return &Broker{Broker: b, limits: limits, store: store}
+}
+
+// SetJournal records every rejection in j
+func (b *Broker) SetJournal(j *journal.Journal) {
+	b.journal = j
+}
+
+type exemptKey struct{}
+
+// Exempt returns a context whose orders skip the limits. It is meant for orders restoring a position
+// that was just closed, such as buying back the contracts of a roll whose open failed, which must go
+// through even when the limits would refuse a new position.
+func Exempt(ctx context.Context) context.Context {
+	return context.WithValue(ctx, exemptKey{}, true)
+}
+
+func exempt(ctx context.Context) bool {
+	exempted, _ := ctx.Value(exemptKey{}).(bool)
+	return exempted
+}
+
+// PlaceOptionBracketOrder checks the order the wrapped broker would build from investmentSize and the
+// quote against the limits before placing it
+func (b *Broker) PlaceOptionBracketOrder(ctx context.Context, clientOrderID string, investmentSize money.Money, optionSymbol string, optionQuote *marketdata.OptionQuote, policy pricing.Policy, exits bracket.Exits) (*alpaca.Order, *bracket.OCO, error) {
+	// An order that can't be built is left to the wrapped broker to refuse with its own error, e.g. a wide spread
+	bracketOrder, err := athenaxalpaca.BuildOptionBracketOrder(clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits)
+	if err == nil && !exempt(ctx) {
+		req := bracketOrder.Request
+		if err := b.gate(ctx, clientOrderID, optionSymbol, *req.Qty, money.FromPtr(req.LimitPrice), nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	order, oco, err := b.Broker.PlaceOptionBracketOrder(ctx, clientOrderID, investmentSize, optionSymbol, optionQuote, policy, exits)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.count(ctx, order)
+	return order, oco, nil
+}
+
+// PlaceOptionBuyOrder checks the order against the limits before placing it
+func (b *Broker) PlaceOptionBuyOrder(ctx context.Context, clientOrderID, optionSymbol string, qty decimal.Decimal, limitPrice money.Money) (*alpaca.Order, error) {
+	if !exempt(ctx) {
+		if err := b.gate(ctx, clientOrderID, optionSymbol, qty, limitPrice, nil); err != nil {
+			return nil, err
+		}
+	}
+	order, err := b.Broker.PlaceOptionBuyOrder(ctx, clientOrderID, optionSymbol, qty, limitPrice)
+	if err != nil {
+		return nil, err
+	}
+	b.count(ctx, order)
+	return order, nil
+}
+
+// PlaceOptionRollOrder checks the roll's opening leg, priced at its ask, against the limits as if the
+// contracts it closes were already sold, as they would be by a close followed by an open. A wrapped broker
+// that can't place multi-leg orders returns an error wrapping broker.ErrMultiLegUnsupported.
+func (b *Broker) PlaceOptionRollOrder(ctx context.Context, clientOrderID, closeSymbol, openSymbol string, qty decimal.Decimal, netDebit money.Money) (*alpaca.Order, error) {
+	multiLeg, err := broker.MultiLeg(b.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if !exempt(ctx) {
+		snapshot, err := b.GetOptionSnapshot(ctx, openSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check risk limits: failed to get snapshot of %s: %w", openSymbol, err)
+		}
+		if snapshot.LatestQuote == nil || snapshot.LatestQuote.AskPrice <= 0 {
+			return nil, fmt.Errorf("failed to check risk limits: no ask for %s", openSymbol)
+		}
+		closing := &closing{symbol: closeSymbol, qty: qty}
+		if err := b.gate(ctx, clientOrderID, openSymbol, qty, money.NewFromFloat(snapshot.LatestQuote.AskPrice), closing); err != nil {
+			return nil, err
+		}
+	}
+	order, err := multiLeg.PlaceOptionRollOrder(ctx, clientOrderID, closeSymbol, openSymbol, qty, netDebit)
+	if err != nil {
+		return nil, err
+	}
+	b.count(ctx, order)
+	return order, nil
+}
+
+// ReplaceOrder checks a buy order repriced to limitPrice against the per-trade limit before replacing it
+func (b *Broker) ReplaceOrder(ctx context.Context, orderID, clientOrderID string, limitPrice money.Money) (*alpaca.Order, error) {
+	if limit := b.limits.MaxTradeNotional; limit.IsPositive() && !exempt(ctx) {
+		order, err := b.GetOrder(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.Side == alpaca.Buy && order.Qty != nil {
+			p := proposal{instanceID: instanceOf(clientOrderID), symbol: order.Symbol, qty: *order.Qty, price: limitPrice}
+			if option, err := athenaxalpaca.ParseOptionTicker(order.Symbol); err == nil {
+				p.underlying = option.Underlying
+			}
+			if notional := p.notional(); notional.GreaterThan(limit) {
+				rejection := p.reject(b.Now(), LimitTradeNotional, notional.Float64(), limit.Float64(),
+					"repricing to %s commits %s of premium, more than the %s allowed per trade", limitPrice, notional, limit)
+				b.record(ctx, rejection)
+				return nil, rejection
+			}
+		}
+	}
+	return b.Broker.ReplaceOrder(ctx, orderID, clientOrderID, limitPrice)
+}
+
+// gate checks an opening order, and the contracts it closes when it is a roll, against the limits,
+// recording and returning the rejection if it breaches one
+func (b *Broker) gate(ctx context.Context, clientOrderID, symbol string, qty decimal.Decimal, price money.Money, closes *closing) error {
+	option, err := athenaxalpaca.ParseOptionTicker(symbol)
+	if err != nil {
+		return err
+	}
+	rejection, err := b.check(ctx, proposal{
+		instanceID: instanceOf(clientOrderID),
+		symbol:     symbol,
+		underlying: option.Underlying,
+		qty:        qty,
+		price:      price,
+		closes:     closes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check risk limits: %w", err)
+	}
+	if rejection != nil {
+		b.record(ctx, rejection)
+		return rejection
+	}
+	return nil
+}
+
+// record logs and journals a rejection. A journal failure is logged; the order is refused either way.
+func (b *Broker) record(ctx context.Context, rejection *Rejection) {
+	log.Printf("[%s] %v", rejection.InstanceID, rejection)
+	if b.journal == nil {
+		return
+	}
+	if err := b.journal.RecordRiskRejection(ctx, journal.RiskRejection(*rejection)); err != nil {
+		log.Printf("Failed to journal risk rejection: %v", err)
+	}
+}
+
+// orderCount is the count of opening orders on one trading day
+type orderCount struct {
+	Day    string `json:"day"`
+	Orders int    `json:"orders"`
+}
+
+// ordersToday returns the number of opening orders submitted on the current trading day
+func (b *Broker) ordersToday(ctx context.Context) (int, error) {
+	count, err := b.loadCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return count.Orders, nil
+}
+
+// count adds a submitted opening order to the day's count, retrying when an overlapping run counted one
+// in the meantime. A failure to save the count is logged: the order is already placed.
+func (b *Broker) count(ctx context.Context, order *alpaca.Order) {
+	if b.limits.MaxOrdersPerDay <= 0 {
+		return
+	}
+	err := statestore.Update(ctx, b.store, ordersKey, func(data []byte) ([]byte, error) {
+		count, err := b.decodeCount(data)
+		if err != nil {
+			return nil, err
+		}
+		count.Orders++
+		return json.Marshal(count)
+	})
+	if err != nil {
+		log.Printf("Failed to count order %s toward the daily order limit: %v", order.ID, err)
+	}
+}
+
+func (b *Broker) loadCount(ctx context.Context) (orderCount, error) {
+	data, err := b.store.Get(ctx, ordersKey)
+	if err != nil && !errors.Is(err, statestore.ErrNotFound) {
+		return orderCount{}, fmt.Errorf("failed to load the day's order count: %w", err)
+	}
+	return b.decodeCount(data)
+}
+
+// decodeCount decodes the stored order count, starting a new one when there is none or it is for an
+// earlier trading day
+func (b *Broker) decodeCount(data []byte) (orderCount, error) {
+	day := b.Now().In(exchangeLocation).Format("2006-01-02")
+	if data == nil {
+		return orderCount{Day: day}, nil
+	}
+	var count orderCount
+	if err := json.Unmarshal(data, &count); err != nil {
+		return orderCount{}, fmt.Errorf("failed to decode the day's order count: %w", err)
+	}
+	if count.Day != day {
+		return orderCount{Day: day}, nil
+	}
+	return count, nil
+}
+
+// instanceOf returns the strategy instance a client order ID belongs to, or an empty string
+func instanceOf(clientOrderID string) string {
+	instanceID, _ := athenaxalpaca.InstanceOfClientOrderID(clientOrderID)
+	return instanceID
+}
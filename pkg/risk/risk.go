@@ -0,0 +1,267 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	athenaxalpaca "github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/journal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// Limits, recorded with the rejections they cause
+const (
+	// LimitTradeNotional caps the premium of a single order
+	LimitTradeNotional = "max-trade-notional"
+	// LimitUnderlyingPercent caps the option premium held on one underlying, as a percent of equity
+	LimitUnderlyingPercent = "max-underlying-percent"
+	// LimitTotalPremium caps the option premium held across the account
+	LimitTotalPremium = "max-total-premium"
+	// LimitPortfolioDelta caps the share-equivalent delta of the option positions
+	LimitPortfolioDelta = "max-portfolio-delta"
+	// LimitDailyLoss stops opening positions once equity fell this far since the previous close
+	LimitDailyLoss = "daily-loss-limit"
+	// LimitOrdersPerDay caps the opening orders submitted in a trading day
+	LimitOrdersPerDay = "max-orders-per-day"
+)
+
+// Limits are the account-wide limits every opening order is checked against; the zero value of each disables it
+type Limits struct {
+	// MaxTradeNotional is the most premium a single order may commit
+	MaxTradeNotional money.Money
+	// MaxUnderlyingPercent is the most option premium, valued at market, held on one underlying as a percent of equity
+	MaxUnderlyingPercent float64
+	// MaxTotalPremium is the most option premium, valued at market, held across the account
+	MaxTotalPremium money.Money
+	// MaxPortfolioDelta is the largest share-equivalent delta of the option positions, long or short
+	MaxPortfolioDelta float64
+	// DailyLossLimit is the equity loss since the previous close at which opening orders stop
+	DailyLossLimit money.Money
+	// MaxOrdersPerDay is the most opening orders submitted in a trading day
+	MaxOrdersPerDay int
+}
+
+// Validate checks that the limits are usable
+func (l Limits) Validate() error {
+	if l.MaxTradeNotional.IsNegative() {
+		return fmt.Errorf("max_trade_notional must not be negative, got %s", l.MaxTradeNotional)
+	}
+	if l.MaxUnderlyingPercent < 0 || l.MaxUnderlyingPercent > 100 {
+		return fmt.Errorf("max_underlying_percent must be in [0, 100], got %.2f", l.MaxUnderlyingPercent)
+	}
+	if l.MaxTotalPremium.IsNegative() {
+		return fmt.Errorf("max_total_premium must not be negative, got %s", l.MaxTotalPremium)
+	}
+	if l.MaxPortfolioDelta < 0 {
+		return fmt.Errorf("max_portfolio_delta must not be negative, got %.2f", l.MaxPortfolioDelta)
+	}
+	if l.DailyLossLimit.IsNegative() {
+		return fmt.Errorf("daily_loss_limit must not be negative, got %s", l.DailyLossLimit)
+	}
+	if l.MaxOrdersPerDay < 0 {
+		return fmt.Errorf("max_orders_per_day must not be negative, got %d", l.MaxOrdersPerDay)
+	}
+	return nil
+}
+
+// Enabled reports whether any limit is set
+func (l Limits) Enabled() bool {
+	return l.MaxTradeNotional.IsPositive() || l.MaxUnderlyingPercent > 0 || l.MaxTotalPremium.IsPositive() ||
+		l.MaxPortfolioDelta > 0 || l.DailyLossLimit.IsPositive() || l.MaxOrdersPerDay > 0
+}
+
+// Rejection is an order the risk manager refused to send. It is returned as the order's error.
+type Rejection journal.RiskRejection
+
+// Error describes the rejection
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("risk limit %s rejected the order for %s: %s", r.Limit, r.Symbol, r.Reason)
+}
+
+// proposal is an opening order as the limits see it
+type proposal struct {
+	instanceID string
+	symbol     string
+	underlying string
+	qty        decimal.Decimal
+	// price is the limit price of one contract
+	price money.Money
+	// closes are the contracts a roll sells in the same order, or nil
+	closes *closing
+}
+
+// closing is a position, or part of one, that an order closes
+type closing struct {
+	symbol string
+	qty    decimal.Decimal
+}
+
+// notional returns the premium the order commits
+func (p proposal) notional() money.Money {
+	return p.price.Mul(p.qty).MulInt(money.ContractMultiplier)
+}
+
+// reject returns the rejection of the order at at by limit, having measured value against threshold
+func (p proposal) reject(at time.Time, limit string, value, threshold float64, reason string, args ...any) *Rejection {
+	return &Rejection{
+		At:         at,
+		InstanceID: p.instanceID,
+		Symbol:     p.symbol,
+		Underlying: p.underlying,
+		Limit:      limit,
+		Value:      value,
+		Threshold:  threshold,
+		Reason:     fmt.Sprintf(reason, args...),
+	}
+}
+
+// check returns the rejection of the first limit the order would breach, or nil to send it
+func (b *Broker) check(ctx context.Context, p proposal) (*Rejection, error) {
+	now := b.Now()
+	notional := p.notional()
+
+	if b.limits.MaxOrdersPerDay > 0 {
+		orders, err := b.ordersToday(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if orders >= b.limits.MaxOrdersPerDay {
+			return p.reject(now, LimitOrdersPerDay, float64(orders+1), float64(b.limits.MaxOrdersPerDay),
+				"%d opening orders were already submitted today, the most allowed", orders), nil
+		}
+	}
+
+	if limit := b.limits.MaxTradeNotional; limit.IsPositive() && notional.GreaterThan(limit) {
+		return p.reject(now, LimitTradeNotional, notional.Float64(), limit.Float64(),
+			"%s of premium is more than the %s allowed per trade", notional, limit), nil
+	}
+
+	var equity money.Money
+	if b.limits.DailyLossLimit.IsPositive() || b.limits.MaxUnderlyingPercent > 0 {
+		current, last, err := b.GetEquity(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get equity: %w", err)
+		}
+		equity = current
+		if limit := b.limits.DailyLossLimit; limit.IsPositive() {
+			if loss := last.Sub(current); loss.GreaterThanOrEqual(limit) {
+				return p.reject(now, LimitDailyLoss, loss.Float64(), limit.Float64(),
+					"equity fell %s since the previous close, at or past the daily loss limit of %s", loss, limit), nil
+			}
+		}
+	}
+
+	if b.limits.MaxUnderlyingPercent <= 0 && !b.limits.MaxTotalPremium.IsPositive() && b.limits.MaxPortfolioDelta <= 0 {
+		return nil, nil
+	}
+	book, err := b.book(ctx, p.underlying, p.closes)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit := b.limits.MaxUnderlyingPercent; limit > 0 {
+		held := book.underlying.Add(notional)
+		percent := 100.0
+		if equity.IsPositive() {
+			percent = held.Ratio(equity).InexactFloat64() * 100
+		}
+		if percent > limit {
+			return p.reject(now, LimitUnderlyingPercent, percent, limit,
+				"%s of %s options would be %.2f%% of the %s equity, more than %.2f%%", held, p.underlying, percent, equity, limit), nil
+		}
+	}
+
+	if limit := b.limits.MaxTotalPremium; limit.IsPositive() {
+		if held := book.premium.Add(notional); held.GreaterThan(limit) {
+			return p.reject(now, LimitTotalPremium, held.Float64(), limit.Float64(),
+				"%s of option premium would be held, more than the %s allowed", held, limit), nil
+		}
+	}
+
+	if limit := b.limits.MaxPortfolioDelta; limit > 0 {
+		delta, err := b.delta(ctx, p.symbol)
+		if err != nil {
+			return nil, err
+		}
+		after := book.delta + delta*p.qty.InexactFloat64()*money.ContractMultiplier
+		if math.Abs(after) > limit && math.Abs(after) > math.Abs(book.delta) {
+			return p.reject(now, LimitPortfolioDelta, after, limit,
+				"portfolio delta would be %.0f, beyond the %.0f allowed", after, limit), nil
+		}
+	}
+	return nil, nil
+}
+
+// book is what the account holds in options, as the limits measure it
+type book struct {
+	// underlying and premium are the market values of the option positions on the order's underlying
+	// and across the account
+	underlying money.Money
+	premium    money.Money
+	// delta is the share-equivalent delta of every option position
+	delta float64
+}
+
+// book measures the option positions held, less the contracts closes sells, looking up the delta of each
+// only when a delta limit is set
+func (b *Broker) book(ctx context.Context, underlying string, closes *closing) (book, error) {
+	positions, err := b.GetAllPositions(ctx)
+	if err != nil {
+		return book{}, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var held book
+	for _, position := range positions {
+		option, err := athenaxalpaca.ParseOptionTicker(position.Symbol)
+		if err != nil {
+			continue
+		}
+		value := money.New(position.CostBasis)
+		if position.MarketValue != nil {
+			value = money.FromPtr(position.MarketValue)
+		}
+		qty := position.Qty
+		if closes != nil && position.Symbol == closes.symbol && qty.IsPositive() {
+			remaining := decimal.Max(qty.Sub(closes.qty), decimal.Zero)
+			value = value.Mul(remaining.Div(qty))
+			qty = remaining
+		}
+		held.premium = held.premium.Add(value)
+		if option.Underlying == underlying {
+			held.underlying = held.underlying.Add(value)
+		}
+		if b.limits.MaxPortfolioDelta > 0 {
+			delta, err := b.delta(ctx, position.Symbol)
+			if err != nil {
+				return book{}, err
+			}
+			held.delta += delta * qty.InexactFloat64() * money.ContractMultiplier
+		}
+	}
+	return held, nil
+}
+
+// delta returns the delta of one contract of an option. Without greeks it is taken as 1 for a call and
+// -1 for a put, the most it can be, so that missing greeks never loosen the limit.
+func (b *Broker) delta(ctx context.Context, symbol string) (float64, error) {
+	option, err := athenaxalpaca.ParseOptionTicker(symbol)
+	if err != nil {
+		return 0, err
+	}
+	snapshot, err := b.GetOptionSnapshot(ctx, symbol)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get snapshot of %s: %w", symbol, err)
+	}
+	if snapshot.Greeks != nil {
+		return snapshot.Greeks.Delta, nil
+	}
+	log.Printf("No greeks for %s, taking its delta as the most it can be", symbol)
+	if option.Type == "P" {
+		return -1, nil
+	}
+	return 1, nil
+}
@@ -0,0 +1,269 @@
+package risk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+const (
+	// leap is bought by the orders under test, at $10.00 with a 0.70 delta
+	leap = "QQQ260320C00450000"
+	// held is already in the account: 3 contracts marked at $9.05, $2,715, with a 0.60 delta
+	held = "QQQ260320C00460000"
+)
+
+// newAccount returns a simulated account with $20,000 of buying power and the held position, so its
+// equity is $22,715
+func newAccount(t *testing.T) *sim.Broker {
+	t.Helper()
+	b := sim.NewBroker(time.Date(2025, 3, 4, 14, 35, 0, 0, time.UTC))
+	b.SetBuyingPower(money.NewFromInt(20000))
+	for symbol, snapshot := range map[string]marketdata.OptionSnapshot{
+		leap: {LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, Greeks: &marketdata.OptionGreeks{Delta: 0.70}},
+		held: {LatestQuote: &marketdata.OptionQuote{BidPrice: 9.00, AskPrice: 9.10}, Greeks: &marketdata.OptionGreeks{Delta: 0.60}},
+	} {
+		if err := b.SetOption(symbol, snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.SetPosition(alpaca.Position{Symbol: held, Qty: decimal.NewFromInt(3), CostBasis: decimal.NewFromInt(3000)})
+	return b
+}
+
+// buy places an order for 2 contracts of the LEAP at $10.00, $2,000 of premium, returning the limit
+// that rejected it or "" if it was placed
+func buy(t *testing.T, b *risk.Broker) string {
+	t.Helper()
+	_, err := b.PlaceOptionBuyOrder(context.Background(), "qqq-gap.20250304.gap-down", leap, decimal.NewFromInt(2), money.NewFromInt(10))
+	return rejectedBy(t, err)
+}
+
+func rejectedBy(t *testing.T, err error) string {
+	t.Helper()
+	var rejection *risk.Rejection
+	if errors.As(err, &rejection) {
+		return rejection.Limit
+	}
+	if err != nil {
+		t.Fatalf("order failed: %v", err)
+	}
+	return ""
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits risk.Limits
+		// setup adjusts the account before the order
+		setup func(t *testing.T, b *sim.Broker)
+		// rejectedBy is the limit refusing the order, or "" if it is placed
+		rejectedBy string
+	}{
+		{name: "no limits"},
+		{name: "trade notional", limits: risk.Limits{MaxTradeNotional: money.NewFromInt(1500)}, rejectedBy: risk.LimitTradeNotional},
+		{name: "trade notional at the limit", limits: risk.Limits{MaxTradeNotional: money.NewFromInt(2000)}},
+		// $4,715 on QQQ is 20.76% of the equity
+		{name: "underlying percent", limits: risk.Limits{MaxUnderlyingPercent: 20}, rejectedBy: risk.LimitUnderlyingPercent},
+		{name: "underlying percent within the limit", limits: risk.Limits{MaxUnderlyingPercent: 25}},
+		{
+			name:   "another underlying doesn't count",
+			limits: risk.Limits{MaxUnderlyingPercent: 10},
+			setup: func(t *testing.T, b *sim.Broker) {
+				b.SetPosition(alpaca.Position{Symbol: held, Qty: decimal.Zero})
+				b.SetPosition(alpaca.Position{Symbol: "SPY260320C00550000", Qty: decimal.NewFromInt(3), CostBasis: decimal.NewFromInt(3000)})
+			},
+		},
+		{name: "total premium", limits: risk.Limits{MaxTotalPremium: money.NewFromInt(4000)}, rejectedBy: risk.LimitTotalPremium},
+		{name: "total premium within the limit", limits: risk.Limits{MaxTotalPremium: money.NewFromInt(5000)}},
+		// The held 180 delta and the order's 140 make 320
+		{name: "portfolio delta", limits: risk.Limits{MaxPortfolioDelta: 300}, rejectedBy: risk.LimitPortfolioDelta},
+		{name: "portfolio delta within the limit", limits: risk.Limits{MaxPortfolioDelta: 350}},
+		{
+			name:   "missing greeks count as the most delta",
+			limits: risk.Limits{MaxPortfolioDelta: 350},
+			setup: func(t *testing.T, b *sim.Broker) {
+				if err := b.SetOption(held, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9.00, AskPrice: 9.10}}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			rejectedBy: risk.LimitPortfolioDelta,
+		},
+		{
+			name:   "daily loss",
+			limits: risk.Limits{DailyLossLimit: money.NewFromInt(1000)},
+			setup: func(t *testing.T, b *sim.Broker) {
+				b.SetLastEquity(money.NewFromInt(24000))
+			},
+			rejectedBy: risk.LimitDailyLoss,
+		},
+		{
+			name:   "daily loss within the limit",
+			limits: risk.Limits{DailyLossLimit: money.NewFromInt(2000)},
+			setup: func(t *testing.T, b *sim.Broker) {
+				b.SetLastEquity(money.NewFromInt(24000))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := newAccount(t)
+			if tt.setup != nil {
+				tt.setup(t, account)
+			}
+			if got := buy(t, risk.NewBroker(account, tt.limits, statestore.NewMemoryStore())); got != tt.rejectedBy {
+				t.Errorf("rejected by %q, want %q", got, tt.rejectedBy)
+			}
+			bought := false
+			for _, order := range account.Orders() {
+				bought = bought || order.Symbol == leap
+			}
+			if bought != (tt.rejectedBy == "") {
+				t.Errorf("order sent = %v, want %v", bought, tt.rejectedBy == "")
+			}
+		})
+	}
+}
+
+func TestOrdersPerDay(t *testing.T) {
+	ctx := context.Background()
+	account := newAccount(t)
+	b := risk.NewBroker(account, risk.Limits{MaxOrdersPerDay: 1}, statestore.NewMemoryStore())
+
+	if got := buy(t, b); got != "" {
+		t.Fatalf("first order rejected by %q", got)
+	}
+	_, err := b.PlaceOptionBuyOrder(ctx, "qqq-gap.20250304.add", leap, decimal.NewFromInt(1), money.NewFromInt(10))
+	if got := rejectedBy(t, err); got != risk.LimitOrdersPerDay {
+		t.Errorf("second order rejected by %q, want %q", got, risk.LimitOrdersPerDay)
+	}
+
+	// Closing orders aren't counted or limited
+	if _, err := b.PlaceOptionSellOrder(ctx, "qqq-gap.20250304.trim", held, decimal.NewFromInt(1), money.Zero); err != nil {
+		t.Errorf("closing order failed: %v", err)
+	}
+
+	// The count starts over the next trading day
+	account.SetTime(time.Date(2025, 3, 5, 14, 35, 0, 0, time.UTC))
+	_, err = b.PlaceOptionBuyOrder(ctx, "qqq-gap.20250305.gap-down", leap, decimal.NewFromInt(1), money.NewFromInt(10))
+	if got := rejectedBy(t, err); got != "" {
+		t.Errorf("next day's order rejected by %q", got)
+	}
+}
+
+// overlappingStore runs another run's order the first time the count is read for an update, between
+// that read and its write
+type overlappingStore struct {
+	*statestore.MemoryStore
+	overlap func()
+}
+
+func (s *overlappingStore) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	value, version, err := s.MemoryStore.GetVersion(ctx, key)
+	if overlap := s.overlap; overlap != nil {
+		s.overlap = nil
+		overlap()
+	}
+	return value, version, err
+}
+
+func TestOrdersPerDayCountsOverlappingRuns(t *testing.T) {
+	limits := risk.Limits{MaxOrdersPerDay: 2}
+	shared := statestore.NewMemoryStore()
+	store := &overlappingStore{MemoryStore: shared}
+	b := risk.NewBroker(newAccount(t), limits, store)
+	other := risk.NewBroker(newAccount(t), limits, shared)
+	store.overlap = func() {
+		if got := buy(t, other); got != "" {
+			t.Errorf("overlapping run's order rejected by %q", got)
+		}
+	}
+
+	if got := buy(t, b); got != "" {
+		t.Fatalf("first order rejected by %q", got)
+	}
+	// Both runs' orders are counted, so a third breaches the limit
+	if got := buy(t, other); got != risk.LimitOrdersPerDay {
+		t.Errorf("third order rejected by %q, want %q", got, risk.LimitOrdersPerDay)
+	}
+}
+
+func TestBracketOrderIsChecked(t *testing.T) {
+	b := risk.NewBroker(newAccount(t), risk.Limits{MaxTradeNotional: money.NewFromInt(2000)}, statestore.NewMemoryStore())
+	quote := &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}
+
+	// $5,000 buys 5 contracts, $5,000 of premium
+	_, _, err := b.PlaceOptionBracketOrder(context.Background(), "qqq-gap.20250304.gap-down", money.NewFromInt(5000), leap,
+		quote, pricing.PercentOfAsk(100), bracket.Exits{TakeProfitPercent: 50})
+	if got := rejectedBy(t, err); got != risk.LimitTradeNotional {
+		t.Errorf("rejected by %q, want %q", got, risk.LimitTradeNotional)
+	}
+
+	_, _, err = b.PlaceOptionBracketOrder(risk.Exempt(context.Background()), "qqq-gap.20250304.restore", money.NewFromInt(5000), leap,
+		quote, pricing.PercentOfAsk(100), bracket.Exits{TakeProfitPercent: 50})
+	if got := rejectedBy(t, err); got != "" {
+		t.Errorf("exempt order rejected by %q", got)
+	}
+}
+
+func TestReplaceOrderIsChecked(t *testing.T) {
+	ctx := context.Background()
+	account := newAccount(t)
+	account.SetFillRule(sim.FillNever)
+	b := risk.NewBroker(account, risk.Limits{MaxTradeNotional: money.NewFromInt(2000)}, statestore.NewMemoryStore())
+
+	order, err := b.PlaceOptionBuyOrder(ctx, "qqq-gap.20250304.gap-down", leap, decimal.NewFromInt(2), money.Cents(950))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.ReplaceOrder(ctx, order.ID, "qqq-gap.20250304.gap-down-r1", money.Cents(1050))
+	if got := rejectedBy(t, err); got != risk.LimitTradeNotional {
+		t.Errorf("repricing to $2,100 rejected by %q, want %q", got, risk.LimitTradeNotional)
+	}
+	if _, err := b.ReplaceOrder(ctx, order.ID, "qqq-gap.20250304.gap-down-r1", money.NewFromInt(10)); err != nil {
+		t.Errorf("repricing to $2,000 failed: %v", err)
+	}
+}
+
+func TestRollOrderIsChecked(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits risk.Limits
+		// bought and rolled are the limits rejecting 3 contracts of the LEAP bought outright, and bought
+		// by a roll closing the held position
+		bought, rolled string
+	}{
+		// $2,715 held and $3,000 bought, against $3,000 once the held position is sold
+		{name: "total premium", limits: risk.Limits{MaxTotalPremium: money.NewFromInt(4000)}, bought: risk.LimitTotalPremium},
+		// 180 delta held and 210 bought, against 210 once the held position is sold
+		{name: "portfolio delta", limits: risk.Limits{MaxPortfolioDelta: 250}, bought: risk.LimitPortfolioDelta},
+		{name: "trade notional", limits: risk.Limits{MaxTradeNotional: money.NewFromInt(2500)}, bought: risk.LimitTradeNotional, rolled: risk.LimitTradeNotional},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := risk.NewBroker(newAccount(t), tt.limits, statestore.NewMemoryStore())
+
+			_, err := b.PlaceOptionBuyOrder(ctx, "qqq-gap.20250304.buy", leap, decimal.NewFromInt(3), money.NewFromInt(10))
+			if got := rejectedBy(t, err); got != tt.bought {
+				t.Errorf("buy rejected by %q, want %q", got, tt.bought)
+			}
+			_, err = b.PlaceOptionRollOrder(ctx, "qqq-gap.20250304.roll", held, leap, decimal.NewFromInt(3), money.Cents(100))
+			if got := rejectedBy(t, err); got != tt.rolled {
+				t.Errorf("roll rejected by %q, want %q", got, tt.rolled)
+			}
+		})
+	}
+}
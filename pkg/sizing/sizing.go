@@ -0,0 +1,231 @@
+package sizing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// Method is how a model sizes a new position
+type Method string
+
+const (
+	// MethodFixedDollar commits Amount to every position
+	MethodFixedDollar Method = "fixed-dollar"
+	// MethodPercentOfEquity commits EquityPercent percent of the account's equity
+	MethodPercentOfEquity Method = "percent-of-equity"
+	// MethodEqualSlot divides the buying power evenly across the instance's remaining position slots
+	MethodEqualSlot Method = "equal-slot"
+	// MethodVolatilityScaled commits EquityPercent percent of equity scaled by TargetVolatility over the
+	// option's implied volatility, so more volatile options get smaller positions
+	MethodVolatilityScaled Method = "volatility-scaled"
+	// MethodKelly commits the Kelly fraction of equity for KellyWinRate and KellyPayoff, multiplied by
+	// KellyFraction and capped at KellyCapPercent percent
+	MethodKelly Method = "kelly"
+	// MethodDeltaDollar buys enough contracts for the position to move DeltaDollars with a 100% move of
+	// the underlying
+	MethodDeltaDollar Method = "delta-dollar"
+)
+
+// Methods lists every sizing method
+var Methods = []Method{MethodFixedDollar, MethodPercentOfEquity, MethodEqualSlot, MethodVolatilityScaled, MethodKelly, MethodDeltaDollar}
+
+// Account is what a model reads of the account; every broker satisfies it
+type Account interface {
+	// GetNonMarginableBuyingPower retrieves the non-marginable buying power in the account
+	GetNonMarginableBuyingPower(ctx context.Context) (money.Money, error)
+	// GetEquity retrieves the account's equity now and as of the previous trading day's close
+	GetEquity(ctx context.Context) (equity, lastEquity money.Money, err error)
+}
+
+// Model sizes new positions. Every size is capped at the non-marginable buying power.
+type Model struct {
+	Method Method
+	// Amount is the premium committed to each position for MethodFixedDollar
+	Amount money.Money
+	// EquityPercent is the percent of equity committed for MethodPercentOfEquity, and the base size
+	// scaled for MethodVolatilityScaled
+	EquityPercent float64
+	// TargetVolatility is the annualized implied volatility, in percent, at which MethodVolatilityScaled
+	// commits exactly EquityPercent
+	TargetVolatility float64
+	// KellyWinRate is the fraction of trades that win, in (0, 1), for MethodKelly
+	KellyWinRate float64
+	// KellyPayoff is the average win over the average loss for MethodKelly
+	KellyPayoff float64
+	// KellyFraction scales the Kelly fraction, e.g. 0.5 for half Kelly
+	KellyFraction float64
+	// KellyCapPercent is the most percent of equity MethodKelly commits
+	KellyCapPercent float64
+	// DeltaDollars is the share-equivalent dollar exposure of each position for MethodDeltaDollar
+	DeltaDollars money.Money
+}
+
+// EqualSlot returns the model dividing the buying power evenly across the remaining slots
+func EqualSlot() Model {
+	return Model{Method: MethodEqualSlot}
+}
+
+// Validate checks that the model's settings are usable by its method
+func (m Model) Validate() error {
+	switch m.Method {
+	case MethodFixedDollar:
+		if !m.Amount.IsPositive() {
+			return fmt.Errorf("fixed dollar amount must be greater than 0, got %s", m.Amount)
+		}
+	case MethodPercentOfEquity:
+		if m.EquityPercent <= 0 || m.EquityPercent > 100 {
+			return fmt.Errorf("percent of equity must be in (0, 100], got %.2f", m.EquityPercent)
+		}
+	case MethodEqualSlot:
+	case MethodVolatilityScaled:
+		if m.EquityPercent <= 0 || m.EquityPercent > 100 {
+			return fmt.Errorf("percent of equity must be in (0, 100], got %.2f", m.EquityPercent)
+		}
+		if m.TargetVolatility <= 0 {
+			return fmt.Errorf("target volatility must be greater than 0, got %.2f", m.TargetVolatility)
+		}
+	case MethodKelly:
+		if m.KellyWinRate <= 0 || m.KellyWinRate >= 1 {
+			return fmt.Errorf("kelly win rate must be in (0, 1), got %.2f", m.KellyWinRate)
+		}
+		if m.KellyPayoff <= 0 {
+			return fmt.Errorf("kelly payoff must be greater than 0, got %.2f", m.KellyPayoff)
+		}
+		if m.KellyFraction <= 0 || m.KellyFraction > 1 {
+			return fmt.Errorf("kelly fraction must be in (0, 1], got %.2f", m.KellyFraction)
+		}
+		if m.KellyCapPercent <= 0 || m.KellyCapPercent > 100 {
+			return fmt.Errorf("kelly cap percent must be in (0, 100], got %.2f", m.KellyCapPercent)
+		}
+	case MethodDeltaDollar:
+		if !m.DeltaDollars.IsPositive() {
+			return fmt.Errorf("delta dollars must be greater than 0, got %s", m.DeltaDollars)
+		}
+	default:
+		return fmt.Errorf("unknown sizing method %q", m.Method)
+	}
+	return nil
+}
+
+// Candidate is the option a position is being sized for
+type Candidate struct {
+	// OpenSlots is the number of positions the instance may still open, including this one
+	OpenSlots int
+	// Ask is the ask of one contract, per share
+	Ask money.Money
+	// Delta is the option's delta, or 0 when it has no greeks
+	Delta float64
+	// ImpliedVolatility is the option's annualized implied volatility as a fraction, or 0 when unknown
+	ImpliedVolatility float64
+	// UnderlyingPrice is the latest price of the underlying
+	UnderlyingPrice money.Money
+}
+
+// Size is the premium a model commits to a position and how it got there
+type Size struct {
+	Amount money.Money
+	// Basis explains the amount for the logs, e.g. "2.00% of $50000.00 equity"
+	Basis string
+}
+
+// Size returns the premium to commit to the candidate. A model that finds no edge, such as Kelly with
+// a losing win rate and payoff, returns a zero amount.
+func (m Model) Size(ctx context.Context, account Account, candidate Candidate) (Size, error) {
+	if err := m.Validate(); err != nil {
+		return Size{}, err
+	}
+
+	buyingPower, err := account.GetNonMarginableBuyingPower(ctx)
+	if err != nil {
+		return Size{}, fmt.Errorf("failed to get non-marginable buying power: %w", err)
+	}
+
+	var size Size
+	switch m.Method {
+	case MethodFixedDollar:
+		size = Size{Amount: m.Amount, Basis: fmt.Sprintf("fixed $%s", m.Amount)}
+
+	case MethodEqualSlot:
+		if candidate.OpenSlots <= 0 {
+			return Size{}, fmt.Errorf("no remaining active option spots available")
+		}
+		size = Size{
+			Amount: buyingPower.DivInt(int64(candidate.OpenSlots)),
+			Basis:  fmt.Sprintf("buying power $%s / %d remaining spots", buyingPower, candidate.OpenSlots),
+		}
+
+	case MethodPercentOfEquity, MethodVolatilityScaled, MethodKelly:
+		equity, _, err := account.GetEquity(ctx)
+		if err != nil {
+			return Size{}, fmt.Errorf("failed to get equity: %w", err)
+		}
+		size, err = m.equitySize(equity, candidate)
+		if err != nil {
+			return Size{}, err
+		}
+
+	case MethodDeltaDollar:
+		if candidate.Delta <= 0 {
+			return Size{}, fmt.Errorf("delta-dollar sizing needs a positive option delta, got %.2f", candidate.Delta)
+		}
+		if !candidate.UnderlyingPrice.IsPositive() || !candidate.Ask.IsPositive() {
+			return Size{}, fmt.Errorf("delta-dollar sizing needs positive prices: underlying=%s, ask=%s", candidate.UnderlyingPrice, candidate.Ask)
+		}
+		// Each contract carries delta × 100 shares of exposure, bought at the ask
+		perContract := candidate.UnderlyingPrice.Mul(decimal.NewFromFloat(candidate.Delta))
+		size = Size{
+			Amount: candidate.Ask.Mul(m.DeltaDollars.Ratio(perContract)),
+			Basis:  fmt.Sprintf("$%s delta dollars at delta %.2f of $%s", m.DeltaDollars, candidate.Delta, candidate.UnderlyingPrice),
+		}
+	}
+
+	if size.Amount.GreaterThan(buyingPower) {
+		size.Amount = buyingPower
+		size.Basis += fmt.Sprintf(", capped at buying power $%s", buyingPower)
+	}
+	if size.Amount.IsNegative() {
+		size.Amount = money.Zero
+	}
+	return size, nil
+}
+
+// equitySize sizes the candidate as a percent of equity for the equity-based methods
+func (m Model) equitySize(equity money.Money, candidate Candidate) (Size, error) {
+	switch m.Method {
+	case MethodVolatilityScaled:
+		if candidate.ImpliedVolatility <= 0 {
+			return Size{}, fmt.Errorf("volatility-scaled sizing needs the option's implied volatility")
+		}
+		scale := m.TargetVolatility / (candidate.ImpliedVolatility * 100)
+		percent := m.EquityPercent * scale
+		return Size{
+			Amount: equity.Percent(percent),
+			Basis: fmt.Sprintf("%.2f%% of $%s equity scaled by %.0f%%/%.0f%% volatility to %.2f%%",
+				m.EquityPercent, equity, m.TargetVolatility, candidate.ImpliedVolatility*100, percent),
+		}, nil
+
+	case MethodKelly:
+		// The Kelly fraction f = p - (1-p)/b for win rate p and payoff b
+		kelly := m.KellyWinRate - (1-m.KellyWinRate)/m.KellyPayoff
+		percent := kelly * m.KellyFraction * 100
+		if percent > m.KellyCapPercent {
+			percent = m.KellyCapPercent
+		}
+		if percent <= 0 {
+			return Size{Amount: money.Zero, Basis: fmt.Sprintf("no edge at win rate %.2f and payoff %.2f", m.KellyWinRate, m.KellyPayoff)}, nil
+		}
+		return Size{
+			Amount: equity.Percent(percent),
+			Basis:  fmt.Sprintf("%.2f%% Kelly of $%s equity", percent, equity),
+		}, nil
+
+	default:
+		return Size{
+			Amount: equity.Percent(m.EquityPercent),
+			Basis:  fmt.Sprintf("%.2f%% of $%s equity", m.EquityPercent, equity),
+		}, nil
+	}
+}
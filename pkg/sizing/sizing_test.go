@@ -0,0 +1,118 @@
+package sizing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
+
+// account is a fixed account balance
+type account struct {
+	buyingPower money.Money
+	equity      money.Money
+	err         error
+}
+
+func (a account) GetNonMarginableBuyingPower(ctx context.Context) (money.Money, error) {
+	return a.buyingPower, nil
+}
+
+func (a account) GetEquity(ctx context.Context) (money.Money, money.Money, error) {
+	return a.equity, a.equity, a.err
+}
+
+func TestSize(t *testing.T) {
+	rich := account{buyingPower: money.NewFromInt(20000), equity: money.NewFromInt(50000)}
+	// candidate is a $10.00 call with a 0.50 delta and 40% implied volatility on a $400 underlying
+	candidate := Candidate{OpenSlots: 4, Ask: money.NewFromInt(10), Delta: 0.5, ImpliedVolatility: 0.4, UnderlyingPrice: money.NewFromInt(400)}
+
+	tests := []struct {
+		name      string
+		model     Model
+		account   account
+		candidate Candidate
+		want      money.Money
+		wantErr   bool
+	}{
+		{name: "fixed dollar", model: Model{Method: MethodFixedDollar, Amount: money.NewFromInt(3000)}, want: money.NewFromInt(3000)},
+		{name: "fixed dollar capped at buying power", model: Model{Method: MethodFixedDollar, Amount: money.NewFromInt(30000)}, want: money.NewFromInt(20000)},
+		{name: "percent of equity", model: Model{Method: MethodPercentOfEquity, EquityPercent: 5}, want: money.NewFromInt(2500)},
+		{name: "equal slot", model: EqualSlot(), want: money.NewFromInt(5000)},
+		{name: "equal slot without a slot", model: EqualSlot(), candidate: Candidate{}, wantErr: true},
+		// 20% target volatility over 40% halves the 5% base
+		{name: "volatility scaled", model: Model{Method: MethodVolatilityScaled, EquityPercent: 5, TargetVolatility: 20}, want: money.NewFromInt(1250)},
+		{name: "volatility scaled without volatility", model: Model{Method: MethodVolatilityScaled, EquityPercent: 5, TargetVolatility: 20},
+			candidate: Candidate{OpenSlots: 1}, wantErr: true},
+		// f = 0.55 - 0.45/1.5 = 0.25, and a quarter of it is 6.25% of equity
+		{name: "kelly", model: Model{Method: MethodKelly, KellyWinRate: 0.55, KellyPayoff: 1.5, KellyFraction: 0.25, KellyCapPercent: 10},
+			want: money.NewFromInt(3125)},
+		{name: "kelly capped", model: Model{Method: MethodKelly, KellyWinRate: 0.55, KellyPayoff: 1.5, KellyFraction: 1, KellyCapPercent: 10},
+			want: money.NewFromInt(5000)},
+		{name: "kelly without an edge", model: Model{Method: MethodKelly, KellyWinRate: 0.4, KellyPayoff: 1, KellyFraction: 1, KellyCapPercent: 10},
+			want: money.Zero},
+		// 0.50 delta × 100 × $400 is $20,000 per contract, so $30,000 of delta buys 1.5 contracts at $1,000
+		{name: "delta dollar", model: Model{Method: MethodDeltaDollar, DeltaDollars: money.NewFromInt(30000)}, want: money.NewFromInt(1500)},
+		{name: "delta dollar without a delta", model: Model{Method: MethodDeltaDollar, DeltaDollars: money.NewFromInt(30000)},
+			candidate: Candidate{Ask: money.NewFromInt(10), UnderlyingPrice: money.NewFromInt(400)}, wantErr: true},
+		{name: "equity unavailable", model: Model{Method: MethodPercentOfEquity, EquityPercent: 5},
+			account: account{buyingPower: money.NewFromInt(20000), err: errors.New("timeout")}, wantErr: true},
+		{name: "invalid model", model: Model{Method: MethodKelly, KellyWinRate: 1.2, KellyPayoff: 1, KellyFraction: 1, KellyCapPercent: 10}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.account == (account{}) {
+				tt.account = rich
+			}
+			if tt.candidate == (Candidate{}) && !tt.wantErr {
+				tt.candidate = candidate
+			}
+			size, err := tt.model.Size(context.Background(), tt.account, tt.candidate)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Size() = %s, want an error", size.Amount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Size() error = %v", err)
+			}
+			// Percentages are floats, so sizes are compared to the cent
+			if size.Amount.String() != tt.want.String() {
+				t.Errorf("Size() = %s (%s), want %s", size.Amount, size.Basis, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Model{
+		{Method: MethodFixedDollar, Amount: money.NewFromInt(1)},
+		{Method: MethodPercentOfEquity, EquityPercent: 100},
+		EqualSlot(),
+		{Method: MethodVolatilityScaled, EquityPercent: 5, TargetVolatility: 20},
+		{Method: MethodKelly, KellyWinRate: 0.5, KellyPayoff: 1, KellyFraction: 1, KellyCapPercent: 100},
+		{Method: MethodDeltaDollar, DeltaDollars: money.NewFromInt(1)},
+	}
+	for _, model := range valid {
+		if err := model.Validate(); err != nil {
+			t.Errorf("%s: Validate() error = %v", model.Method, err)
+		}
+	}
+
+	invalid := []Model{
+		{Method: MethodFixedDollar},
+		{Method: MethodPercentOfEquity, EquityPercent: 101},
+		{Method: MethodVolatilityScaled, EquityPercent: 5},
+		{Method: MethodKelly, KellyWinRate: 0.5, KellyPayoff: 1, KellyFraction: 0, KellyCapPercent: 10},
+		{Method: MethodKelly, KellyWinRate: 0.5, KellyPayoff: 1, KellyFraction: 1, KellyCapPercent: 0},
+		{Method: MethodDeltaDollar},
+		{Method: "martingale"},
+	}
+	for _, model := range invalid {
+		if err := model.Validate(); err == nil {
+			t.Errorf("%+v: Validate() succeeded", model)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package statestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// MemoryStore keeps state in memory, for backtests and simulations
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+// Ensure MemoryStore satisfies the VersionedStore interface
+var _ VersionedStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Get returns a copy of the value stored under key
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Put stores a copy of value under key
+func (s *MemoryStore) Put(ctx context.Context, key string, value []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Create stores a copy of value under key unless the key is taken
+func (s *MemoryStore) Create(ctx context.Context, key string, value []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		return ErrExists
+	}
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// GetVersion returns a copy of the value stored under key, versioned by its hash
+func (s *MemoryStore) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	return append([]byte(nil), value...), version(value), nil
+}
+
+// PutVersion stores a copy of value under key if the stored value still has version
+func (s *MemoryStore) PutVersion(ctx context.Context, key string, value []byte, ver string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.values[key]
+	if ok != (ver != "") || ok && version(current) != ver {
+		return ErrConflict
+	}
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes the value under key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+	return nil
+}
+
+// version identifies a stored value by its content
+func version(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
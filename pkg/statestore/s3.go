@@ -0,0 +1,316 @@
+package statestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3-compatible object store
+type S3Config struct {
+	// Endpoint is the base URL of an S3-compatible service such as MinIO, e.g. "http://localhost:9000",
+	// addressed path-style. Empty uses AWS S3 in Region, addressed virtual-hosted style.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every key, e.g. "paper/"
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials, such as a Lambda execution role's
+	SessionToken string
+}
+
+// S3Store keeps each key in an object of an S3-compatible bucket. Requests are signed with AWS
+// Signature Version 4. Create and PutVersion rely on conditional writes (If-None-Match and If-Match), which
+// AWS S3 and MinIO support; an object's version is its ETag.
+type S3Store struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// Ensure S3Store satisfies the VersionedStore interface
+var _ VersionedStore = (*S3Store)(nil)
+
+// NewS3Store creates a store for the configured bucket
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key ID and secret access key are required")
+	}
+	if config.Endpoint != "" {
+		endpoint, err := url.Parse(config.Endpoint)
+		if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+		}
+	}
+	return &S3Store{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+// Get downloads the key's object
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := s.GetVersion(ctx, key)
+	return value, err
+}
+
+// GetVersion downloads the key's object and its ETag
+func (s *S3Store) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, "", ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("failed to get state %s: %w", key, responseError(resp))
+	}
+	value, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get state %s: %w", key, err)
+	}
+	return value, resp.Header.Get("ETag"), nil
+}
+
+// Put uploads value as the key's object
+func (s *S3Store) Put(ctx context.Context, key string, value []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, value, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to put state %s: %w", key, responseError(resp))
+	}
+	return nil
+}
+
+// Create uploads value only if the key has no object, using a conditional write
+func (s *S3Store) Create(ctx context.Context, key string, value []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, value, http.Header{"If-None-Match": {"*"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		// 409 means a concurrent conditional write to the key is in flight, which will win
+		return ErrExists
+	default:
+		return fmt.Errorf("failed to create state %s: %w", key, responseError(resp))
+	}
+}
+
+// PutVersion uploads value only if the key's object still has the ETag version, using a conditional write
+func (s *S3Store) PutVersion(ctx context.Context, key string, value []byte, version string) error {
+	header := http.Header{"If-Match": {version}}
+	if version == "" {
+		header = http.Header{"If-None-Match": {"*"}}
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, value, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound:
+		// 404 means the object was deleted since it was read
+		return ErrConflict
+	default:
+		return fmt.Errorf("failed to put state %s: %w", key, responseError(resp))
+	}
+}
+
+// Delete removes the key's object
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete state %s: %w", key, responseError(resp))
+	}
+	return nil
+}
+
+// do sends a signed request for the key's object
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, body, s.now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s state %s: %w", strings.ToLower(method), key, err)
+	}
+	return resp, nil
+}
+
+// objectURL addresses the key's object path-style on a custom endpoint and virtual-hosted style on AWS
+func (s *S3Store) objectURL(key string) *url.URL {
+	path := "/" + s.config.Prefix + key
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", s.config.Bucket, s.config.Region)}
+	if s.config.Endpoint != "" {
+		endpoint, _ := url.Parse(s.config.Endpoint)
+		u = &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host}
+		path = strings.TrimSuffix(endpoint.Path, "/") + "/" + s.config.Bucket + path
+	}
+	u.Path = path
+	u.RawPath = escapePath(path)
+	return u
+}
+
+// sign adds AWS Signature Version 4 headers to req, signing the host, every header already set and the payload hash
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	const algorithm = "AWS4-HMAC-SHA256"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// escapePath URI-encodes every byte of path except unreserved characters and '/', as SigV4 requires
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '/' || isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, escapeQuery(key)+"="+escapeQuery(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(escapePath(s), "/", "%2F")
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError describes a failed S3 response using the start of its error document
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Errorf("S3 returned %s: %s", resp.Status, message)
+	}
+	return fmt.Errorf("S3 returned %s", resp.Status)
+}
@@ -0,0 +1,404 @@
+package statestore_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+const (
+	testAccessKeyID     = "AKIDEXAMPLE"
+	testSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion          = "us-west-2"
+	testBucket          = "athenax-state"
+	// testEndpointPath is the path the stand-in serves under, like MinIO behind a reverse proxy
+	testEndpointPath = "/minio"
+)
+
+// s3Server is a MinIO-style stand-in for an S3-compatible service holding one bucket. It checks every
+// request's Signature Version 4 signature and supports conditional writes the way S3 does: If-None-Match: *
+// fails with 412 when the object exists, If-Match fails with 412 when its ETag differs and 404 when the
+// object is missing, and a conditional write racing another one to the same key fails with 409.
+type s3Server struct {
+	*httptest.Server
+
+	// sessionToken is the security token requests must carry, if set
+	sessionToken string
+
+	mu      sync.Mutex
+	objects map[string]s3Object
+	// writing holds the keys with a conditional write in flight
+	writing map[string]bool
+	// paths are the escaped paths of the requests received
+	paths []string
+}
+
+type s3Object struct {
+	body []byte
+	etag string
+}
+
+func newS3Server(t *testing.T) *s3Server {
+	t.Helper()
+	s := &s3Server{objects: make(map[string]s3Object), writing: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// store returns an S3 store for the stand-in's bucket with the given key prefix
+func (s *s3Server) store(t *testing.T, prefix string) *statestore.S3Store {
+	t.Helper()
+	store, err := statestore.NewS3Store(statestore.S3Config{
+		Endpoint:        s.URL + testEndpointPath,
+		Region:          testRegion,
+		Bucket:          testBucket,
+		Prefix:          prefix,
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: testSecretAccessKey,
+		SessionToken:    s.sessionToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// object returns the body stored under the bucket's key
+func (s *s3Server) object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.objects[key]
+	return object.body, ok
+}
+
+func (s *s3Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s3Error(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+	s.mu.Lock()
+	s.paths = append(s.paths, r.URL.EscapedPath())
+	s.mu.Unlock()
+
+	if code := s.verify(r, body); code != "" {
+		s3Error(w, http.StatusForbidden, code)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, testEndpointPath+"/"+testBucket+"/")
+	if !ok || key == "" {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		object, ok := s.object(key)
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", etag(object))
+		_, _ = w.Write(object)
+	case http.MethodPut:
+		s.put(w, r, key, body)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// put stores body under key if the request's conditions hold. A conditional write stays in flight for a
+// moment before it lands, so concurrent conditional writes to the key conflict.
+func (s *s3Server) put(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	ifNoneMatch, ifMatch := r.Header.Get("If-None-Match"), r.Header.Get("If-Match")
+	conditional := ifNoneMatch != "" || ifMatch != ""
+
+	s.mu.Lock()
+	current, exists := s.objects[key]
+	switch {
+	case conditional && s.writing[key]:
+		s.mu.Unlock()
+		s3Error(w, http.StatusConflict, "ConditionalRequestConflict")
+		return
+	case ifNoneMatch == "*" && exists:
+		s.mu.Unlock()
+		s3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	case ifMatch != "" && !exists:
+		s.mu.Unlock()
+		s3Error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	case ifMatch != "" && current.etag != ifMatch:
+		s.mu.Unlock()
+		s3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+	if conditional {
+		s.writing[key] = true
+		s.mu.Unlock()
+		time.Sleep(time.Millisecond)
+		s.mu.Lock()
+		delete(s.writing, key)
+	}
+	s.objects[key] = s3Object{body: body, etag: etag(body)}
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", etag(body))
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the request's Signature Version 4 signature, returning the S3 error code rejecting it
+func (s *s3Server) verify(r *http.Request, body []byte) string {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return "AccessDenied"
+	}
+	fields := make(map[string]string)
+	for _, field := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return "AccessDenied"
+	}
+	if skew := time.Since(signedAt); skew > 15*time.Minute || skew < -15*time.Minute {
+		return "RequestTimeTooSkewed"
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if fields["Credential"] != testAccessKeyID+"/"+scope {
+		return "InvalidAccessKeyId"
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != hexSHA256(body) {
+		return "XAmzContentSHA256Mismatch"
+	}
+	if s.sessionToken != "" && r.Header.Get("X-Amz-Security-Token") != s.sessionToken {
+		return "InvalidToken"
+	}
+
+	// Every x-amz- header and the host must be signed
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return "SignatureDoesNotMatch"
+	}
+	required := []string{"host"}
+	for name := range r.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			required = append(required, name)
+		}
+	}
+	for _, name := range required {
+		if i := sort.SearchStrings(signed, name); i == len(signed) || signed[i] != name {
+			return "AccessDenied"
+		}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		value := r.Host
+		if name != "host" {
+			var values []string
+			for _, value := range r.Header.Values(name) {
+				values = append(values, strings.Join(strings.Fields(value), " "))
+			}
+			value = strings.Join(values, ",")
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		hexSHA256(body),
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + testSecretAccessKey)
+	for _, part := range []string{amzDate[:8], testRegion, "s3", "aws4_request"} {
+		key = hmacSum(key, part)
+	}
+	want := hex.EncodeToString(hmacSum(key, stringToSign))
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(want)) {
+		return "SignatureDoesNotMatch"
+	}
+	return ""
+}
+
+// s3Error writes an S3 error document
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code></Error>", code)
+}
+
+// etag is the quoted MD5 hash S3 gives an object uploaded in one part
+func etag(body []byte) string {
+	sum := md5.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSum(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func TestS3Addressing(t *testing.T) {
+	ctx := context.Background()
+	server := newS3Server(t)
+	store := server.store(t, "paper/")
+
+	// The space is escaped in the path and in the signed canonical request alike
+	key := "strategies/qqq gap/state~1.json"
+	if err := store.Put(ctx, key, []byte("{}")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := store.Get(ctx, key); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	want := testEndpointPath + "/" + testBucket + "/paper/strategies/qqq%20gap/state~1.json"
+	if len(server.paths) != 2 {
+		t.Fatalf("%d requests, want 2", len(server.paths))
+	}
+	for _, path := range server.paths {
+		if path != want {
+			t.Errorf("request path = %s, want %s", path, want)
+		}
+	}
+	if _, ok := server.object("paper/" + key); !ok {
+		t.Errorf("object paper/%s not stored", key)
+	}
+}
+
+func TestS3Signing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("session token", func(t *testing.T) {
+		server := newS3Server(t)
+		server.sessionToken = "session-token"
+		if err := server.store(t, "").Put(ctx, "journal.db", []byte("rows")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		server := newS3Server(t)
+		store, err := statestore.NewS3Store(statestore.S3Config{
+			Endpoint:        server.URL + testEndpointPath,
+			Region:          testRegion,
+			Bucket:          testBucket,
+			AccessKeyID:     testAccessKeyID,
+			SecretAccessKey: "not-the-secret",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Put(ctx, "journal.db", []byte("rows"))
+		if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+			t.Fatalf("Put() error = %v, want the signature rejected", err)
+		}
+		if _, ok := server.object("journal.db"); ok {
+			t.Error("object stored despite the bad signature")
+		}
+	})
+}
+
+func TestS3ConditionalStatus(t *testing.T) {
+	// Each case answers the request with status, as S3 and MinIO do under the named condition
+	tests := []struct {
+		name    string
+		status  int
+		create  error
+		version error
+	}{
+		{name: "written", status: http.StatusOK},
+		{name: "precondition failed", status: http.StatusPreconditionFailed, create: statestore.ErrExists, version: statestore.ErrConflict},
+		{name: "concurrent conditional write", status: http.StatusConflict, create: statestore.ErrExists, version: statestore.ErrConflict},
+		{name: "object deleted since read", status: http.StatusNotFound, version: statestore.ErrConflict},
+		{name: "server error", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(server.Close)
+			store, err := statestore.NewS3Store(statestore.S3Config{
+				Endpoint: server.URL, Bucket: testBucket, AccessKeyID: testAccessKeyID, SecretAccessKey: testSecretAccessKey,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			check := func(method string, err, want error) {
+				t.Helper()
+				switch {
+				case tt.status == http.StatusOK && err != nil:
+					t.Errorf("%s() error = %v, want success", method, err)
+				case want != nil && !errors.Is(err, want):
+					t.Errorf("%s() error = %v, want %v", method, err, want)
+				case tt.status != http.StatusOK && want == nil && (err == nil || !strings.Contains(err.Error(), fmt.Sprint(tt.status))):
+					t.Errorf("%s() error = %v, want the %d reported", method, err, tt.status)
+				}
+			}
+			check("Create", store.Create(ctx, "key", nil), tt.create)
+			check("PutVersion", store.PutVersion(ctx, "key", nil, `"etag"`), tt.version)
+		})
+	}
+}
+
+func TestNewS3Store(t *testing.T) {
+	valid := statestore.S3Config{Bucket: testBucket, AccessKeyID: testAccessKeyID, SecretAccessKey: testSecretAccessKey}
+	tests := []struct {
+		name    string
+		change  func(c *statestore.S3Config)
+		wantErr string
+	}{
+		{name: "AWS", change: func(c *statestore.S3Config) {}},
+		{name: "endpoint", change: func(c *statestore.S3Config) { c.Endpoint = "http://localhost:9000" }},
+		{name: "no bucket", change: func(c *statestore.S3Config) { c.Bucket = "" }, wantErr: "bucket is required"},
+		{name: "no secret", change: func(c *statestore.S3Config) { c.SecretAccessKey = "" }, wantErr: "secret access key are required"},
+		{name: "endpoint without scheme", change: func(c *statestore.S3Config) { c.Endpoint = "localhost:9000" }, wantErr: "invalid S3 endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.change(&config)
+			_, err := statestore.NewS3Store(config)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("NewS3Store() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by Get when the key has no value
+	ErrNotFound = errors.New("state not found")
+	// ErrExists is returned by Create when the key already has a value
+	ErrExists = errors.New("state already exists")
+	// ErrConflict is returned by PutVersion when the value changed since its version was read
+	ErrConflict = errors.New("state changed since it was read")
+)
+
+// StateStore persists small blobs of state by key: the trade journal, idempotency keys and
+// strategy state. Keys are slash-separated paths such as "strategies/qqq-gap/state.json".
+type StateStore interface {
+	// Get returns the value stored under key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores value under key, replacing any existing value
+	Put(ctx context.Context, key string, value []byte) error
+
+	// Create stores value under key only if the key has no value yet, returning ErrExists otherwise.
+	// It is atomic, so of several concurrent callers exactly one succeeds.
+	Create(ctx context.Context, key string, value []byte) error
+
+	// Delete removes the value under key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// VersionedStore is implemented by stores that can replace a value only if nobody else replaced it since
+// it was read, for values such as the trade journal that a run downloads, changes and uploads again
+type VersionedStore interface {
+	StateStore
+
+	// GetVersion returns the value stored under key with an opaque version identifying it, or ErrNotFound
+	GetVersion(ctx context.Context, key string) ([]byte, string, error)
+
+	// PutVersion stores value under key only if the stored value still has version, returning ErrConflict
+	// otherwise. An empty version requires the key to have no value yet.
+	PutVersion(ctx context.Context, key string, value []byte, version string) error
+}
+
+const (
+	// updateAttempts is how many times Update reads and writes a value before giving up to other writers
+	updateAttempts = 10
+	// updateBackoff is the longest Update waits after its first conflict, doubling with each one after,
+	// so that writers which conflicted don't keep reading the value while another write is in flight
+	updateBackoff = 10 * time.Millisecond
+)
+
+// Update replaces the value under key with what update makes of the stored value, which is nil when the
+// key has none; update returning a nil value leaves the key as it is. On a VersionedStore the value is
+// written only if nobody replaced it since it was read, and update runs again on the newer value after a
+// short random wait otherwise, so overlapping runs don't lose each other's changes. Other stores write it
+// unconditionally.
+func Update(ctx context.Context, store StateStore, key string, update func(value []byte) ([]byte, error)) error {
+	versioned, ok := store.(VersionedStore)
+	if !ok {
+		value, err := store.Get(ctx, key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if value, err = update(value); err != nil || value == nil {
+			return err
+		}
+		return store.Put(ctx, key, value)
+	}
+
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		value, version, err := versioned.GetVersion(ctx, key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if value, err = update(value); err != nil || value == nil {
+			return err
+		}
+		err = versioned.PutVersion(ctx, key, value, version)
+		if !errors.Is(err, ErrConflict) {
+			return err
+		}
+		select {
+		case <-time.After(rand.N(updateBackoff << attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("%s changed on each of %d attempts to update it: %w", key, updateAttempts, ErrConflict)
+}
+
+// ValidateKey checks that key is a relative slash-separated path without empty, "." or ".." segments
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("state key cannot be empty")
+	}
+	for _, segment := range strings.Split(key, "/") {
+		switch segment {
+		case "", ".", "..":
+			return fmt.Errorf("invalid state key %q", key)
+		}
+	}
+	if strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("invalid state key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package statestore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// stores builds a fresh, empty store of each kind
+var stores = []struct {
+	name string
+	new  func(t *testing.T) statestore.StateStore
+}{
+	{
+		name: "local",
+		new: func(t *testing.T) statestore.StateStore {
+			store, err := statestore.NewLocalStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	},
+	{
+		name: "memory",
+		new:  func(t *testing.T) statestore.StateStore { return statestore.NewMemoryStore() },
+	},
+	{
+		name: "s3",
+		new:  func(t *testing.T) statestore.StateStore { return newS3Server(t).store(t, "paper/") },
+	},
+}
+
+const testKey = "strategies/qqq-gap/state.json"
+
+// get returns the value under key, failing the test on an error
+func get(t *testing.T, store statestore.StateStore, key string) []byte {
+	t.Helper()
+	value, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", key, err)
+	}
+	return value
+}
+
+func TestStateStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store statestore.StateStore)
+	}{
+		{
+			name: "get missing",
+			run: func(t *testing.T, store statestore.StateStore) {
+				if _, err := store.Get(context.Background(), testKey); !errors.Is(err, statestore.ErrNotFound) {
+					t.Errorf("Get() error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "put replaces",
+			run: func(t *testing.T, store statestore.StateStore) {
+				ctx := context.Background()
+				for _, value := range []string{"first", "second"} {
+					if err := store.Put(ctx, testKey, []byte(value)); err != nil {
+						t.Fatalf("Put(%s) error = %v", value, err)
+					}
+					if got := get(t, store, testKey); string(got) != value {
+						t.Errorf("Get() = %q, want %q", got, value)
+					}
+				}
+			},
+		},
+		{
+			name: "values are copies",
+			run: func(t *testing.T, store statestore.StateStore) {
+				value := []byte("state")
+				if err := store.Put(context.Background(), testKey, value); err != nil {
+					t.Fatal(err)
+				}
+				value[0] = 'X'
+				got := get(t, store, testKey)
+				got[1] = 'X'
+				if got := get(t, store, testKey); string(got) != "state" {
+					t.Errorf("Get() = %q after changing the put and got slices, want %q", got, "state")
+				}
+			},
+		},
+		{
+			name: "create once",
+			run: func(t *testing.T, store statestore.StateStore) {
+				ctx := context.Background()
+				if err := store.Create(ctx, testKey, []byte("first")); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				if err := store.Create(ctx, testKey, []byte("second")); !errors.Is(err, statestore.ErrExists) {
+					t.Errorf("second Create() error = %v, want ErrExists", err)
+				}
+				if got := get(t, store, testKey); string(got) != "first" {
+					t.Errorf("Get() = %q, want the first value", got)
+				}
+			},
+		},
+		{
+			name: "concurrent create has one winner",
+			run: func(t *testing.T, store statestore.StateStore) {
+				const callers = 16
+				errs := make([]error, callers)
+				var wg sync.WaitGroup
+				for i := range errs {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						errs[i] = store.Create(context.Background(), testKey, []byte(strconv.Itoa(i)))
+					}()
+				}
+				wg.Wait()
+
+				winner := -1
+				for i, err := range errs {
+					switch {
+					case err == nil && winner >= 0:
+						t.Errorf("callers %d and %d both created the key", winner, i)
+					case err == nil:
+						winner = i
+					case !errors.Is(err, statestore.ErrExists):
+						t.Errorf("caller %d: Create() error = %v, want ErrExists", i, err)
+					}
+				}
+				if winner < 0 {
+					t.Fatal("no caller created the key")
+				}
+				if got := get(t, store, testKey); string(got) != strconv.Itoa(winner) {
+					t.Errorf("Get() = %q, want the winner's %d", got, winner)
+				}
+			},
+		},
+		{
+			name: "delete",
+			run: func(t *testing.T, store statestore.StateStore) {
+				ctx := context.Background()
+				if err := store.Put(ctx, testKey, []byte("state")); err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 2; i++ {
+					if err := store.Delete(ctx, testKey); err != nil {
+						t.Fatalf("Delete() #%d error = %v", i+1, err)
+					}
+				}
+				if _, err := store.Get(ctx, testKey); !errors.Is(err, statestore.ErrNotFound) {
+					t.Errorf("Get() error = %v after Delete, want ErrNotFound", err)
+				}
+				if err := store.Create(ctx, testKey, []byte("again")); err != nil {
+					t.Errorf("Create() error = %v after Delete", err)
+				}
+			},
+		},
+		{
+			name: "invalid keys",
+			run: func(t *testing.T, store statestore.StateStore) {
+				ctx := context.Background()
+				for _, key := range []string{"", "../journal.db", "strategies//state.json", "strategies/./state.json", `strategies\state.json`} {
+					if err := store.Put(ctx, key, nil); err == nil {
+						t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+					}
+					if err := store.Create(ctx, key, nil); err == nil {
+						t.Errorf("Create(%q) succeeded, want an invalid key error", key)
+					}
+				}
+			},
+		},
+	}
+
+	for _, kind := range stores {
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				tt.run(t, kind.new(t))
+			})
+		}
+	}
+}
+
+func TestVersionedStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store statestore.VersionedStore)
+	}{
+		{
+			name: "get version missing",
+			run: func(t *testing.T, store statestore.VersionedStore) {
+				if _, _, err := store.GetVersion(context.Background(), testKey); !errors.Is(err, statestore.ErrNotFound) {
+					t.Errorf("GetVersion() error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "empty version creates",
+			run: func(t *testing.T, store statestore.VersionedStore) {
+				ctx := context.Background()
+				if err := store.PutVersion(ctx, testKey, []byte("first"), ""); err != nil {
+					t.Fatalf("PutVersion() error = %v", err)
+				}
+				if err := store.PutVersion(ctx, testKey, []byte("second"), ""); !errors.Is(err, statestore.ErrConflict) {
+					t.Errorf("second PutVersion() error = %v, want ErrConflict", err)
+				}
+				if got := get(t, store, testKey); string(got) != "first" {
+					t.Errorf("Get() = %q, want the first value", got)
+				}
+			},
+		},
+		{
+			name: "stale version conflicts",
+			run: func(t *testing.T, store statestore.VersionedStore) {
+				ctx := context.Background()
+				if err := store.Put(ctx, testKey, []byte("first")); err != nil {
+					t.Fatal(err)
+				}
+				value, read, err := store.GetVersion(ctx, testKey)
+				if err != nil || string(value) != "first" {
+					t.Fatalf("GetVersion() = %q, %v", value, err)
+				}
+				if err := store.PutVersion(ctx, testKey, []byte("second"), read); err != nil {
+					t.Fatalf("PutVersion() error = %v", err)
+				}
+				if err := store.PutVersion(ctx, testKey, []byte("third"), read); !errors.Is(err, statestore.ErrConflict) {
+					t.Errorf("PutVersion() with the stale version error = %v, want ErrConflict", err)
+				}
+				value, current, err := store.GetVersion(ctx, testKey)
+				if err != nil || string(value) != "second" || current == read {
+					t.Errorf("GetVersion() = %q version %q, %v; want the second value under a new version", value, current, err)
+				}
+			},
+		},
+		{
+			name: "deleted since read conflicts",
+			run: func(t *testing.T, store statestore.VersionedStore) {
+				ctx := context.Background()
+				if err := store.Put(ctx, testKey, []byte("first")); err != nil {
+					t.Fatal(err)
+				}
+				_, read, err := store.GetVersion(ctx, testKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Delete(ctx, testKey); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.PutVersion(ctx, testKey, []byte("second"), read); !errors.Is(err, statestore.ErrConflict) {
+					t.Errorf("PutVersion() error = %v, want ErrConflict", err)
+				}
+			},
+		},
+		{
+			name: "concurrent read-modify-write loses no update",
+			run: func(t *testing.T, store statestore.VersionedStore) {
+				const writers = 8
+				var wg sync.WaitGroup
+				for i := 0; i < writers; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if err := appendLine(context.Background(), store, strconv.Itoa(i)); err != nil {
+							t.Error(err)
+						}
+					}()
+				}
+				wg.Wait()
+
+				lines := bytes.Fields(get(t, store, testKey))
+				if len(lines) != writers {
+					t.Errorf("%d lines written, want %d: %q", len(lines), writers, lines)
+				}
+			},
+		},
+	}
+
+	for _, kind := range stores {
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				store, ok := kind.new(t).(statestore.VersionedStore)
+				if !ok {
+					t.Skipf("%s store is not versioned", kind.name)
+				}
+				tt.run(t, store)
+			})
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	for _, kind := range stores {
+		t.Run(kind.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := kind.new(t)
+
+			appendA := func(value []byte) ([]byte, error) { return append(value, 'a'), nil }
+			if err := statestore.Update(ctx, store, testKey, appendA); err != nil {
+				t.Fatalf("Update() of a missing key error = %v", err)
+			}
+			if got := get(t, store, testKey); string(got) != "a" {
+				t.Errorf("Get() = %q, want %q", got, "a")
+			}
+
+			// Neither a nil value nor an error writes anything
+			if err := statestore.Update(ctx, store, testKey, func([]byte) ([]byte, error) { return nil, nil }); err != nil {
+				t.Errorf("Update() leaving the value error = %v", err)
+			}
+			failed := errors.New("bad value")
+			if err := statestore.Update(ctx, store, testKey, func([]byte) ([]byte, error) { return []byte("b"), failed }); !errors.Is(err, failed) {
+				t.Errorf("Update() error = %v, want %v", err, failed)
+			}
+			if got := get(t, store, testKey); string(got) != "a" {
+				t.Errorf("Get() = %q, want it left at %q", got, "a")
+			}
+
+			if _, ok := store.(statestore.VersionedStore); !ok {
+				return
+			}
+			const writers = 8
+			var wg sync.WaitGroup
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := statestore.Update(ctx, store, testKey, appendA); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := get(t, store, testKey); len(got) != writers+1 {
+				t.Errorf("Get() = %q after %d concurrent updates, want %d", got, writers, writers+1)
+			}
+		})
+	}
+}
+
+// appendLine adds line to the value under testKey, reading it again whenever another writer got there first
+func appendLine(ctx context.Context, store statestore.VersionedStore, line string) error {
+	for attempt := 0; attempt < 100; attempt++ {
+		value, version, err := store.GetVersion(ctx, testKey)
+		if err != nil && !errors.Is(err, statestore.ErrNotFound) {
+			return err
+		}
+		err = store.PutVersion(ctx, testKey, append(value, line+"\n"...), version)
+		if !errors.Is(err, statestore.ErrConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("line %s: too many conflicts", line)
+}
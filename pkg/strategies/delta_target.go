@@ -0,0 +1,522 @@
+package strategies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+	"github.com/vignesh-goutham/AthenaX/pkg/sizing"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// DeltaTargetParams holds the tunable settings of the DeltaTarget strategy
+type DeltaTargetParams struct {
+	// Ticker is the underlying whose delta is held with call LEAPs
+	Ticker string
+	// TargetDelta is the share-equivalent delta to hold, e.g. 200 for the exposure of 200 shares
+	TargetDelta float64
+	// Band is how far the delta may drift either side of the target before the strategy trades
+	Band float64
+	// MinDelta is the minimum delta of the call LEAP to add
+	MinDelta float64
+	// LeapsMinMonths is how many months out the added option must expire
+	LeapsMinMonths int
+	// MaxContractsPerRun caps the contracts bought or sold in a single run
+	MaxContractsPerRun int
+	// Pricing prices the limit of contracts added from the option's bid and ask
+	Pricing pricing.Policy
+	// Sizing caps the premium of each addition, which the delta gap caps in turn. An addition is the only
+	// slot equal-slot sizing divides the buying power over.
+	Sizing sizing.Model
+}
+
+// DeltaTargetName is the registered name of the DeltaTarget strategy
+const DeltaTargetName = "delta-target"
+
+func init() {
+	Register(Definition{
+		Name: DeltaTargetName,
+		Description: "Holds a target share-equivalent delta in the ticker with call LEAPs, adding LEAPs with " +
+			"delta >= min_delta when the delta falls below the band and trimming the highest-delta contracts above it",
+		Params: slices.Concat([]Param{
+			{Name: "ticker", Type: ParamString, Default: "QQQ", Description: "Underlying to hold delta in with call LEAPs"},
+			{Name: "target_delta", Type: ParamFloat, Default: "200", Description: "Share-equivalent delta to hold (delta x contracts x 100)"},
+			{Name: "band", Type: ParamFloat, Default: "50", Description: "Delta either side of the target within which nothing is traded; at least 50, half a contract"},
+			{Name: "min_delta", Type: ParamFloat, Default: "0.60", Description: "Minimum delta of the call LEAP to add"},
+			{Name: "leaps_min_months", Type: ParamInt, Default: "11", Description: "Minimum months to expiry of the call LEAP to add"},
+			{Name: "max_contracts_per_run", Type: ParamInt, Default: "5", Description: "Most contracts bought or sold in one run"},
+		}, pricingParams, sizingParams),
+		Validate: func(params Params) error {
+			return deltaTargetParams(params).Validate()
+		},
+		New: func(id string, broker broker.Broker, params Params) (Strategy, error) {
+			return NewDeltaTarget(id, broker, deltaTargetParams(params)), nil
+		},
+	})
+}
+
+func deltaTargetParams(params Params) DeltaTargetParams {
+	return DeltaTargetParams{
+		Ticker:             params.String("ticker"),
+		TargetDelta:        params.Float("target_delta"),
+		Band:               params.Float("band"),
+		MinDelta:           params.Float("min_delta"),
+		LeapsMinMonths:     params.Int("leaps_min_months"),
+		MaxContractsPerRun: params.Int("max_contracts_per_run"),
+		Pricing:            pricingPolicy(params),
+		Sizing:             sizingModel(params),
+	}
+}
+
+// Validate checks that the parameters are usable
+func (p DeltaTargetParams) Validate() error {
+	if p.Ticker == "" {
+		return fmt.Errorf("ticker is required")
+	}
+	if p.TargetDelta <= 0 {
+		return fmt.Errorf("target_delta must be greater than 0, got %v", p.TargetDelta)
+	}
+	// A contract carries at most 100 delta, so a band of half that always has room for the contract
+	// that brings the delta back into it, and the strategy never trades from one side to the other
+	if p.Band < money.ContractMultiplier/2 {
+		return fmt.Errorf("band must be at least %d, got %v", money.ContractMultiplier/2, p.Band)
+	}
+	if p.Band >= p.TargetDelta {
+		return fmt.Errorf("band must be less than target_delta %v, got %v", p.TargetDelta, p.Band)
+	}
+	if p.MinDelta <= 0 || p.MinDelta > 1 {
+		return fmt.Errorf("min_delta must be in (0, 1], got %v", p.MinDelta)
+	}
+	if p.LeapsMinMonths <= 0 {
+		return fmt.Errorf("leaps_min_months must be greater than 0, got %d", p.LeapsMinMonths)
+	}
+	if p.MaxContractsPerRun <= 0 {
+		return fmt.Errorf("max_contracts_per_run must be greater than 0, got %d", p.MaxContractsPerRun)
+	}
+	if err := p.Pricing.Validate(); err != nil {
+		return fmt.Errorf("invalid entry pricing: %w", err)
+	}
+	if err := p.Sizing.Validate(); err != nil {
+		return fmt.Errorf("invalid sizing: %w", err)
+	}
+	return nil
+}
+
+// State keys of the DeltaTarget strategy
+const (
+	// stateLastRebalanceDate is the exchange date, YYYY-MM-DD, of the instance's last submitted orders
+	stateLastRebalanceDate = "last_rebalance_date"
+)
+
+// DeltaTarget keeps the instance's delta in the ticker within a band around a target, the "LEAPs as
+// stock replacement" approach. Only the positions opened by the instance's own orders count, and it
+// trades at most once per trading day.
+type DeltaTarget struct {
+	id     string
+	broker broker.Broker
+	params DeltaTargetParams
+	state  *State
+	// journal is nil when the trade journal is disabled
+	journal Journal
+	// claims is nil when there is no state store to claim client order IDs in
+	claims statestore.StateStore
+	// exits is nil when client-managed exits aren't enforced
+	exits *bracket.Monitor
+}
+
+// NewDeltaTarget creates a new DeltaTarget strategy instance
+func NewDeltaTarget(id string, broker broker.Broker, params DeltaTargetParams) *DeltaTarget {
+	return &DeltaTarget{
+		id:     id,
+		broker: broker,
+		params: params,
+		state:  NewState(nil),
+	}
+}
+
+// SetState sets the state persisted between runs
+func (s *DeltaTarget) SetState(state *State) {
+	s.state = state
+}
+
+// SetJournal sets the journal checked for orders already placed today
+func (s *DeltaTarget) SetJournal(journal Journal) {
+	s.journal = journal
+}
+
+// SetClaimStore sets the store each order's client order ID is claimed in before it is submitted
+func (s *DeltaTarget) SetClaimStore(store statestore.StateStore) {
+	s.claims = store
+}
+
+// SetExitMonitor sets the monitor whose client-managed exits on a trimmed position are dropped
+func (s *DeltaTarget) SetExitMonitor(monitor *bracket.Monitor) {
+	s.exits = monitor
+}
+
+// ID returns the instance ID
+func (s *DeltaTarget) ID() string {
+	return s.id
+}
+
+// Name returns the strategy name
+func (s *DeltaTarget) Name() string {
+	return DeltaTargetName
+}
+
+// holding is an option position with the delta and bid of one of its contracts
+type holding struct {
+	symbol string
+	qty    int64
+	// delta is the share-equivalent delta of one contract, delta x 100
+	delta float64
+	bid   money.Money
+}
+
+func (s *DeltaTarget) Run(ctx context.Context) (*Result, error) {
+	result := NewResult()
+	if lastRebalance := s.state.Get(stateLastRebalanceDate); lastRebalance != "" {
+		result.Diagnostics[stateLastRebalanceDate] = lastRebalance
+	}
+
+	// Step 1: Measure the delta held in the ticker
+	holdings, current, err := s.holdings(ctx)
+	if err != nil {
+		return result, err
+	}
+	low, high := s.params.TargetDelta-s.params.Band, s.params.TargetDelta+s.params.Band
+	result.Diagnostics["open_positions"] = len(holdings)
+	result.Diagnostics["portfolio_delta"] = current
+	result.Diagnostics["target_delta"] = s.params.TargetDelta
+	result.Signals = append(result.Signals,
+		Signal{Name: "delta_below_band", Value: current, Threshold: low, Triggered: current < low},
+		Signal{Name: "delta_above_band", Value: current, Threshold: high, Triggered: current > high},
+	)
+
+	// Step 2: Within the band there's nothing to do
+	if current >= low && current <= high {
+		result.Decision = DecisionNoSignal
+		result.Message = fmt.Sprintf("%s delta %.0f is within %.0f-%.0f of the %.0f target",
+			s.params.Ticker, current, low, high, s.params.TargetDelta)
+		log.Printf("[%s] %s", s.id, result.Message)
+		return result, nil
+	}
+
+	log.Printf("[%s] %s delta %.0f is outside %.0f-%.0f, rebalancing toward %.0f",
+		s.id, s.params.Ticker, current, low, high, s.params.TargetDelta)
+
+	day := tradingDay(s.broker.Now())
+	if s.state.Get(stateLastRebalanceDate) == day.Format("2006-01-02") {
+		return s.skipActed(result, current, "already rebalanced today"), nil
+	}
+	if s.journal != nil {
+		ordered, err := s.journal.OrderedOn(ctx, s.id, day)
+		if err != nil {
+			return result, fmt.Errorf("failed to check the journal for today's orders: %w", err)
+		}
+		if ordered {
+			return s.skipActed(result, current, "the journal already records an order today"), nil
+		}
+	}
+
+	// Step 3: Add contracts below the band, trim the highest-delta ones above it
+	if current < low {
+		err = s.add(ctx, result, day, current)
+	} else {
+		err = s.trim(ctx, result, day, holdings, current)
+	}
+	if err != nil {
+		return result, err
+	}
+	if result.Decision == DecisionOrdered && !result.DryRun() {
+		s.state.Set(stateLastRebalanceDate, day.Format("2006-01-02"))
+	}
+	return result, nil
+}
+
+// holdings returns the instance's option positions on the ticker and their total share-equivalent delta
+func (s *DeltaTarget) holdings(ctx context.Context) ([]holding, float64, error) {
+	positions, err := s.broker.GetInstanceOptionsPositions(ctx, s.id, s.params.Ticker)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get %s option positions: %w", s.params.Ticker, err)
+	}
+
+	var holdings []holding
+	var total float64
+	for _, position := range positions {
+		snapshot, err := s.broker.GetOptionSnapshot(ctx, position.Symbol)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get snapshot of %s: %w", position.Symbol, err)
+		}
+		// The delta decides what to trade, so a position without one can't be guessed at
+		if snapshot.Greeks == nil {
+			return nil, 0, fmt.Errorf("no greeks for %s to measure its delta", position.Symbol)
+		}
+		held := holding{
+			symbol: position.Symbol,
+			qty:    position.Qty.IntPart(),
+			delta:  snapshot.Greeks.Delta * money.ContractMultiplier,
+		}
+		if snapshot.LatestQuote != nil {
+			held.bid = money.NewFromFloat(snapshot.LatestQuote.BidPrice)
+		}
+		holdings = append(holdings, held)
+		total += held.delta * float64(held.qty)
+	}
+	return holdings, total, nil
+}
+
+// add buys the contracts of the LEAP GetCallLeapsByDelta selects that bring the delta closest to the target
+func (s *DeltaTarget) add(ctx context.Context, result *Result, day time.Time, current float64) error {
+	optionSymbol, optionSnapshot, err := s.broker.GetCallLeapsByDelta(ctx, s.params.Ticker, s.params.MinDelta, s.params.LeapsMinMonths)
+	if err != nil {
+		return fmt.Errorf("failed to get call LEAPS option for %s: %w", s.params.Ticker, err)
+	}
+	result.Diagnostics["option_symbol"] = optionSymbol
+	if optionSnapshot.Greeks == nil || optionSnapshot.Greeks.Delta <= 0 {
+		return fmt.Errorf("no delta for %s to size the addition", optionSymbol)
+	}
+	if optionSnapshot.LatestQuote == nil {
+		return fmt.Errorf("no quote for %s to price the addition", optionSymbol)
+	}
+	contractDelta := optionSnapshot.Greeks.Delta * money.ContractMultiplier
+	bid, ask := money.NewFromFloat(optionSnapshot.LatestQuote.BidPrice), money.NewFromFloat(optionSnapshot.LatestQuote.AskPrice)
+	result.Diagnostics["option_delta"] = optionSnapshot.Greeks.Delta
+	result.Diagnostics["option_bid"] = bid
+	result.Diagnostics["option_ask"] = ask
+	result.Diagnostics["spread_percent"] = pricing.SpreadPercent(bid, ask)
+
+	limitPrice, err := s.params.Pricing.LimitPrice(bid, ask)
+	if errors.Is(err, pricing.ErrSpreadTooWide) {
+		result.Decision = DecisionSkippedWideSpread
+		result.Message = fmt.Sprintf("%s delta %.0f is below the band but %s: %v", s.params.Ticker, current, optionSymbol, err)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to price %s: %w", optionSymbol, err)
+	}
+
+	// The nearest whole number of contracts to the target, which the band always has room for
+	contracts := max(int64(math.Round((s.params.TargetDelta-current)/contractDelta)), 1)
+	contracts = min(contracts, int64(s.params.MaxContractsPerRun))
+
+	size, err := s.size(ctx, optionSnapshot)
+	if err != nil {
+		return fmt.Errorf("failed to calculate investment size: %w", err)
+	}
+	result.Diagnostics["sizing"] = string(s.params.Sizing.Method)
+	result.Diagnostics["investment_size"] = size.Amount
+	contractCost := limitPrice.MulInt(money.ContractMultiplier)
+	if sized := size.Amount.Ratio(contractCost).IntPart(); sized < contracts {
+		log.Printf("[%s] Sizing (%s) covers %d of %d contracts at $%s: %s = $%s",
+			s.id, s.params.Sizing.Method, sized, contracts, limitPrice, size.Basis, size.Amount)
+		contracts = sized
+	}
+	result.Diagnostics["contracts"] = contracts
+	if contracts <= 0 {
+		result.Decision = DecisionSkippedNoSize
+		result.Message = fmt.Sprintf("%s delta %.0f is below the band but %s sizing allots $%s, less than one contract of %s at $%s",
+			s.params.Ticker, current, s.params.Sizing.Method, size.Amount, optionSymbol, limitPrice)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return nil
+	}
+
+	clientOrderID := alpaca.NewClientOrderID(s.id, day, "add-delta")
+	if acted, err := s.submitted(ctx, clientOrderID); err != nil || acted != "" {
+		if err == nil {
+			s.skipActed(result, current, acted)
+		}
+		return err
+	}
+	if claimed, err := claim(ctx, s.claims, clientOrderID, s.broker.Now()); err != nil || !claimed {
+		if err == nil {
+			s.skipActed(result, current, "another run already claimed order "+clientOrderID)
+		}
+		return err
+	}
+
+	log.Printf("[%s] Buying %d x %s (%.0f delta each) at $%s", s.id, contracts, optionSymbol, contractDelta, limitPrice)
+	order, err := s.broker.PlaceOptionBuyOrder(ctx, clientOrderID, optionSymbol, decimal.NewFromInt(contracts), limitPrice)
+	// An order that wasn't submitted, or was only a dry run, leaves the signal to a later run
+	if err != nil || order.Status == dryrun.Status {
+		release(ctx, s.claims, clientOrderID)
+	}
+	var rejection *risk.Rejection
+	if errors.As(err, &rejection) {
+		result.Decision = DecisionSkippedRiskLimit
+		result.Message = fmt.Sprintf("%s delta %.0f is below the band but %v", s.params.Ticker, current, rejection)
+		result.Diagnostics["risk_limit"] = rejection.Limit
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to place order: %w", err)
+	}
+
+	result.Decision = DecisionOrdered
+	result.Orders = append(result.Orders, NewOrder(order))
+	result.Message = fmt.Sprintf("%s delta %.0f is below %.0f: buying %d x %s for about %.0f more delta. Order ID: %s",
+		s.params.Ticker, current, s.params.TargetDelta-s.params.Band, contracts, optionSymbol, float64(contracts)*contractDelta, order.ID)
+	return nil
+}
+
+// size sizes an addition in the option with the instance's sizing model
+func (s *DeltaTarget) size(ctx context.Context, option *marketdata.OptionSnapshot) (sizing.Size, error) {
+	candidate := sizing.Candidate{
+		OpenSlots:         1,
+		Ask:               money.NewFromFloat(option.LatestQuote.AskPrice),
+		Delta:             option.Greeks.Delta,
+		ImpliedVolatility: option.ImpliedVolatility,
+	}
+	// Only delta-dollar sizing reads the underlying's price
+	if s.params.Sizing.Method == sizing.MethodDeltaDollar {
+		price, err := s.broker.GetLatestQuote(ctx, s.params.Ticker)
+		if err != nil {
+			return sizing.Size{}, fmt.Errorf("failed to get latest quote for %s: %w", s.params.Ticker, err)
+		}
+		candidate.UnderlyingPrice = price
+	}
+	return s.params.Sizing.Size(ctx, s.broker, candidate)
+}
+
+// trim sells the highest-delta contracts, one at a time, while doing so brings the delta closer to the target.
+// Open sell orders in a position, such as bracket legs, are cancelled before it is trimmed and its
+// client-managed exits dropped once the trim is placed, so the contracts aren't sold twice.
+func (s *DeltaTarget) trim(ctx context.Context, result *Result, day time.Time, holdings []holding, current float64) error {
+	sort.SliceStable(holdings, func(i, j int) bool {
+		return holdings[i].delta > holdings[j].delta
+	})
+
+	excess := current - s.params.TargetDelta
+	budget := int64(s.params.MaxContractsPerRun)
+	sells := map[string]int64{}
+	var trims []holding
+	for _, held := range holdings {
+		if held.delta <= 0 || !held.bid.IsPositive() {
+			continue
+		}
+		for sold := int64(0); sold < held.qty && budget > 0 && excess > held.delta/2; sold++ {
+			if sells[held.symbol] == 0 {
+				trims = append(trims, held)
+			}
+			sells[held.symbol]++
+			excess -= held.delta
+			budget--
+		}
+	}
+	if len(trims) == 0 {
+		result.Decision = DecisionNoSignal
+		result.Message = fmt.Sprintf("%s delta %.0f is above the band but no call with a bid can be trimmed", s.params.Ticker, current)
+		log.Printf("[%s] %s", s.id, result.Message)
+		return nil
+	}
+
+	clientOrderIDs := make([]string, len(trims))
+	for i, held := range trims {
+		clientOrderIDs[i] = alpaca.NewClientOrderID(s.id, day, "trim-"+held.symbol)
+		acted, err := s.submitted(ctx, clientOrderIDs[i])
+		if err != nil {
+			return err
+		}
+		if acted != "" {
+			s.skipActed(result, current, acted)
+			return nil
+		}
+	}
+	for i, clientOrderID := range clientOrderIDs {
+		claimed, err := claim(ctx, s.claims, clientOrderID, s.broker.Now())
+		if err != nil || !claimed {
+			for _, earlier := range clientOrderIDs[:i] {
+				release(ctx, s.claims, earlier)
+			}
+			if err == nil {
+				s.skipActed(result, current, "another run already claimed order "+clientOrderID)
+			}
+			return err
+		}
+	}
+
+	// The orders placed so far keep their claims; the rest won't be submitted this run
+	unclaim := func(from int) {
+		for _, unplaced := range clientOrderIDs[from:] {
+			release(ctx, s.claims, unplaced)
+		}
+	}
+	var trimmed float64
+	var contracts int64
+	for i, held := range trims {
+		clientOrderID := clientOrderIDs[i]
+		qty := sells[held.symbol]
+		log.Printf("[%s] Selling %d x %s (%.0f delta each) at $%s", s.id, qty, held.symbol, held.delta, held.bid)
+		// The position's resting exits would hold the contracts, or sell them again once trimmed
+		if err := cancelOpenSells(ctx, s.broker, held.symbol); err != nil {
+			unclaim(i)
+			return err
+		}
+		placed, err := s.broker.PlaceOptionSellOrder(ctx, clientOrderID, held.symbol, decimal.NewFromInt(qty), held.bid)
+		if err != nil {
+			unclaim(i)
+			return fmt.Errorf("failed to place order to trim %s: %w", held.symbol, err)
+		}
+		if placed.Status == dryrun.Status {
+			release(ctx, s.claims, clientOrderID)
+		} else {
+			s.dropExits(ctx, held.symbol)
+		}
+		result.Orders = append(result.Orders, NewOrder(placed))
+		trimmed += float64(qty) * held.delta
+		contracts += qty
+	}
+
+	result.Decision = DecisionOrdered
+	result.Diagnostics["trimmed_delta"] = trimmed
+	result.Message = fmt.Sprintf("%s delta %.0f is above %.0f: trimming %.0f delta by selling %d of the highest-delta contracts",
+		s.params.Ticker, current, s.params.TargetDelta+s.params.Band, trimmed, contracts)
+	return nil
+}
+
+// submitted checks the broker for an order already submitted under clientOrderID, returning why it counts
+// as acted on, or "" if there is none
+func (s *DeltaTarget) submitted(ctx context.Context, clientOrderID string) (string, error) {
+	order, err := s.broker.GetOrderByClientOrderID(ctx, clientOrderID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check for an existing order %s: %w", clientOrderID, err)
+	}
+	if order != nil {
+		return fmt.Sprintf("order %s (%s) was already submitted today", order.ID, order.Status), nil
+	}
+	return "", nil
+}
+
+// skipActed records that the instance already traded today
+func (s *DeltaTarget) skipActed(result *Result, current float64, acted string) *Result {
+	result.Decision = DecisionSkippedAlreadyActed
+	result.Message = fmt.Sprintf("%s delta %.0f is outside the band but %s", s.params.Ticker, current, acted)
+	log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+	return result
+}
+
+// dropExits stops the exit monitor from enforcing client-managed exits on a trimmed position
+func (s *DeltaTarget) dropExits(ctx context.Context, symbol string) {
+	if s.exits == nil {
+		return
+	}
+	if err := s.exits.Drop(ctx, symbol); err != nil {
+		log.Printf("[%s] Failed to drop client-managed exits of %s: %v", s.id, symbol, err)
+	}
+}
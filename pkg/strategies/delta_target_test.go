@@ -0,0 +1,333 @@
+package strategies
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/sizing"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+const (
+	testDeltaInstance = "qqq-delta"
+	// testDeepLeap is a deep in-the-money QQQ call with a 0.90 delta, bid at $50.00
+	testDeepLeap = "QQQ260320C00400000"
+	// testHighLeap is a QQQ call with a 0.60 delta, bid at $9.00
+	testHighLeap = "QQQ260320C00460000"
+)
+
+// newDeltaTargetBroker returns a simulated broker on testDay with testLeap, testDeepLeap and testHighLeap
+// on the QQQ chain and $25,000 of buying power
+func newDeltaTargetBroker(t *testing.T) *sim.Broker {
+	t.Helper()
+	b := sim.NewBroker(testDay)
+	for symbol, snapshot := range map[string]marketdata.OptionSnapshot{
+		testLeap:     {LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00}, Greeks: &marketdata.OptionGreeks{Delta: 0.70}},
+		testDeepLeap: {LatestQuote: &marketdata.OptionQuote{BidPrice: 50.00, AskPrice: 50.50}, Greeks: &marketdata.OptionGreeks{Delta: 0.90}},
+		testHighLeap: {LatestQuote: &marketdata.OptionQuote{BidPrice: 9.00, AskPrice: 9.10}, Greeks: &marketdata.OptionGreeks{Delta: 0.60}},
+	} {
+		if err := b.SetOption(symbol, snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.SetBuyingPower(money.NewFromInt(25000))
+	return b
+}
+
+// holdContracts gives the instance qty contracts of symbol bought at the ask the day before testDay
+func holdContracts(t *testing.T, b *sim.Broker, instanceID, symbol string, qty int64) {
+	t.Helper()
+	snapshot, err := b.GetOptionSnapshot(context.Background(), symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientOrderID := alpaca.NewClientOrderID(instanceID, testDay.AddDate(0, 0, -1), "hold-"+symbol)
+	ask := money.NewFromFloat(snapshot.LatestQuote.AskPrice)
+	if _, err := b.PlaceOptionBuyOrder(context.Background(), clientOrderID, symbol, decimal.NewFromInt(qty), ask); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testDeltaTargetParams() DeltaTargetParams {
+	return DeltaTargetParams{
+		Ticker:             "QQQ",
+		TargetDelta:        200,
+		Band:               50,
+		MinDelta:           0.70,
+		LeapsMinMonths:     11,
+		MaxContractsPerRun: 5,
+		Pricing:            pricing.PercentOfAsk(100),
+		Sizing:             sizing.EqualSlot(),
+	}
+}
+
+func TestDeltaTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup adjusts the broker, params and state before the run
+		setup    func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State)
+		decision Decision
+		// sells maps each option the run sells to its contracts; buy is the contracts of testLeap it buys
+		sells map[string]int64
+		buy   int64
+	}{
+		{
+			name:     "no position adds toward the target",
+			decision: DecisionOrdered,
+			// 200 delta over 70 a contract is nearest 3 contracts
+			buy: 3,
+		},
+		{
+			name: "below the band",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				holdContracts(t, b, testDeltaInstance, testLeap, 2)
+			},
+			decision: DecisionOrdered,
+			// 60 delta short of the target is nearest 1 contract
+			buy: 1,
+		},
+		{
+			name: "within the band",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				holdContracts(t, b, testDeltaInstance, testLeap, 3)
+			},
+			decision: DecisionNoSignal,
+		},
+		{
+			name: "another instance's delta doesn't count",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				holdContracts(t, b, "qqq-gap", testLeap, 3)
+			},
+			decision: DecisionOrdered,
+			buy:      3,
+		},
+		{
+			name: "max contracts per run",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				params.MaxContractsPerRun = 2
+			},
+			decision: DecisionOrdered,
+			buy:      2,
+		},
+		{
+			name: "buying power covers fewer contracts",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				b.SetBuyingPower(money.NewFromInt(2500))
+			},
+			decision: DecisionOrdered,
+			buy:      2,
+		},
+		{
+			name: "buying power covers no contract",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				b.SetBuyingPower(money.NewFromInt(500))
+			},
+			decision: DecisionSkippedNoSize,
+		},
+		{
+			name: "sizing covers fewer contracts",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodFixedDollar, Amount: money.NewFromInt(2500)}
+			},
+			decision: DecisionOrdered,
+			buy:      2,
+		},
+		{
+			name: "delta gap caps the sizing",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodFixedDollar, Amount: money.NewFromInt(10000)}
+			},
+			decision: DecisionOrdered,
+			buy:      3,
+		},
+		{
+			name: "sizing covers no contract",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodFixedDollar, Amount: money.NewFromInt(500)}
+			},
+			decision: DecisionSkippedNoSize,
+		},
+		{
+			name: "delta dollar sizing",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				// 0.70 delta × 100 shares × $500 is $35,000 of exposure per contract
+				b.SetQuote("QQQ", 500, 500)
+				params.Sizing = sizing.Model{Method: sizing.MethodDeltaDollar, DeltaDollars: money.NewFromInt(70000)}
+			},
+			decision: DecisionOrdered,
+			buy:      2,
+		},
+		{
+			name: "spread too wide to add",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				params.Pricing.MaxSpreadPercent = 0.5
+			},
+			decision: DecisionSkippedWideSpread,
+		},
+		{
+			// 180 + 120 is 300, and one 90-delta contract brings it to 210
+			name: "above the band trims the highest delta first",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				holdContracts(t, b, testDeltaInstance, testHighLeap, 2)
+				holdContracts(t, b, testDeltaInstance, testDeepLeap, 2)
+			},
+			decision: DecisionOrdered,
+			sells:    map[string]int64{testDeepLeap: 1},
+		},
+		{
+			// 90 + 180 is 270, 130 over the target, and one 90-delta and one 60-delta contract bring it to 120
+			name: "above the band trims across positions",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				holdContracts(t, b, testDeltaInstance, testDeepLeap, 1)
+				holdContracts(t, b, testDeltaInstance, testHighLeap, 3)
+				params.TargetDelta = 140
+			},
+			decision: DecisionOrdered,
+			sells:    map[string]int64{testDeepLeap: 1, testHighLeap: 1},
+		},
+		{
+			name: "above the band without a bid",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				holdContracts(t, b, testDeltaInstance, testDeepLeap, 4)
+				if err := b.SetOptionQuote(testDeepLeap, 0, 50.50); err != nil {
+					t.Fatal(err)
+				}
+			},
+			decision: DecisionNoSignal,
+		},
+		{
+			name: "already rebalanced today",
+			setup: func(t *testing.T, b *sim.Broker, params *DeltaTargetParams, state *State) {
+				state.Set(stateLastRebalanceDate, "2025-03-04")
+			},
+			decision: DecisionSkippedAlreadyActed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDeltaTargetBroker(t)
+			params := testDeltaTargetParams()
+			state := NewState(nil)
+			if tt.setup != nil {
+				tt.setup(t, b, &params, state)
+			}
+			before := len(b.Orders())
+
+			s := NewDeltaTarget(testDeltaInstance, b, params)
+			s.SetState(state)
+			result, err := s.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result.Decision != tt.decision {
+				t.Fatalf("Decision = %s, want %s: %s", result.Decision, tt.decision, result.Message)
+			}
+
+			placed := b.Orders()[before:]
+			if want := len(tt.sells) + min(int(tt.buy), 1); len(placed) != want {
+				t.Fatalf("placed %d orders, want %d", len(placed), want)
+			}
+			for _, order := range placed {
+				var want int64
+				var tag string
+				switch {
+				case order.Side == "buy" && order.Symbol == testLeap:
+					want, tag = tt.buy, "add-delta"
+				case order.Side == "sell":
+					want, tag = tt.sells[order.Symbol], "trim-"+order.Symbol
+				}
+				if want == 0 {
+					t.Errorf("unexpected %s order for %s", order.Side, order.Symbol)
+					continue
+				}
+				if !order.Qty.Equal(decimal.NewFromInt(want)) {
+					t.Errorf("%s %s: Qty = %s, want %d", order.Side, order.Symbol, order.Qty, want)
+				}
+				if clientOrderID := alpaca.NewClientOrderID(testDeltaInstance, testDay, tag); order.ClientOrderID != clientOrderID {
+					t.Errorf("%s %s: ClientOrderID = %s, want %s", order.Side, order.Symbol, order.ClientOrderID, clientOrderID)
+				}
+			}
+			if len(placed) > 0 {
+				if got := state.Get(stateLastRebalanceDate); got != "2025-03-04" {
+					t.Errorf("last rebalance date = %q, want 2025-03-04", got)
+				}
+			}
+		})
+	}
+}
+
+func TestDeltaTargetRebalancesOncePerDay(t *testing.T) {
+	ctx := context.Background()
+	b := newDeltaTargetBroker(t)
+	holdContracts(t, b, testDeltaInstance, testDeepLeap, 4)
+
+	// 360 delta is trimmed to 180
+	first, err := NewDeltaTarget(testDeltaInstance, b, testDeltaTargetParams()).Run(ctx)
+	if err != nil || first.Decision != DecisionOrdered {
+		t.Fatalf("first Run() = %v, %v; want ordered", first.Decision, err)
+	}
+	// A second run with fresh state and a lower target finds the first run's order at the broker
+	params := testDeltaTargetParams()
+	params.TargetDelta = 100
+	second, err := NewDeltaTarget(testDeltaInstance, b, params).Run(ctx)
+	if err != nil || second.Decision != DecisionSkippedAlreadyActed {
+		t.Fatalf("second Run() = %v, %v; want skipped-already-acted", second.Decision, err)
+	}
+	if n := len(b.Orders()); n != 2 {
+		t.Errorf("placed %d orders, want the holding and one trim", n)
+	}
+}
+
+func TestDeltaTargetTrimCancelsExits(t *testing.T) {
+	ctx := context.Background()
+	b := newDeltaTargetBroker(t)
+	holdContracts(t, b, testDeltaInstance, testDeepLeap, 4)
+	holdContracts(t, b, "qqq-gap", testLeap, 1)
+	// A take profit rests on the deep LEAP, and client-managed exits are enforced on it and on qqq-gap's LEAP
+	takeProfit, err := b.PlaceOptionSellOrder(ctx, "qqq-gap.20250303.take-profit", testDeepLeap, decimal.NewFromInt(4), money.NewFromInt(75))
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor := bracket.NewMonitor(b, statestore.NewMemoryStore())
+	for _, symbol := range []string{testDeepLeap, testLeap} {
+		oco := bracket.OCO{EntryOrderID: "entry-" + symbol, ClientOrderID: "qqq-gap.20250303.gap-down", Symbol: symbol, Underlying: "QQQ",
+			Prices: bracket.Prices{TakeProfit: money.NewFromInt(100)}}
+		if err := monitor.Add(ctx, oco); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 360 delta is trimmed by two 90-delta contracts
+	s := NewDeltaTarget(testDeltaInstance, b, testDeltaTargetParams())
+	s.SetExitMonitor(monitor)
+	result, err := s.Run(ctx)
+	if err != nil || result.Decision != DecisionOrdered {
+		t.Fatalf("Run() = %v, %v; want ordered", result.Decision, err)
+	}
+
+	order, err := b.GetOrder(ctx, takeProfit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "canceled" {
+		t.Errorf("take profit is %s, want canceled before the trim", order.Status)
+	}
+	if len(result.Orders) != 1 || result.Orders[0].Symbol != testDeepLeap || result.Orders[0].Qty != 2 {
+		t.Errorf("orders = %+v, want 2 contracts of %s sold", result.Orders, testDeepLeap)
+	}
+	brackets, err := monitor.Brackets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brackets) != 1 || brackets[0].Symbol != testLeap {
+		t.Errorf("brackets = %+v, want only the untrimmed %s's", brackets, testLeap)
+	}
+}
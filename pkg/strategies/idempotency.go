@@ -0,0 +1,69 @@
+package strategies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+// Journal is the part of the trade journal strategies use to tell whether they already acted
+type Journal interface {
+	// OrderedOn reports whether a strategy instance submitted an order on the day containing day
+	OrderedOn(ctx context.Context, instanceID string, day time.Time) (bool, error)
+}
+
+// JournalAware is implemented by strategies that consult the trade journal before ordering
+type JournalAware interface {
+	// SetJournal hands the strategy the journal before a run
+	SetJournal(journal Journal)
+}
+
+// ClaimAware is implemented by strategies that claim an order's idempotency key in the state store
+// before submitting it
+type ClaimAware interface {
+	// SetClaimStore hands the strategy the store its claims are created in before a run
+	SetClaimStore(store statestore.StateStore)
+}
+
+// claimKey is the state store key claiming a client order ID
+func claimKey(clientOrderID string) string {
+	return "idempotency/" + clientOrderID
+}
+
+// claim atomically claims clientOrderID in store before its order is submitted, so of two overlapping
+// runs acting on the same signal only one submits. It returns false if another run holds the claim.
+// Without a store every claim succeeds.
+func claim(ctx context.Context, store statestore.StateStore, clientOrderID string, now time.Time) (bool, error) {
+	if store == nil {
+		return true, nil
+	}
+	err := store.Create(ctx, claimKey(clientOrderID), []byte(now.UTC().Format(time.RFC3339)))
+	if errors.Is(err, statestore.ErrExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim client order ID %s: %w", clientOrderID, err)
+	}
+	return true, nil
+}
+
+// release gives up the claim of an order that wasn't submitted, or was only a dry run, so a later run
+// can act on the signal. A claim left behind only makes later runs skip the signal, so a failure is logged.
+func release(ctx context.Context, store statestore.StateStore, clientOrderID string) {
+	if store == nil {
+		return
+	}
+	if err := store.Delete(ctx, claimKey(clientOrderID)); err != nil {
+		log.Printf("Failed to release the claim of client order ID %s: %v", clientOrderID, err)
+	}
+}
+
+// tradingDay returns midnight, exchange time, of the trading day containing t
+func tradingDay(t time.Time) time.Time {
+	t = t.In(exchangeLocation)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, exchangeLocation)
+}
@@ -0,0 +1,86 @@
+package strategies
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
+)
+
+func TestTwoPercentDownClaims(t *testing.T) {
+	const key = "idempotency/qqq-gap.20250304.gap-down"
+
+	tests := []struct {
+		name string
+		// setup adjusts the broker and store, returning the broker the strategy orders through
+		setup    func(t *testing.T, b *sim.Broker, store *statestore.MemoryStore) broker.Broker
+		decision Decision
+		wantErr  bool
+		// claimed is whether the claim is held after the run
+		claimed bool
+	}{
+		{
+			name:     "ordered",
+			decision: DecisionOrdered,
+			claimed:  true,
+		},
+		{
+			name: "claimed by another run",
+			setup: func(t *testing.T, b *sim.Broker, store *statestore.MemoryStore) broker.Broker {
+				if err := store.Create(context.Background(), key, []byte("2025-03-04T14:35:00Z")); err != nil {
+					t.Fatal(err)
+				}
+				return b
+			},
+			decision: DecisionSkippedAlreadyActed,
+			claimed:  true,
+		},
+		{
+			name: "order failed",
+			setup: func(t *testing.T, b *sim.Broker, store *statestore.MemoryStore) broker.Broker {
+				b.FailOrders(errors.New("connection reset"))
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "dry run",
+			setup: func(t *testing.T, b *sim.Broker, store *statestore.MemoryStore) broker.Broker {
+				return dryrun.NewBroker(b)
+			},
+			decision: DecisionOrdered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newGapDownBroker(t, 480)
+			store := statestore.NewMemoryStore()
+			var orders broker.Broker = b
+			if tt.setup != nil {
+				orders = tt.setup(t, b, store)
+			}
+
+			s := NewTwoPercentDown(testInstance, orders, testTwoPercentDownParams())
+			s.SetClaimStore(store)
+			result, err := s.Run(ctx)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && result.Decision != tt.decision {
+				t.Errorf("Decision = %s, want %s: %s", result.Decision, tt.decision, result.Message)
+			}
+			if _, err := store.Get(ctx, key); (err == nil) != tt.claimed {
+				t.Errorf("claim held = %v, want %v", err == nil, tt.claimed)
+			}
+			if tt.decision != DecisionOrdered && len(b.Orders()) != 0 {
+				t.Errorf("placed %d orders, want none", len(b.Orders()))
+			}
+		})
+	}
+}
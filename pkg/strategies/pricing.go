@@ -0,0 +1,27 @@
+package strategies
+
+import (
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+)
+
+// pricingParams are the parameters pricing a strategy's entry limit orders, shared by every strategy that buys options
+var pricingParams = []Param{
+	{Name: "pricing", Type: ParamString, Default: string(pricing.MethodPercentOfAsk), Description: "Entry pricing: percent-of-ask, mid, mid-plus-spread or ask-minus-ticks"},
+	{Name: "limit_percent_of_ask", Type: ParamFloat, Default: "99.0", Description: "Entry limit price as a percentage of the option's ask, for percent-of-ask pricing"},
+	{Name: "spread_percent", Type: ParamFloat, Default: "25.0", Description: "Percentage of the spread added to the mid, for mid-plus-spread pricing"},
+	{Name: "ask_minus_ticks", Type: ParamInt, Default: "1", Description: "Tick increments below the ask, for ask-minus-ticks pricing"},
+	{Name: "max_spread_percent", Type: ParamFloat, Default: "0", Description: "Skip the entry when the spread is wider than this percentage of the mid; 0 disables the guard"},
+	{Name: "tick_rule", Type: ParamString, Default: string(pricing.TickStandard), Description: "Option tick sizes: standard ($0.05 at or above $3) or penny ($0.01 at every price)"},
+}
+
+// pricingPolicy returns the entry pricing policy selected by the parameters
+func pricingPolicy(params Params) pricing.Policy {
+	return pricing.Policy{
+		Method:           pricing.Method(params.String("pricing")),
+		PercentOfAsk:     params.Float("limit_percent_of_ask"),
+		SpreadPercent:    params.Float("spread_percent"),
+		Ticks:            params.Int("ask_minus_ticks"),
+		MaxSpreadPercent: params.Float("max_spread_percent"),
+		TickRule:         pricing.TickRule(params.String("tick_rule")),
+	}
+}
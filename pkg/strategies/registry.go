@@ -0,0 +1,197 @@
+package strategies
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+)
+
+// ParamType is the value type of a strategy parameter
+type ParamType string
+
+const (
+	ParamFloat  ParamType = "float"
+	ParamInt    ParamType = "int"
+	ParamString ParamType = "string"
+	ParamBool   ParamType = "bool"
+)
+
+// Param describes one tunable strategy parameter
+type Param struct {
+	Name        string
+	Type        ParamType
+	Default     string
+	Description string
+	// Env optionally names an environment variable that overrides the default
+	Env string
+}
+
+// Factory creates a strategy instance with the given instance ID from resolved parameters
+type Factory func(id string, broker broker.Broker, params Params) (Strategy, error)
+
+// Definition describes a registered strategy
+type Definition struct {
+	Name        string
+	Description string
+	Params      []Param
+	// Validate optionally checks resolved parameters beyond their types
+	Validate func(params Params) error
+	New      Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Definition)
+)
+
+// Register makes a strategy available by name. It panics if the name is already taken
+// or a parameter default does not parse, since both are programming errors.
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if def.Name == "" || def.New == nil {
+		panic("strategies: Register requires a name and a factory")
+	}
+	if _, exists := registry[def.Name]; exists {
+		panic("strategies: Register called twice for " + def.Name)
+	}
+	for _, param := range def.Params {
+		if _, err := param.parse(param.Default); err != nil {
+			panic(fmt.Sprintf("strategies: invalid default for %s.%s: %v", def.Name, param.Name, err))
+		}
+	}
+	registry[def.Name] = def
+}
+
+// Lookup returns the strategy registered under name
+func Lookup(name string) (Definition, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := registry[name]
+	if !ok {
+		return Definition{}, fmt.Errorf("unknown strategy: %s", name)
+	}
+	return def, nil
+}
+
+// List returns every registered strategy ordered by name
+func List() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+	return defs
+}
+
+// Param returns the parameter with the given name
+func (d Definition) Param(name string) (Param, bool) {
+	for _, param := range d.Params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return Param{}, false
+}
+
+// Resolve validates overrides against the parameter schema and returns the full set of values.
+// Each parameter takes, in increasing precedence, its default, its environment variable and its override.
+func (d Definition) Resolve(overrides map[string]string) (Params, error) {
+	for name := range overrides {
+		if _, ok := d.Param(name); !ok {
+			return nil, fmt.Errorf("strategy %s has no parameter %q", d.Name, name)
+		}
+	}
+
+	params := make(Params, len(d.Params))
+	for _, param := range d.Params {
+		raw, source := param.Default, "default"
+		if param.Env != "" {
+			if env := os.Getenv(param.Env); env != "" {
+				raw, source = env, param.Env
+			}
+		}
+		if override, ok := overrides[param.Name]; ok {
+			raw, source = override, "override"
+		}
+
+		value, err := param.parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s for %s parameter %s (from %s): %w", param.Type, d.Name, param.Name, source, err)
+		}
+		params[param.Name] = value
+	}
+
+	if d.Validate != nil {
+		if err := d.Validate(params); err != nil {
+			return nil, fmt.Errorf("invalid %s parameters: %w", d.Name, err)
+		}
+	}
+	return params, nil
+}
+
+// Build resolves overrides and creates a strategy instance identified by id
+func (d Definition) Build(id string, broker broker.Broker, overrides map[string]string) (Strategy, error) {
+	if err := alpaca.ValidateInstanceID(id); err != nil {
+		return nil, err
+	}
+	params, err := d.Resolve(overrides)
+	if err != nil {
+		return nil, err
+	}
+	return d.New(id, broker, params)
+}
+
+func (p Param) parse(raw string) (any, error) {
+	switch p.Type {
+	case ParamFloat:
+		return strconv.ParseFloat(raw, 64)
+	case ParamInt:
+		return strconv.Atoi(raw)
+	case ParamBool:
+		return strconv.ParseBool(raw)
+	case ParamString:
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %q", p.Type)
+	}
+}
+
+// Params holds resolved, typed parameter values keyed by name
+type Params map[string]any
+
+// Float returns a float parameter
+func (p Params) Float(name string) float64 {
+	v, _ := p[name].(float64)
+	return v
+}
+
+// Int returns an int parameter
+func (p Params) Int(name string) int {
+	v, _ := p[name].(int)
+	return v
+}
+
+// String returns a string parameter
+func (p Params) String(name string) string {
+	v, _ := p[name].(string)
+	return v
+}
+
+// Bool returns a bool parameter
+func (p Params) Bool(name string) bool {
+	v, _ := p[name].(bool)
+	return v
+}
@@ -0,0 +1,34 @@
+package strategies
+
+import (
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/sizing"
+)
+
+// sizingParams are the parameters selecting a strategy's position sizing model, shared by every strategy that sizes entries
+var sizingParams = []Param{
+	{Name: "sizing", Type: ParamString, Default: string(sizing.MethodEqualSlot), Description: "Position sizing: fixed-dollar, percent-of-equity, equal-slot, volatility-scaled, kelly or delta-dollar"},
+	{Name: "sizing_amount", Type: ParamFloat, Default: "0", Description: "Premium per position, for fixed-dollar sizing"},
+	{Name: "sizing_equity_percent", Type: ParamFloat, Default: "5.0", Description: "Percent of equity per position, for percent-of-equity and volatility-scaled sizing"},
+	{Name: "sizing_target_volatility", Type: ParamFloat, Default: "25.0", Description: "Implied volatility, in percent, at which volatility-scaled sizing commits sizing_equity_percent"},
+	{Name: "kelly_win_rate", Type: ParamFloat, Default: "0.5", Description: "Fraction of trades that win, for kelly sizing"},
+	{Name: "kelly_payoff", Type: ParamFloat, Default: "1.0", Description: "Average win over average loss, for kelly sizing"},
+	{Name: "kelly_fraction", Type: ParamFloat, Default: "0.5", Description: "Multiple of the Kelly fraction to commit, e.g. 0.5 for half Kelly"},
+	{Name: "kelly_cap_percent", Type: ParamFloat, Default: "10.0", Description: "Most percent of equity kelly sizing commits"},
+	{Name: "sizing_delta_dollars", Type: ParamFloat, Default: "0", Description: "Share-equivalent dollar exposure per position, for delta-dollar sizing"},
+}
+
+// sizingModel returns the sizing model selected by the parameters
+func sizingModel(params Params) sizing.Model {
+	return sizing.Model{
+		Method:           sizing.Method(params.String("sizing")),
+		Amount:           money.NewFromFloat(params.Float("sizing_amount")),
+		EquityPercent:    params.Float("sizing_equity_percent"),
+		TargetVolatility: params.Float("sizing_target_volatility"),
+		KellyWinRate:     params.Float("kelly_win_rate"),
+		KellyPayoff:      params.Float("kelly_payoff"),
+		KellyFraction:    params.Float("kelly_fraction"),
+		KellyCapPercent:  params.Float("kelly_cap_percent"),
+		DeltaDollars:     money.NewFromFloat(params.Float("sizing_delta_dollars")),
+	}
+}
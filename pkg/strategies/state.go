@@ -0,0 +1,78 @@
+package strategies
+
+import (
+	"sync"
+	"time"
+
+	// Embeds the time zone database so exchange dates work where the system has none, e.g. on Lambda
+	_ "time/tzdata"
+)
+
+// exchangeLocation is the exchange's time zone, in which trading days are dated
+var exchangeLocation = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic("strategies: " + err.Error())
+	}
+	return loc
+}
+
+// Stateful is implemented by strategies that keep state between runs
+type Stateful interface {
+	// SetState hands the strategy its persisted state before a run; changes made during the run are saved after it
+	SetState(state *State)
+}
+
+// State is a strategy instance's state persisted between runs, e.g. the last day it bought
+type State struct {
+	mu      sync.Mutex
+	values  map[string]string
+	changed bool
+}
+
+// NewState creates state holding a copy of values
+func NewState(values map[string]string) *State {
+	state := &State{values: make(map[string]string, len(values))}
+	for key, value := range values {
+		state.values[key] = value
+	}
+	return state
+}
+
+// Get returns the value of key, or "" when unset
+func (s *State) Get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set sets key to value
+func (s *State) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.values[key]; ok && current == value {
+		return
+	}
+	s.values[key] = value
+	s.changed = true
+}
+
+// Values returns a copy of every key and value
+func (s *State) Values() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]string, len(s.values))
+	for key, value := range s.values {
+		values[key] = value
+	}
+	return values
+}
+
+// Changed reports whether Set changed any value
+func (s *State) Changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
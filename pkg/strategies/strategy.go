@@ -1,7 +1,190 @@
 package strategies
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/dryrun"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+)
 
 type Strategy interface {
-	Run(ctx context.Context) error
+	// ID uniquely identifies the strategy instance in logs, notifications and orders
+	ID() string
+	// Name is the registered name of the strategy the instance runs
+	Name() string
+	// Run evaluates the strategy once. A returned error means the run failed; the result may still
+	// carry whatever signals and diagnostics were computed before the failure.
+	Run(ctx context.Context) (*Result, error)
+}
+
+// ExitAware is implemented by strategies that sell contracts the exit monitor may be enforcing
+// client-managed exits on
+type ExitAware interface {
+	// SetExitMonitor hands the strategy the monitor before a run
+	SetExitMonitor(monitor *bracket.Monitor)
+}
+
+// Decision is what a strategy decided to do in a run
+type Decision string
+
+const (
+	// DecisionNoSignal means no entry signal fired
+	DecisionNoSignal Decision = "no-signal"
+	// DecisionSkippedMaxPositions means a signal fired but the instance is at its position cap
+	DecisionSkippedMaxPositions Decision = "skipped-max-positions"
+	// DecisionSkippedAlreadyActed means a signal fired but the instance already ordered on it today
+	DecisionSkippedAlreadyActed Decision = "skipped-already-acted"
+	// DecisionSkippedWideSpread means a signal fired but the option's bid/ask spread was too wide to trade
+	DecisionSkippedWideSpread Decision = "skipped-wide-spread"
+	// DecisionSkippedRiskLimit means a signal fired but the risk manager rejected the order
+	DecisionSkippedRiskLimit Decision = "skipped-risk-limit"
+	// DecisionSkippedHalted means the instance didn't run, or its order was refused, because trading is halted
+	DecisionSkippedHalted Decision = "skipped-halted"
+	// DecisionSkippedNoSize means a signal fired but the sizing model allotted nothing to the entry
+	DecisionSkippedNoSize Decision = "skipped-no-size"
+	// DecisionOrdered means at least one order was submitted
+	DecisionOrdered Decision = "ordered"
+	// DecisionError means the run failed
+	DecisionError Decision = "error"
+)
+
+// Outcome summarizes a run across strategies for reporting
+type Outcome string
+
+const (
+	// OutcomeRan means the strategy evaluated its signal and had nothing to do
+	OutcomeRan Outcome = "ran"
+	// OutcomeSkipped means the strategy had a signal but chose not to act on it, or didn't run at all
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeOrdered means the strategy submitted at least one order
+	OutcomeOrdered Outcome = "ordered"
+	// OutcomeFailed means the run returned an error, panicked or timed out
+	OutcomeFailed Outcome = "failed"
+)
+
+// Outcome maps a decision to its reporting outcome
+func (d Decision) Outcome() Outcome {
+	switch d {
+	case DecisionOrdered:
+		return OutcomeOrdered
+	case DecisionSkippedMaxPositions, DecisionSkippedAlreadyActed, DecisionSkippedWideSpread, DecisionSkippedRiskLimit, DecisionSkippedNoSize, DecisionSkippedHalted:
+		return OutcomeSkipped
+	case DecisionError:
+		return OutcomeFailed
+	default:
+		return OutcomeRan
+	}
+}
+
+// Signal is one condition a strategy evaluated
+type Signal struct {
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Triggered bool    `json:"triggered"`
+}
+
+// Order is an order submitted during a run
+type Order struct {
+	ID            string      `json:"id"`
+	ClientOrderID string      `json:"client_order_id"`
+	Symbol        string      `json:"symbol"`
+	Side          string      `json:"side"`
+	Qty           float64     `json:"qty"`
+	LimitPrice    money.Money `json:"limit_price"`
+	// TakeProfitPrice is the limit price of the attached take-profit leg, if any
+	TakeProfitPrice money.Money `json:"take_profit_price,omitzero"`
+	// StopPrice is the stop price of the attached stop-loss leg, if any
+	StopPrice money.Money `json:"stop_price,omitzero"`
+	// ClientExits are the exits the broker doesn't hold, which a bracket.Monitor enforces instead
+	ClientExits *bracket.OCO `json:"client_exits,omitempty"`
+	// DryRun marks an order that was computed but never sent to the broker
+	DryRun bool `json:"dry_run,omitempty"`
+	// Events are the transitions the engine observed while tracking the order after submission
+	Events []OrderEvent `json:"events,omitempty"`
+}
+
+// OrderEvent is a transition of a submitted order: a fill, a repricing or a cancellation
+type OrderEvent struct {
+	At             time.Time   `json:"at"`
+	Type           string      `json:"type"`
+	OrderID        string      `json:"order_id"`
+	ClientOrderID  string      `json:"client_order_id"`
+	Status         string      `json:"status"`
+	LimitPrice     money.Money `json:"limit_price"`
+	FilledQty      float64     `json:"filled_qty"`
+	FilledAvgPrice money.Money `json:"filled_avg_price,omitzero"`
+	Message        string      `json:"message"`
+}
+
+// Result is the structured outcome of a single strategy run
+type Result struct {
+	Decision Decision `json:"decision"`
+	// Message is a human-readable summary used in logs and notifications
+	Message     string         `json:"message"`
+	Signals     []Signal       `json:"signals,omitempty"`
+	Orders      []Order        `json:"orders,omitempty"`
+	Diagnostics map[string]any `json:"diagnostics,omitempty"`
+}
+
+// DryRun reports whether the run's orders were only computed, never submitted
+func (r *Result) DryRun() bool {
+	for _, order := range r.Orders {
+		if order.DryRun {
+			return true
+		}
+	}
+	return false
+}
+
+// NewResult creates an empty result that collects diagnostics
+func NewResult() *Result {
+	return &Result{Diagnostics: make(map[string]any)}
+}
+
+// NewOrder summarizes an order returned by the broker
+func NewOrder(order *alpaca.Order) Order {
+	summary := Order{
+		ID:            order.ID,
+		ClientOrderID: order.ClientOrderID,
+		Symbol:        order.Symbol,
+		Side:          string(order.Side),
+		DryRun:        order.Status == dryrun.Status,
+	}
+	if order.Qty != nil {
+		summary.Qty = order.Qty.InexactFloat64()
+	}
+	summary.LimitPrice = money.FromPtr(order.LimitPrice)
+	for _, leg := range order.Legs {
+		if leg.Side == alpaca.Sell && leg.Type == alpaca.Limit && leg.LimitPrice != nil {
+			summary.TakeProfitPrice = money.FromPtr(leg.LimitPrice)
+		}
+		if leg.Side == alpaca.Sell && leg.Type == alpaca.Stop && leg.StopPrice != nil {
+			summary.StopPrice = money.FromPtr(leg.StopPrice)
+		}
+	}
+	return summary
+}
+
+// cancelOpenSells cancels the open sell orders in symbol, such as the take-profit and stop legs of a
+// bracket, so they don't hold the contracts about to be sold or sell them a second time
+func cancelOpenSells(ctx context.Context, b broker.Broker, symbol string) error {
+	open, err := b.GetOpenOrders(ctx, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get open orders in %s: %w", symbol, err)
+	}
+	for _, order := range open {
+		if order.Side != alpaca.Sell {
+			continue
+		}
+		if err := b.CancelOrder(ctx, order.ID); err != nil {
+			return fmt.Errorf("failed to cancel open sell order %s in %s: %w", order.ID, symbol, err)
+		}
+	}
+	return nil
 }
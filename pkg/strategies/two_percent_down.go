@@ -2,132 +2,405 @@ package strategies
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
+	"slices"
+	"time"
 
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
-	"github.com/vignesh-goutham/AthenaX/pkg/notification"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/risk"
+	"github.com/vignesh-goutham/AthenaX/pkg/sizing"
+	"github.com/vignesh-goutham/AthenaX/pkg/statestore"
 )
 
-const ticker = "QQQ"
+// TwoPercentDownParams holds the tunable settings of the TwoPercentDown strategy
+type TwoPercentDownParams struct {
+	// Ticker is the underlying watched for gap downs and whose LEAPs are bought
+	Ticker string
+	// GapThreshold is the percent change from yesterday's close at or below which the strategy buys
+	GapThreshold float64
+	// MinDelta is the minimum delta of the call LEAP to buy
+	MinDelta float64
+	// Pricing prices the entry limit from the option's bid and ask
+	Pricing pricing.Policy
+	// TakeProfitPercent is the gain on the entry price at which the position is sold
+	TakeProfitPercent float64
+	// StopLoss is the protective exit attached next to the take profit
+	StopLoss bracket.StopLoss
+	// LeapsMinMonths is how many months out the option must expire
+	LeapsMinMonths int
+	// MaxActiveOptions caps the number of option positions held on the ticker
+	MaxActiveOptions int
+	// Sizing sizes each entry
+	Sizing sizing.Model
+}
 
-type TwoPercentDown struct {
-	broker           *alpaca.Client
-	maxActiveOptions int
-	notifier         *notification.Client
+// TwoPercentDownName is the registered name of the TwoPercentDown strategy
+const TwoPercentDownName = "two-percent-down"
+
+func init() {
+	Register(Definition{
+		Name: TwoPercentDownName,
+		Description: "When the ticker gaps down past the threshold, buys a call LEAP with delta >= min_delta " +
+			"using a limit order with a take profit and an optional stop-loss attached",
+		Params: slices.Concat([]Param{
+			{Name: "ticker", Type: ParamString, Default: "QQQ", Description: "Underlying to watch and buy LEAPs on"},
+			{Name: "gap_threshold", Type: ParamFloat, Default: "-2.0", Description: "Percent change from yesterday's close at or below which to buy"},
+			{Name: "min_delta", Type: ParamFloat, Default: "0.60", Description: "Minimum delta of the call LEAP"},
+		}, pricingParams, []Param{
+			{Name: "take_profit_percent", Type: ParamFloat, Default: "50.0", Description: "Gain on the entry price at which to take profit"},
+			{Name: "stop_loss", Type: ParamString, Default: string(bracket.StopNone), Description: "Stop-loss: none, percent, price or underlying"},
+			{Name: "stop_loss_percent", Type: ParamFloat, Default: "30.0", Description: "Loss on the entry price at which to stop out, for percent stop-losses"},
+			{Name: "stop_loss_price", Type: ParamFloat, Default: "0", Description: "Option price at which to stop out, for price stop-losses"},
+			{Name: "stop_loss_underlying_price", Type: ParamFloat, Default: "0", Description: "Underlying price at or below which to stop out, for underlying stop-losses, which are client-managed"},
+			{Name: "leaps_min_months", Type: ParamInt, Default: "11", Description: "Minimum months to expiry of the call LEAP"},
+			{Name: "max_active_options", Type: ParamInt, Default: "5", Description: "Maximum number of option positions held on the ticker", Env: "MAX_ACTIVE_OPTIONS"},
+		}, sizingParams),
+		Validate: func(params Params) error {
+			return twoPercentDownParams(params).Validate()
+		},
+		New: func(id string, broker broker.Broker, params Params) (Strategy, error) {
+			return NewTwoPercentDown(id, broker, twoPercentDownParams(params)), nil
+		},
+	})
 }
 
-// NewTwoPercentDown creates a new TwoPercentDown strategy instance
-func NewTwoPercentDown(broker *alpaca.Client, notifier *notification.Client) *TwoPercentDown {
-	// Get max active options from environment variable, default to 5
-	maxActiveOptions := 5
-	if envMax := os.Getenv("MAX_ACTIVE_OPTIONS"); envMax != "" {
-		if parsed, err := strconv.Atoi(envMax); err == nil && parsed > 0 {
-			maxActiveOptions = parsed
-		}
+func twoPercentDownParams(params Params) TwoPercentDownParams {
+	return TwoPercentDownParams{
+		Ticker:            params.String("ticker"),
+		GapThreshold:      params.Float("gap_threshold"),
+		MinDelta:          params.Float("min_delta"),
+		Pricing:           pricingPolicy(params),
+		TakeProfitPercent: params.Float("take_profit_percent"),
+		StopLoss: bracket.StopLoss{
+			Kind:            bracket.StopKind(params.String("stop_loss")),
+			Percent:         params.Float("stop_loss_percent"),
+			Price:           money.NewFromFloat(params.Float("stop_loss_price")),
+			UnderlyingPrice: money.NewFromFloat(params.Float("stop_loss_underlying_price")),
+		},
+		LeapsMinMonths:   params.Int("leaps_min_months"),
+		MaxActiveOptions: params.Int("max_active_options"),
+		Sizing:           sizingModel(params),
+	}
+}
+
+// Validate checks that the parameters are usable
+func (p TwoPercentDownParams) Validate() error {
+	if p.Ticker == "" {
+		return fmt.Errorf("ticker is required")
+	}
+	if p.GapThreshold >= 0 {
+		return fmt.Errorf("gap_threshold must be negative, got %v", p.GapThreshold)
+	}
+	if p.MinDelta <= 0 || p.MinDelta > 1 {
+		return fmt.Errorf("min_delta must be in (0, 1], got %v", p.MinDelta)
+	}
+	if err := p.Pricing.Validate(); err != nil {
+		return fmt.Errorf("invalid entry pricing: %w", err)
 	}
+	if p.TakeProfitPercent <= 0 {
+		return fmt.Errorf("take_profit_percent must be greater than 0, got %v", p.TakeProfitPercent)
+	}
+	if err := p.StopLoss.Validate(); err != nil {
+		return fmt.Errorf("invalid stop_loss: %w", err)
+	}
+	if p.LeapsMinMonths <= 0 {
+		return fmt.Errorf("leaps_min_months must be greater than 0, got %d", p.LeapsMinMonths)
+	}
+	if p.MaxActiveOptions <= 0 {
+		return fmt.Errorf("max_active_options must be greater than 0, got %d", p.MaxActiveOptions)
+	}
+	if err := p.Sizing.Validate(); err != nil {
+		return fmt.Errorf("invalid sizing: %w", err)
+	}
+	return nil
+}
+
+// State keys of the TwoPercentDown strategy
+const (
+	// stateLastBuyDate is the exchange date, YYYY-MM-DD, of the instance's last submitted order
+	stateLastBuyDate = "last_buy_date"
+)
+
+type TwoPercentDown struct {
+	id     string
+	broker broker.Broker
+	params TwoPercentDownParams
+	state  *State
+	// journal is nil when the trade journal is disabled
+	journal Journal
+	// claims is nil when there is no state store to claim client order IDs in
+	claims statestore.StateStore
+}
 
+// NewTwoPercentDown creates a new TwoPercentDown strategy instance
+func NewTwoPercentDown(id string, broker broker.Broker, params TwoPercentDownParams) *TwoPercentDown {
 	return &TwoPercentDown{
-		broker:           broker,
-		maxActiveOptions: maxActiveOptions,
-		notifier:         notifier,
+		id:     id,
+		broker: broker,
+		params: params,
+		state:  NewState(nil),
 	}
 }
 
-func (s *TwoPercentDown) Run(ctx context.Context) error {
-	// Step 1: Get yesterday's close of ticker
-	yesterdayClose, err := s.broker.GetLastTradingDayClose(ctx, ticker)
+// SetState sets the state persisted between runs
+func (s *TwoPercentDown) SetState(state *State) {
+	s.state = state
+}
+
+// SetJournal sets the journal checked for orders already placed today
+func (s *TwoPercentDown) SetJournal(journal Journal) {
+	s.journal = journal
+}
+
+// SetClaimStore sets the store each order's client order ID is claimed in before it is submitted
+func (s *TwoPercentDown) SetClaimStore(store statestore.StateStore) {
+	s.claims = store
+}
+
+// ID returns the instance ID
+func (s *TwoPercentDown) ID() string {
+	return s.id
+}
+
+// Name returns the strategy name
+func (s *TwoPercentDown) Name() string {
+	return TwoPercentDownName
+}
+
+func (s *TwoPercentDown) Run(ctx context.Context) (*Result, error) {
+	result := NewResult()
+	if lastBuyDate := s.state.Get(stateLastBuyDate); lastBuyDate != "" {
+		result.Diagnostics[stateLastBuyDate] = lastBuyDate
+	}
+
+	// Step 1: Get yesterday's close of the ticker
+	yesterdayClose, err := s.broker.GetLastTradingDayClose(ctx, s.params.Ticker)
 	if err != nil {
-		return s.notifier.Failure(fmt.Sprintf("failed to get yesterday's close for %s: %w", ticker, err))
+		return result, fmt.Errorf("failed to get yesterday's close for %s: %w", s.params.Ticker, err)
 	}
+	result.Diagnostics["yesterday_close"] = yesterdayClose
 
 	// Step 2: Get latest quote now
-	currentPrice, err := s.broker.GetLatestQuote(ctx, ticker)
+	currentPrice, err := s.broker.GetLatestQuote(ctx, s.params.Ticker)
 	if err != nil {
-		return s.notifier.Failure(fmt.Sprintf("failed to get latest quote for %s: %w", ticker, err))
+		return result, fmt.Errorf("failed to get latest quote for %s: %w", s.params.Ticker, err)
 	}
+	result.Diagnostics["current_price"] = currentPrice
 
 	// Step 3: Calculate gap down if any
-	changePercent := ((currentPrice - yesterdayClose) / yesterdayClose) * 100
+	changePercent := yesterdayClose.PercentChange(currentPrice)
+	gapDown := changePercent <= s.params.GapThreshold
+	result.Signals = append(result.Signals, Signal{
+		Name:      "change_percent",
+		Value:     changePercent,
+		Threshold: s.params.GapThreshold,
+		Triggered: gapDown,
+	})
 
-	// Step 4: If it's 2% or more gap down, print it's a gapdown
-	if changePercent <= -2.0 {
-		log.Printf("GAP DOWN DETECTED: %s is down %.2f%% from yesterday's close (Current: $%.2f, Yesterday: $%.2f)",
-			ticker, -changePercent, currentPrice, yesterdayClose)
+	// Step 4: If it's not a gap down past the threshold, there's nothing to do
+	if !gapDown {
+		result.Decision = DecisionNoSignal
+		result.Message = fmt.Sprintf("No significant gap down: %s is %+.2f%% from yesterday's close (Current: $%s, Yesterday: $%s)",
+			s.params.Ticker, changePercent, currentPrice, yesterdayClose)
+		log.Printf("[%s] %s", s.id, result.Message)
+		return result, nil
+	}
 
-		// Check current number of QQQ call options
-		openOptions, err := s.broker.GetOptionsPositions(ctx, ticker)
-		if err != nil {
-			return s.notifier.Failure(fmt.Sprintf("failed to get QQQ option positions: %w", err))
-		}
+	log.Printf("[%s] GAP DOWN DETECTED: %s is down %.2f%% from yesterday's close (Current: $%s, Yesterday: $%s)",
+		s.id, s.params.Ticker, -changePercent, currentPrice, yesterdayClose)
 
-		if len(openOptions) >= s.maxActiveOptions {
-			log.Printf("Already have maximum number of active options (%d). Skipping.", s.maxActiveOptions)
-			return s.notifier.MaxActiveOptions(fmt.Sprintf("Already have maximum number of active options (%d)", s.maxActiveOptions))
-		}
+	// The client order ID is the same for every run acting on today's gap down, so a repeated
+	// invocation finds the earlier order instead of buying a second LEAP
+	day := tradingDay(s.broker.Now())
+	clientOrderID := alpaca.NewClientOrderID(s.id, day, "gap-down")
+	result.Diagnostics["client_order_id"] = clientOrderID
 
-		log.Printf("Current active options: %d/%d", len(openOptions), s.maxActiveOptions)
+	acted, err := s.alreadyActed(ctx, day, clientOrderID)
+	if err != nil {
+		return result, err
+	}
+	if acted != "" {
+		result.Decision = DecisionSkippedAlreadyActed
+		result.Message = fmt.Sprintf("%s gap down %.2f%% but %s", s.params.Ticker, changePercent, acted)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return result, nil
+	}
 
-		// Step 5: Get the lowest strike call LEAPS option with delta >= 0.6
-		optionSymbol, optionSnapshot, err := s.broker.GetCallLeapsByDelta(ctx, ticker, 0.60)
-		if err != nil {
-			return s.notifier.Failure(fmt.Sprintf("failed to get call LEAPS option for %s: %w", ticker, err))
-		}
-		log.Printf("Found option symbol: %s\n", optionSymbol)
-		log.Printf("Found option snapshot: %+v\n", optionSnapshot)
+	// Check current number of call options held by this instance
+	openOptions, err := s.broker.GetInstanceOptionsPositions(ctx, s.id, s.params.Ticker)
+	if err != nil {
+		return result, fmt.Errorf("failed to get %s option positions: %w", s.params.Ticker, err)
+	}
+	result.Diagnostics["open_positions"] = len(openOptions)
+	result.Diagnostics["max_active_options"] = s.params.MaxActiveOptions
 
-		// Calculate investment size for this option
-		investmentSize, err := s.calculateInvestmentSize(ctx)
-		if err != nil {
-			return s.notifier.Failure(fmt.Sprintf("failed to calculate investment size: %w", err))
-		}
+	if len(openOptions) >= s.params.MaxActiveOptions {
+		result.Decision = DecisionSkippedMaxPositions
+		result.Message = fmt.Sprintf("%s gap down %.2f%% but already have maximum number of active options (%d)",
+			s.params.Ticker, changePercent, s.params.MaxActiveOptions)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return result, nil
+	}
 
-		log.Printf("Will invest $%.2f in option %s", investmentSize, optionSymbol)
+	log.Printf("[%s] Current active options: %d/%d", s.id, len(openOptions), s.params.MaxActiveOptions)
 
-		// Place the order
-		order, err := s.broker.PlaceOptionLimitOrderWithTakeProfit(ctx, investmentSize, optionSymbol, optionSnapshot.LatestQuote, 50.0)
-		if err != nil {
-			return fmt.Errorf("failed to place order: %w", err)
+	// Step 5: Get the lowest strike call LEAPS option with delta >= MinDelta
+	optionSymbol, optionSnapshot, err := s.broker.GetCallLeapsByDelta(ctx, s.params.Ticker, s.params.MinDelta, s.params.LeapsMinMonths)
+	if err != nil {
+		return result, fmt.Errorf("failed to get call LEAPS option for %s: %w", s.params.Ticker, err)
+	}
+	log.Printf("[%s] Found option symbol: %s\n", s.id, optionSymbol)
+	log.Printf("[%s] Found option snapshot: %+v\n", s.id, optionSnapshot)
+	result.Diagnostics["option_symbol"] = optionSymbol
+	if optionSnapshot.Greeks != nil {
+		result.Diagnostics["option_delta"] = optionSnapshot.Greeks.Delta
+	}
+	if quote := optionSnapshot.LatestQuote; quote != nil {
+		bid, ask := money.NewFromFloat(quote.BidPrice), money.NewFromFloat(quote.AskPrice)
+		result.Diagnostics["option_bid"] = bid
+		result.Diagnostics["option_ask"] = ask
+		result.Diagnostics["spread_percent"] = pricing.SpreadPercent(bid, ask)
+	}
+
+	// Calculate investment size for this option
+	investmentSize, err := s.calculateInvestmentSize(ctx, len(openOptions), currentPrice, optionSnapshot)
+	if err != nil {
+		return result, fmt.Errorf("failed to calculate investment size: %w", err)
+	}
+	result.Diagnostics["sizing"] = string(s.params.Sizing.Method)
+	result.Diagnostics["investment_size"] = investmentSize
+
+	if !investmentSize.IsPositive() {
+		result.Decision = DecisionSkippedNoSize
+		result.Message = fmt.Sprintf("%s gap down %.2f%% but %s sizing allots nothing to %s",
+			s.params.Ticker, changePercent, s.params.Sizing.Method, optionSymbol)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return result, nil
+	}
+
+	// The order buys the whole contracts the size covers at the ask, so a size below one contract is no order
+	if quote := optionSnapshot.LatestQuote; quote != nil && quote.AskPrice > 0 {
+		contractCost := money.NewFromFloat(quote.AskPrice).MulInt(money.ContractMultiplier)
+		contracts := investmentSize.Ratio(contractCost).Floor()
+		result.Diagnostics["contracts"] = contracts.IntPart()
+		if !contracts.IsPositive() {
+			result.Decision = DecisionSkippedNoSize
+			result.Message = fmt.Sprintf("%s gap down %.2f%% but %s sizing allots $%s, less than one contract of %s at $%s",
+				s.params.Ticker, changePercent, s.params.Sizing.Method, investmentSize, optionSymbol, contractCost)
+			log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+			return result, nil
 		}
-		return s.notifier.OrderPlaced(fmt.Sprintf("QQQ gap down %.2f%%. Order ID: %s", changePercent, order.ID))
+	}
+
+	log.Printf("[%s] Will invest $%s in option %s", s.id, investmentSize, optionSymbol)
+
+	// Claim the client order ID, so an overlapping run that passed the checks above doesn't order too
+	claimed, err := claim(ctx, s.claims, clientOrderID, s.broker.Now())
+	if err != nil {
+		return result, err
+	}
+	if !claimed {
+		result.Decision = DecisionSkippedAlreadyActed
+		result.Message = fmt.Sprintf("%s gap down %.2f%% but another run already claimed order %s", s.params.Ticker, changePercent, clientOrderID)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return result, nil
+	}
 
+	// Place the order
+	order, clientExits, err := s.broker.PlaceOptionBracketOrder(ctx, clientOrderID, investmentSize, optionSymbol, optionSnapshot.LatestQuote, s.params.Pricing, s.exits())
+	if err != nil {
+		release(ctx, s.claims, clientOrderID)
+	}
+	if errors.Is(err, pricing.ErrSpreadTooWide) {
+		result.Decision = DecisionSkippedWideSpread
+		result.Message = fmt.Sprintf("%s gap down %.2f%% but %s: %v", s.params.Ticker, changePercent, optionSymbol, err)
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return result, nil
+	}
+	var rejection *risk.Rejection
+	if errors.As(err, &rejection) {
+		result.Decision = DecisionSkippedRiskLimit
+		result.Message = fmt.Sprintf("%s gap down %.2f%% but %v", s.params.Ticker, changePercent, rejection)
+		result.Diagnostics["risk_limit"] = rejection.Limit
+		log.Printf("[%s] %s. Skipping.", s.id, result.Message)
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to place order: %w", err)
+	}
+
+	result.Decision = DecisionOrdered
+	summary := NewOrder(order)
+	summary.ClientExits = clientExits
+	result.Orders = append(result.Orders, summary)
+	if result.DryRun() {
+		release(ctx, s.claims, clientOrderID)
 	} else {
-		log.Printf("No significant gap down: %s is %+.2f%% from yesterday's close (Current: $%.2f, Yesterday: $%.2f)",
-			ticker, changePercent, currentPrice, yesterdayClose)
-		return s.notifier.NoGapDown(fmt.Sprintf("No significant gap down: %s is %+.2f%% from yesterday's close (Current: $%.2f, Yesterday: $%.2f)",
-			ticker, changePercent, currentPrice, yesterdayClose))
+		s.state.Set(stateLastBuyDate, day.Format("2006-01-02"))
 	}
+	result.Message = fmt.Sprintf("%s gap down %.2f%%. Order ID: %s", s.params.Ticker, changePercent, order.ID)
+	return result, nil
+}
 
-	return nil
+// exits returns the exits attached to each entry
+func (s *TwoPercentDown) exits() bracket.Exits {
+	return bracket.Exits{TakeProfitPercent: s.params.TakeProfitPercent, StopLoss: s.params.StopLoss}
 }
 
-// calculateInvestmentSize determines the investment size per option based on remaining spots and buying power
-func (s *TwoPercentDown) calculateInvestmentSize(ctx context.Context) (float64, error) {
-	// Get all QQQ option positions
-	openOptions, err := s.broker.GetOptionsPositions(ctx, ticker)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get QQQ option positions: %w", err)
+// alreadyActed checks the persisted state, the journal and the broker for an order this instance
+// already placed on day, returning why it counts as acted on, or "" if it hasn't acted
+func (s *TwoPercentDown) alreadyActed(ctx context.Context, day time.Time, clientOrderID string) (string, error) {
+	if s.state.Get(stateLastBuyDate) == day.Format("2006-01-02") {
+		return "already bought today", nil
 	}
 
-	// Calculate remaining active option spots
-	remainingSpots := s.maxActiveOptions - len(openOptions)
-	if remainingSpots <= 0 {
-		return 0, fmt.Errorf("no remaining active option spots available")
+	if s.journal != nil {
+		ordered, err := s.journal.OrderedOn(ctx, s.id, day)
+		if err != nil {
+			return "", fmt.Errorf("failed to check the journal for today's orders: %w", err)
+		}
+		if ordered {
+			return "the journal already records an order today", nil
+		}
 	}
 
-	// Get non-marginable buying power
-	buyingPower, err := s.broker.GetNonMarginableBuyingPower(ctx)
+	order, err := s.broker.GetOrderByClientOrderID(ctx, clientOrderID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get non-marginable buying power: %w", err)
+		return "", fmt.Errorf("failed to check for an existing order %s: %w", clientOrderID, err)
 	}
+	if order != nil {
+		return fmt.Sprintf("order %s (%s) was already submitted today", order.ID, order.Status), nil
+	}
+	return "", nil
+}
 
-	// Calculate investment size per option
-	investmentSize := buyingPower / float64(remainingSpots)
+// calculateInvestmentSize sizes an entry in the option with the instance's sizing model
+func (s *TwoPercentDown) calculateInvestmentSize(ctx context.Context, openOptions int, underlyingPrice money.Money, option *marketdata.OptionSnapshot) (money.Money, error) {
+	candidate := sizing.Candidate{
+		OpenSlots:         s.params.MaxActiveOptions - openOptions,
+		ImpliedVolatility: option.ImpliedVolatility,
+		UnderlyingPrice:   underlyingPrice,
+	}
+	if option.LatestQuote != nil {
+		candidate.Ask = money.NewFromFloat(option.LatestQuote.AskPrice)
+	}
+	if option.Greeks != nil {
+		candidate.Delta = option.Greeks.Delta
+	}
+
+	size, err := s.params.Sizing.Size(ctx, s.broker, candidate)
+	if err != nil {
+		return money.Zero, err
+	}
 
-	log.Printf("Investment calculation: Buying power $%.2f / %d remaining spots = $%.2f per trade",
-		buyingPower, remainingSpots, investmentSize)
+	log.Printf("[%s] Investment calculation (%s): %s = $%s per trade", s.id, s.params.Sizing.Method, size.Basis, size.Amount)
 
-	return investmentSize, nil
+	return size.Amount, nil
 }
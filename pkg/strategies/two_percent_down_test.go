@@ -0,0 +1,298 @@
+package strategies
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+	"github.com/vignesh-goutham/AthenaX/pkg/alpaca"
+	"github.com/vignesh-goutham/AthenaX/pkg/bracket"
+	"github.com/vignesh-goutham/AthenaX/pkg/broker/sim"
+	"github.com/vignesh-goutham/AthenaX/pkg/money"
+	"github.com/vignesh-goutham/AthenaX/pkg/pricing"
+	"github.com/vignesh-goutham/AthenaX/pkg/sizing"
+)
+
+const (
+	testInstance = "qqq-gap"
+	// testLeap is a QQQ call expiring more than 11 months after testDay, priced at $10.00
+	testLeap = "QQQ260320C00450000"
+)
+
+// testDay is the trading day the tests run on, 09:35 exchange time
+var testDay = time.Date(2025, 3, 4, 9, 35, 0, 0, exchangeLocation)
+
+// newGapDownBroker returns a simulated broker on testDay with QQQ closing at $500 the day before and
+// trading at price, one call LEAP and $25,000 of buying power
+func newGapDownBroker(t *testing.T, price float64) *sim.Broker {
+	t.Helper()
+	b := sim.NewBroker(testDay)
+	b.AddDailyBars("QQQ", marketdata.Bar{Timestamp: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), Close: 500})
+	b.SetQuote("QQQ", price, price)
+	if err := b.SetOption(testLeap, marketdata.OptionSnapshot{
+		LatestQuote: &marketdata.OptionQuote{BidPrice: 9.90, AskPrice: 10.00},
+		Greeks:      &marketdata.OptionGreeks{Delta: 0.70},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b.SetBuyingPower(money.NewFromInt(25000))
+	return b
+}
+
+// holdOptions gives the instance n filled positions bought on earlier days
+func holdOptions(t *testing.T, b *sim.Broker, instanceID string, n int) {
+	t.Helper()
+	for i := range n {
+		symbol := "QQQ260320C0046" + string(rune('0'+i)) + "000"
+		if err := b.SetOption(symbol, marketdata.OptionSnapshot{LatestQuote: &marketdata.OptionQuote{BidPrice: 9, AskPrice: 9.10}}); err != nil {
+			t.Fatal(err)
+		}
+		clientOrderID := alpaca.NewClientOrderID(instanceID, testDay.AddDate(0, 0, -i-1), "gap-down")
+		if _, err := b.PlaceOptionBuyOrder(context.Background(), clientOrderID, symbol, decimal.NewFromInt(1), money.NewFromInt(9)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testTwoPercentDownParams() TwoPercentDownParams {
+	return TwoPercentDownParams{
+		Ticker:            "QQQ",
+		GapThreshold:      -2,
+		MinDelta:          0.60,
+		Pricing:           pricing.PercentOfAsk(100),
+		TakeProfitPercent: 50,
+		StopLoss:          bracket.StopLoss{Kind: bracket.StopNone},
+		LeapsMinMonths:    11,
+		MaxActiveOptions:  5,
+		Sizing:            sizing.EqualSlot(),
+	}
+}
+
+func TestTwoPercentDown(t *testing.T) {
+	tests := []struct {
+		name  string
+		price float64
+		// setup adjusts the broker, params and state before the run
+		setup    func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State)
+		decision Decision
+		// qty is the number of contracts the run buys, 0 if it places no order
+		qty     int64
+		wantErr bool
+	}{
+		{
+			name:     "no gap down",
+			price:    495,
+			decision: DecisionNoSignal,
+		},
+		{
+			name:     "gap down at the threshold",
+			price:    490,
+			decision: DecisionOrdered,
+			// $25,000 over 5 open slots buys 5 contracts at $1,000
+			qty: 5,
+		},
+		{
+			name:     "gap up",
+			price:    520,
+			decision: DecisionNoSignal,
+		},
+		{
+			name:  "deeper threshold not reached",
+			price: 490,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.GapThreshold = -3
+			},
+			decision: DecisionNoSignal,
+		},
+		{
+			name:  "max options held",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				holdOptions(t, b, testInstance, 5)
+			},
+			decision: DecisionSkippedMaxPositions,
+		},
+		{
+			name:  "another instance's options don't count",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				holdOptions(t, b, "spy-gap", 5)
+				b.SetBuyingPower(money.NewFromInt(25000))
+			},
+			decision: DecisionOrdered,
+			qty:      5,
+		},
+		{
+			name:  "lower max options",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.MaxActiveOptions = 2
+				holdOptions(t, b, testInstance, 2)
+			},
+			decision: DecisionSkippedMaxPositions,
+		},
+		{
+			name:  "equal slot divides over the remaining slots",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				holdOptions(t, b, testInstance, 3)
+				b.SetBuyingPower(money.NewFromInt(6000))
+			},
+			decision: DecisionOrdered,
+			// $6,000 over the 2 open slots buys 3 contracts
+			qty: 3,
+		},
+		{
+			name:  "equal slot below one contract",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				b.SetBuyingPower(money.NewFromInt(4000))
+			},
+			// $800 a slot doesn't cover one contract at $1,000, so there is nothing to order
+			decision: DecisionSkippedNoSize,
+		},
+		{
+			name:  "fixed dollar",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodFixedDollar, Amount: money.NewFromInt(2500)}
+			},
+			decision: DecisionOrdered,
+			qty:      2,
+		},
+		{
+			name:  "fixed dollar capped at buying power",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodFixedDollar, Amount: money.NewFromInt(100000)}
+			},
+			decision: DecisionOrdered,
+			qty:      25,
+		},
+		{
+			name:  "fixed dollar below one contract",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodFixedDollar, Amount: money.NewFromInt(500)}
+			},
+			// $500 doesn't cover one contract at $1,000, so there is nothing to order
+			decision: DecisionSkippedNoSize,
+		},
+		{
+			name:  "delta dollar",
+			price: 490,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				// 0.70 delta × 100 shares × $490 is $34,300 of exposure per contract
+				params.Sizing = sizing.Model{Method: sizing.MethodDeltaDollar, DeltaDollars: money.NewFromInt(70000)}
+			},
+			decision: DecisionOrdered,
+			qty:      2,
+		},
+		{
+			name:  "kelly capped",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				// Half of the 40% Kelly fraction is capped at 10% of the $25,000 equity
+				params.Sizing = sizing.Model{Method: sizing.MethodKelly, KellyWinRate: 0.6, KellyPayoff: 2, KellyFraction: 0.5, KellyCapPercent: 10}
+			},
+			decision: DecisionOrdered,
+			qty:      2,
+		},
+		{
+			name:  "kelly without an edge",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.Sizing = sizing.Model{Method: sizing.MethodKelly, KellyWinRate: 0.3, KellyPayoff: 1, KellyFraction: 1, KellyCapPercent: 25}
+			},
+			decision: DecisionSkippedNoSize,
+		},
+		{
+			name:  "no LEAP",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				b.ClearOptionChain("QQQ")
+			},
+			wantErr: true,
+		},
+		{
+			name:  "already bought today",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				state.Set(stateLastBuyDate, "2025-03-04")
+			},
+			decision: DecisionSkippedAlreadyActed,
+		},
+		{
+			name:  "spread too wide",
+			price: 480,
+			setup: func(t *testing.T, b *sim.Broker, params *TwoPercentDownParams, state *State) {
+				params.Pricing.MaxSpreadPercent = 0.5
+			},
+			decision: DecisionSkippedWideSpread,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newGapDownBroker(t, tt.price)
+			params := testTwoPercentDownParams()
+			state := NewState(nil)
+			if tt.setup != nil {
+				tt.setup(t, b, &params, state)
+			}
+			before := len(b.Orders())
+
+			s := NewTwoPercentDown(testInstance, b, params)
+			s.SetState(state)
+			result, err := s.Run(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if result.Decision != tt.decision {
+				t.Fatalf("Decision = %s, want %s: %s", result.Decision, tt.decision, result.Message)
+			}
+
+			placed := b.Orders()[before:]
+			if tt.qty == 0 {
+				if len(placed) != 0 {
+					t.Fatalf("placed %d orders, want none", len(placed))
+				}
+				return
+			}
+			if len(placed) != 1 {
+				t.Fatalf("placed %d orders, want 1", len(placed))
+			}
+			order := placed[0]
+			if order.Symbol != testLeap || order.ClientOrderID != "qqq-gap.20250304.gap-down" {
+				t.Errorf("ordered %s as %s, want %s as qqq-gap.20250304.gap-down", order.Symbol, order.ClientOrderID, testLeap)
+			}
+			if !order.Qty.Equal(decimal.NewFromInt(tt.qty)) {
+				t.Errorf("Qty = %s, want %d", order.Qty, tt.qty)
+			}
+			if got := state.Get(stateLastBuyDate); got != "2025-03-04" {
+				t.Errorf("last buy date = %q, want 2025-03-04", got)
+			}
+		})
+	}
+}
+
+func TestTwoPercentDownOrdersOncePerDay(t *testing.T) {
+	ctx := context.Background()
+	b := newGapDownBroker(t, 480)
+
+	first, err := NewTwoPercentDown(testInstance, b, testTwoPercentDownParams()).Run(ctx)
+	if err != nil || first.Decision != DecisionOrdered {
+		t.Fatalf("first Run() = %v, %v; want ordered", first.Decision, err)
+	}
+	// A second run with fresh state finds the first run's order at the broker
+	second, err := NewTwoPercentDown(testInstance, b, testTwoPercentDownParams()).Run(ctx)
+	if err != nil || second.Decision != DecisionSkippedAlreadyActed {
+		t.Fatalf("second Run() = %v, %v; want skipped-already-acted", second.Decision, err)
+	}
+	if n := len(b.Orders()); n != 1 {
+		t.Errorf("placed %d orders, want 1", n)
+	}
+}